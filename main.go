@@ -1,56 +1,398 @@
 package main
 
 import (
+	"context"
 	"database/sql"
+	"flag"
+	"fmt"
 	"log"
+	"log/slog"
+	"os"
+	"os/signal"
+	"syscall"
+	"time"
 
 	"github.com/gofiber/fiber/v2"
-	"github.com/gofiber/fiber/v2/middleware/logger"
 	_ "github.com/lib/pq"
 
+	"sass-billing-service/src/cli"
 	"sass-billing-service/src/config"
 	"sass-billing-service/src/controllers"
+	"sass-billing-service/src/helpers"
+	"sass-billing-service/src/jobs"
+	"sass-billing-service/src/jwks"
+	"sass-billing-service/src/logging"
+	"sass-billing-service/src/metrics"
+	"sass-billing-service/src/migrations"
+	"sass-billing-service/src/ratelimit"
 	"sass-billing-service/src/repositories"
 	router "sass-billing-service/src/routes"
 	"sass-billing-service/src/services"
+	"sass-billing-service/src/tracing"
 )
 
+const usage = `usage: sass-billing-service [command] [flags]
+
+commands:
+  serve [--store postgres|memory]    start the HTTP API (default); memory needs no database
+  migrate up|down [n]|status|to <n>  apply or revert database migrations
+  migrate baseline <n>               mark migrations up to <n> as applied without running them
+  invoice create|get|list|void       manage invoices of a tenant
+  subscription renew [--dry-run]     invoice the next period of subscriptions that are due
+  export                             dump invoices as CSV, JSON Lines or Parquet
+  reconcile                          check the ledger against invoice statuses
+
+Run "<command> -h" for the flags of each command; most accept --tenant and --json.
+`
+
+// commands son los subcomandos que comparten configuración y servicios con el servidor
+var commands = map[string]bool{"serve": true, "migrate": true, "invoice": true, "subscription": true, "export": true, "reconcile": true}
+
+// backgroundJob es una tarea que informa de su estado a /readyz y avisa al terminar
+type backgroundJob interface {
+	Status() jobs.Status
+	Done() <-chan struct{}
+}
+
 func main() {
-	// Cargar configuración
-	cfg := config.LoadConfig()
+	command, args := "serve", os.Args[1:]
+	if len(args) > 0 {
+		command, args = args[0], args[1:]
+	}
+	if !commands[command] {
+		fmt.Fprint(os.Stderr, usage)
+		if command == "help" || command == "-h" || command == "--help" {
+			return
+		}
+		os.Exit(2)
+	}
+
+	// serve --store tiene prioridad sobre STORE del fichero y del entorno
+	overrides := map[string]string{}
+	if command == "serve" {
+		flags := flag.NewFlagSet("serve", flag.ExitOnError)
+		store := flags.String("store", "", "data store: postgres or memory (overrides STORE)")
+		flags.Parse(args)
+		overrides["STORE"] = *store
+	}
 
-	// Conectar a PostgreSQL
-	db, err := sql.Open("postgres",
-		"host="+cfg.DBHost+" port="+cfg.DBPort+" user="+cfg.DBUser+
-			" password="+cfg.DBPassword+" dbname="+cfg.DBName+" sslmode=disable")
+	// Cargar configuración: valores por defecto, fichero opcional (CONFIG_FILE o .env), entorno y flags
+	cfg, err := config.Load(os.Getenv("CONFIG_FILE"), overrides)
 	if err != nil {
-		log.Fatalf("Error connecting to database: %v", err)
+		log.Fatalf("Error loading configuration: %v", err)
+	}
+
+	// Logs estructurados con request_id, tenant_id y principal; también recoge la salida del paquete log
+	logger, err := logging.New(os.Stderr, cfg.LogFormat, cfg.LogLevel)
+	if err != nil {
+		log.Fatalf("Error configuring logging: %v", err)
+	}
+	slog.SetDefault(logger)
+
+	// Trazas de OpenTelemetry; los spans pendientes se envían al terminar. En los subcomandos
+	// el exportador stdout escribe en stderr para no mezclarse con su salida
+	traceOutput := os.Stdout
+	if command != "serve" {
+		traceOutput = os.Stderr
+	}
+	shutdownTracing, err := tracing.Setup(context.Background(), cfg.TracingExporter, cfg.TracingEndpoint, traceOutput)
+	if err != nil {
+		log.Fatalf("Error configuring tracing: %v", err)
+	}
+	defer func() {
+		if err := shutdownTracing(context.Background()); err != nil {
+			slog.Error("error flushing traces", "error", err)
+		}
+	}()
+
+	// Los subcomandos trabajan sobre datos que deben sobrevivir al proceso
+	if cfg.Store == "memory" && command != "serve" {
+		log.Fatalf("Error running %s: it needs STORE=postgres", command)
 	}
-	defer db.Close()
 
-	// Verificar conexión
-	if err := db.Ping(); err != nil {
-		log.Fatalf("Error pinging database: %v", err)
+	var (
+		db              *sql.DB
+		migrationRunner *migrations.Runner
+		store           backend
+	)
+	if cfg.Store == "memory" {
+		slog.Warn("using the in-memory store, data is lost when the process stops")
+		store = newMemoryBackend()
+	} else {
+		// Conectar a PostgreSQL
+		db, err = sql.Open("postgres", cfg.DSN())
+		if err != nil {
+			log.Fatalf("Error connecting to database: %v", err)
+		}
+		defer db.Close()
+
+		// Verificar conexión
+		if err := db.Ping(); err != nil {
+			log.Fatalf("Error pinging database: %v", err)
+		}
+
+		// Migraciones embebidas en el binario
+		migrationRunner, err = migrations.NewRunner(db, migrations.Files)
+		if err != nil {
+			log.Fatalf("Error loading migrations: %v", err)
+		}
+
+		if command == "serve" && cfg.AutoMigrate {
+			applied, err := migrationRunner.Up(context.Background())
+			if err != nil {
+				log.Fatalf("Error applying migrations: %v", err)
+			}
+			slog.Info("applied migrations", "count", len(applied))
+		}
+
+		store = newPostgresBackend(db, migrationRunner, cfg)
 	}
 
-	// Inicializar repositorio, servicio y controlador
-	invoiceRepo := repositories.NewInvoiceRepository(db)
-	invoiceService := services.NewInvoiceService(invoiceRepo)
+	// Inicializar servicios y controladores sobre los repositorios del almacén elegido
+	invoiceService := services.NewInvoiceService(store.invoices, store.tx)
 	invoiceController := controllers.NewInvoiceController(invoiceService)
 
+	ledgerService := services.NewLedgerService(store.ledger)
+	ledgerController := controllers.NewLedgerController(ledgerService)
+
+	revenueService := services.NewRevenueService(store.revenue)
+	metricsService := services.NewMetricsService(store.metrics, cfg.ReportingCurrency)
+	agingService := services.NewAgingService(store.aging)
+	reportController := controllers.NewReportController(revenueService, metricsService, agingService)
+
+	exportService := services.NewExportService(store.export)
+	exportController := controllers.NewExportController(exportService)
+
+	// Subcomandos: usan los mismos servicios que la API y terminan sin levantar el servidor
+	ctx := context.Background()
+	switch command {
+	case "migrate":
+		err = cli.RunMigrate(ctx, migrationRunner, args, os.Stdout)
+	case "invoice":
+		err = cli.RunInvoice(ctx, invoiceService, args, os.Stdout)
+	case "subscription":
+		subscriptionService := services.NewSubscriptionService(repositories.NewSubscriptionRepository(db), invoiceService)
+		err = cli.RunSubscription(ctx, subscriptionService, args, os.Stdout)
+	case "export":
+		err = cli.RunExport(ctx, exportService, args, os.Stdout)
+	case "reconcile":
+		err = cli.RunReconcile(ctx, ledgerService, args, os.Stdout)
+	}
+	if err != nil {
+		log.Fatalf("Error running %s: %v", command, err)
+	}
+	if command != "serve" {
+		return
+	}
+
+	apiKeyService := services.NewAPIKeyService(store.apiKeys)
+	apiKeyController := controllers.NewAPIKeyController(apiKeyService)
+
+	// Verificador de JWT: JWKS del proveedor de identidad si está configurado, si no el secreto HMAC
+	var keySet *jwks.KeySet
+	if cfg.JWKSSource != "" {
+		keySet = jwks.NewKeySet(cfg.JWKSSource, cfg.JWKSRefreshInterval, cfg.JWKSMinRefreshInterval)
+	}
+	tokenVerifier, err := helpers.NewJWTVerifier(helpers.JWTVerifierOptions{
+		Secret:     []byte(cfg.JWTSecret),
+		KeySet:     keySet,
+		Algorithms: cfg.JWTAlgorithms,
+		Issuer:     cfg.JWTIssuer,
+		Audience:   cfg.JWTAudience,
+		ClockSkew:  cfg.JWTClockSkew,
+	})
+	if err != nil {
+		log.Fatalf("Error configuring JWT verification: %v", err)
+	}
+	auth := helpers.NewAuthMiddleware(tokenVerifier, apiKeyService)
+
+	idempotency := helpers.IdempotencyMiddleware(store.idempotency, cfg.IdempotencyKeyTTL)
+
+	// Limitador: en memoria por réplica o en PostgreSQL, compartido entre réplicas
+	var rateLimitStore helpers.RateLimitStore = ratelimit.NewMemoryStore()
+	var rateLimitRepo *repositories.RateLimitRepository
+	if cfg.RateLimitBackend == "postgres" {
+		rateLimitRepo = repositories.NewRateLimitRepository(db)
+		rateLimitStore = rateLimitRepo
+	}
+	rateLimitPolicy, err := newRateLimitPolicy(cfg.RateLimit)
+	if err != nil {
+		log.Fatalf("Error configuring rate limits: %v", err)
+	}
+	rateLimit := helpers.RateLimitMiddleware(rateLimitStore, rateLimitPolicy)
+
+	// Tareas en segundo plano: cierre mensual de ingresos y limpieza de claves de idempotencia.
+	// Tienen su propio contexto para pararlas después de drenar las peticiones HTTP.
+	jobsCtx, stopJobs := context.WithCancel(context.Background())
+	revenueCloseJob := jobs.NewRevenueCloseJob(revenueService, time.Hour)
+	idempotencyCleanupJob := jobs.NewIdempotencyCleanupJob(store.idempotency, time.Hour)
+	revenueCloseJob.Start(jobsCtx)
+	idempotencyCleanupJob.Start(jobsCtx)
+	workers := []backgroundJob{revenueCloseJob, idempotencyCleanupJob}
+	if rateLimitRepo != nil {
+		rateLimitCleanupJob := jobs.NewRateLimitCleanupJob(rateLimitRepo, 10*time.Minute)
+		rateLimitCleanupJob.Start(jobsCtx)
+		workers = append(workers, rateLimitCleanupJob)
+	}
+
+	healthWorkers := make([]controllers.Worker, len(workers))
+	for i, worker := range workers {
+		healthWorkers[i] = worker
+	}
+	healthController := controllers.NewHealthController(store.db, store.schema, healthWorkers...)
+
 	// Crear aplicación Fiber
-	app := fiber.New()
-	app.Use(logger.New())
+	app := fiber.New(fiber.Config{ErrorHandler: helpers.ErrorHandler})
+	app.Use(metrics.Middleware())
+	app.Use(tracing.Middleware())
+	app.Use(helpers.RequestID())
+	app.Use(helpers.AccessLog())
+	app.Use(helpers.HandleErrors())
+
+	// Métricas del pool de conexiones; /metrics se registra con el resto de rutas
+	if db != nil {
+		if err := metrics.RegisterDB(db, cfg.DBName); err != nil {
+			log.Fatalf("Error registering database metrics: %v", err)
+		}
+	}
 
 	// Rutas
+	router.SetupHealthRoutes(app, healthController)
+	router.SetupMetricsRoutes(app)
 	api := app.Group("/api")
-	router.SetupRoutes(api, invoiceController)
+	router.SetupDocsRoutes(api)
+	router.SetupRoutes(api, invoiceController, ledgerController, reportController, exportController, apiKeyController, auth, rateLimit, idempotency)
 
 	// Iniciar servidor
-	port := ":" + cfg.ServerPort
-	if err := app.Listen(port); err != nil {
+	signals, stopSignals := signal.NotifyContext(context.Background(), syscall.SIGINT, syscall.SIGTERM)
+	defer stopSignals()
+
+	listenErr := make(chan error, 1)
+	go func() {
+		listenErr <- app.Listen(":" + cfg.ServerPort)
+	}()
+	slog.Info("server running", "port", cfg.ServerPort)
+
+	select {
+	case err := <-listenErr:
 		log.Fatalf("Error starting server: %v", err)
+	case <-signals.Done():
+	}
+
+	// Apagado ordenado: dejar de estar listo, esperar a que el balanceador lo note, drenar las
+	// peticiones en curso y después parar las tareas. Una segunda señal termina el proceso sin esperar.
+	stopSignals()
+	healthController.ShuttingDown()
+	slog.Info("shutting down, waiting for readiness to propagate", "delay", cfg.ShutdownDrainDelay.String())
+	time.Sleep(cfg.ShutdownDrainDelay)
+
+	slog.Info("draining requests", "timeout", cfg.ShutdownTimeout.String())
+	if err := app.ShutdownWithTimeout(cfg.ShutdownTimeout); err != nil {
+		slog.Error("error draining HTTP server", "error", err)
+	}
+
+	stopJobs()
+	waitCtx, cancelWait := context.WithTimeout(context.Background(), cfg.ShutdownTimeout)
+	defer cancelWait()
+	for _, job := range workers {
+		select {
+		case <-job.Done():
+		case <-waitCtx.Done():
+			slog.Warn("timed out waiting for background jobs")
+		}
+	}
+
+	slog.Info("server stopped")
+}
+
+// backend reúne los repositorios de un almacén; los servicios no distinguen si son de PostgreSQL o en memoria
+type backend struct {
+	invoices    services.InvoiceStore
+	tx          services.Transactor
+	ledger      services.LedgerStore
+	revenue     services.RevenueStore
+	metrics     services.MetricsStore
+	aging       services.AgingStore
+	export      services.ExportStore
+	apiKeys     services.APIKeyStore
+	idempotency idempotencyStore
+	db          controllers.Pinger
+	schema      controllers.SchemaVersioner
+}
+
+// idempotencyStore sirve al middleware y a la tarea que borra las claves caducadas
+type idempotencyStore interface {
+	helpers.IdempotencyStore
+	jobs.ExpiredKeyDeleter
+}
+
+func newPostgresBackend(db *sql.DB, migrationRunner *migrations.Runner, cfg *config.Config) backend {
+	return backend{
+		invoices:    repositories.NewInvoiceRepository(db),
+		tx:          repositories.NewTxManager(db, cfg.DBIsolation, cfg.DBTxRetries),
+		ledger:      repositories.NewLedgerRepository(db),
+		revenue:     repositories.NewRevenueRepository(db),
+		metrics:     repositories.NewMetricsRepository(db),
+		aging:       repositories.NewAgingRepository(db),
+		export:      repositories.NewExportRepository(db),
+		apiKeys:     repositories.NewAPIKeyRepository(db),
+		idempotency: repositories.NewIdempotencyRepository(db),
+		db:          db,
+		schema:      migrationRunner,
+	}
+}
+
+// newMemoryBackend levanta la API completa sin PostgreSQL; cada repositorio en memoria mantiene
+// la semántica del de PostgreSQL
+func newMemoryBackend() backend {
+	memoryDB := repositories.NewMemoryDB()
+
+	return backend{
+		invoices:    repositories.NewMemoryInvoiceRepository(memoryDB),
+		tx:          repositories.NoTx{},
+		ledger:      repositories.NewMemoryLedgerRepository(memoryDB),
+		revenue:     repositories.NewMemoryRevenueRepository(memoryDB),
+		metrics:     repositories.NewMemoryMetricsRepository(memoryDB),
+		aging:       repositories.NewMemoryAgingRepository(memoryDB),
+		export:      repositories.NewMemoryExportRepository(memoryDB),
+		apiKeys:     repositories.NewMemoryAPIKeyRepository(memoryDB),
+		idempotency: repositories.NewMemoryIdempotencyRepository(),
+		db:          memoryDB,
+		schema:      memoryDB,
+	}
+}
+
+// newRateLimitPolicy traduce los límites de la configuración a la política del limitador
+func newRateLimitPolicy(limits config.RateLimits) (ratelimit.Policy, error) {
+	policy := ratelimit.Policy{
+		Routes:  make(map[string]ratelimit.Limit, len(limits.Routes)),
+		Tenants: make(map[string]ratelimit.Limit, len(limits.Tenants)),
+	}
+
+	parse := func(value string) (ratelimit.Limit, error) {
+		if value == "" {
+			return ratelimit.Limit{}, nil
+		}
+		return ratelimit.ParseLimit(value)
+	}
+
+	var err error
+	if policy.Default, err = parse(limits.Default); err != nil {
+		return ratelimit.Policy{}, err
+	}
+	if policy.Tenant, err = parse(limits.Tenant); err != nil {
+		return ratelimit.Policy{}, err
+	}
+	for route, value := range limits.Routes {
+		if policy.Routes[route], err = parse(value); err != nil {
+			return ratelimit.Policy{}, err
+		}
+	}
+	for tenantID, value := range limits.Tenants {
+		if policy.Tenants[tenantID], err = parse(value); err != nil {
+			return ratelimit.Policy{}, err
+		}
 	}
 
-	log.Printf("Server running on port %s", cfg.ServerPort)
+	return policy, nil
 }
@@ -0,0 +1,193 @@
+package apperror
+
+import (
+	"context"
+	"database/sql"
+	"database/sql/driver"
+	"errors"
+	"net"
+	"net/http"
+
+	"github.com/lib/pq"
+)
+
+// Code identifica el tipo de error para los clientes; a diferencia del mensaje, no cambia nunca
+type Code string
+
+const (
+	CodeValidationFailed    Code = "validation_failed"
+	CodeUnauthorized        Code = "unauthorized"
+	CodeForbidden           Code = "forbidden"
+	CodeNotFound            Code = "not_found"
+	CodeConflict            Code = "conflict"
+	CodeUnprocessable       Code = "unprocessable"
+	CodeRateLimited         Code = "rate_limited"
+	CodeInternal            Code = "internal"
+	CodeUpstreamUnavailable Code = "upstream_unavailable"
+)
+
+var statuses = map[Code]int{
+	CodeValidationFailed:    http.StatusBadRequest,
+	CodeUnauthorized:        http.StatusUnauthorized,
+	CodeForbidden:           http.StatusForbidden,
+	CodeNotFound:            http.StatusNotFound,
+	CodeConflict:            http.StatusConflict,
+	CodeUnprocessable:       http.StatusUnprocessableEntity,
+	CodeRateLimited:         http.StatusTooManyRequests,
+	CodeInternal:            http.StatusInternalServerError,
+	CodeUpstreamUnavailable: http.StatusServiceUnavailable,
+}
+
+// FieldError señala un campo concreto de la petición que no es válido
+type FieldError struct {
+	Field   string `json:"field"`
+	Message string `json:"message"`
+}
+
+// Error es un error de dominio con un mensaje apto para el cliente. La causa solo se usa
+// para los logs y para errors.Is/As; nunca se devuelve en la respuesta.
+type Error struct {
+	Code    Code
+	Message string
+	Fields  []FieldError
+	cause   error
+}
+
+func (e *Error) Error() string {
+	message := e.Message
+	// Los campos hacen legible el error fuera de HTTP, por ejemplo en la CLI
+	for i, field := range e.Fields {
+		separator := "; "
+		if i == 0 {
+			separator = ": "
+		}
+		message += separator + field.Field + " " + field.Message
+	}
+	if e.cause != nil {
+		return message + ": " + e.cause.Error()
+	}
+
+	return message
+}
+
+func (e *Error) Unwrap() error {
+	return e.cause
+}
+
+// Status devuelve el código HTTP que corresponde al error
+func (e *Error) Status() int {
+	if status, ok := statuses[e.Code]; ok {
+		return status
+	}
+
+	return http.StatusInternalServerError
+}
+
+// WithCause devuelve una copia del error que conserva la causa original
+func (e *Error) WithCause(cause error) *Error {
+	copy := *e
+	copy.cause = cause
+	return &copy
+}
+
+func New(code Code, message string) *Error {
+	return &Error{Code: code, Message: message}
+}
+
+func Validation(message string, fields ...FieldError) *Error {
+	return &Error{Code: CodeValidationFailed, Message: message, Fields: fields}
+}
+
+func Field(field, message string) FieldError {
+	return FieldError{Field: field, Message: message}
+}
+
+func Unauthorized(message string) *Error {
+	return New(CodeUnauthorized, message)
+}
+
+func Forbidden(message string) *Error {
+	return New(CodeForbidden, message)
+}
+
+func NotFound(message string) *Error {
+	return New(CodeNotFound, message)
+}
+
+func Conflict(message string) *Error {
+	return New(CodeConflict, message)
+}
+
+func Unprocessable(message string) *Error {
+	return New(CodeUnprocessable, message)
+}
+
+func RateLimited(message string) *Error {
+	return New(CodeRateLimited, message)
+}
+
+// Internal envuelve un error inesperado tras un mensaje genérico
+func Internal(cause error) *Error {
+	return &Error{Code: CodeInternal, Message: "Internal server error", cause: cause}
+}
+
+// Unavailable envuelve un fallo de una dependencia (base de datos, proveedor externo)
+func Unavailable(cause error) *Error {
+	return &Error{Code: CodeUpstreamUnavailable, Message: "A required service is temporarily unavailable", cause: cause}
+}
+
+// From clasifica cualquier error: los de dominio se devuelven tal cual, los de la base de datos
+// se traducen y el resto se considera interno
+func From(err error) *Error {
+	var appErr *Error
+	if errors.As(err, &appErr) {
+		return appErr
+	}
+
+	switch {
+	case errors.Is(err, sql.ErrNoRows):
+		return NotFound("Resource not found").WithCause(err)
+	case isUnavailable(err):
+		return Unavailable(err)
+	}
+
+	var pqErr *pq.Error
+	if errors.As(err, &pqErr) {
+		switch pqErr.Code.Name() {
+		case "unique_violation":
+			return Conflict("Resource already exists").WithCause(err)
+		// Solo llegan aquí cuando el TxManager ya agotó sus reintentos
+		case "serialization_failure", "deadlock_detected":
+			return Conflict("Resource was modified concurrently, retry the request").WithCause(err)
+		}
+	}
+
+	return Internal(err)
+}
+
+// isUnavailable reconoce los errores de conexión y de tiempo agotado con la base de datos
+func isUnavailable(err error) bool {
+	if errors.Is(err, context.DeadlineExceeded) || errors.Is(err, driver.ErrBadConn) || errors.Is(err, sql.ErrConnDone) {
+		return true
+	}
+
+	var netErr net.Error
+	if errors.As(err, &netErr) {
+		return true
+	}
+
+	// 08: excepciones de conexión; 53: recursos insuficientes; 57P01-03: servidor apagándose
+	var pqErr *pq.Error
+	if errors.As(err, &pqErr) {
+		switch pqErr.Code.Class() {
+		case "08", "53":
+			return true
+		}
+		switch pqErr.Code {
+		case "57P01", "57P02", "57P03":
+			return true
+		}
+	}
+
+	return false
+}
@@ -0,0 +1,59 @@
+package cli
+
+import (
+	"context"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"sass-billing-service/src/export"
+	"sass-billing-service/src/models"
+	"sass-billing-service/src/services"
+	"sass-billing-service/src/tenant"
+	"sass-billing-service/src/utils"
+)
+
+// RunExport implementa "export": vuelca las facturas de un tenant a un fichero o a la salida estándar
+func RunExport(ctx context.Context, service *services.ExportService, args []string, stdout io.Writer) error {
+	flags := flag.NewFlagSet("export", flag.ContinueOnError)
+	format := flags.String("format", export.FormatCSV, "csv, jsonl or parquet")
+	tenantID := flags.String("tenant", models.DefaultTenantID, "tenant to export")
+	from := flags.String("from", "", "first creation date (YYYY-MM-DD)")
+	to := flags.String("to", "", "last creation date, inclusive (YYYY-MM-DD)")
+	status := flags.String("status", "", "only invoices in this status")
+	lines := flags.Bool("lines", false, "include invoice lines")
+	payments := flags.Bool("payments", false, "include payments and refunds")
+	out := flags.String("out", "", "output file (stdout if empty)")
+	if err := flags.Parse(args); err != nil {
+		return err
+	}
+
+	if !export.ValidFormat(*format) {
+		return fmt.Errorf("%w: %s", export.ErrUnsupportedFormat, *format)
+	}
+
+	fromDate, toDate, err := utils.ParseDateRange(*from, *to)
+	if err != nil {
+		return fmt.Errorf("invalid date range: %v", err)
+	}
+
+	w := stdout
+	if *out != "" {
+		file, err := os.Create(*out)
+		if err != nil {
+			return err
+		}
+		defer file.Close()
+		w = file
+	}
+
+	filter := models.ExportFilter{
+		From:            fromDate,
+		To:              toDate,
+		Status:          *status,
+		IncludeLines:    *lines,
+		IncludePayments: *payments,
+	}
+
+	return service.ExportInvoices(tenant.WithTenant(ctx, *tenantID), filter, *format, w)
+}
@@ -0,0 +1,151 @@
+package cli
+
+import (
+	"context"
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"sass-billing-service/src/apperror"
+	"sass-billing-service/src/models"
+	"sass-billing-service/src/services"
+	"sass-billing-service/src/tenant"
+	"sass-billing-service/src/utils"
+	"sass-billing-service/src/validation"
+	"strconv"
+	"strings"
+	"time"
+)
+
+const invoiceUsage = "usage: invoice create | get <id> | list | void <id>"
+
+// RunInvoice implementa "invoice create|get|list|void" sobre el mismo servicio que la API
+func RunInvoice(ctx context.Context, service *services.InvoiceService, args []string, stdout io.Writer) error {
+	if len(args) == 0 {
+		return errors.New(invoiceUsage)
+	}
+
+	flags := flag.NewFlagSet("invoice "+args[0], flag.ContinueOnError)
+	tenantID := flags.String("tenant", models.DefaultTenantID, "tenant the invoices belong to")
+	asJSON := flags.Bool("json", false, "print JSON instead of a table")
+
+	switch args[0] {
+	case "create":
+		return createInvoice(ctx, service, flags, tenantID, asJSON, args[1:], stdout)
+	case "get", "void":
+		positional, err := parseArgs(flags, args[1:])
+		if err != nil {
+			return err
+		}
+		if len(positional) != 1 {
+			return fmt.Errorf("usage: invoice %s <id>", args[0])
+		}
+		id, err := strconv.Atoi(positional[0])
+		if err != nil {
+			return fmt.Errorf("invalid invoice ID %q", positional[0])
+		}
+
+		scope := tenant.WithTenant(ctx, *tenantID)
+		var invoice *models.Invoice
+		if args[0] == "get" {
+			invoice, err = service.GetInvoiceByID(scope, id)
+		} else {
+			invoice, err = service.VoidInvoice(scope, id)
+		}
+		if err != nil {
+			return err
+		}
+		return printInvoice(stdout, *asJSON, invoice)
+	case "list":
+		return listInvoices(ctx, service, flags, tenantID, asJSON, args[1:], stdout)
+	default:
+		return errors.New(invoiceUsage)
+	}
+}
+
+func createInvoice(ctx context.Context, service *services.InvoiceService, flags *flag.FlagSet, tenantID *string, asJSON *bool, args []string, stdout io.Writer) error {
+	var req models.CreateInvoiceRequest
+	flags.IntVar(&req.UserID, "user", 0, "customer user ID (required)")
+	flags.Float64Var(&req.Amount, "amount", 0, "total amount including tax (required)")
+	flags.Float64Var(&req.TaxAmount, "tax", 0, "tax included in the amount")
+	flags.StringVar(&req.Currency, "currency", models.DefaultCurrency, "ISO 4217 currency")
+	flags.StringVar(&req.Description, "description", "", "invoice description (required)")
+	flags.StringVar(&req.PaymentMethod, "payment-method", "", "payment method (required)")
+	flags.StringVar(&req.CustomerName, "customer-name", "", "customer name")
+	flags.StringVar(&req.CustomerEmail, "customer-email", "", "customer email")
+	due := flags.String("due", "", "due date (YYYY-MM-DD), 30 days from today if empty")
+	if _, err := parseArgs(flags, args); err != nil {
+		return err
+	}
+
+	// Mismas reglas que POST /api/invoices
+	req.Normalize()
+	if err := validation.Struct(&req); err != nil {
+		return err
+	}
+	if *due != "" {
+		dueDate, err := time.Parse("2006-01-02", *due)
+		if err != nil {
+			return fmt.Errorf("invalid due date: %v", err)
+		}
+		req.DueDate = &dueDate
+	}
+
+	invoice, err := service.CreateInvoice(tenant.WithTenant(ctx, *tenantID), &req)
+	if err != nil {
+		return err
+	}
+
+	return printInvoice(stdout, *asJSON, invoice)
+}
+
+func listInvoices(ctx context.Context, service *services.InvoiceService, flags *flag.FlagSet, tenantID *string, asJSON *bool, args []string, stdout io.Writer) error {
+	var filter models.InvoiceFilter
+	var page models.PageRequest
+	flags.IntVar(&filter.UserID, "user", 0, "only invoices of this user")
+	flags.StringVar(&filter.Status, "status", "", "only invoices in this status")
+	flags.StringVar(&filter.Currency, "currency", "", "only invoices in this currency")
+	flags.IntVar(&page.Limit, "limit", models.DefaultPageSize, fmt.Sprintf("page size (max %d)", models.MaxPageSize))
+	flags.StringVar(&page.Cursor, "cursor", "", "next_cursor of the previous page")
+	flags.StringVar(&page.Sort, "sort", "", strings.Join(models.InvoiceSorts, ", "))
+	from := flags.String("from", "", "first creation date (YYYY-MM-DD)")
+	to := flags.String("to", "", "last creation date, inclusive (YYYY-MM-DD)")
+	if _, err := parseArgs(flags, args); err != nil {
+		return err
+	}
+
+	if fields := validation.Var("limit", page.Limit, models.PageLimitRules); len(fields) > 0 {
+		return apperror.Validation("Invalid flags", fields...)
+	}
+	filter.Currency = strings.ToUpper(filter.Currency)
+
+	var err error
+	if filter.CreatedFrom, filter.CreatedTo, err = utils.ParseDateRange(*from, *to); err != nil {
+		return fmt.Errorf("invalid date range: %v", err)
+	}
+
+	result, err := service.ListInvoices(tenant.WithTenant(ctx, *tenantID), filter, page)
+	if err != nil {
+		return err
+	}
+
+	// Mismo sobre que GET /api/invoices
+	if *asJSON {
+		return writeJSON(stdout, utils.Response{Success: true, Data: result.Invoices, NextCursor: result.NextCursor})
+	}
+	if err := writeInvoiceTable(stdout, result.Invoices...); err != nil {
+		return err
+	}
+	if result.NextCursor != "" {
+		fmt.Fprintf(stdout, "\nnext page: --cursor %s\n", result.NextCursor)
+	}
+	return nil
+}
+
+func printInvoice(stdout io.Writer, asJSON bool, invoice *models.Invoice) error {
+	if asJSON {
+		return writeJSON(stdout, invoice)
+	}
+
+	return writeInvoiceTable(stdout, *invoice)
+}
@@ -0,0 +1,84 @@
+package cli
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"sass-billing-service/src/migrations"
+	"strconv"
+	"text/tabwriter"
+)
+
+const migrateUsage = "usage: migrate up | down [steps] | status | to <version> | baseline <version>"
+
+// RunMigrate implementa "migrate up|down|status|to N|baseline N" sobre las migraciones embebidas
+func RunMigrate(ctx context.Context, runner *migrations.Runner, args []string, stdout io.Writer) error {
+	if len(args) == 0 {
+		return errors.New(migrateUsage)
+	}
+
+	var done []migrations.Migration
+	var err error
+	switch args[0] {
+	case "up":
+		done, err = runner.Up(ctx)
+	case "down":
+		steps := 1
+		if len(args) > 1 {
+			if steps, err = strconv.Atoi(args[1]); err != nil || steps < 1 {
+				return fmt.Errorf("invalid number of steps %q", args[1])
+			}
+		}
+		done, err = runner.Down(ctx, steps)
+	case "to", "baseline":
+		if len(args) < 2 {
+			return errors.New(migrateUsage)
+		}
+		version, convErr := strconv.Atoi(args[1])
+		if convErr != nil || version < 0 {
+			return fmt.Errorf("invalid version %q", args[1])
+		}
+		if args[0] == "to" {
+			done, err = runner.To(ctx, version)
+		} else {
+			// Para una base de datos creada a mano: registra hasta version sin ejecutar nada
+			done, err = runner.Baseline(ctx, version)
+		}
+	case "status":
+		return printMigrationStatus(ctx, runner, stdout)
+	default:
+		return errors.New(migrateUsage)
+	}
+
+	for _, migration := range done {
+		fmt.Fprintf(stdout, "%s %03d_%s\n", args[0], migration.Version, migration.Name)
+	}
+	if err == nil && len(done) == 0 {
+		fmt.Fprintln(stdout, "nothing to do")
+	}
+
+	return err
+}
+
+func printMigrationStatus(ctx context.Context, runner *migrations.Runner, stdout io.Writer) error {
+	statuses, err := runner.Status(ctx)
+	if err != nil {
+		return err
+	}
+
+	w := tabwriter.NewWriter(stdout, 0, 0, 2, ' ', 0)
+	fmt.Fprintln(w, "VERSION\tNAME\tSTATUS\tAPPLIED AT")
+	for _, status := range statuses {
+		state, appliedAt := "pending", ""
+		if status.Applied {
+			state, appliedAt = "applied", status.AppliedAt.Format("2006-01-02 15:04:05")
+		}
+		if status.Modified {
+			state = "modified"
+		}
+		fmt.Fprintf(w, "%03d\t%s\t%s\t%s\n", status.Version, status.Name, state, appliedAt)
+	}
+
+	return w.Flush()
+}
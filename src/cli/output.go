@@ -0,0 +1,48 @@
+package cli
+
+import (
+	"encoding/json"
+	"flag"
+	"fmt"
+	"io"
+	"sass-billing-service/src/models"
+	"text/tabwriter"
+)
+
+// parseArgs admite flags antes y después de los argumentos posicionales ("invoice get 42 --json")
+func parseArgs(flags *flag.FlagSet, args []string) ([]string, error) {
+	var positional []string
+	for {
+		if err := flags.Parse(args); err != nil {
+			return nil, err
+		}
+		args = flags.Args()
+		if len(args) == 0 {
+			return positional, nil
+		}
+		positional, args = append(positional, args[0]), args[1:]
+	}
+}
+
+func writeJSON(w io.Writer, value interface{}) error {
+	encoder := json.NewEncoder(w)
+	encoder.SetIndent("", "  ")
+	return encoder.Encode(value)
+}
+
+func newTable(w io.Writer, header string) *tabwriter.Writer {
+	table := tabwriter.NewWriter(w, 0, 0, 2, ' ', 0)
+	fmt.Fprintln(table, header)
+	return table
+}
+
+func writeInvoiceTable(w io.Writer, invoices ...models.Invoice) error {
+	table := newTable(w, "ID\tNUMBER\tUSER\tSTATUS\tAMOUNT\tCURRENCY\tDUE\tCREATED")
+	for _, invoice := range invoices {
+		fmt.Fprintf(table, "%d\t%s\t%d\t%s\t%.2f\t%s\t%s\t%s\n",
+			invoice.ID, invoice.InvoiceNumber, invoice.UserID, invoice.Status, invoice.Amount, invoice.Currency,
+			invoice.DueDate.Format("2006-01-02"), invoice.CreatedAt.Format("2006-01-02 15:04"))
+	}
+
+	return table.Flush()
+}
@@ -0,0 +1,61 @@
+package cli
+
+import (
+	"context"
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"sass-billing-service/src/models"
+	"sass-billing-service/src/services"
+	"sass-billing-service/src/tenant"
+)
+
+// ErrReconciliationFailed hace que "reconcile" termine con error para poder usarlo en scripts y alertas
+var ErrReconciliationFailed = errors.New("ledger does not reconcile")
+
+// RunReconcile implementa "reconcile": cuadre del libro y cuentas por cobrar frente al estado de las facturas
+func RunReconcile(ctx context.Context, service *services.LedgerService, args []string, stdout io.Writer) error {
+	flags := flag.NewFlagSet("reconcile", flag.ContinueOnError)
+	tenantID := flags.String("tenant", models.DefaultTenantID, "tenant to reconcile")
+	asJSON := flags.Bool("json", false, "print JSON instead of a table")
+	if _, err := parseArgs(flags, args); err != nil {
+		return err
+	}
+
+	result, err := service.Reconcile(tenant.WithTenant(ctx, *tenantID))
+	if err != nil {
+		return err
+	}
+
+	if *asJSON {
+		err = writeJSON(stdout, result)
+	} else {
+		err = printReconciliation(stdout, result)
+	}
+	if err != nil {
+		return err
+	}
+
+	if !result.Clean() {
+		return ErrReconciliationFailed
+	}
+	return nil
+}
+
+func printReconciliation(stdout io.Writer, result *models.Reconciliation) error {
+	fmt.Fprintf(stdout, "trial balance: %.2f\n", result.TrialBalance)
+	if len(result.Discrepancies) == 0 {
+		fmt.Fprintln(stdout, "accounts receivable matches every invoice")
+		return nil
+	}
+
+	fmt.Fprintf(stdout, "%d invoices do not match accounts receivable:\n\n", len(result.Discrepancies))
+	table := newTable(stdout, "INVOICE\tNUMBER\tSTATUS\tEXPECTED\tLEDGER")
+	for _, discrepancy := range result.Discrepancies {
+		fmt.Fprintf(table, "%d\t%s\t%s\t%.2f\t%.2f\n",
+			discrepancy.InvoiceID, discrepancy.InvoiceNumber, discrepancy.Status, discrepancy.Expected, discrepancy.Ledger)
+	}
+
+	return table.Flush()
+}
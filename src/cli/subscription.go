@@ -0,0 +1,78 @@
+package cli
+
+import (
+	"context"
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"sass-billing-service/src/models"
+	"sass-billing-service/src/services"
+	"sass-billing-service/src/tenant"
+	"time"
+)
+
+const subscriptionUsage = "usage: subscription renew [--through YYYY-MM-DD] [--dry-run]"
+
+// RunSubscription implementa "subscription renew": factura el periodo siguiente de las suscripciones que vencen
+func RunSubscription(ctx context.Context, service *services.SubscriptionService, args []string, stdout io.Writer) error {
+	if len(args) == 0 || args[0] != "renew" {
+		return errors.New(subscriptionUsage)
+	}
+
+	flags := flag.NewFlagSet("subscription renew", flag.ContinueOnError)
+	tenantID := flags.String("tenant", models.DefaultTenantID, "tenant whose subscriptions are renewed")
+	through := flags.String("through", "", "renew periods ending on or before this date (YYYY-MM-DD), today if empty")
+	dryRun := flags.Bool("dry-run", false, "list the renewals without issuing invoices")
+	asJSON := flags.Bool("json", false, "print JSON instead of a table")
+	if _, err := parseArgs(flags, args[1:]); err != nil {
+		return err
+	}
+
+	date := time.Now().UTC().Truncate(24 * time.Hour)
+	if *through != "" {
+		var err error
+		if date, err = time.Parse("2006-01-02", *through); err != nil {
+			return fmt.Errorf("invalid date: %v", err)
+		}
+	}
+
+	// Las renovaciones ya emitidas se muestran aunque otra falle después
+	renewals, err := service.Renew(tenant.WithTenant(ctx, *tenantID), date, *dryRun)
+	if renewals != nil {
+		var printErr error
+		if *asJSON {
+			printErr = writeJSON(stdout, renewals)
+		} else {
+			printErr = printRenewals(stdout, renewals, *dryRun)
+		}
+		if err == nil {
+			err = printErr
+		}
+	}
+
+	return err
+}
+
+func printRenewals(stdout io.Writer, renewals []models.Renewal, dryRun bool) error {
+	table := newTable(stdout, "USER\tPRODUCT\tPERIOD\tAMOUNT\tCURRENCY\tPREVIOUS\tINVOICE")
+	for _, renewal := range renewals {
+		invoice := "-"
+		if renewal.InvoiceID != 0 {
+			invoice = fmt.Sprint(renewal.InvoiceID)
+		}
+		fmt.Fprintf(table, "%d\t%s\t%s..%s\t%.2f\t%s\t%d\t%s\n",
+			renewal.UserID, renewal.Product, renewal.ServiceStart.Format("2006-01-02"), renewal.ServiceEnd.Format("2006-01-02"),
+			renewal.Amount, renewal.Currency, renewal.PreviousInvoiceID, invoice)
+	}
+	if err := table.Flush(); err != nil {
+		return err
+	}
+
+	if dryRun {
+		fmt.Fprintf(stdout, "\ndry run: %d subscriptions would be renewed\n", len(renewals))
+	} else {
+		fmt.Fprintf(stdout, "\n%d subscriptions renewed\n", len(renewals))
+	}
+	return nil
+}
@@ -1,34 +1,463 @@
 package config
 
 import (
-	"log"
+	"database/sql"
+	"fmt"
 	"os"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
 
 	"github.com/joho/godotenv"
+	"gopkg.in/yaml.v3"
 )
 
 type Config struct {
+	// Almacén de datos: postgres, o memory para desarrollo y tests (sin persistencia ni base de datos)
+	Store string
+
 	DBHost     string
 	DBPort     string
 	DBUser     string
 	DBPassword string
 	DBName     string
+	DBSSLMode  string
 	ServerPort string
+
+	// Aislamiento de las transacciones del TxManager y reintentos ante fallos de serialización
+	DBIsolation sql.IsolationLevel
+	DBTxRetries int
+
+	// Aplica las migraciones pendientes al arrancar el servidor
+	AutoMigrate bool
+
+	// Tiempo máximo para drenar las peticiones en curso al recibir SIGTERM
+	ShutdownTimeout time.Duration
+
+	// Espera entre fallar /readyz y dejar de aceptar conexiones; debe superar periodSeconds ×
+	// failureThreshold de la readiness probe para que Kubernetes retire antes el pod de los endpoints
+	ShutdownDrainDelay time.Duration
+
+	// Nivel (debug, info, warn, error) y formato (json, text) de los logs
+	LogLevel  string
+	LogFormat string
+
+	// Exportador de trazas (none, stdout, otlp) y endpoint OTLP opcional
+	TracingExporter string
+	TracingEndpoint string
+
+	// Limitador de peticiones: backend (memory, postgres) y límites por ruta y por tenant
+	RateLimitBackend string
+	RateLimit        RateLimits
+
+	// Moneda a la que se normalizan los informes de métricas
+	ReportingCurrency string
+
+	// Tiempo durante el que se recuerda una Idempotency-Key
+	IdempotencyKeyTTL time.Duration
+
+	// Verificación de JWT: secreto HMAC o JWKS (fichero o URL) del proveedor de identidad
+	JWTSecret              string
+	JWKSSource             string
+	JWKSRefreshInterval    time.Duration
+	JWKSMinRefreshInterval time.Duration
+	JWTAlgorithms          []string
+	JWTIssuer              string
+	JWTAudience            string
+	JWTClockSkew           time.Duration
+}
+
+// RateLimits guarda los límites tal como se configuran ("120/m"), ya validados; main construye con
+// ellos la política del limitador. Un límite vacío significa "sin límite".
+type RateLimits struct {
+	Default string            // por principal y ruta
+	Routes  map[string]string // por ruta, con clave "GET /api/invoices"
+	Tenant  string            // suma de todas las peticiones de un tenant
+	Tenants map[string]string // por tenant, sustituye a Tenant
+}
+
+// DSN devuelve la cadena de conexión de lib/pq
+func (c *Config) DSN() string {
+	return "host=" + c.DBHost + " port=" + c.DBPort + " user=" + c.DBUser +
+		" password=" + c.DBPassword + " dbname=" + c.DBName + " sslmode=" + c.DBSSLMode
+}
+
+// ValidationError reúne todos los ajustes ausentes o inválidos para corregirlos de una vez
+type ValidationError struct {
+	Problems []string
+}
+
+func (e *ValidationError) Error() string {
+	return "invalid configuration: " + strings.Join(e.Problems, "; ")
+}
+
+// settings lista todas las claves reconocidas; en YAML se escriben en minúsculas (db_host)
+var settings = []string{
+	"STORE", "DB_HOST", "DB_PORT", "DB_USER", "DB_PASSWORD", "DB_NAME", "DB_SSLMODE", "DB_ISOLATION_LEVEL", "DB_TX_RETRIES",
+	"SERVER_PORT", "AUTO_MIGRATE", "SHUTDOWN_TIMEOUT", "SHUTDOWN_DRAIN_DELAY",
+	"LOG_LEVEL", "LOG_FORMAT", "TRACING_EXPORTER", "TRACING_ENDPOINT", "REPORTING_CURRENCY", "IDEMPOTENCY_KEY_TTL",
+	"RATE_LIMIT_BACKEND", "RATE_LIMIT_DEFAULT", "RATE_LIMIT_ROUTES", "RATE_LIMIT_TENANT", "RATE_LIMIT_TENANTS",
+	"JWT_SECRET", "JWT_JWKS", "JWT_JWKS_REFRESH", "JWT_JWKS_MIN_REFRESH", "JWT_ALGORITHMS", "JWT_ISSUER", "JWT_AUDIENCE", "JWT_CLOCK_SKEW",
+}
+
+var defaults = map[string]string{
+	"STORE":                "postgres",
+	"DB_PORT":              "5432",
+	"DB_SSLMODE":           "disable",
+	"DB_ISOLATION_LEVEL":   "read_committed",
+	"DB_TX_RETRIES":        "3",
+	"SERVER_PORT":          "8080",
+	"AUTO_MIGRATE":         "false",
+	"SHUTDOWN_TIMEOUT":     "30s",
+	"SHUTDOWN_DRAIN_DELAY": "15s",
+	"LOG_LEVEL":            "info",
+	"LOG_FORMAT":           "json",
+	"TRACING_EXPORTER":     "none",
+	"RATE_LIMIT_BACKEND":   "memory",
+	"RATE_LIMIT_DEFAULT":   "120/m",
+	"RATE_LIMIT_TENANT":    "1200/m",
+	"REPORTING_CURRENCY":   "USD",
+	"IDEMPOTENCY_KEY_TTL":  "24h",
+	"JWT_JWKS_REFRESH":     "15m",
+	"JWT_JWKS_MIN_REFRESH": "30s",
+	"JWT_CLOCK_SKEW":       "30s",
+}
+
+// DefaultFile se lee si existe y no se indica otro fichero
+const DefaultFile = ".env"
+
+var (
+	currencyCode = regexp.MustCompile(`^[A-Z]{3}$`)
+	rateLimit    = regexp.MustCompile(`^[1-9][0-9]*/[smh]$`)
+)
+
+// Load construye la configuración por capas, de menor a mayor prioridad: valores por defecto,
+// fichero opcional (YAML por extensión .yaml/.yml, formato .env en otro caso), variables de entorno
+// y overrides, los ajustes que el llamante recibe por línea de comandos (clave STORE, vacío si no se indicó).
+// Un fichero indicado explícitamente debe existir; el .env por defecto es opcional.
+func Load(file string, overrides map[string]string) (*Config, error) {
+	values := make(map[string]string, len(settings))
+	for key, value := range defaults {
+		values[key] = value
+	}
+
+	if file == "" {
+		if _, err := os.Stat(DefaultFile); err == nil {
+			file = DefaultFile
+		}
+	}
+	if file != "" {
+		fromFile, err := readFile(file)
+		if err != nil {
+			return nil, fmt.Errorf("error loading config file %s: %w", file, err)
+		}
+		for key, value := range fromFile {
+			values[key] = value
+		}
+	}
+
+	for _, key := range settings {
+		if value := os.Getenv(key); value != "" {
+			values[key] = value
+		}
+	}
+
+	var unknown []string
+	for key, value := range overrides {
+		if !isSetting(key) {
+			unknown = append(unknown, "unknown setting "+key)
+			continue
+		}
+		if value != "" {
+			values[key] = value
+		}
+	}
+	if len(unknown) > 0 {
+		sort.Strings(unknown)
+		return nil, &ValidationError{Problems: unknown}
+	}
+
+	return parse(values)
+}
+
+func readFile(file string) (map[string]string, error) {
+	// Un .env puede compartirse con otras herramientas (docker compose), así que sus claves ajenas se ignoran
+	if ext := strings.ToLower(filepath.Ext(file)); ext != ".yaml" && ext != ".yml" {
+		return godotenv.Read(file)
+	}
+
+	content, err := os.ReadFile(file)
+	if err != nil {
+		return nil, err
+	}
+	var document map[string]interface{}
+	if err := yaml.Unmarshal(content, &document); err != nil {
+		return nil, err
+	}
+
+	// En YAML una clave desconocida casi siempre es una errata
+	values := make(map[string]string, len(document))
+	var unknown []string
+	for key, value := range document {
+		key = strings.ToUpper(key)
+		if !isSetting(key) {
+			unknown = append(unknown, "unknown setting "+strings.ToLower(key))
+			continue
+		}
+		values[key] = yamlString(value)
+	}
+	if len(unknown) > 0 {
+		sort.Strings(unknown)
+		return nil, &ValidationError{Problems: unknown}
+	}
+
+	return values, nil
+}
+
+// yamlString aplana listas ([RS256, ES256]) al mismo formato separado por comas que las variables de entorno
+func yamlString(value interface{}) string {
+	switch v := value.(type) {
+	case nil:
+		return ""
+	case []interface{}:
+		items := make([]string, len(v))
+		for i, item := range v {
+			items[i] = fmt.Sprint(item)
+		}
+		return strings.Join(items, ",")
+	default:
+		return fmt.Sprint(v)
+	}
+}
+
+func isSetting(key string) bool {
+	for _, setting := range settings {
+		if setting == key {
+			return true
+		}
+	}
+
+	return false
+}
+
+func parse(values map[string]string) (*Config, error) {
+	p := &parser{values: values}
+
+	// Sin PostgreSQL los datos de conexión no hacen falta
+	store := strings.ToLower(values["STORE"])
+	database := p.required
+	if store == "memory" {
+		database = func(key string) string { return values[key] }
+	}
+
+	cfg := &Config{
+		Store: store,
+
+		DBHost:     database("DB_HOST"),
+		DBPort:     p.port("DB_PORT"),
+		DBUser:     database("DB_USER"),
+		DBPassword: values["DB_PASSWORD"],
+		DBName:     database("DB_NAME"),
+		DBSSLMode:  values["DB_SSLMODE"],
+		ServerPort: p.port("SERVER_PORT"),
+
+		DBIsolation: p.isolation("DB_ISOLATION_LEVEL"),
+		DBTxRetries: p.count("DB_TX_RETRIES"),
+
+		AutoMigrate:        p.bool("AUTO_MIGRATE"),
+		ShutdownTimeout:    p.duration("SHUTDOWN_TIMEOUT", false),
+		ShutdownDrainDelay: p.duration("SHUTDOWN_DRAIN_DELAY", true),
+
+		LogLevel:  strings.ToLower(values["LOG_LEVEL"]),
+		LogFormat: strings.ToLower(values["LOG_FORMAT"]),
+
+		TracingExporter: strings.ToLower(values["TRACING_EXPORTER"]),
+		TracingEndpoint: values["TRACING_ENDPOINT"],
+
+		RateLimitBackend: strings.ToLower(values["RATE_LIMIT_BACKEND"]),
+		RateLimit: RateLimits{
+			Default: p.limit("RATE_LIMIT_DEFAULT"),
+			Routes:  p.limits("RATE_LIMIT_ROUTES"),
+			Tenant:  p.limit("RATE_LIMIT_TENANT"),
+			Tenants: p.limits("RATE_LIMIT_TENANTS"),
+		},
+
+		ReportingCurrency: values["REPORTING_CURRENCY"],
+		IdempotencyKeyTTL: p.duration("IDEMPOTENCY_KEY_TTL", false),
+
+		JWTSecret:              values["JWT_SECRET"],
+		JWKSSource:             values["JWT_JWKS"],
+		JWKSRefreshInterval:    p.duration("JWT_JWKS_REFRESH", false),
+		JWKSMinRefreshInterval: p.duration("JWT_JWKS_MIN_REFRESH", true),
+		JWTAlgorithms:          p.list("JWT_ALGORITHMS"),
+		JWTIssuer:              values["JWT_ISSUER"],
+		JWTAudience:            values["JWT_AUDIENCE"],
+		JWTClockSkew:           p.duration("JWT_CLOCK_SKEW", true),
+	}
+
+	switch cfg.LogLevel {
+	case "debug", "info", "warn", "error":
+	default:
+		p.invalid("LOG_LEVEL", "must be one of debug, info, warn, error")
+	}
+	if cfg.LogFormat != "json" && cfg.LogFormat != "text" {
+		p.invalid("LOG_FORMAT", "must be json or text")
+	}
+	switch cfg.TracingExporter {
+	case "none", "stdout", "otlp":
+	default:
+		p.invalid("TRACING_EXPORTER", "must be one of none, stdout, otlp")
+	}
+	if cfg.Store != "postgres" && cfg.Store != "memory" {
+		p.invalid("STORE", "must be postgres or memory")
+	}
+	if cfg.RateLimitBackend != "memory" && cfg.RateLimitBackend != "postgres" {
+		p.invalid("RATE_LIMIT_BACKEND", "must be memory or postgres")
+	} else if cfg.RateLimitBackend == "postgres" && cfg.Store == "memory" {
+		p.invalid("RATE_LIMIT_BACKEND", "must be memory when STORE is memory")
+	}
+	if !currencyCode.MatchString(cfg.ReportingCurrency) {
+		p.invalid("REPORTING_CURRENCY", "must be a 3-letter ISO 4217 code")
+	}
+	if cfg.JWTSecret == "" && cfg.JWKSSource == "" {
+		p.problems = append(p.problems, "JWT_SECRET or JWT_JWKS is required")
+	}
+	// RS256/ES256 cuando hay JWKS y HS256 con el secreto compartido
+	if len(cfg.JWTAlgorithms) == 0 {
+		cfg.JWTAlgorithms = []string{"HS256"}
+		if cfg.JWKSSource != "" {
+			cfg.JWTAlgorithms = []string{"RS256", "ES256"}
+		}
+	}
+
+	if len(p.problems) > 0 {
+		return nil, &ValidationError{Problems: p.problems}
+	}
+
+	return cfg, nil
+}
+
+// parser acumula los problemas en lugar de detenerse en el primero
+type parser struct {
+	values   map[string]string
+	problems []string
 }
 
-func LoadConfig() *Config {
-	err := godotenv.Load()
+func (p *parser) invalid(key, reason string) {
+	p.problems = append(p.problems, key+" "+reason)
+}
 
+func (p *parser) required(key string) string {
+	value := p.values[key]
+	if value == "" {
+		p.invalid(key, "is required")
+	}
+
+	return value
+}
+
+func (p *parser) port(key string) string {
+	value := p.required(key)
+	if value == "" {
+		return value
+	}
+
+	if port, err := strconv.Atoi(value); err != nil || port < 1 || port > 65535 {
+		p.invalid(key, fmt.Sprintf("must be a port number, got %q", value))
+	}
+
+	return value
+}
+
+func (p *parser) duration(key string, allowZero bool) time.Duration {
+	value := p.values[key]
+	duration, err := time.ParseDuration(value)
 	if err != nil {
-		log.Fatalf("Error loading .env file: %v", err)
+		p.invalid(key, fmt.Sprintf("must be a duration such as 30s or 15m, got %q", value))
+		return 0
+	}
+	if duration < 0 || (duration == 0 && !allowZero) {
+		p.invalid(key, "must be positive")
+	}
+
+	return duration
+}
+
+func (p *parser) bool(key string) bool {
+	value, err := strconv.ParseBool(p.values[key])
+	if err != nil {
+		p.invalid(key, fmt.Sprintf("must be true or false, got %q", p.values[key]))
+	}
+
+	return value
+}
+
+func (p *parser) count(key string) int {
+	value, err := strconv.Atoi(p.values[key])
+	if err != nil || value < 0 {
+		p.invalid(key, fmt.Sprintf("must be a non-negative integer, got %q", p.values[key]))
+	}
+
+	return value
+}
+
+// isolation lee el nivel de aislamiento con los nombres de PostgreSQL en snake_case
+func (p *parser) isolation(key string) sql.IsolationLevel {
+	switch strings.ToLower(p.values[key]) {
+	case "read_committed":
+		return sql.LevelReadCommitted
+	case "repeatable_read":
+		return sql.LevelRepeatableRead
+	case "serializable":
+		return sql.LevelSerializable
+	}
+
+	p.invalid(key, "must be one of read_committed, repeatable_read, serializable")
+	return sql.LevelDefault
+}
+
+func (p *parser) list(key string) []string {
+	var items []string
+	for _, item := range strings.Split(p.values[key], ",") {
+		if item = strings.TrimSpace(item); item != "" {
+			items = append(items, item)
+		}
 	}
 
-	return &Config{
-		DBHost:     os.Getenv("DB_HOST"),
-		DBPort:     os.Getenv("DB_PORT"),
-		DBUser:     os.Getenv("DB_USER"),
-		DBPassword: os.Getenv("DB_PASSWORD"),
-		DBName:     os.Getenv("DB_NAME"),
-		ServerPort: os.Getenv("SERVER_PORT"),
+	return items
+}
+
+// limit lee un límite "120/m" y lo devuelve sin espacios; "off" lo desactiva y se guarda vacío
+func (p *parser) limit(key string) string {
+	value := p.values[key]
+	if value == "" || strings.EqualFold(value, "off") {
+		return ""
 	}
+
+	limit := strings.ReplaceAll(value, " ", "")
+	if !rateLimit.MatchString(limit) {
+		p.invalid(key, fmt.Sprintf("must be <requests>/<s|m|h> or off, got %q", value))
+	}
+
+	return limit
+}
+
+// limits lee una lista "clave=límite" separada por comas, p. ej. "GET /api/invoices=60/m, POST /api/invoices=20/m"
+func (p *parser) limits(key string) map[string]string {
+	limits := make(map[string]string)
+	for _, item := range p.list(key) {
+		name, value, found := strings.Cut(item, "=")
+		name = strings.TrimSpace(name)
+		limit := strings.ReplaceAll(value, " ", "")
+		if !found || name == "" || !rateLimit.MatchString(limit) {
+			p.invalid(key, fmt.Sprintf("must be a list of <name>=<requests>/<s|m|h>, got %q", item))
+			continue
+		}
+		limits[name] = limit
+	}
+
+	return limits
 }
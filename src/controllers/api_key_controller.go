@@ -0,0 +1,99 @@
+package controllers
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"sass-billing-service/src/apperror"
+	"sass-billing-service/src/helpers"
+	"sass-billing-service/src/models"
+	"sass-billing-service/src/services"
+	"sass-billing-service/src/utils"
+	"sass-billing-service/src/validation"
+	"strconv"
+	"strings"
+
+	"github.com/gofiber/fiber/v2"
+)
+
+type APIKeyController struct {
+	service APIKeyService
+}
+
+// APIKeyService es la gestión de API keys que expone el controlador; la implementa services.APIKeyService
+type APIKeyService interface {
+	ListAPIKeys(ctx context.Context) ([]models.APIKey, error)
+	CreateAPIKey(ctx context.Context, req *models.CreateAPIKeyRequest, createdBy string) (*models.IssuedAPIKey, error)
+	RotateAPIKey(ctx context.Context, id int) (*models.IssuedAPIKey, error)
+	RevokeAPIKey(ctx context.Context, id int) (*models.APIKey, error)
+}
+
+var _ APIKeyService = (*services.APIKeyService)(nil)
+
+func NewAPIKeyController(service APIKeyService) *APIKeyController {
+	return &APIKeyController{service: service}
+}
+
+func (c *APIKeyController) ListAPIKeys(ctx *fiber.Ctx) error {
+	keys, err := c.service.ListAPIKeys(ctx.UserContext())
+	if err != nil {
+		return err
+	}
+
+	return utils.SuccessResponse(ctx, fiber.StatusOK, keys)
+}
+
+// CreateAPIKey emite una clave; la respuesta es la única ocasión en que se devuelve completa
+func (c *APIKeyController) CreateAPIKey(ctx *fiber.Ctx) error {
+	var req models.CreateAPIKeyRequest
+	if err := ctx.BodyParser(&req); err != nil {
+		return apperror.Validation("Invalid request body")
+	}
+
+	req.Name = strings.TrimSpace(req.Name)
+	if err := validation.Struct(&req); err != nil {
+		return err
+	}
+
+	// ErrInvalidScope ya es un error de validación
+	key, err := c.service.CreateAPIKey(ctx.UserContext(), &req, helpers.CurrentPrincipal(ctx).Subject)
+	if err != nil {
+		return err
+	}
+
+	return utils.SuccessResponse(ctx, fiber.StatusCreated, key)
+}
+
+func (c *APIKeyController) RotateAPIKey(ctx *fiber.Ctx) error {
+	id, err := strconv.Atoi(ctx.Params("id"))
+	if err != nil {
+		return apperror.Validation("Invalid API key ID", apperror.Field("id", "must be an integer"))
+	}
+
+	key, err := c.service.RotateAPIKey(ctx.UserContext(), id)
+	if errors.Is(err, sql.ErrNoRows) {
+		return apperror.NotFound("API key not found").WithCause(err)
+	}
+	if err != nil {
+		return err
+	}
+
+	return utils.SuccessResponse(ctx, fiber.StatusCreated, key)
+}
+
+func (c *APIKeyController) RevokeAPIKey(ctx *fiber.Ctx) error {
+	id, err := strconv.Atoi(ctx.Params("id"))
+	if err != nil {
+		return apperror.Validation("Invalid API key ID", apperror.Field("id", "must be an integer"))
+	}
+
+	key, err := c.service.RevokeAPIKey(ctx.UserContext(), id)
+	if errors.Is(err, sql.ErrNoRows) {
+		return apperror.NotFound("API key not found").WithCause(err)
+	}
+	if err != nil {
+		return err
+	}
+
+	return utils.SuccessResponse(ctx, fiber.StatusOK, key)
+}
@@ -0,0 +1,101 @@
+package controllers
+
+import (
+	"bufio"
+	"context"
+	"io"
+	"log/slog"
+	"sass-billing-service/src/apperror"
+	"sass-billing-service/src/export"
+	"sass-billing-service/src/helpers"
+	"sass-billing-service/src/models"
+	"sass-billing-service/src/services"
+	"sass-billing-service/src/tenant"
+	"sass-billing-service/src/utils"
+	"sass-billing-service/src/validation"
+	"strings"
+
+	"github.com/gofiber/fiber/v2"
+)
+
+type ExportController struct {
+	service ExportService
+}
+
+// ExportService escribe la exportación en el formato pedido; la implementa services.ExportService
+type ExportService interface {
+	ExportInvoices(ctx context.Context, filter models.ExportFilter, format string, w io.Writer) error
+}
+
+var _ ExportService = (*services.ExportService)(nil)
+
+func NewExportController(service ExportService) *ExportController {
+	return &ExportController{service: service}
+}
+
+// ExportInvoices acepta ?format=csv|jsonl|parquet&from=&to=&status=&include=lines,payments
+// y va escribiendo la respuesta a medida que llegan las filas del cursor
+func (c *ExportController) ExportInvoices(ctx *fiber.Ctx) error {
+	format := ctx.Query("format", export.FormatCSV)
+	if !export.ValidFormat(format) {
+		return apperror.Validation("Invalid query parameters", apperror.Field("format", "must be one of csv, jsonl, parquet"))
+	}
+
+	from, to, err := utils.ParseDateRange(ctx.Query("from"), ctx.Query("to"))
+	if err != nil {
+		return apperror.Validation("Invalid query parameters", apperror.Field("from", "must be a date range in YYYY-MM-DD format"))
+	}
+
+	filter := models.ExportFilter{
+		From:   from,
+		To:     to,
+		Status: ctx.Query("status"),
+	}
+
+	// Un filtro o un include con errata exportaría en silencio algo distinto de lo pedido
+	var fields []apperror.FieldError
+	if filter.Status != "" {
+		fields = append(fields, validation.Var("status", filter.Status, models.InvoiceStatusRules)...)
+	}
+	if value := ctx.Query("include"); value != "" {
+		for _, include := range strings.Split(value, ",") {
+			include = strings.TrimSpace(include)
+			if problems := validation.Var("include", include, models.ExportIncludeRules); len(problems) > 0 {
+				fields = append(fields, problems...)
+				break
+			}
+			filter.IncludeLines = filter.IncludeLines || include == "lines"
+			filter.IncludePayments = filter.IncludePayments || include == "payments"
+		}
+	}
+	if len(fields) > 0 {
+		return apperror.Validation("Invalid query parameters", fields...)
+	}
+
+	ctx.Set(fiber.HeaderContentType, export.ContentType(format))
+	ctx.Set(fiber.HeaderContentDisposition, `attachment; filename="invoices.`+format+`"`)
+
+	// El contexto de Fiber se recicla al volver del handler, por eso el volcado usa su propio contexto.
+	// Cuando falla a mitad el 200 ya está enviado: el error cierra la tubería, fasthttp no escribe el
+	// chunk final y corta la conexión, y el cliente ve una respuesta truncada en lugar de un fichero
+	// aparentemente completo. Si es el cliente quien se va, fasthttp cierra el lector y el volcado
+	// se detiene en la siguiente escritura.
+	scope := tenant.WithTenant(context.WithoutCancel(ctx.UserContext()), helpers.TenantID(ctx))
+	body, stream := io.Pipe()
+	go func() {
+		w := bufio.NewWriter(stream)
+		err := c.service.ExportInvoices(scope, filter, format, w)
+		if err == nil {
+			err = w.Flush()
+		}
+		if err != nil {
+			slog.ErrorContext(scope, "error exporting invoices", "error", err)
+			stream.CloseWithError(err)
+			return
+		}
+		stream.Close()
+	}()
+	ctx.Context().SetBodyStream(body, -1)
+
+	return nil
+}
@@ -0,0 +1,109 @@
+package controllers
+
+import (
+	"context"
+	"sass-billing-service/src/apperror"
+	"sass-billing-service/src/jobs"
+	"sass-billing-service/src/utils"
+	"sync/atomic"
+	"time"
+
+	"github.com/gofiber/fiber/v2"
+)
+
+// readinessTimeout acota cada comprobación para que la sonda no se quede colgada con la base de datos caída
+const readinessTimeout = 2 * time.Second
+
+type Pinger interface {
+	PingContext(ctx context.Context) error
+}
+
+// SchemaVersioner informa de la migración aplicada y de la última que trae el binario
+type SchemaVersioner interface {
+	Version(ctx context.Context) (int, error)
+	Latest() int
+}
+
+type Worker interface {
+	Status() jobs.Status
+}
+
+type ReadinessCheck struct {
+	OK     bool   `json:"ok"`
+	Detail string `json:"detail,omitempty"`
+}
+
+type Readiness struct {
+	Database   ReadinessCheck `json:"database"`
+	Migrations ReadinessCheck `json:"migrations"`
+	Workers    []jobs.Status  `json:"workers"`
+}
+
+type HealthController struct {
+	db           Pinger
+	schema       SchemaVersioner
+	workers      []Worker
+	shuttingDown atomic.Bool
+}
+
+func NewHealthController(db Pinger, schema SchemaVersioner, workers ...Worker) *HealthController {
+	return &HealthController{db: db, schema: schema, workers: workers}
+}
+
+// ShuttingDown hace que /readyz falle para que el balanceador deje de enviar tráfico mientras se drena
+func (c *HealthController) ShuttingDown() {
+	c.shuttingDown.Store(true)
+}
+
+// Liveness solo indica que el proceso atiende peticiones; no depende de la base de datos
+// para que una caída de Postgres no provoque reinicios en cadena
+func (c *HealthController) Liveness(ctx *fiber.Ctx) error {
+	return utils.SuccessResponse(ctx, fiber.StatusOK, fiber.Map{"status": "ok"})
+}
+
+// Readiness comprueba base de datos, versión del esquema y tareas en segundo plano. Si algo falla
+// responde 503 como cualquier otro error, con una entrada en errors por cada comprobación fallida.
+func (c *HealthController) Readiness(ctx *fiber.Ctx) error {
+	if c.shuttingDown.Load() {
+		return apperror.New(apperror.CodeUpstreamUnavailable, "Shutting down")
+	}
+
+	checkCtx, cancel := context.WithTimeout(ctx.UserContext(), readinessTimeout)
+	defer cancel()
+
+	readiness := Readiness{Database: ReadinessCheck{OK: true}, Migrations: ReadinessCheck{OK: true}, Workers: []jobs.Status{}}
+	var failures []apperror.FieldError
+
+	if err := c.db.PingContext(checkCtx); err != nil {
+		readiness.Database = ReadinessCheck{Detail: "database unreachable"}
+		failures = append(failures, apperror.Field("database", readiness.Database.Detail))
+	}
+
+	// Un esquema más nuevo que el binario es normal durante un despliegue progresivo
+	version, err := c.schema.Version(checkCtx)
+	switch {
+	case err != nil:
+		readiness.Migrations = ReadinessCheck{Detail: "schema version unavailable"}
+	case version < c.schema.Latest():
+		readiness.Migrations = ReadinessCheck{Detail: "pending migrations"}
+	}
+	if !readiness.Migrations.OK {
+		failures = append(failures, apperror.Field("migrations", readiness.Migrations.Detail))
+	}
+
+	for _, worker := range c.workers {
+		status := worker.Status()
+		if !status.Running {
+			failures = append(failures, apperror.Field("workers."+status.Name, "not running"))
+		}
+		readiness.Workers = append(readiness.Workers, status)
+	}
+
+	if len(failures) > 0 {
+		notReady := apperror.New(apperror.CodeUpstreamUnavailable, "Not ready")
+		notReady.Fields = failures
+		return notReady
+	}
+
+	return utils.SuccessResponse(ctx, fiber.StatusOK, readiness)
+}
@@ -1,45 +1,140 @@
 package controllers
 
 import (
+	"context"
+	"database/sql"
+	"errors"
+	"sass-billing-service/src/apperror"
+	"sass-billing-service/src/helpers"
 	"sass-billing-service/src/models"
+	"sass-billing-service/src/policy"
 	"sass-billing-service/src/services"
 	"sass-billing-service/src/utils"
+	"sass-billing-service/src/validation"
 	"strconv"
+	"strings"
 
 	"github.com/gofiber/fiber/v2"
 )
 
 type InvoiceController struct {
-	service *services.InvoiceService
+	service InvoiceService
 }
 
-func NewInvoiceController(service *services.InvoiceService) *InvoiceController {
+// InvoiceService son las operaciones de facturación que usa el controlador; la implementa services.InvoiceService
+type InvoiceService interface {
+	GetInvoiceByID(ctx context.Context, id int) (*models.Invoice, error)
+	ListInvoices(ctx context.Context, filter models.InvoiceFilter, page models.PageRequest) (*models.InvoicePage, error)
+	SearchInvoices(ctx context.Context, userID int, q string, limit int) ([]models.InvoiceSearchResult, error)
+	CreateInvoice(ctx context.Context, req *models.CreateInvoiceRequest) (*models.Invoice, error)
+	PayInvoice(ctx context.Context, id int) (*models.Invoice, error)
+	VoidInvoice(ctx context.Context, id int) (*models.Invoice, error)
+	RefundInvoice(ctx context.Context, id int) (*models.Invoice, error)
+}
+
+var _ InvoiceService = (*services.InvoiceService)(nil)
+
+func NewInvoiceController(service InvoiceService) *InvoiceController {
 	return &InvoiceController{service: service}
 }
 
+// GetInvoices lista las facturas del tenant con filtros opcionales y paginación por cursor:
+// ?user_id=&status=&created_from=&created_to=&min_amount=&max_amount=&currency=&payment_method=&sort=&limit=&cursor=
 func (c *InvoiceController) GetInvoices(ctx *fiber.Ctx) error {
-	userID, err := strconv.Atoi(ctx.Query("user_id"))
+	filter := models.InvoiceFilter{
+		Status:        ctx.Query("status"),
+		Currency:      strings.ToUpper(ctx.Query("currency")),
+		PaymentMethod: ctx.Query("payment_method"),
+	}
+
+	if value := ctx.Query("user_id"); value != "" {
+		userID, err := strconv.Atoi(value)
+		if err != nil {
+			return apperror.Validation("Invalid query parameters", apperror.Field("user_id", "must be an integer"))
+		}
+		filter.UserID = userID
+	}
+
+	userID, ok := scopedUserID(ctx, filter.UserID)
+	if !ok {
+		return apperror.Forbidden("Customers can only list their own invoices")
+	}
+	filter.UserID = userID
+
+	from, to, err := utils.ParseDateRange(ctx.Query("created_from"), ctx.Query("created_to"))
 	if err != nil {
-		return utils.ErrorResponse(ctx, fiber.StatusBadRequest, "Invalid user ID")
+		return apperror.Validation("Invalid query parameters", apperror.Field("created_from", "must be a date range in YYYY-MM-DD format"))
 	}
+	filter.CreatedFrom, filter.CreatedTo = from, to
 
-	invoices, err := c.service.GetInvoicesByUserID(ctx.Context(), userID)
+	// Se acumulan todos los parámetros inválidos para informar de ellos a la vez
+	var fields []apperror.FieldError
+	if filter.MinAmount, err = parseOptionalFloat(ctx.Query("min_amount")); err != nil {
+		fields = append(fields, apperror.Field("min_amount", "must be a number"))
+	}
+	if filter.MaxAmount, err = parseOptionalFloat(ctx.Query("max_amount")); err != nil {
+		fields = append(fields, apperror.Field("max_amount", "must be a number"))
+	}
+
+	page := models.PageRequest{Cursor: ctx.Query("cursor"), Sort: ctx.Query("sort")}
+	if value := ctx.Query("limit"); value != "" {
+		if page.Limit, err = strconv.Atoi(value); err != nil {
+			fields = append(fields, apperror.Field("limit", "must be an integer"))
+		} else {
+			fields = append(fields, validation.Var("limit", page.Limit, models.PageLimitRules)...)
+		}
+	}
+	if len(fields) > 0 {
+		return apperror.Validation("Invalid query parameters", fields...)
+	}
+
+	// ErrInvalidCursor y ErrInvalidSort ya son errores de validación
+	result, err := c.service.ListInvoices(ctx.UserContext(), filter, page)
 	if err != nil {
-		return utils.ErrorResponse(ctx, fiber.StatusInternalServerError, err.Error())
+		return err
 	}
 
-	return utils.SuccessResponse(ctx, fiber.StatusOK, invoices)
+	return utils.PaginatedResponse(ctx, fiber.StatusOK, result.Invoices, result.NextCursor)
+}
+
+// SearchInvoices hace búsqueda de texto completo con sintaxis web: ?q="march pro" upgrade -trial&limit=
+func (c *InvoiceController) SearchInvoices(ctx *fiber.Ctx) error {
+	q := strings.TrimSpace(ctx.Query("q"))
+	if q == "" {
+		return apperror.Validation("Missing search query", apperror.Field("q", "is required"))
+	}
+
+	limit := ctx.QueryInt("limit", models.DefaultPageSize)
+	if fields := validation.Var("limit", limit, models.PageLimitRules); len(fields) > 0 {
+		return apperror.Validation("Invalid query parameters", fields...)
+	}
+
+	userID, ok := scopedUserID(ctx, 0)
+	if !ok {
+		return apperror.Forbidden("Customers can only search their own invoices")
+	}
+
+	results, err := c.service.SearchInvoices(ctx.UserContext(), userID, q, limit)
+	if err != nil {
+		return err
+	}
+
+	return utils.SuccessResponse(ctx, fiber.StatusOK, results)
 }
 
 func (c *InvoiceController) GetInvoice(ctx *fiber.Ctx) error {
-	id, err := strconv.Atoi(ctx.Params("id"))
+	id, err := invoiceID(ctx)
 	if err != nil {
-		return utils.ErrorResponse(ctx, fiber.StatusBadRequest, "Invalid invoice ID")
+		return err
 	}
 
-	invoice, err := c.service.GetInvoiceByID(ctx.Context(), id)
+	invoice, err := c.service.GetInvoiceByID(ctx.UserContext(), id)
 	if err != nil {
-		return utils.ErrorResponse(ctx, fiber.StatusNotFound, "Invoice not found")
+		return invoiceError(err)
+	}
+
+	if !policy.CanAccessInvoice(helpers.CurrentPrincipal(ctx), policy.InvoicesRead, invoice) {
+		return apperror.Forbidden("Forbidden")
 	}
 
 	return utils.SuccessResponse(ctx, fiber.StatusOK, invoice)
@@ -48,18 +143,99 @@ func (c *InvoiceController) GetInvoice(ctx *fiber.Ctx) error {
 func (c *InvoiceController) CreateInvoice(ctx *fiber.Ctx) error {
 	var req models.CreateInvoiceRequest
 	if err := ctx.BodyParser(&req); err != nil {
-		return utils.ErrorResponse(ctx, fiber.StatusBadRequest, "Invalid request body")
+		return apperror.Validation("Invalid request body")
 	}
 
-	// Validar campos requeridos
-	if req.UserID == 0 || req.Amount <= 0 || req.Description == "" || req.PaymentMethod == "" {
-		return utils.ErrorResponse(ctx, fiber.StatusBadRequest, "Missing required fields")
+	// Las reglas están en las etiquetas validate y en Validate() del modelo
+	req.Normalize()
+	if err := validation.Struct(&req); err != nil {
+		return err
 	}
 
-	invoice, err := c.service.CreateInvoice(ctx.Context(), &req)
+	invoice, err := c.service.CreateInvoice(ctx.UserContext(), &req)
 	if err != nil {
-		return utils.ErrorResponse(ctx, fiber.StatusInternalServerError, err.Error())
+		return err
 	}
 
 	return utils.SuccessResponse(ctx, fiber.StatusCreated, invoice)
 }
+
+func invoiceID(ctx *fiber.Ctx) (int, error) {
+	id, err := strconv.Atoi(ctx.Params("id"))
+	if err != nil {
+		return 0, apperror.Validation("Invalid invoice ID", apperror.Field("id", "must be an integer"))
+	}
+
+	return id, nil
+}
+
+// invoiceError distingue una factura inexistente de un fallo de la base de datos, que no debe parecer un 404
+func invoiceError(err error) error {
+	if errors.Is(err, sql.ErrNoRows) {
+		return apperror.NotFound("Invoice not found").WithCause(err)
+	}
+
+	return err
+}
+
+func parseOptionalFloat(value string) (*float64, error) {
+	if value == "" {
+		return nil, nil
+	}
+
+	parsed, err := strconv.ParseFloat(value, 64)
+	if err != nil {
+		return nil, err
+	}
+
+	return &parsed, nil
+}
+
+func (c *InvoiceController) PayInvoice(ctx *fiber.Ctx) error {
+	return c.changeStatus(ctx, policy.InvoicesPay, c.service.PayInvoice)
+}
+
+func (c *InvoiceController) VoidInvoice(ctx *fiber.Ctx) error {
+	return c.changeStatus(ctx, policy.InvoicesVoid, c.service.VoidInvoice)
+}
+
+func (c *InvoiceController) RefundInvoice(ctx *fiber.Ctx) error {
+	return c.changeStatus(ctx, policy.InvoicesRefund, c.service.RefundInvoice)
+}
+
+func (c *InvoiceController) changeStatus(ctx *fiber.Ctx, permission policy.Permission, action func(context.Context, int) (*models.Invoice, error)) error {
+	id, err := invoiceID(ctx)
+	if err != nil {
+		return err
+	}
+
+	current, err := c.service.GetInvoiceByID(ctx.UserContext(), id)
+	if err != nil {
+		return invoiceError(err)
+	}
+	if !policy.CanAccessInvoice(helpers.CurrentPrincipal(ctx), permission, current) {
+		return apperror.Forbidden("Forbidden")
+	}
+
+	// ErrInvalidInvoiceStatus ya es un conflicto
+	invoice, err := action(ctx.UserContext(), id)
+	if err != nil {
+		return invoiceError(err)
+	}
+
+	return utils.SuccessResponse(ctx, fiber.StatusOK, invoice)
+}
+
+// scopedUserID aplica la restricción de propiedad: un cliente solo puede consultar su propio
+// user_id, así que se fuerza aunque no venga en la query y se rechaza si pide otro.
+func scopedUserID(ctx *fiber.Ctx, requested int) (int, bool) {
+	principal := helpers.CurrentPrincipal(ctx)
+	if !policy.OwnResourcesOnly(principal) {
+		return requested, true
+	}
+	if principal == nil || principal.UserID == 0 || (requested != 0 && requested != principal.UserID) {
+		return 0, false
+	}
+
+	return principal.UserID, true
+}
@@ -0,0 +1,34 @@
+package controllers
+
+import (
+	"context"
+	"sass-billing-service/src/models"
+	"sass-billing-service/src/services"
+	"sass-billing-service/src/utils"
+
+	"github.com/gofiber/fiber/v2"
+)
+
+type LedgerController struct {
+	service LedgerService
+}
+
+// LedgerService es lo que el controlador necesita del libro mayor; la implementa services.LedgerService
+type LedgerService interface {
+	GetTrialBalance(ctx context.Context) (*models.TrialBalance, error)
+}
+
+var _ LedgerService = (*services.LedgerService)(nil)
+
+func NewLedgerController(service LedgerService) *LedgerController {
+	return &LedgerController{service: service}
+}
+
+func (c *LedgerController) GetTrialBalance(ctx *fiber.Ctx) error {
+	balance, err := c.service.GetTrialBalance(ctx.UserContext())
+	if err != nil {
+		return err
+	}
+
+	return utils.SuccessResponse(ctx, fiber.StatusOK, balance)
+}
@@ -0,0 +1,140 @@
+package controllers
+
+import (
+	"context"
+	"sass-billing-service/src/apperror"
+	"sass-billing-service/src/models"
+	"sass-billing-service/src/reports"
+	"sass-billing-service/src/services"
+	"sass-billing-service/src/utils"
+	"time"
+
+	"github.com/gofiber/fiber/v2"
+)
+
+type ReportController struct {
+	revenueService RevenueService
+	metricsService MetricsService
+	agingService   AgingService
+}
+
+// RevenueService, MetricsService y AgingService son los informes que sirve el controlador;
+// los implementan los servicios del mismo nombre
+type RevenueService interface {
+	GetRevenueReport(ctx context.Context, from, to time.Time) (*models.RevenueReport, error)
+}
+
+type MetricsService interface {
+	GetMRRReport(ctx context.Context, from, to time.Time) (*reports.MRRReport, error)
+}
+
+type AgingService interface {
+	GetAgingReport(ctx context.Context, asOf time.Time) (*models.AgingReport, error)
+}
+
+var (
+	_ RevenueService = (*services.RevenueService)(nil)
+	_ MetricsService = (*services.MetricsService)(nil)
+	_ AgingService   = (*services.AgingService)(nil)
+)
+
+func NewReportController(revenueService RevenueService, metricsService MetricsService, agingService AgingService) *ReportController {
+	return &ReportController{revenueService: revenueService, metricsService: metricsService, agingService: agingService}
+}
+
+func (c *ReportController) GetRevenueReport(ctx *fiber.Ctx) error {
+	from, err := parseMonth(ctx.Query("from"))
+	if err != nil {
+		return invalidMonth("from")
+	}
+
+	to, err := parseMonth(ctx.Query("to"))
+	if err != nil {
+		return invalidMonth("to")
+	}
+
+	if to.Before(from) {
+		return apperror.Validation("Invalid query parameters", apperror.Field("to", "must not be before from"))
+	}
+
+	report, err := c.revenueService.GetRevenueReport(ctx.UserContext(), from, to)
+	if err != nil {
+		return err
+	}
+
+	return utils.SuccessResponse(ctx, fiber.StatusOK, report)
+}
+
+// GetMRRReport devuelve por defecto los últimos 12 meses; con ?format=csv responde en CSV
+func (c *ReportController) GetMRRReport(ctx *fiber.Ctx) error {
+	now := time.Now()
+	to := time.Date(now.Year(), now.Month(), 1, 0, 0, 0, 0, time.UTC)
+	from := to.AddDate(0, -11, 0)
+
+	var err error
+	if value := ctx.Query("from"); value != "" {
+		if from, err = parseMonth(value); err != nil {
+			return invalidMonth("from")
+		}
+	}
+	if value := ctx.Query("to"); value != "" {
+		if to, err = parseMonth(value); err != nil {
+			return invalidMonth("to")
+		}
+	}
+
+	if to.Before(from) {
+		return apperror.Validation("Invalid query parameters", apperror.Field("to", "must not be before from"))
+	}
+
+	// ErrMissingExchangeRate ya se traduce a 422
+	report, err := c.metricsService.GetMRRReport(ctx.UserContext(), from, to)
+	if err != nil {
+		return err
+	}
+
+	if ctx.Query("format") == "csv" {
+		ctx.Set(fiber.HeaderContentType, "text/csv")
+		ctx.Set(fiber.HeaderContentDisposition, `attachment; filename="mrr.csv"`)
+		return reports.WriteMRRCSV(ctx, report)
+	}
+
+	return utils.SuccessResponse(ctx, fiber.StatusOK, report)
+}
+
+// GetAgingReport calcula la antigüedad de saldos a fecha ?as_of= (hoy por defecto) para el tenant del token
+func (c *ReportController) GetAgingReport(ctx *fiber.Ctx) error {
+	asOf := time.Now()
+	if value := ctx.Query("as_of"); value != "" {
+		var err error
+		if asOf, err = time.Parse("2006-01-02", value); err != nil {
+			return apperror.Validation("Invalid query parameters", apperror.Field("as_of", "must be a date in YYYY-MM-DD format"))
+		}
+	}
+
+	report, err := c.agingService.GetAgingReport(ctx.UserContext(), asOf)
+	if err != nil {
+		return err
+	}
+
+	if ctx.Query("format") == "csv" {
+		ctx.Set(fiber.HeaderContentType, "text/csv")
+		ctx.Set(fiber.HeaderContentDisposition, `attachment; filename="ar-aging.csv"`)
+		return reports.WriteAgingCSV(ctx, report)
+	}
+
+	return utils.SuccessResponse(ctx, fiber.StatusOK, report)
+}
+
+func invalidMonth(field string) error {
+	return apperror.Validation("Invalid query parameters", apperror.Field(field, "must be a month in YYYY-MM format"))
+}
+
+// parseMonth acepta "2006-01" o una fecha completa "2006-01-02"
+func parseMonth(value string) (time.Time, error) {
+	if month, err := time.Parse("2006-01", value); err == nil {
+		return month, nil
+	}
+
+	return time.Parse("2006-01-02", value)
+}
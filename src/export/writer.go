@@ -0,0 +1,172 @@
+package export
+
+import (
+	"encoding/csv"
+	"encoding/json"
+	"errors"
+	"io"
+	"sass-billing-service/src/models"
+	"strconv"
+	"time"
+
+	"github.com/parquet-go/parquet-go"
+)
+
+const (
+	FormatCSV     = "csv"
+	FormatJSONL   = "jsonl"
+	FormatParquet = "parquet"
+)
+
+var ErrUnsupportedFormat = errors.New("unsupported export format")
+
+// RowWriter escribe filas de una en una; Close vuelca lo pendiente (el pie del fichero en Parquet)
+type RowWriter interface {
+	Write(row *models.ExportRow) error
+	Close() error
+}
+
+func NewWriter(format string, w io.Writer) (RowWriter, error) {
+	switch format {
+	case FormatCSV:
+		return newCSVWriter(w)
+	case FormatJSONL:
+		return &jsonlWriter{encoder: json.NewEncoder(w)}, nil
+	case FormatParquet:
+		return newParquetWriter(w), nil
+	default:
+		return nil, ErrUnsupportedFormat
+	}
+}
+
+func ContentType(format string) string {
+	switch format {
+	case FormatCSV:
+		return "text/csv"
+	case FormatJSONL:
+		return "application/x-ndjson"
+	default:
+		return "application/vnd.apache.parquet"
+	}
+}
+
+var csvHeader = []string{"id", "tenant_id", "invoice_number", "user_id", "customer_name", "customer_email", "amount", "tax_amount", "currency", "description", "status",
+	"payment_method", "due_date", "created_at", "updated_at", "lines", "payments"}
+
+type csvWriter struct {
+	writer *csv.Writer
+}
+
+func newCSVWriter(w io.Writer) (*csvWriter, error) {
+	writer := csv.NewWriter(w)
+	if err := writer.Write(csvHeader); err != nil {
+		return nil, err
+	}
+
+	return &csvWriter{writer: writer}, nil
+}
+
+// Las líneas y los pagos se escriben como JSON dentro de su columna
+func (c *csvWriter) Write(row *models.ExportRow) error {
+	return c.writer.Write([]string{
+		strconv.Itoa(row.ID),
+		row.TenantID,
+		row.InvoiceNumber,
+		strconv.Itoa(row.UserID),
+		row.CustomerName,
+		row.CustomerEmail,
+		strconv.FormatFloat(row.Amount, 'f', 2, 64),
+		strconv.FormatFloat(row.TaxAmount, 'f', 2, 64),
+		row.Currency,
+		row.Description,
+		row.Status,
+		row.PaymentMethod,
+		row.DueDate.Format("2006-01-02"),
+		row.CreatedAt.Format(time.RFC3339),
+		row.UpdatedAt.Format(time.RFC3339),
+		string(row.Lines),
+		string(row.Payments),
+	})
+}
+
+func (c *csvWriter) Close() error {
+	c.writer.Flush()
+	return c.writer.Error()
+}
+
+type jsonlWriter struct {
+	encoder *json.Encoder
+}
+
+func (j *jsonlWriter) Write(row *models.ExportRow) error {
+	return j.encoder.Encode(row)
+}
+
+func (j *jsonlWriter) Close() error {
+	return nil
+}
+
+type parquetRow struct {
+	ID            int64     `parquet:"id"`
+	TenantID      string    `parquet:"tenant_id,dict"`
+	InvoiceNumber string    `parquet:"invoice_number"`
+	UserID        int64     `parquet:"user_id"`
+	CustomerName  string    `parquet:"customer_name"`
+	CustomerEmail string    `parquet:"customer_email"`
+	Amount        float64   `parquet:"amount"`
+	TaxAmount     float64   `parquet:"tax_amount"`
+	Currency      string    `parquet:"currency,dict"`
+	Description   string    `parquet:"description"`
+	Status        string    `parquet:"status,dict"`
+	PaymentMethod string    `parquet:"payment_method,dict"`
+	DueDate       time.Time `parquet:"due_date,timestamp(millisecond)"`
+	CreatedAt     time.Time `parquet:"created_at,timestamp(millisecond)"`
+	UpdatedAt     time.Time `parquet:"updated_at,timestamp(millisecond)"`
+	Lines         string    `parquet:"lines,optional"`
+	Payments      string    `parquet:"payments,optional"`
+}
+
+// Cada grupo de filas se vuelca al alcanzar parquetRowGroupSize, así la memoria no crece con la exportación
+const parquetRowGroupSize = 10000
+
+type parquetWriter struct {
+	writer *parquet.GenericWriter[parquetRow]
+}
+
+func newParquetWriter(w io.Writer) *parquetWriter {
+	return &parquetWriter{
+		writer: parquet.NewGenericWriter[parquetRow](w, parquet.MaxRowsPerRowGroup(parquetRowGroupSize)),
+	}
+}
+
+func (p *parquetWriter) Write(row *models.ExportRow) error {
+	_, err := p.writer.Write([]parquetRow{{
+		ID:            int64(row.ID),
+		TenantID:      row.TenantID,
+		InvoiceNumber: row.InvoiceNumber,
+		UserID:        int64(row.UserID),
+		CustomerName:  row.CustomerName,
+		CustomerEmail: row.CustomerEmail,
+		Amount:        row.Amount,
+		TaxAmount:     row.TaxAmount,
+		Currency:      row.Currency,
+		Description:   row.Description,
+		Status:        row.Status,
+		PaymentMethod: row.PaymentMethod,
+		DueDate:       row.DueDate,
+		CreatedAt:     row.CreatedAt,
+		UpdatedAt:     row.UpdatedAt,
+		Lines:         string(row.Lines),
+		Payments:      string(row.Payments),
+	}})
+
+	return err
+}
+
+func (p *parquetWriter) Close() error {
+	return p.writer.Close()
+}
+
+func ValidFormat(format string) bool {
+	return format == FormatCSV || format == FormatJSONL || format == FormatParquet
+}
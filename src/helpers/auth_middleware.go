@@ -1,76 +1,123 @@
 package helpers
 
 import (
-	"database/sql"
-	"fmt"
-	"log"
-	"os"
+	"context"
+	"log/slog"
+	"sass-billing-service/src/apperror"
+	"sass-billing-service/src/logging"
+	"sass-billing-service/src/models"
+	"sass-billing-service/src/policy"
+	"sass-billing-service/src/tenant"
+	"strings"
 
 	"github.com/gofiber/fiber/v2"
 	"github.com/golang-jwt/jwt/v5"
-	"github.com/joho/godotenv"
 )
 
+// APIKeyHeader es la cabecera alternativa a Authorization para clientes de servidor a servidor
+const APIKeyHeader = "X-API-Key"
+
+// Claims son los claims propios del servicio; el sujeto es el claim registrado "sub"
 type Claims struct {
-	useranme string `json:"username"`
+	UserID   int      `json:"user_id"`
+	TenantID string   `json:"tenant_id"`
+	Roles    []string `json:"roles"`
 	jwt.RegisteredClaims
 }
 
-var db *sql.DB
+// APIKeyAuthenticator resuelve una API key al principal que representa
+type APIKeyAuthenticator interface {
+	Authenticate(ctx context.Context, key string) (*models.Principal, error)
+}
 
-func validateToken(tokenString string, jwtSecret []byte) (*Claims, error) {
-	token, err := jwt.ParseWithClaims(tokenString, &Claims{}, func(token *jwt.Token) (interface{}, error) {
-		return jwtSecret, nil
-	})
+// NewAuthMiddleware acepta "Authorization: Bearer <jwt>" o una API key en la cabecera X-API-Key;
+// las dos credenciales acaban en el mismo Principal
+func NewAuthMiddleware(tokens TokenVerifier, apiKeys APIKeyAuthenticator) fiber.Handler {
+	return func(c *fiber.Ctx) error {
+		key := c.Get(APIKeyHeader)
+		if key == "" {
+			return jwtAuth(c, tokens)
+		}
 
-	if err != nil {
-		return nil, fmt.Errorf("error parsing token: %v", err)
-	}
+		// ErrInvalidAPIKey ya es un 401; cualquier otro fallo lo clasifica el ErrorHandler
+		principal, err := apiKeys.Authenticate(c.UserContext(), key)
+		if err != nil {
+			return err
+		}
 
-	if Claims, ok := token.Claims.(*Claims); ok && token.Valid {
-		return Claims, nil
-	} else {
-		return nil, fmt.Errorf("invalid token")
+		return authenticated(c, principal)
 	}
 }
 
-func AuthMiddleware(c *fiber.Ctx) error {
-	err := godotenv.Load(".env")
-	if err != nil {
-		log.Fatal("Error loading .env file")
-		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
-			"error": "Internal server error",
-		})
-	}
-
-	jwtSecret := []byte(os.Getenv(os.Getenv("JWT_SECRET")))
-	if jwtSecret == nil {
-		log.Fatal("JWT_SECRET not set in .env file")
-		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
-			"error": "Internal server error",
-		})
-	}
-
-	tokenString := c.Get("Authorization")
+func jwtAuth(c *fiber.Ctx, tokens TokenVerifier) error {
+	tokenString := strings.TrimPrefix(c.Get("Authorization"), "Bearer ")
 
 	if tokenString == "" {
-		return c.Status(fiber.StatusUnauthorized).JSON(fiber.Map{
-			"error": "Authorization header is required",
-		})
+		return apperror.Unauthorized("Authorization header is required")
 	}
 
-	Claims, err := validateToken(tokenString, jwtSecret)
+	// El motivo concreto (firma, expiración, emisor...) solo va al log
+	Claims, err := tokens.Verify(c.UserContext(), tokenString)
 	if err != nil {
-		return c.Status(fiber.StatusUnauthorized).JSON(fiber.Map{
-			"error": fmt.Sprintf("Invalid token: %v", err),
-		})
+		slog.DebugContext(c.UserContext(), "invalid token", "error", err)
+		return apperror.Unauthorized("Invalid or expired token").WithCause(err)
+	}
+	// Sin sujeto, todos esos tokens compartirían las claves de idempotencia y los buckets del rate limit
+	if Claims.Subject == "" {
+		return apperror.Unauthorized("Token has no subject")
 	}
 
-	// Almacenar los claims en el contexto local de Fiber
-	c.Locals("user", Claims.useranme)
-	log.Println("User authenticated:", Claims.useranme)
+	return authenticated(c, &models.Principal{
+		Subject:    Claims.Subject,
+		UserID:     Claims.UserID,
+		TenantID:   Claims.TenantID,
+		Roles:      Claims.Roles,
+		AuthMethod: models.AuthMethodJWT,
+	})
+}
+
+// authenticated deja el principal en la petición y continúa con el siguiente handler. Una credencial
+// sin tenant se rechaza: asignarle uno por defecto la dejaría leer y escribir los datos de ese tenant.
+func authenticated(c *fiber.Ctx, principal *models.Principal) error {
+	if principal.TenantID == "" {
+		return apperror.Unauthorized("Credentials are not bound to a tenant")
+	}
+
+	// Almacenar la identidad en el contexto local de Fiber
+	c.Locals("user", principal.Subject)
+	c.Locals("tenant_id", principal.TenantID)
+	c.Locals("principal", principal)
+	// El tenant viaja en el contexto hasta los repositorios, que lo aplican a cada consulta
+	// y el principal acompaña a cada línea de log de la petición
+	ctx := logging.WithPrincipal(tenant.WithTenant(c.UserContext(), principal.TenantID), principal.Subject)
+	c.SetUserContext(ctx)
+	slog.DebugContext(ctx, "authenticated", "auth_method", principal.AuthMethod)
 
 	// Continuar con el siguiente middleware/handler
 	return c.Next()
+}
+
+// TenantID devuelve el tenant del principal autenticado; el tenant por defecto solo queda para rutas sin autenticación
+func TenantID(c *fiber.Ctx) string {
+	if tenantID, ok := c.Locals("tenant_id").(string); ok && tenantID != "" {
+		return tenantID
+	}
 
+	return models.DefaultTenantID
+}
+
+func CurrentPrincipal(c *fiber.Ctx) *models.Principal {
+	principal, _ := c.Locals("principal").(*models.Principal)
+	return principal
+}
+
+// RequirePermission corta la petición con 403 si ni los roles del token ni los scopes de la API key conceden el permiso
+func RequirePermission(permission policy.Permission) fiber.Handler {
+	return func(c *fiber.Ctx) error {
+		if !policy.Can(CurrentPrincipal(c), permission) {
+			return apperror.Forbidden("Missing permission " + string(permission))
+		}
+
+		return c.Next()
+	}
 }
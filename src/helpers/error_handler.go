@@ -0,0 +1,92 @@
+package helpers
+
+import (
+	"errors"
+	"log/slog"
+	"net/http"
+	"sass-billing-service/src/apperror"
+	"sass-billing-service/src/logging"
+
+	"github.com/gofiber/fiber/v2"
+	"go.opentelemetry.io/otel/trace"
+)
+
+const ProblemContentType = "application/problem+json"
+
+// Problem es el cuerpo de error de RFC 7807, con el código estable y los campos inválidos como extensiones
+type Problem struct {
+	Type      string                `json:"type"`
+	Title     string                `json:"title"`
+	Status    int                   `json:"status"`
+	Detail    string                `json:"detail,omitempty"`
+	Instance  string                `json:"instance,omitempty"`
+	Code      apperror.Code         `json:"code"`
+	RequestID string                `json:"request_id,omitempty"`
+	Errors    []apperror.FieldError `json:"errors,omitempty"`
+}
+
+// fiberCodes traduce los errores propios de Fiber (ruta inexistente, método no permitido, cuerpo demasiado grande...)
+var fiberCodes = map[int]apperror.Code{
+	fiber.StatusBadRequest:            apperror.CodeValidationFailed,
+	fiber.StatusUnauthorized:          apperror.CodeUnauthorized,
+	fiber.StatusForbidden:             apperror.CodeForbidden,
+	fiber.StatusNotFound:              apperror.CodeNotFound,
+	fiber.StatusMethodNotAllowed:      apperror.CodeNotFound,
+	fiber.StatusConflict:              apperror.CodeConflict,
+	fiber.StatusRequestEntityTooLarge: apperror.CodeValidationFailed,
+	fiber.StatusUnprocessableEntity:   apperror.CodeUnprocessable,
+	fiber.StatusTooManyRequests:       apperror.CodeRateLimited,
+	fiber.StatusServiceUnavailable:    apperror.CodeUpstreamUnavailable,
+}
+
+// ErrorHandler es el ErrorHandler de Fiber: convierte cualquier error devuelto por un handler en
+// application/problem+json. Los errores internos se registran con su causa y al cliente solo
+// le llega un mensaje genérico.
+func ErrorHandler(c *fiber.Ctx, err error) error {
+	status, problem := http.StatusInternalServerError, apperror.From(err)
+
+	var fiberErr *fiber.Error
+	if errors.As(err, &fiberErr) {
+		status = fiberErr.Code
+		code, ok := fiberCodes[status]
+		if !ok {
+			code = apperror.CodeInternal
+		}
+		problem = apperror.New(code, fiberErr.Message)
+	} else {
+		status = problem.Status()
+	}
+
+	if status >= fiber.StatusInternalServerError {
+		slog.ErrorContext(c.UserContext(), "request failed", "code", problem.Code, "error", err)
+		trace.SpanFromContext(c.UserContext()).RecordError(err)
+	}
+
+	return c.Status(status).JSON(Problem{
+		Type:      "/problems/" + string(problem.Code),
+		Title:     http.StatusText(status),
+		Status:    status,
+		Detail:    problem.Message,
+		Instance:  c.Path(),
+		Code:      problem.Code,
+		RequestID: logging.RequestID(c.UserContext()),
+		Errors:    problem.Fields,
+	}, ProblemContentType)
+}
+
+// HandleErrors convierte una sola vez el error de los handlers en respuesta con el ErrorHandler de la
+// aplicación. Va después de los middlewares de métricas, trazas y logs, que así leen el estado real.
+func HandleErrors() fiber.Handler {
+	return func(c *fiber.Ctx) error {
+		if err := c.Next(); err != nil {
+			respondError(c, err)
+		}
+		return nil
+	}
+}
+
+func respondError(c *fiber.Ctx, err error) {
+	if handlerErr := c.App().ErrorHandler(c, err); handlerErr != nil {
+		_ = c.SendStatus(fiber.StatusInternalServerError)
+	}
+}
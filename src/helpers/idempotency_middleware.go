@@ -0,0 +1,105 @@
+package helpers
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"log/slog"
+	"sass-billing-service/src/apperror"
+	"sass-billing-service/src/models"
+	"sass-billing-service/src/tenant"
+	"time"
+
+	"github.com/gofiber/fiber/v2"
+)
+
+const IdempotencyKeyHeader = "Idempotency-Key"
+
+// IdempotencyStore guarda las claves de cada principal dentro del tenant que lleve el contexto
+type IdempotencyStore interface {
+	Reserve(ctx context.Context, principal, key, fingerprint string, ttl time.Duration) (*models.IdempotencyRecord, bool, error)
+	Complete(ctx context.Context, principal, key string, statusCode int, contentType string, body []byte) error
+	Release(ctx context.Context, principal, key string) error
+}
+
+// IdempotencyMiddleware repite la respuesta original cuando un cliente reintenta con la misma
+// Idempotency-Key. Debe ir después de AuthMiddleware porque las claves se aíslan por tenant y principal:
+// la respuesta guardada solo se repite a quien creó la clave.
+func IdempotencyMiddleware(store IdempotencyStore, ttl time.Duration) fiber.Handler {
+	return func(c *fiber.Ctx) error {
+		key := c.Get(IdempotencyKeyHeader)
+		if key == "" {
+			return c.Next()
+		}
+
+		if len(key) > 255 {
+			return apperror.Validation("Invalid Idempotency-Key", apperror.Field(IdempotencyKeyHeader, "must be at most 255 characters"))
+		}
+
+		// La respuesta se guarda después del handler, cuando el contexto de Fiber ya puede estar reciclado;
+		// se conservan los valores del contexto (tenant, request ID) pero no su cancelación
+		scope := tenant.WithTenant(context.WithoutCancel(c.UserContext()), TenantID(c))
+		fingerprint := requestFingerprint(c)
+		var principal string
+		if current := CurrentPrincipal(c); current != nil {
+			principal = current.Subject
+		}
+
+		record, reserved, err := store.Reserve(scope, principal, key, fingerprint, ttl)
+		if err != nil {
+			return err
+		}
+
+		if !reserved {
+			if record.Fingerprint != fingerprint {
+				return apperror.Unprocessable("Idempotency-Key was already used with a different request")
+			}
+
+			if !record.Completed() {
+				return apperror.Conflict("A request with this Idempotency-Key is still being processed")
+			}
+
+			c.Set("Idempotent-Replayed", "true")
+			c.Set(fiber.HeaderContentType, record.ContentType)
+			return c.Status(record.StatusCode).Send(record.ResponseBody)
+		}
+
+		// Los errores de los handlers se convierten aquí en respuesta para poder memorizar los 4xx
+		if err := c.Next(); err != nil {
+			respondError(c, err)
+		}
+
+		// Los errores de servidor no se memorizan: el cliente debe poder reintentar
+		status := c.Response().StatusCode()
+		if status >= fiber.StatusInternalServerError {
+			releaseKey(scope, store, principal, key)
+			return nil
+		}
+
+		body := append([]byte(nil), c.Response().Body()...)
+		contentType := string(c.Response().Header.ContentType())
+		if err := store.Complete(scope, principal, key, status, contentType, body); err != nil {
+			slog.ErrorContext(scope, "error storing idempotent response", "error", err)
+		}
+
+		return nil
+	}
+}
+
+// requestFingerprint identifica la petición por método, ruta y cuerpo
+func requestFingerprint(c *fiber.Ctx) string {
+	hash := sha256.New()
+	hash.Write([]byte(c.Method()))
+	hash.Write([]byte{' '})
+	hash.Write([]byte(c.Path()))
+	hash.Write([]byte{'\n'})
+	hash.Write(c.Body())
+
+	return hex.EncodeToString(hash.Sum(nil))
+}
+
+func releaseKey(ctx context.Context, store IdempotencyStore, principal, key string) {
+	if err := store.Release(ctx, principal, key); err != nil {
+		slog.ErrorContext(ctx, "error releasing idempotency key", "error", err)
+	}
+}
@@ -0,0 +1,118 @@
+package helpers
+
+import (
+	"context"
+	"crypto/ecdsa"
+	"crypto/rsa"
+	"errors"
+	"fmt"
+	"sass-billing-service/src/jwks"
+	"strings"
+	"time"
+
+	"github.com/golang-jwt/jwt/v5"
+)
+
+// TokenVerifier valida un JWT y devuelve sus claims
+type TokenVerifier interface {
+	Verify(ctx context.Context, tokenString string) (*Claims, error)
+}
+
+type JWTVerifierOptions struct {
+	// Secreto HMAC; solo se usa si no hay JWKS configurado
+	Secret []byte
+	// Claves públicas del proveedor de identidad
+	KeySet *jwks.KeySet
+	// Algoritmos aceptados en la cabecera "alg"
+	Algorithms []string
+	Issuer     string
+	Audience   string
+	// Margen tolerado en exp, nbf e iat por desfase de relojes
+	ClockSkew time.Duration
+}
+
+type JWTVerifier struct {
+	options JWTVerifierOptions
+	parser  *jwt.Parser
+}
+
+func NewJWTVerifier(options JWTVerifierOptions) (*JWTVerifier, error) {
+	if options.KeySet == nil && len(options.Secret) == 0 {
+		return nil, errors.New("either a JWT secret or a JWKS source is required")
+	}
+	if len(options.Algorithms) == 0 {
+		return nil, errors.New("at least one JWT algorithm must be allowed")
+	}
+
+	for _, alg := range options.Algorithms {
+		method := jwt.GetSigningMethod(alg)
+		if method == nil || alg == jwt.SigningMethodNone.Alg() {
+			return nil, fmt.Errorf("unsupported JWT algorithm %q", alg)
+		}
+		// Con JWKS solo se aceptan firmas asimétricas: un HS256 firmado con la clave pública no debe colar
+		if _, hmac := method.(*jwt.SigningMethodHMAC); hmac == (options.KeySet != nil) {
+			return nil, fmt.Errorf("JWT algorithm %q does not match the configured key source", alg)
+		}
+	}
+
+	parserOptions := []jwt.ParserOption{
+		jwt.WithValidMethods(options.Algorithms),
+		jwt.WithLeeway(options.ClockSkew),
+		jwt.WithIssuedAt(),
+	}
+	if options.Issuer != "" {
+		parserOptions = append(parserOptions, jwt.WithIssuer(options.Issuer))
+	}
+	if options.Audience != "" {
+		parserOptions = append(parserOptions, jwt.WithAudience(options.Audience))
+	}
+
+	return &JWTVerifier{options: options, parser: jwt.NewParser(parserOptions...)}, nil
+}
+
+func (v *JWTVerifier) Verify(ctx context.Context, tokenString string) (*Claims, error) {
+	token, err := v.parser.ParseWithClaims(tokenString, &Claims{}, func(token *jwt.Token) (interface{}, error) {
+		if v.options.KeySet == nil {
+			return v.options.Secret, nil
+		}
+		return v.publicKey(ctx, token)
+	})
+	if err != nil {
+		return nil, fmt.Errorf("error parsing token: %v", err)
+	}
+
+	if claims, ok := token.Claims.(*Claims); ok && token.Valid {
+		return claims, nil
+	}
+	return nil, fmt.Errorf("invalid token")
+}
+
+// publicKey elige la clave por kid y comprueba que su tipo corresponde al algoritmo del token
+func (v *JWTVerifier) publicKey(ctx context.Context, token *jwt.Token) (interface{}, error) {
+	kid, _ := token.Header["kid"].(string)
+	if kid == "" {
+		return nil, errors.New("token has no kid")
+	}
+
+	key, err := v.options.KeySet.Key(ctx, kid)
+	if err != nil {
+		return nil, err
+	}
+
+	alg := token.Method.Alg()
+	if key.Algorithm != "" && key.Algorithm != alg {
+		return nil, fmt.Errorf("key %q is not valid for %s", kid, alg)
+	}
+
+	switch key.Public.(type) {
+	case *rsa.PublicKey:
+		if strings.HasPrefix(alg, "RS") || strings.HasPrefix(alg, "PS") {
+			return key.Public, nil
+		}
+	case *ecdsa.PublicKey:
+		if strings.HasPrefix(alg, "ES") {
+			return key.Public, nil
+		}
+	}
+	return nil, fmt.Errorf("key %q is not valid for %s", kid, alg)
+}
@@ -0,0 +1,85 @@
+package helpers
+
+import (
+	"context"
+	"log/slog"
+	"math"
+	"sass-billing-service/src/apperror"
+	"sass-billing-service/src/ratelimit"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/gofiber/fiber/v2"
+)
+
+// RateLimitStore consume un token del bucket indicado; lo cumplen ratelimit.MemoryStore
+// y repositories.RateLimitRepository
+type RateLimitStore interface {
+	Take(ctx context.Context, key string, limit ratelimit.Limit) (ratelimit.Result, error)
+}
+
+// RateLimitMiddleware aplica dos token buckets: uno por principal y ruta y otro para todo el tenant.
+// Debe ir después de AuthMiddleware y dentro de la ruta, cuando ya se conocen el principal y el
+// patrón de la ruta. Si el almacén falla la petición pasa: el limitador no debe tumbar la API.
+func RateLimitMiddleware(store RateLimitStore, policy ratelimit.Policy) fiber.Handler {
+	return func(c *fiber.Ctx) error {
+		ctx := c.UserContext()
+		tenantID := TenantID(c)
+
+		// Sin principal (rutas públicas) se limita por IP
+		subject := "ip:" + c.IP()
+		if principal := CurrentPrincipal(c); principal != nil {
+			subject = "principal:" + tenantID + ":" + principal.Subject
+		}
+
+		// Las rutas de grupo ("/api/invoices/") se configuran sin la barra final
+		route := c.Method() + " " + strings.TrimSuffix(c.Route().Path, "/")
+
+		var binding *ratelimit.Result
+		if limit := policy.RouteLimit(route); limit.Enabled() {
+			result, err := store.Take(ctx, "route:"+route+":"+subject, limit)
+			if err != nil {
+				slog.ErrorContext(ctx, "error applying rate limit", "error", err)
+				return c.Next()
+			}
+			binding = &result
+		}
+
+		// El bucket del tenant solo se gasta si la petición ha pasado el de su principal
+		if limit := policy.TenantLimit(tenantID); limit.Enabled() && tenantID != "" && (binding == nil || binding.Allowed) {
+			result, err := store.Take(ctx, "tenant:"+tenantID, limit)
+			if err != nil {
+				slog.ErrorContext(ctx, "error applying rate limit", "error", err)
+				return c.Next()
+			}
+			if binding == nil || !result.Allowed || result.Remaining < binding.Remaining {
+				binding = &result
+			}
+		}
+
+		if binding == nil {
+			return c.Next()
+		}
+
+		setRateLimitHeaders(c, binding)
+		if !binding.Allowed {
+			c.Set(fiber.HeaderRetryAfter, strconv.Itoa(ceilSeconds(binding.RetryAfter)))
+			return apperror.RateLimited("Rate limit exceeded")
+		}
+
+		return c.Next()
+	}
+}
+
+// setRateLimitHeaders publica el estado del bucket más restrictivo con las cabeceras RateLimit-*
+// del borrador de la IETF; Reset son segundos hasta que el bucket vuelve a estar lleno
+func setRateLimitHeaders(c *fiber.Ctx, result *ratelimit.Result) {
+	c.Set("RateLimit-Limit", strconv.Itoa(result.Limit))
+	c.Set("RateLimit-Remaining", strconv.Itoa(result.Remaining))
+	c.Set("RateLimit-Reset", strconv.Itoa(ceilSeconds(result.Reset)))
+}
+
+func ceilSeconds(d time.Duration) int {
+	return int(math.Ceil(d.Seconds()))
+}
@@ -0,0 +1,66 @@
+package helpers
+
+import (
+	"crypto/rand"
+	"encoding/hex"
+	"log/slog"
+	"regexp"
+	"sass-billing-service/src/logging"
+	"time"
+
+	"github.com/gofiber/fiber/v2"
+)
+
+const RequestIDHeader = "X-Request-ID"
+
+// Solo se acepta un X-Request-ID entrante con forma razonable para no inyectar basura en los logs
+var validRequestID = regexp.MustCompile(`^[A-Za-z0-9._:-]{1,128}$`)
+
+// RequestID reutiliza el X-Request-ID del cliente o genera uno, lo devuelve en la respuesta
+// y lo deja en el contexto para que aparezca en los logs de servicios y repositorios
+func RequestID() fiber.Handler {
+	return func(c *fiber.Ctx) error {
+		requestID := c.Get(RequestIDHeader)
+		if !validRequestID.MatchString(requestID) {
+			requestID = newRequestID()
+		}
+
+		c.Set(RequestIDHeader, requestID)
+		c.Locals("request_id", requestID)
+		c.SetUserContext(logging.WithRequestID(c.UserContext(), requestID))
+
+		return c.Next()
+	}
+}
+
+func newRequestID() string {
+	buf := make([]byte, 16)
+	rand.Read(buf)
+	return hex.EncodeToString(buf)
+}
+
+// AccessLog escribe una línea estructurada por petición; la ruta se registra sin query string.
+// Como metrics.Middleware y tracing.Middleware, lee el estado que deja HandleErrors.
+func AccessLog() fiber.Handler {
+	return func(c *fiber.Ctx) error {
+		start := time.Now()
+		err := c.Next()
+		status := c.Response().StatusCode()
+
+		level := slog.LevelInfo
+		if status >= fiber.StatusInternalServerError {
+			level = slog.LevelError
+		}
+
+		slog.Log(c.UserContext(), level, "request",
+			"method", c.Method(),
+			"path", c.Path(),
+			"route", c.Route().Path,
+			"status", status,
+			"duration_ms", time.Since(start).Milliseconds(),
+			"ip", c.IP(),
+		)
+
+		return err
+	}
+}
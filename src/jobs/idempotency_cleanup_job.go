@@ -0,0 +1,56 @@
+package jobs
+
+import (
+	"context"
+	"log/slog"
+	"sass-billing-service/src/repositories"
+	"sass-billing-service/src/tenant"
+	"time"
+)
+
+// ExpiredKeyDeleter borra las claves caducadas; lo cumplen repositories.IdempotencyRepository
+// y repositories.MemoryIdempotencyRepository
+type ExpiredKeyDeleter interface {
+	DeleteExpired(ctx context.Context) (int64, error)
+}
+
+var (
+	_ ExpiredKeyDeleter = (*repositories.IdempotencyRepository)(nil)
+	_ ExpiredKeyDeleter = (*repositories.MemoryIdempotencyRepository)(nil)
+)
+
+// IdempotencyCleanupJob borra las Idempotency-Key caducadas para que la tabla no crezca sin límite
+type IdempotencyCleanupJob struct {
+	worker
+	repo     ExpiredKeyDeleter
+	interval time.Duration
+}
+
+func NewIdempotencyCleanupJob(repo ExpiredKeyDeleter, interval time.Duration) *IdempotencyCleanupJob {
+	return &IdempotencyCleanupJob{worker: newWorker("idempotency-cleanup"), repo: repo, interval: interval}
+}
+
+func (j *IdempotencyCleanupJob) Start(ctx context.Context) {
+	ctx = tenant.WithSystem(ctx)
+	j.started()
+	go func() {
+		defer j.stopped()
+		ticker := time.NewTicker(j.interval)
+		defer ticker.Stop()
+
+		for {
+			select {
+			case <-ctx.Done():
+				return
+			case <-ticker.C:
+				deleted, err := j.repo.DeleteExpired(ctx)
+				j.record(err)
+				if err != nil {
+					slog.ErrorContext(ctx, "error deleting expired idempotency keys", "job", j.name, "error", err)
+				} else if deleted > 0 {
+					slog.InfoContext(ctx, "deleted expired idempotency keys", "job", j.name, "deleted", deleted)
+				}
+			}
+		}
+	}()
+}
@@ -0,0 +1,47 @@
+package jobs
+
+import (
+	"context"
+	"log/slog"
+	"sass-billing-service/src/repositories"
+	"time"
+)
+
+// staleBucketAge es la ventana más larga que admite un límite ("/h"): un bucket sin uso
+// durante ese tiempo ya está lleno y se puede borrar sin cambiar el resultado
+const staleBucketAge = time.Hour
+
+// RateLimitCleanupJob borra los buckets del limitador en PostgreSQL que ya no aportan nada
+type RateLimitCleanupJob struct {
+	worker
+	repo     *repositories.RateLimitRepository
+	interval time.Duration
+}
+
+func NewRateLimitCleanupJob(repo *repositories.RateLimitRepository, interval time.Duration) *RateLimitCleanupJob {
+	return &RateLimitCleanupJob{worker: newWorker("rate-limit-cleanup"), repo: repo, interval: interval}
+}
+
+func (j *RateLimitCleanupJob) Start(ctx context.Context) {
+	j.started()
+	go func() {
+		defer j.stopped()
+		ticker := time.NewTicker(j.interval)
+		defer ticker.Stop()
+
+		for {
+			select {
+			case <-ctx.Done():
+				return
+			case <-ticker.C:
+				deleted, err := j.repo.DeleteStale(ctx, time.Now().Add(-staleBucketAge))
+				j.record(err)
+				if err != nil {
+					slog.ErrorContext(ctx, "error deleting stale rate limit buckets", "job", j.name, "error", err)
+				} else if deleted > 0 {
+					slog.DebugContext(ctx, "deleted stale rate limit buckets", "job", j.name, "deleted", deleted)
+				}
+			}
+		}
+	}()
+}
@@ -0,0 +1,57 @@
+package jobs
+
+import (
+	"context"
+	"log/slog"
+	"sass-billing-service/src/services"
+	"sass-billing-service/src/tenant"
+	"time"
+)
+
+// RevenueCloseJob cierra periódicamente los meses ya terminados, moviendo lo programado
+// de ingresos diferidos a ingresos reconocidos
+type RevenueCloseJob struct {
+	worker
+	service  *services.RevenueService
+	interval time.Duration
+}
+
+func NewRevenueCloseJob(service *services.RevenueService, interval time.Duration) *RevenueCloseJob {
+	return &RevenueCloseJob{worker: newWorker("revenue-close"), service: service, interval: interval}
+}
+
+func (j *RevenueCloseJob) Start(ctx context.Context) {
+	j.started()
+	go func() {
+		defer j.stopped()
+		ticker := time.NewTicker(j.interval)
+		defer ticker.Stop()
+
+		for {
+			j.RunOnce(ctx, time.Now())
+
+			select {
+			case <-ctx.Done():
+				return
+			case <-ticker.C:
+			}
+		}
+	}()
+}
+
+// RunOnce cierra hasta el mes anterior a now para todos los tenants; el mes en curso se cierra cuando termina
+func (j *RevenueCloseJob) RunOnce(ctx context.Context, now time.Time) {
+	ctx = tenant.WithSystem(ctx)
+	lastClosedMonth := time.Date(now.Year(), now.Month(), 1, 0, 0, 0, 0, time.UTC).AddDate(0, -1, 0)
+
+	entries, err := j.service.CloseMonth(ctx, lastClosedMonth)
+	j.record(err)
+	if err != nil {
+		slog.ErrorContext(ctx, "error closing revenue", "job", j.name, "through", lastClosedMonth.Format("2006-01"), "error", err)
+		return
+	}
+
+	if len(entries) > 0 {
+		slog.InfoContext(ctx, "revenue recognized", "job", j.name, "periods", len(entries), "through", lastClosedMonth.Format("2006-01"))
+	}
+}
@@ -0,0 +1,70 @@
+package jobs
+
+import (
+	"sync"
+	"time"
+)
+
+// Status es el estado de una tarea periódica tal como lo publica /readyz
+type Status struct {
+	Name      string     `json:"name"`
+	Running   bool       `json:"running"`
+	LastRunAt *time.Time `json:"last_run_at,omitempty"`
+	LastError string     `json:"last_error,omitempty"`
+}
+
+// worker lleva el estado común de las tareas: si el bucle sigue vivo, la última ejecución
+// y un canal que se cierra al terminar para que el apagado pueda esperarlo
+type worker struct {
+	name string
+
+	mu      sync.Mutex
+	running bool
+	lastRun time.Time
+	lastErr error
+	done    chan struct{}
+}
+
+func newWorker(name string) worker {
+	return worker{name: name, done: make(chan struct{})}
+}
+
+func (w *worker) started() {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	w.running = true
+}
+
+func (w *worker) stopped() {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	w.running = false
+	close(w.done)
+}
+
+func (w *worker) record(err error) {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	w.lastRun, w.lastErr = time.Now(), err
+}
+
+// Done se cierra cuando el bucle de la tarea ha terminado tras cancelar su contexto
+func (w *worker) Done() <-chan struct{} {
+	return w.done
+}
+
+func (w *worker) Status() Status {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+
+	status := Status{Name: w.name, Running: w.running}
+	if !w.lastRun.IsZero() {
+		lastRun := w.lastRun
+		status.LastRunAt = &lastRun
+	}
+	if w.lastErr != nil {
+		status.LastError = w.lastErr.Error()
+	}
+
+	return status
+}
@@ -0,0 +1,228 @@
+package jwks
+
+import (
+	"context"
+	"crypto"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rsa"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"log/slog"
+	"math/big"
+	"net/http"
+	"os"
+	"strings"
+	"sync"
+	"time"
+
+	"sass-billing-service/src/tracing"
+)
+
+var ErrKeyNotFound = errors.New("signing key not found in JWKS")
+
+// Key es una clave pública de firma publicada por el proveedor de identidad
+type Key struct {
+	ID        string
+	Algorithm string
+	Public    crypto.PublicKey
+}
+
+type jsonWebKey struct {
+	Kty string `json:"kty"`
+	Kid string `json:"kid"`
+	Use string `json:"use"`
+	Alg string `json:"alg"`
+	N   string `json:"n"`
+	E   string `json:"e"`
+	Crv string `json:"crv"`
+	X   string `json:"x"`
+	Y   string `json:"y"`
+}
+
+// KeySet cachea un documento JWKS leído de un fichero o de una URL http(s).
+// Se recarga cuando caduca la caché o cuando llega un kid desconocido (rotación de claves),
+// pero nunca más de una vez por minRefresh para que un token inventado no sature al proveedor.
+// La descarga se hace fuera del cerrojo y solo hay una en curso a la vez.
+type KeySet struct {
+	source     string
+	refresh    time.Duration
+	minRefresh time.Duration
+	client     *http.Client
+
+	mu          sync.Mutex
+	keys        map[string]*Key
+	loadErr     error
+	loadedAt    time.Time
+	attemptedAt time.Time
+	// loading se cierra al terminar la recarga en curso; nil si no hay ninguna
+	loading chan struct{}
+}
+
+func NewKeySet(source string, refresh, minRefresh time.Duration) *KeySet {
+	return &KeySet{
+		source:     source,
+		refresh:    refresh,
+		minRefresh: minRefresh,
+		client:     &http.Client{Timeout: 10 * time.Second, Transport: tracing.Transport(nil)},
+	}
+}
+
+// Key devuelve la clave con el kid indicado. Una clave ya conocida se sirve de la caché aunque
+// haya caducado mientras se recarga en segundo plano; un kid desconocido espera a la recarga.
+// Si la recarga falla se sigue sirviendo la copia en caché.
+func (s *KeySet) Key(ctx context.Context, kid string) (*Key, error) {
+	s.mu.Lock()
+	now := time.Now()
+	key, known := s.keys[kid]
+	stale := now.Sub(s.loadedAt) >= s.refresh
+	if (stale || !known) && s.loading == nil && now.Sub(s.attemptedAt) >= s.minRefresh {
+		s.attemptedAt = now
+		s.loading = make(chan struct{})
+		// La recarga conserva la traza de la petición pero no su cancelación
+		go s.reload(context.WithoutCancel(ctx), s.loading)
+	}
+	loading := s.loading
+	s.mu.Unlock()
+
+	if !known && loading != nil {
+		select {
+		case <-loading:
+		case <-ctx.Done():
+			return nil, ctx.Err()
+		}
+
+		s.mu.Lock()
+		key, known = s.keys[kid]
+		cached, err := s.keys != nil, s.loadErr
+		s.mu.Unlock()
+		if !cached && err != nil {
+			return nil, err
+		}
+	}
+
+	if !known {
+		return nil, fmt.Errorf("%w: kid %q", ErrKeyNotFound, kid)
+	}
+	return key, nil
+}
+
+func (s *KeySet) reload(ctx context.Context, done chan struct{}) {
+	keys, err := s.load(ctx)
+
+	s.mu.Lock()
+	s.loadErr = err
+	if err == nil {
+		s.keys, s.loadedAt = keys, time.Now()
+	}
+	s.loading = nil
+	s.mu.Unlock()
+	close(done)
+}
+
+func (s *KeySet) load(ctx context.Context) (map[string]*Key, error) {
+	body, err := s.read(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("error reading JWKS: %w", err)
+	}
+
+	var document struct {
+		Keys []jsonWebKey `json:"keys"`
+	}
+	if err := json.Unmarshal(body, &document); err != nil {
+		return nil, fmt.Errorf("error decoding JWKS: %w", err)
+	}
+
+	keys := make(map[string]*Key, len(document.Keys))
+	for _, jwk := range document.Keys {
+		// Las claves de cifrado no sirven para verificar firmas
+		if jwk.Use != "" && jwk.Use != "sig" {
+			continue
+		}
+		// Una clave que no sabemos leer (p. ej. OKP/Ed25519) no debe invalidar las demás
+		public, err := jwk.publicKey()
+		if err != nil {
+			slog.WarnContext(ctx, "skipping unsupported JWKS key", "kid", jwk.Kid, "error", err)
+			continue
+		}
+		keys[jwk.Kid] = &Key{ID: jwk.Kid, Algorithm: jwk.Alg, Public: public}
+	}
+
+	return keys, nil
+}
+
+func (s *KeySet) read(ctx context.Context) ([]byte, error) {
+	if !strings.HasPrefix(s.source, "http://") && !strings.HasPrefix(s.source, "https://") {
+		return os.ReadFile(s.source)
+	}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, s.source, nil)
+	if err != nil {
+		return nil, err
+	}
+	resp, err := s.client.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("unexpected status %d", resp.StatusCode)
+	}
+	return io.ReadAll(io.LimitReader(resp.Body, 1<<20))
+}
+
+func (k *jsonWebKey) publicKey() (crypto.PublicKey, error) {
+	switch k.Kty {
+	case "RSA":
+		n, err := decodeInt(k.N)
+		if err != nil {
+			return nil, err
+		}
+		e, err := decodeInt(k.E)
+		if err != nil {
+			return nil, err
+		}
+		if !e.IsInt64() || e.Int64() < 3 {
+			return nil, errors.New("invalid RSA exponent")
+		}
+		return &rsa.PublicKey{N: n, E: int(e.Int64())}, nil
+	case "EC":
+		var curve elliptic.Curve
+		switch k.Crv {
+		case "P-256":
+			curve = elliptic.P256()
+		case "P-384":
+			curve = elliptic.P384()
+		case "P-521":
+			curve = elliptic.P521()
+		default:
+			return nil, fmt.Errorf("unsupported curve %q", k.Crv)
+		}
+		x, err := decodeInt(k.X)
+		if err != nil {
+			return nil, err
+		}
+		y, err := decodeInt(k.Y)
+		if err != nil {
+			return nil, err
+		}
+		if !curve.IsOnCurve(x, y) {
+			return nil, errors.New("point is not on curve")
+		}
+		return &ecdsa.PublicKey{Curve: curve, X: x, Y: y}, nil
+	default:
+		return nil, fmt.Errorf("unsupported key type %q", k.Kty)
+	}
+}
+
+func decodeInt(value string) (*big.Int, error) {
+	raw, err := base64.RawURLEncoding.DecodeString(value)
+	if err != nil || len(raw) == 0 {
+		return nil, errors.New("invalid base64url integer")
+	}
+	return new(big.Int).SetBytes(raw), nil
+}
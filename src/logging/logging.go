@@ -0,0 +1,111 @@
+package logging
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"log/slog"
+	"sass-billing-service/src/tenant"
+	"strings"
+
+	"go.opentelemetry.io/otel/trace"
+)
+
+const redacted = "[REDACTED]"
+
+// sensitiveKeys son fragmentos de nombre de atributo cuyo valor nunca debe llegar a los logs
+var sensitiveKeys = []string{
+	"authorization", "token", "password", "secret", "api_key", "apikey", "cookie",
+	"card", "cvv", "cvc", "iban", "account_number", "routing_number",
+}
+
+type requestIDKey struct{}
+
+type principalKey struct{}
+
+// WithRequestID guarda el identificador de la petición para que acompañe a cada línea de log
+func WithRequestID(ctx context.Context, requestID string) context.Context {
+	return context.WithValue(ctx, requestIDKey{}, requestID)
+}
+
+func RequestID(ctx context.Context) string {
+	requestID, _ := ctx.Value(requestIDKey{}).(string)
+	return requestID
+}
+
+// WithPrincipal guarda el sujeto autenticado (usuario o "api-key:<prefijo>")
+func WithPrincipal(ctx context.Context, subject string) context.Context {
+	return context.WithValue(ctx, principalKey{}, subject)
+}
+
+// New crea un logger JSON (o texto, para desarrollo local) que añade a cada registro
+// request_id, tenant_id, principal y la traza activa desde el contexto y oculta los atributos sensibles
+func New(w io.Writer, format, level string) (*slog.Logger, error) {
+	var logLevel slog.Level
+	if err := logLevel.UnmarshalText([]byte(level)); err != nil {
+		return nil, fmt.Errorf("invalid log level %q", level)
+	}
+
+	options := &slog.HandlerOptions{Level: logLevel, ReplaceAttr: redact}
+	var handler slog.Handler
+	switch format {
+	case "json":
+		handler = slog.NewJSONHandler(w, options)
+	case "text":
+		handler = slog.NewTextHandler(w, options)
+	default:
+		return nil, fmt.Errorf("invalid log format %q", format)
+	}
+
+	return slog.New(&contextHandler{Handler: handler}), nil
+}
+
+// IsSensitive indica si un atributo con esta clave debe ocultarse
+func IsSensitive(key string) bool {
+	key = strings.ToLower(strings.ReplaceAll(key, "-", "_"))
+	for _, fragment := range sensitiveKeys {
+		if strings.Contains(key, fragment) {
+			return true
+		}
+	}
+
+	return false
+}
+
+func redact(groups []string, attr slog.Attr) slog.Attr {
+	if attr.Value.Kind() != slog.KindGroup && IsSensitive(attr.Key) {
+		return slog.String(attr.Key, redacted)
+	}
+
+	return attr
+}
+
+type contextHandler struct {
+	slog.Handler
+}
+
+func (h *contextHandler) Handle(ctx context.Context, record slog.Record) error {
+	if requestID := RequestID(ctx); requestID != "" {
+		record.AddAttrs(slog.String("request_id", requestID))
+	}
+	if tenantID, ok := tenant.FromContext(ctx); ok {
+		record.AddAttrs(slog.String("tenant_id", tenantID))
+	}
+	if subject, _ := ctx.Value(principalKey{}).(string); subject != "" {
+		record.AddAttrs(slog.String("principal", subject))
+	}
+	// Permite saltar de una línea de log a su traza
+	if span := trace.SpanContextFromContext(ctx); span.IsValid() {
+		record.AddAttrs(slog.String("trace_id", span.TraceID().String()), slog.String("span_id", span.SpanID().String()))
+	}
+
+	return h.Handler.Handle(ctx, record)
+}
+
+func (h *contextHandler) WithAttrs(attrs []slog.Attr) slog.Handler {
+	return &contextHandler{Handler: h.Handler.WithAttrs(attrs)}
+}
+
+func (h *contextHandler) WithGroup(name string) slog.Handler {
+	return &contextHandler{Handler: h.Handler.WithGroup(name)}
+}
@@ -0,0 +1,164 @@
+package metrics
+
+import (
+	"database/sql"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/gofiber/fiber/v2"
+	"github.com/gofiber/fiber/v2/middleware/adaptor"
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/collectors"
+	"github.com/prometheus/client_golang/prometheus/promhttp"
+)
+
+// Las etiquetas solo toman valores de conjuntos cerrados (patrón de ruta, código de estado,
+// nombre de método, tipo de evento, pasarela de pago) para que el número de series no dependa del tráfico.
+
+// unmatchedRoute agrupa todas las peticiones que no encajan con ninguna ruta registrada
+const unmatchedRoute = "unmatched"
+
+// Invoice events
+const (
+	InvoiceCreated   = "created"
+	InvoiceFinalized = "finalized"
+	InvoicePaid      = "paid"
+	InvoiceVoided    = "voided"
+	InvoiceRefunded  = "refunded"
+)
+
+// Motivos de un cobro fallido
+const (
+	PaymentNotFound      = "not_found"
+	PaymentInvalidStatus = "invalid_status"
+	PaymentError         = "error"
+)
+
+// Resultados de un intento de cobro de una factura vencida
+const (
+	DunningCollected = "collected"
+	DunningFailed    = "failed"
+)
+
+// gateways son los medios de pago con serie propia; payment_method es texto libre y el resto cuenta como otherGateway
+var gateways = map[string]bool{"card": true, "bank_transfer": true, "sepa_debit": true, "ach": true, "paypal": true}
+
+const otherGateway = "other"
+
+var (
+	Registry = prometheus.NewRegistry()
+
+	httpRequestDuration = prometheus.NewHistogramVec(prometheus.HistogramOpts{
+		Namespace: "billing",
+		Name:      "http_request_duration_seconds",
+		Help:      "HTTP request latency by route pattern, method and status code.",
+		Buckets:   prometheus.DefBuckets,
+	}, []string{"method", "route", "status"})
+
+	dbQueryDuration = prometheus.NewHistogramVec(prometheus.HistogramOpts{
+		Namespace: "billing",
+		Name:      "db_query_duration_seconds",
+		Help:      "Duration of repository methods, including their transaction.",
+		Buckets:   []float64{.001, .0025, .005, .01, .025, .05, .1, .25, .5, 1, 2.5, 5},
+	}, []string{"repository", "method"})
+
+	invoiceEvents = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Namespace: "billing",
+		Name:      "invoice_events_total",
+		Help:      "Invoices created, finalized, paid, voided and refunded.",
+	}, []string{"event"})
+
+	paymentFailures = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Namespace: "billing",
+		Name:      "payment_failures_total",
+		Help:      "Payment attempts that did not mark the invoice as paid, by gateway and reason.",
+	}, []string{"gateway", "reason"})
+
+	dunningAttempts = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Namespace: "billing",
+		Name:      "dunning_attempts_total",
+		Help:      "Payment attempts on overdue pending invoices, by outcome.",
+	}, []string{"outcome"})
+
+	txRetries = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Namespace: "billing",
+		Name:      "db_tx_retries_total",
+		Help:      "Transactions retried after a serialization failure or deadlock.",
+	}, []string{"reason"})
+)
+
+func init() {
+	Registry.MustRegister(
+		collectors.NewGoCollector(),
+		collectors.NewProcessCollector(collectors.ProcessCollectorOpts{}),
+		httpRequestDuration,
+		dbQueryDuration,
+		invoiceEvents,
+		paymentFailures,
+		dunningAttempts,
+		txRetries,
+	)
+}
+
+// RegisterDB publica las estadísticas del pool de conexiones (sql.DB.Stats) en cada scrape
+func RegisterDB(db *sql.DB, name string) error {
+	return Registry.Register(collectors.NewDBStatsCollector(db, name))
+}
+
+// Handler sirve el registro en el formato de exposición de Prometheus
+func Handler() fiber.Handler {
+	return adaptor.HTTPHandler(promhttp.HandlerFor(Registry, promhttp.HandlerOpts{}))
+}
+
+// Middleware mide la latencia de cada petición etiquetada con el patrón de la ruta
+// ("/api/invoices/:id"), nunca con la URL concreta. El estado se lee de la respuesta, así que
+// helpers.HandleErrors debe ir por dentro para que los errores de los handlers ya sean respuesta.
+func Middleware() fiber.Handler {
+	return func(c *fiber.Ctx) error {
+		start := time.Now()
+		own := c.Route()
+
+		err := c.Next()
+		status := c.Response().StatusCode()
+
+		route := c.Route().Path
+		if c.Route() == own {
+			route = unmatchedRoute
+		}
+
+		httpRequestDuration.WithLabelValues(c.Method(), route, strconv.Itoa(status)).Observe(time.Since(start).Seconds())
+		return err
+	}
+}
+
+// ObserveQuery registra la duración de un método de repositorio; se usa con defer al inicio del método:
+//
+//	defer metrics.ObserveQuery("invoice", "GetByID", time.Now())
+func ObserveQuery(repository, method string, start time.Time) {
+	dbQueryDuration.WithLabelValues(repository, method).Observe(time.Since(start).Seconds())
+}
+
+// InvoiceEvent cuenta un evento del ciclo de vida de una factura
+func InvoiceEvent(event string) {
+	invoiceEvents.WithLabelValues(event).Inc()
+}
+
+// PaymentFailure cuenta un cobro fallido; el medio de pago se reduce a un conjunto cerrado de pasarelas
+func PaymentFailure(paymentMethod, reason string) {
+	gateway := strings.ToLower(paymentMethod)
+	if !gateways[gateway] {
+		gateway = otherGateway
+	}
+	paymentFailures.WithLabelValues(gateway, reason).Inc()
+}
+
+// DunningAttempt cuenta un intento de cobro de una factura pendiente ya vencida
+func DunningAttempt(outcome string) {
+	dunningAttempts.WithLabelValues(outcome).Inc()
+}
+
+// TxRetry cuenta un reintento de transacción por su causa (serialization_failure, deadlock_detected)
+func TxRetry(reason string) {
+	txRetries.WithLabelValues(reason).Inc()
+}
@@ -0,0 +1,8 @@
+DROP TRIGGER journal_lines_balanced ON journal_lines;
+DROP FUNCTION check_journal_entry_balanced();
+
+DROP TABLE journal_lines;
+DROP TABLE journal_entries;
+DROP TABLE ledger_accounts;
+
+ALTER TABLE invoices DROP COLUMN tax_amount;
@@ -0,0 +1,52 @@
+ALTER TABLE invoices ADD COLUMN tax_amount DECIMAL(10, 2) NOT NULL DEFAULT 0;
+
+CREATE TABLE ledger_accounts (
+  code VARCHAR(10) PRIMARY KEY,
+  name TEXT NOT NULL,
+  type VARCHAR(20) NOT NULL
+);
+
+INSERT INTO ledger_accounts (code, name, type) VALUES
+  ('1000', 'Cash', 'asset'),
+  ('1100', 'Accounts receivable', 'asset'),
+  ('2200', 'Tax payable', 'liability'),
+  ('2400', 'Deferred revenue', 'liability'),
+  ('4000', 'Revenue', 'revenue');
+
+CREATE TABLE journal_entries (
+  id SERIAL PRIMARY KEY,
+  entry_type VARCHAR(30) NOT NULL,
+  reference_type VARCHAR(30) NOT NULL,
+  reference_id INTEGER NOT NULL,
+  description TEXT NOT NULL DEFAULT '',
+  posted_at TIMESTAMP WITH TIME ZONE NOT NULL DEFAULT NOW()
+);
+
+CREATE TABLE journal_lines (
+  id SERIAL PRIMARY KEY,
+  entry_id INTEGER NOT NULL REFERENCES journal_entries(id),
+  account_code VARCHAR(10) NOT NULL REFERENCES ledger_accounts(code),
+  debit_cents BIGINT NOT NULL DEFAULT 0 CHECK (debit_cents >= 0),
+  credit_cents BIGINT NOT NULL DEFAULT 0 CHECK (credit_cents >= 0),
+  CHECK (debit_cents = 0 OR credit_cents = 0)
+);
+
+CREATE INDEX idx_journal_entries_reference ON journal_entries(reference_type, reference_id);
+CREATE INDEX idx_journal_lines_entry_id ON journal_lines(entry_id);
+CREATE INDEX idx_journal_lines_account_code ON journal_lines(account_code);
+
+-- Segunda línea de defensa: ningún asiento descuadrado puede llegar a confirmarse
+CREATE FUNCTION check_journal_entry_balanced() RETURNS TRIGGER AS $$
+BEGIN
+  IF (SELECT COALESCE(SUM(debit_cents), 0) - COALESCE(SUM(credit_cents), 0)
+      FROM journal_lines WHERE entry_id = NEW.entry_id) <> 0 THEN
+    RAISE EXCEPTION 'journal entry % is not balanced', NEW.entry_id;
+  END IF;
+  RETURN NULL;
+END;
+$$ LANGUAGE plpgsql;
+
+CREATE CONSTRAINT TRIGGER journal_lines_balanced
+  AFTER INSERT OR UPDATE ON journal_lines
+  DEFERRABLE INITIALLY DEFERRED
+  FOR EACH ROW EXECUTE FUNCTION check_journal_entry_balanced();
@@ -0,0 +1,2 @@
+DROP TABLE revenue_schedules;
+DROP TABLE invoice_lines;
@@ -0,0 +1,29 @@
+CREATE TABLE invoice_lines (
+  id SERIAL PRIMARY KEY,
+  invoice_id INTEGER NOT NULL REFERENCES invoices(id),
+  product VARCHAR(100) NOT NULL,
+  description TEXT NOT NULL DEFAULT '',
+  amount DECIMAL(10, 2) NOT NULL,
+  service_start DATE,
+  service_end DATE,
+  CHECK ((service_start IS NULL) = (service_end IS NULL)),
+  CHECK (service_end >= service_start)
+);
+
+CREATE INDEX idx_invoice_lines_invoice_id ON invoice_lines(invoice_id);
+
+CREATE TABLE revenue_schedules (
+  id SERIAL PRIMARY KEY,
+  invoice_id INTEGER NOT NULL REFERENCES invoices(id),
+  invoice_line_id INTEGER NOT NULL REFERENCES invoice_lines(id),
+  product VARCHAR(100) NOT NULL,
+  period DATE NOT NULL,
+  amount_cents BIGINT NOT NULL,
+  status VARCHAR(20) NOT NULL DEFAULT 'scheduled',
+  booked_at TIMESTAMP WITH TIME ZONE NOT NULL,
+  recognized_at TIMESTAMP WITH TIME ZONE,
+  journal_entry_id INTEGER REFERENCES journal_entries(id)
+);
+
+CREATE INDEX idx_revenue_schedules_invoice_id ON revenue_schedules(invoice_id);
+CREATE INDEX idx_revenue_schedules_status_period ON revenue_schedules(status, period);
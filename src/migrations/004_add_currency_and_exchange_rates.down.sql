@@ -0,0 +1,3 @@
+DROP TABLE exchange_rates;
+
+ALTER TABLE invoices DROP COLUMN currency;
@@ -0,0 +1,10 @@
+ALTER TABLE invoices ADD COLUMN currency CHAR(3) NOT NULL DEFAULT 'USD';
+
+-- rate convierte una unidad de from_currency a to_currency a partir de effective_date
+CREATE TABLE exchange_rates (
+  from_currency CHAR(3) NOT NULL,
+  to_currency CHAR(3) NOT NULL,
+  rate DECIMAL(18, 8) NOT NULL CHECK (rate > 0),
+  effective_date DATE NOT NULL,
+  PRIMARY KEY (from_currency, to_currency, effective_date)
+);
@@ -0,0 +1,4 @@
+DROP INDEX idx_invoices_tenant_id_user_id;
+
+ALTER TABLE invoices DROP COLUMN due_date;
+ALTER TABLE invoices DROP COLUMN tenant_id;
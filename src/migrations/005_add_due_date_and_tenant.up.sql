@@ -0,0 +1,8 @@
+ALTER TABLE invoices ADD COLUMN tenant_id VARCHAR(64) NOT NULL DEFAULT 'default';
+ALTER TABLE invoices ADD COLUMN due_date DATE;
+
+UPDATE invoices SET due_date = (created_at + INTERVAL '30 days')::date;
+
+ALTER TABLE invoices ALTER COLUMN due_date SET NOT NULL;
+
+CREATE INDEX idx_invoices_tenant_id_user_id ON invoices(tenant_id, user_id);
@@ -0,0 +1,3 @@
+DROP INDEX idx_invoices_tenant_user_created_at_id;
+DROP INDEX idx_invoices_tenant_amount_id;
+DROP INDEX idx_invoices_tenant_created_at_id;
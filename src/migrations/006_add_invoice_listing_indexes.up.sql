@@ -0,0 +1,4 @@
+-- Índices para la paginación por keyset de GET /api/invoices
+CREATE INDEX idx_invoices_tenant_created_at_id ON invoices(tenant_id, created_at, id);
+CREATE INDEX idx_invoices_tenant_amount_id ON invoices(tenant_id, amount, id);
+CREATE INDEX idx_invoices_tenant_user_created_at_id ON invoices(tenant_id, user_id, created_at, id);
@@ -0,0 +1,15 @@
+DROP INDEX idx_invoices_search_vector;
+
+DROP TRIGGER invoice_lines_search_vector ON invoice_lines;
+DROP FUNCTION invoice_lines_search_vector_update();
+DROP TRIGGER invoices_search_vector ON invoices;
+DROP FUNCTION invoices_search_vector_update();
+DROP FUNCTION invoice_search_document(invoices);
+
+DROP INDEX idx_invoices_invoice_number;
+ALTER TABLE invoices DROP COLUMN search_vector;
+ALTER TABLE invoices DROP COLUMN customer_email;
+ALTER TABLE invoices DROP COLUMN customer_name;
+ALTER TABLE invoices DROP COLUMN invoice_number;
+
+DROP SEQUENCE invoice_number_seq;
@@ -0,0 +1,52 @@
+CREATE SEQUENCE invoice_number_seq;
+
+ALTER TABLE invoices ADD COLUMN invoice_number VARCHAR(32);
+ALTER TABLE invoices ADD COLUMN customer_name TEXT NOT NULL DEFAULT '';
+ALTER TABLE invoices ADD COLUMN customer_email TEXT NOT NULL DEFAULT '';
+ALTER TABLE invoices ADD COLUMN search_vector TSVECTOR;
+
+UPDATE invoices SET invoice_number = 'INV-' || LPAD(id::text, 6, '0');
+SELECT setval('invoice_number_seq', COALESCE((SELECT MAX(id) FROM invoices), 0) + 1, false);
+
+ALTER TABLE invoices ALTER COLUMN invoice_number SET DEFAULT 'INV-' || LPAD(nextval('invoice_number_seq')::text, 6, '0');
+ALTER TABLE invoices ALTER COLUMN invoice_number SET NOT NULL;
+CREATE UNIQUE INDEX idx_invoices_invoice_number ON invoices(invoice_number);
+
+-- El documento indexado pesa más el número y el cliente que las descripciones
+CREATE FUNCTION invoice_search_document(inv invoices) RETURNS TSVECTOR AS $$
+  SELECT
+    setweight(to_tsvector('english', COALESCE(inv.invoice_number, '')), 'A') ||
+    setweight(to_tsvector('english', COALESCE(inv.customer_name, '') || ' ' || COALESCE(inv.customer_email, '')), 'A') ||
+    setweight(to_tsvector('english', COALESCE(inv.description, '')), 'B') ||
+    setweight(to_tsvector('english', COALESCE(
+      (SELECT string_agg(l.product || ' ' || l.description, ' ') FROM invoice_lines l WHERE l.invoice_id = inv.id), ''
+    )), 'C')
+$$ LANGUAGE sql STABLE;
+
+CREATE FUNCTION invoices_search_vector_update() RETURNS TRIGGER AS $$
+BEGIN
+  NEW.search_vector := invoice_search_document(NEW);
+  RETURN NEW;
+END;
+$$ LANGUAGE plpgsql;
+
+CREATE TRIGGER invoices_search_vector
+  BEFORE INSERT OR UPDATE OF invoice_number, customer_name, customer_email, description, search_vector ON invoices
+  FOR EACH ROW EXECUTE FUNCTION invoices_search_vector_update();
+
+-- Las líneas se insertan después de la factura: al cambiar fuerzan el recálculo del documento de su factura
+CREATE FUNCTION invoice_lines_search_vector_update() RETURNS TRIGGER AS $$
+BEGIN
+  UPDATE invoices SET search_vector = NULL
+  WHERE id = CASE WHEN TG_OP = 'DELETE' THEN OLD.invoice_id ELSE NEW.invoice_id END;
+  RETURN NULL;
+END;
+$$ LANGUAGE plpgsql;
+
+CREATE TRIGGER invoice_lines_search_vector
+  AFTER INSERT OR UPDATE OR DELETE ON invoice_lines
+  FOR EACH ROW EXECUTE FUNCTION invoice_lines_search_vector_update();
+
+UPDATE invoices SET search_vector = NULL;
+
+CREATE INDEX idx_invoices_search_vector ON invoices USING GIN(search_vector);
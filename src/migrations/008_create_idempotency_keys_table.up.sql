@@ -0,0 +1,13 @@
+CREATE TABLE idempotency_keys (
+  tenant_id VARCHAR(64) NOT NULL,
+  idempotency_key VARCHAR(255) NOT NULL,
+  fingerprint CHAR(64) NOT NULL,
+  status_code INTEGER,
+  content_type TEXT,
+  response_body BYTEA,
+  created_at TIMESTAMP WITH TIME ZONE NOT NULL DEFAULT NOW(),
+  expires_at TIMESTAMP WITH TIME ZONE NOT NULL,
+  PRIMARY KEY (tenant_id, idempotency_key)
+);
+
+CREATE INDEX idx_idempotency_keys_expires_at ON idempotency_keys(expires_at);
@@ -0,0 +1,31 @@
+DROP POLICY tenant_isolation ON idempotency_keys;
+ALTER TABLE idempotency_keys NO FORCE ROW LEVEL SECURITY;
+ALTER TABLE idempotency_keys DISABLE ROW LEVEL SECURITY;
+
+DROP POLICY tenant_isolation ON journal_lines;
+ALTER TABLE journal_lines NO FORCE ROW LEVEL SECURITY;
+ALTER TABLE journal_lines DISABLE ROW LEVEL SECURITY;
+
+DROP POLICY tenant_isolation ON journal_entries;
+ALTER TABLE journal_entries NO FORCE ROW LEVEL SECURITY;
+ALTER TABLE journal_entries DISABLE ROW LEVEL SECURITY;
+
+DROP POLICY tenant_isolation ON revenue_schedules;
+ALTER TABLE revenue_schedules NO FORCE ROW LEVEL SECURITY;
+ALTER TABLE revenue_schedules DISABLE ROW LEVEL SECURITY;
+
+DROP POLICY tenant_isolation ON invoice_lines;
+ALTER TABLE invoice_lines NO FORCE ROW LEVEL SECURITY;
+ALTER TABLE invoice_lines DISABLE ROW LEVEL SECURITY;
+
+DROP POLICY tenant_isolation ON invoices;
+ALTER TABLE invoices NO FORCE ROW LEVEL SECURITY;
+ALTER TABLE invoices DISABLE ROW LEVEL SECURITY;
+
+DROP INDEX idx_journal_entries_tenant_id;
+DROP INDEX idx_revenue_schedules_tenant_status_period;
+DROP INDEX idx_invoice_lines_tenant_id;
+
+ALTER TABLE journal_entries DROP COLUMN tenant_id;
+ALTER TABLE revenue_schedules DROP COLUMN tenant_id;
+ALTER TABLE invoice_lines DROP COLUMN tenant_id;
@@ -0,0 +1,59 @@
+-- El tenant se copia a las tablas hijas para que consultas y políticas no dependan de joins
+ALTER TABLE invoice_lines ADD COLUMN tenant_id VARCHAR(64);
+UPDATE invoice_lines l SET tenant_id = i.tenant_id FROM invoices i WHERE i.id = l.invoice_id;
+ALTER TABLE invoice_lines ALTER COLUMN tenant_id SET NOT NULL;
+
+ALTER TABLE revenue_schedules ADD COLUMN tenant_id VARCHAR(64);
+UPDATE revenue_schedules s SET tenant_id = i.tenant_id FROM invoices i WHERE i.id = s.invoice_id;
+ALTER TABLE revenue_schedules ALTER COLUMN tenant_id SET NOT NULL;
+
+ALTER TABLE journal_entries ADD COLUMN tenant_id VARCHAR(64);
+UPDATE journal_entries e SET tenant_id = i.tenant_id
+FROM invoices i WHERE e.reference_type = 'invoice' AND i.id = e.reference_id;
+-- Los asientos de reconocimiento anteriores eran globales; todos los datos previos eran del tenant por defecto
+UPDATE journal_entries SET tenant_id = 'default' WHERE tenant_id IS NULL;
+ALTER TABLE journal_entries ALTER COLUMN tenant_id SET NOT NULL;
+
+CREATE INDEX idx_invoice_lines_tenant_id ON invoice_lines(tenant_id);
+CREATE INDEX idx_revenue_schedules_tenant_status_period ON revenue_schedules(tenant_id, status, period);
+CREATE INDEX idx_journal_entries_tenant_id ON journal_entries(tenant_id);
+
+-- Segunda línea de defensa: cada transacción fija app.tenant_id y PostgreSQL oculta y rechaza
+-- las filas de otros tenants aunque la consulta olvide filtrar. Solo las tareas de mantenimiento
+-- fijan app.bypass_rls. FORCE hace que las políticas apliquen también al propietario de las tablas.
+ALTER TABLE invoices ENABLE ROW LEVEL SECURITY;
+ALTER TABLE invoices FORCE ROW LEVEL SECURITY;
+CREATE POLICY tenant_isolation ON invoices
+  USING (tenant_id = current_setting('app.tenant_id', true) OR current_setting('app.bypass_rls', true) = 'on')
+  WITH CHECK (tenant_id = current_setting('app.tenant_id', true) OR current_setting('app.bypass_rls', true) = 'on');
+
+ALTER TABLE invoice_lines ENABLE ROW LEVEL SECURITY;
+ALTER TABLE invoice_lines FORCE ROW LEVEL SECURITY;
+CREATE POLICY tenant_isolation ON invoice_lines
+  USING (tenant_id = current_setting('app.tenant_id', true) OR current_setting('app.bypass_rls', true) = 'on')
+  WITH CHECK (tenant_id = current_setting('app.tenant_id', true) OR current_setting('app.bypass_rls', true) = 'on');
+
+ALTER TABLE revenue_schedules ENABLE ROW LEVEL SECURITY;
+ALTER TABLE revenue_schedules FORCE ROW LEVEL SECURITY;
+CREATE POLICY tenant_isolation ON revenue_schedules
+  USING (tenant_id = current_setting('app.tenant_id', true) OR current_setting('app.bypass_rls', true) = 'on')
+  WITH CHECK (tenant_id = current_setting('app.tenant_id', true) OR current_setting('app.bypass_rls', true) = 'on');
+
+ALTER TABLE journal_entries ENABLE ROW LEVEL SECURITY;
+ALTER TABLE journal_entries FORCE ROW LEVEL SECURITY;
+CREATE POLICY tenant_isolation ON journal_entries
+  USING (tenant_id = current_setting('app.tenant_id', true) OR current_setting('app.bypass_rls', true) = 'on')
+  WITH CHECK (tenant_id = current_setting('app.tenant_id', true) OR current_setting('app.bypass_rls', true) = 'on');
+
+-- Las líneas de asiento heredan la visibilidad de su asiento, que ya está filtrado por su propia política
+ALTER TABLE journal_lines ENABLE ROW LEVEL SECURITY;
+ALTER TABLE journal_lines FORCE ROW LEVEL SECURITY;
+CREATE POLICY tenant_isolation ON journal_lines
+  USING (EXISTS (SELECT 1 FROM journal_entries e WHERE e.id = entry_id))
+  WITH CHECK (EXISTS (SELECT 1 FROM journal_entries e WHERE e.id = entry_id));
+
+ALTER TABLE idempotency_keys ENABLE ROW LEVEL SECURITY;
+ALTER TABLE idempotency_keys FORCE ROW LEVEL SECURITY;
+CREATE POLICY tenant_isolation ON idempotency_keys
+  USING (tenant_id = current_setting('app.tenant_id', true) OR current_setting('app.bypass_rls', true) = 'on')
+  WITH CHECK (tenant_id = current_setting('app.tenant_id', true) OR current_setting('app.bypass_rls', true) = 'on');
@@ -0,0 +1,9 @@
+-- Buckets del limitador compartidos entre réplicas. La clave ya incluye el tenant y el limitador
+-- los consulta antes de fijar app.tenant_id, por eso esta tabla no tiene políticas RLS.
+CREATE TABLE rate_limit_buckets (
+  bucket_key VARCHAR(512) PRIMARY KEY,
+  tokens DOUBLE PRECISION NOT NULL,
+  updated_at TIMESTAMP WITH TIME ZONE NOT NULL
+);
+
+CREATE INDEX idx_rate_limit_buckets_updated_at ON rate_limit_buckets(updated_at);
@@ -0,0 +1,5 @@
+-- Las claves son efímeras; se descartan para no chocar con la clave primaria anterior
+DELETE FROM idempotency_keys;
+ALTER TABLE idempotency_keys DROP CONSTRAINT idempotency_keys_pkey;
+ALTER TABLE idempotency_keys DROP COLUMN principal;
+ALTER TABLE idempotency_keys ADD PRIMARY KEY (tenant_id, idempotency_key);
@@ -0,0 +1,5 @@
+-- Una respuesta memorizada solo se repite a quien creó la clave: otro usuario del mismo tenant
+-- podría no tener acceso al recurso. Las claves anteriores quedan con principal vacío y no vuelven a coincidir.
+ALTER TABLE idempotency_keys ADD COLUMN principal VARCHAR(255) NOT NULL DEFAULT '';
+ALTER TABLE idempotency_keys DROP CONSTRAINT idempotency_keys_pkey;
+ALTER TABLE idempotency_keys ADD PRIMARY KEY (tenant_id, principal, idempotency_key);
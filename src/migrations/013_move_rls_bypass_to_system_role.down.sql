@@ -0,0 +1,42 @@
+DO $$
+DECLARE
+  owner NAME := (SELECT tableowner FROM pg_tables WHERE schemaname = 'public' AND tablename = 'invoices');
+BEGIN
+  EXECUTE format('ALTER DEFAULT PRIVILEGES FOR ROLE %I IN SCHEMA public REVOKE ALL ON TABLES FROM billing_system', owner);
+  EXECUTE format('ALTER DEFAULT PRIVILEGES FOR ROLE %I IN SCHEMA public REVOKE ALL ON SEQUENCES FROM billing_system', owner);
+END
+$$;
+
+REVOKE ALL ON ALL TABLES IN SCHEMA public FROM billing_system;
+REVOKE ALL ON ALL SEQUENCES IN SCHEMA public FROM billing_system;
+DROP ROLE billing_system;
+
+DROP POLICY tenant_isolation ON invoices;
+CREATE POLICY tenant_isolation ON invoices
+  USING (tenant_id = current_setting('app.tenant_id', true) OR current_setting('app.bypass_rls', true) = 'on')
+  WITH CHECK (tenant_id = current_setting('app.tenant_id', true) OR current_setting('app.bypass_rls', true) = 'on');
+
+DROP POLICY tenant_isolation ON invoice_lines;
+CREATE POLICY tenant_isolation ON invoice_lines
+  USING (tenant_id = current_setting('app.tenant_id', true) OR current_setting('app.bypass_rls', true) = 'on')
+  WITH CHECK (tenant_id = current_setting('app.tenant_id', true) OR current_setting('app.bypass_rls', true) = 'on');
+
+DROP POLICY tenant_isolation ON revenue_schedules;
+CREATE POLICY tenant_isolation ON revenue_schedules
+  USING (tenant_id = current_setting('app.tenant_id', true) OR current_setting('app.bypass_rls', true) = 'on')
+  WITH CHECK (tenant_id = current_setting('app.tenant_id', true) OR current_setting('app.bypass_rls', true) = 'on');
+
+DROP POLICY tenant_isolation ON journal_entries;
+CREATE POLICY tenant_isolation ON journal_entries
+  USING (tenant_id = current_setting('app.tenant_id', true) OR current_setting('app.bypass_rls', true) = 'on')
+  WITH CHECK (tenant_id = current_setting('app.tenant_id', true) OR current_setting('app.bypass_rls', true) = 'on');
+
+DROP POLICY tenant_isolation ON idempotency_keys;
+CREATE POLICY tenant_isolation ON idempotency_keys
+  USING (tenant_id = current_setting('app.tenant_id', true) OR current_setting('app.bypass_rls', true) = 'on')
+  WITH CHECK (tenant_id = current_setting('app.tenant_id', true) OR current_setting('app.bypass_rls', true) = 'on');
+
+DROP POLICY tenant_isolation ON api_keys;
+CREATE POLICY tenant_isolation ON api_keys
+  USING (tenant_id = current_setting('app.tenant_id', true) OR current_setting('app.bypass_rls', true) = 'on')
+  WITH CHECK (tenant_id = current_setting('app.tenant_id', true) OR current_setting('app.bypass_rls', true) = 'on');
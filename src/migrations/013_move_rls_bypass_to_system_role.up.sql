@@ -0,0 +1,50 @@
+-- Las tareas de mantenimiento dejan de atravesar RLS con app.bypass_rls, una variable que cualquier
+-- sesión del rol de la aplicación puede fijar, y pasan a hacerlo con SET LOCAL ROLE billing_system.
+-- Las peticiones nunca cambian de rol, así que una consulta que olvide tenant_id sigue filtrada.
+-- Crear un rol BYPASSRLS requiere superusuario: si el rol de la aplicación no lo es, un administrador
+-- debe aplicar esta migración. El rol se concede al propietario de las tablas, no a quien la aplique.
+DO $$
+DECLARE
+  owner NAME := (SELECT tableowner FROM pg_tables WHERE schemaname = 'public' AND tablename = 'invoices');
+BEGIN
+  IF NOT EXISTS (SELECT 1 FROM pg_roles WHERE rolname = 'billing_system') THEN
+    CREATE ROLE billing_system NOLOGIN BYPASSRLS;
+  END IF;
+  EXECUTE format('GRANT billing_system TO %I', owner);
+  EXECUTE format('ALTER DEFAULT PRIVILEGES FOR ROLE %I IN SCHEMA public GRANT SELECT, INSERT, UPDATE, DELETE ON TABLES TO billing_system', owner);
+  EXECUTE format('ALTER DEFAULT PRIVILEGES FOR ROLE %I IN SCHEMA public GRANT USAGE, SELECT ON SEQUENCES TO billing_system', owner);
+END
+$$;
+
+GRANT SELECT, INSERT, UPDATE, DELETE ON ALL TABLES IN SCHEMA public TO billing_system;
+GRANT USAGE, SELECT ON ALL SEQUENCES IN SCHEMA public TO billing_system;
+
+DROP POLICY tenant_isolation ON invoices;
+CREATE POLICY tenant_isolation ON invoices
+  USING (tenant_id = current_setting('app.tenant_id', true))
+  WITH CHECK (tenant_id = current_setting('app.tenant_id', true));
+
+DROP POLICY tenant_isolation ON invoice_lines;
+CREATE POLICY tenant_isolation ON invoice_lines
+  USING (tenant_id = current_setting('app.tenant_id', true))
+  WITH CHECK (tenant_id = current_setting('app.tenant_id', true));
+
+DROP POLICY tenant_isolation ON revenue_schedules;
+CREATE POLICY tenant_isolation ON revenue_schedules
+  USING (tenant_id = current_setting('app.tenant_id', true))
+  WITH CHECK (tenant_id = current_setting('app.tenant_id', true));
+
+DROP POLICY tenant_isolation ON journal_entries;
+CREATE POLICY tenant_isolation ON journal_entries
+  USING (tenant_id = current_setting('app.tenant_id', true))
+  WITH CHECK (tenant_id = current_setting('app.tenant_id', true));
+
+DROP POLICY tenant_isolation ON idempotency_keys;
+CREATE POLICY tenant_isolation ON idempotency_keys
+  USING (tenant_id = current_setting('app.tenant_id', true))
+  WITH CHECK (tenant_id = current_setting('app.tenant_id', true));
+
+DROP POLICY tenant_isolation ON api_keys;
+CREATE POLICY tenant_isolation ON api_keys
+  USING (tenant_id = current_setting('app.tenant_id', true))
+  WITH CHECK (tenant_id = current_setting('app.tenant_id', true));
@@ -0,0 +1,2 @@
+-- Falla si ya hay claves con el prefijo nuevo: deben revocarse y borrarse antes
+ALTER TABLE api_keys ALTER COLUMN prefix TYPE VARCHAR(16);
@@ -0,0 +1,2 @@
+-- El prefijo público pasa de 8 a 16 caracteres hex (bk_ + 16); las claves anteriores conservan el suyo
+ALTER TABLE api_keys ALTER COLUMN prefix TYPE VARCHAR(32);
@@ -0,0 +1 @@
+ALTER TABLE revenue_schedules DROP COLUMN cancelled_at;
@@ -0,0 +1,14 @@
+-- Momento en que se anuló cada calendario: el informe de ingresos sigue contándolo como diferido
+-- en los cierres de mes anteriores a la anulación
+ALTER TABLE revenue_schedules ADD COLUMN cancelled_at TIMESTAMP WITH TIME ZONE;
+
+-- Las filas ya anuladas toman la fecha de la última transición de su factura: anulada y reembolsada
+-- son estados finales, así que updated_at es el momento de la anulación. Con RLS el relleno tiene
+-- que hacerse con el rol de sistema.
+SET LOCAL ROLE billing_system;
+UPDATE revenue_schedules s SET cancelled_at = i.updated_at
+FROM invoices i WHERE i.id = s.invoice_id AND s.status = 'cancelled';
+RESET ROLE;
+
+ALTER TABLE revenue_schedules ADD CONSTRAINT revenue_schedules_cancelled_at
+  CHECK ((status = 'cancelled') = (cancelled_at IS NOT NULL));
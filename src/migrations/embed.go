@@ -0,0 +1,8 @@
+package migrations
+
+import "embed"
+
+// Files contiene los ficheros NNN_nombre.up.sql / NNN_nombre.down.sql compilados en el binario
+//
+//go:embed *.sql
+var Files embed.FS
@@ -0,0 +1,367 @@
+package migrations
+
+import (
+	"context"
+	"crypto/sha256"
+	"database/sql"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"io/fs"
+	"regexp"
+	"sort"
+	"strconv"
+	"time"
+)
+
+var (
+	ErrChecksumMismatch = errors.New("applied migration has been modified")
+	ErrUnknownMigration = errors.New("applied migration not found in this build")
+	ErrIrreversible     = errors.New("migration has no down file")
+	ErrUnknownVersion   = errors.New("unknown migration version")
+)
+
+// lockID identifica el advisory lock que serializa los runners de todas las réplicas
+const lockID = 720_390_119
+
+var fileName = regexp.MustCompile(`^(\d+)_(\w+)\.(up|down)\.sql$`)
+
+type Migration struct {
+	Version  int
+	Name     string
+	Up       string
+	Down     string
+	Checksum string
+}
+
+// Status describe una migración conocida y si está aplicada en la base de datos
+type Status struct {
+	Migration
+	Applied   bool
+	AppliedAt *time.Time
+	// Modified indica que el fichero ha cambiado desde que se aplicó
+	Modified bool
+}
+
+type appliedMigration struct {
+	checksum  string
+	appliedAt time.Time
+}
+
+type Runner struct {
+	db         *sql.DB
+	migrations []Migration
+}
+
+func NewRunner(db *sql.DB, fsys fs.FS) (*Runner, error) {
+	migrations, err := Load(fsys)
+	if err != nil {
+		return nil, err
+	}
+
+	return &Runner{db: db, migrations: migrations}, nil
+}
+
+// Load lee las migraciones ordenadas por versión; el checksum cubre solo el fichero up
+func Load(fsys fs.FS) ([]Migration, error) {
+	entries, err := fs.ReadDir(fsys, ".")
+	if err != nil {
+		return nil, err
+	}
+
+	byVersion := make(map[int]*Migration)
+	for _, entry := range entries {
+		match := fileName.FindStringSubmatch(entry.Name())
+		if entry.IsDir() || match == nil {
+			continue
+		}
+
+		version, _ := strconv.Atoi(match[1])
+		content, err := fs.ReadFile(fsys, entry.Name())
+		if err != nil {
+			return nil, err
+		}
+
+		migration, ok := byVersion[version]
+		if !ok {
+			migration = &Migration{Version: version, Name: match[2]}
+			byVersion[version] = migration
+		}
+		if migration.Name != match[2] {
+			return nil, fmt.Errorf("migration %d has two names: %s and %s", version, migration.Name, match[2])
+		}
+
+		if match[3] == "up" {
+			sum := sha256.Sum256(content)
+			migration.Up, migration.Checksum = string(content), hex.EncodeToString(sum[:])
+		} else {
+			migration.Down = string(content)
+		}
+	}
+
+	migrations := make([]Migration, 0, len(byVersion))
+	for _, migration := range byVersion {
+		if migration.Up == "" {
+			return nil, fmt.Errorf("migration %d_%s has no up file", migration.Version, migration.Name)
+		}
+		migrations = append(migrations, *migration)
+	}
+	sort.Slice(migrations, func(i, j int) bool { return migrations[i].Version < migrations[j].Version })
+
+	return migrations, nil
+}
+
+// Latest devuelve la versión más alta incluida en el binario
+func (r *Runner) Latest() int {
+	if len(r.migrations) == 0 {
+		return 0
+	}
+
+	return r.migrations[len(r.migrations)-1].Version
+}
+
+// Version devuelve la última migración aplicada (0 si no hay ninguna)
+func (r *Runner) Version(ctx context.Context) (int, error) {
+	var version int
+	err := r.db.QueryRowContext(ctx, `SELECT COALESCE(MAX(version), 0) FROM schema_migrations`).Scan(&version)
+	return version, err
+}
+
+func (r *Runner) Status(ctx context.Context) ([]Status, error) {
+	conn, err := r.db.Conn(ctx)
+	if err != nil {
+		return nil, err
+	}
+	defer conn.Close()
+
+	if err := ensureTable(ctx, conn); err != nil {
+		return nil, err
+	}
+	applied, err := loadApplied(ctx, conn)
+	if err != nil {
+		return nil, err
+	}
+
+	statuses := make([]Status, 0, len(r.migrations))
+	for _, migration := range r.migrations {
+		status := Status{Migration: migration}
+		if row, ok := applied[migration.Version]; ok {
+			appliedAt := row.appliedAt
+			status.Applied, status.AppliedAt = true, &appliedAt
+			status.Modified = row.checksum != migration.Checksum
+		}
+		statuses = append(statuses, status)
+	}
+
+	return statuses, nil
+}
+
+// Up aplica todas las migraciones pendientes
+func (r *Runner) Up(ctx context.Context) ([]Migration, error) {
+	return r.To(ctx, r.Latest())
+}
+
+// Down revierte las últimas steps migraciones aplicadas
+func (r *Runner) Down(ctx context.Context, steps int) ([]Migration, error) {
+	var done []Migration
+	err := r.withLock(ctx, func(conn *sql.Conn, applied map[int]appliedMigration) error {
+		for i := len(r.migrations) - 1; i >= 0 && len(done) < steps; i-- {
+			if _, ok := applied[r.migrations[i].Version]; !ok {
+				continue
+			}
+			if err := r.revert(ctx, conn, r.migrations[i]); err != nil {
+				return err
+			}
+			done = append(done, r.migrations[i])
+		}
+		return nil
+	})
+
+	return done, err
+}
+
+// To deja la base de datos exactamente en la versión indicada, aplicando o revirtiendo lo necesario
+func (r *Runner) To(ctx context.Context, version int) ([]Migration, error) {
+	if _, ok := r.find(version); version != 0 && !ok {
+		return nil, fmt.Errorf("%w: %d", ErrUnknownVersion, version)
+	}
+
+	var done []Migration
+	err := r.withLock(ctx, func(conn *sql.Conn, applied map[int]appliedMigration) error {
+		for i := len(r.migrations) - 1; i >= 0; i-- {
+			migration := r.migrations[i]
+			if _, ok := applied[migration.Version]; !ok || migration.Version <= version {
+				continue
+			}
+			if err := r.revert(ctx, conn, migration); err != nil {
+				return err
+			}
+			done = append(done, migration)
+		}
+
+		for _, migration := range r.migrations {
+			if _, ok := applied[migration.Version]; ok || migration.Version > version {
+				continue
+			}
+			if err := r.apply(ctx, conn, migration); err != nil {
+				return err
+			}
+			done = append(done, migration)
+		}
+		return nil
+	})
+
+	return done, err
+}
+
+// Baseline marca como aplicadas, sin ejecutarlas, las migraciones hasta la versión indicada. Sirve para
+// adoptar una base de datos cuyo esquema ya existía, creado a mano: después up solo aplica las siguientes.
+func (r *Runner) Baseline(ctx context.Context, version int) ([]Migration, error) {
+	if _, ok := r.find(version); !ok {
+		return nil, fmt.Errorf("%w: %d", ErrUnknownVersion, version)
+	}
+
+	var done []Migration
+	err := r.withLock(ctx, func(conn *sql.Conn, applied map[int]appliedMigration) error {
+		tx, err := conn.BeginTx(ctx, nil)
+		if err != nil {
+			return err
+		}
+		defer tx.Rollback()
+
+		for _, migration := range r.migrations {
+			if _, ok := applied[migration.Version]; ok || migration.Version > version {
+				continue
+			}
+			if err := record(ctx, tx, migration); err != nil {
+				return err
+			}
+			done = append(done, migration)
+		}
+
+		return tx.Commit()
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return done, nil
+}
+
+// withLock ejecuta fn con el advisory lock tomado en una conexión dedicada, después de
+// comprobar que ninguna migración aplicada ha cambiado ni ha desaparecido del binario
+func (r *Runner) withLock(ctx context.Context, fn func(conn *sql.Conn, applied map[int]appliedMigration) error) error {
+	conn, err := r.db.Conn(ctx)
+	if err != nil {
+		return err
+	}
+	defer conn.Close()
+
+	if _, err := conn.ExecContext(ctx, `SELECT pg_advisory_lock($1)`, lockID); err != nil {
+		return fmt.Errorf("error acquiring migration lock: %w", err)
+	}
+	defer conn.ExecContext(context.Background(), `SELECT pg_advisory_unlock($1)`, lockID)
+
+	if err := ensureTable(ctx, conn); err != nil {
+		return err
+	}
+	applied, err := loadApplied(ctx, conn)
+	if err != nil {
+		return err
+	}
+
+	for version, row := range applied {
+		migration, ok := r.find(version)
+		if !ok {
+			return fmt.Errorf("%w: %d", ErrUnknownMigration, version)
+		}
+		if row.checksum != migration.Checksum {
+			return fmt.Errorf("%w: %d_%s", ErrChecksumMismatch, migration.Version, migration.Name)
+		}
+	}
+
+	return fn(conn, applied)
+}
+
+func (r *Runner) find(version int) (Migration, bool) {
+	for _, migration := range r.migrations {
+		if migration.Version == version {
+			return migration, true
+		}
+	}
+
+	return Migration{}, false
+}
+
+// apply ejecuta el fichero up y lo registra en la misma transacción
+func (r *Runner) apply(ctx context.Context, conn *sql.Conn, migration Migration) error {
+	return inTx(ctx, conn, migration, migration.Up, func(tx *sql.Tx) error {
+		return record(ctx, tx, migration)
+	})
+}
+
+// record anota la migración en schema_migrations con el checksum del fichero up
+func record(ctx context.Context, tx *sql.Tx, migration Migration) error {
+	_, err := tx.ExecContext(ctx,
+		`INSERT INTO schema_migrations (version, name, checksum, applied_at) VALUES ($1, $2, $3, $4)`,
+		migration.Version, migration.Name, migration.Checksum, time.Now())
+	return err
+}
+
+func (r *Runner) revert(ctx context.Context, conn *sql.Conn, migration Migration) error {
+	if migration.Down == "" {
+		return fmt.Errorf("%w: %d_%s", ErrIrreversible, migration.Version, migration.Name)
+	}
+
+	return inTx(ctx, conn, migration, migration.Down, func(tx *sql.Tx) error {
+		_, err := tx.ExecContext(ctx, `DELETE FROM schema_migrations WHERE version = $1`, migration.Version)
+		return err
+	})
+}
+
+func inTx(ctx context.Context, conn *sql.Conn, migration Migration, script string, record func(tx *sql.Tx) error) error {
+	tx, err := conn.BeginTx(ctx, nil)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	if _, err := tx.ExecContext(ctx, script); err != nil {
+		return fmt.Errorf("migration %d_%s failed: %w", migration.Version, migration.Name, err)
+	}
+	if err := record(tx); err != nil {
+		return err
+	}
+
+	return tx.Commit()
+}
+
+func ensureTable(ctx context.Context, conn *sql.Conn) error {
+	_, err := conn.ExecContext(ctx, `CREATE TABLE IF NOT EXISTS schema_migrations (
+  version INTEGER PRIMARY KEY,
+  name TEXT NOT NULL,
+  checksum CHAR(64) NOT NULL,
+  applied_at TIMESTAMP WITH TIME ZONE NOT NULL DEFAULT NOW()
+)`)
+	return err
+}
+
+func loadApplied(ctx context.Context, conn *sql.Conn) (map[int]appliedMigration, error) {
+	rows, err := conn.QueryContext(ctx, `SELECT version, checksum, applied_at FROM schema_migrations`)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	applied := make(map[int]appliedMigration)
+	for rows.Next() {
+		var version int
+		var row appliedMigration
+		if err := rows.Scan(&version, &row.checksum, &row.appliedAt); err != nil {
+			return nil, err
+		}
+		applied[version] = row
+	}
+
+	return applied, rows.Err()
+}
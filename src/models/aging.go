@@ -0,0 +1,19 @@
+package models
+
+type AgingRow struct {
+	UserID     int     `json:"user_id,omitempty"` // 0 en las filas de totales
+	Currency   string  `json:"currency"`
+	Current    float64 `json:"current"`
+	Days1To30  float64 `json:"days_1_30"`
+	Days31To60 float64 `json:"days_31_60"`
+	Days61To90 float64 `json:"days_61_90"`
+	Over90     float64 `json:"days_over_90"`
+	Total      float64 `json:"total"`
+	Invoices   int     `json:"invoices"`
+}
+
+type AgingReport struct {
+	AsOf      string     `json:"as_of"` // "2006-01-02"
+	Customers []AgingRow `json:"customers"`
+	Totals    []AgingRow `json:"totals"` // una fila por moneda
+}
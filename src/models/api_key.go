@@ -0,0 +1,48 @@
+package models
+
+import (
+	"sass-billing-service/src/apperror"
+	"time"
+)
+
+// APIKey es una credencial de servidor a servidor. Solo se guarda el hash; el prefijo queda
+// visible para que el cliente identifique la clave sin conocer el secreto.
+type APIKey struct {
+	ID         int        `json:"id"`
+	TenantID   string     `json:"tenant_id"`
+	Name       string     `json:"name"`
+	Prefix     string     `json:"prefix"`
+	KeyHash    string     `json:"-"`
+	Scopes     []string   `json:"scopes"`
+	CreatedBy  string     `json:"created_by"`
+	CreatedAt  time.Time  `json:"created_at"`
+	ExpiresAt  *time.Time `json:"expires_at,omitempty"`
+	LastUsedAt *time.Time `json:"last_used_at,omitempty"`
+	RevokedAt  *time.Time `json:"revoked_at,omitempty"`
+}
+
+// Active indica si la clave puede autenticar en el instante indicado
+func (k *APIKey) Active(now time.Time) bool {
+	return k.RevokedAt == nil && (k.ExpiresAt == nil || now.Before(*k.ExpiresAt))
+}
+
+type CreateAPIKeyRequest struct {
+	Name      string     `json:"name" validate:"required,max=100"`
+	Scopes    []string   `json:"scopes" validate:"required,max=20,dive,required"`
+	ExpiresAt *time.Time `json:"expires_at"` // sin caducidad si se omite
+}
+
+// Validate rechaza una caducidad que ya ha pasado
+func (r *CreateAPIKeyRequest) Validate() []apperror.FieldError {
+	if r.ExpiresAt != nil && !r.ExpiresAt.After(time.Now()) {
+		return []apperror.FieldError{apperror.Field("expires_at", "must be in the future")}
+	}
+
+	return nil
+}
+
+// IssuedAPIKey es la respuesta de creación y rotación: la única vez que se devuelve la clave completa
+type IssuedAPIKey struct {
+	APIKey
+	Key string `json:"key"`
+}
@@ -0,0 +1,27 @@
+package models
+
+import (
+	"encoding/json"
+	"time"
+)
+
+// Reglas de los parámetros de la exportación (validation.Var)
+const (
+	InvoiceStatusRules = "oneof=pending paid cancelled refunded"
+	ExportIncludeRules = "oneof=lines payments"
+)
+
+type ExportFilter struct {
+	From            *time.Time // created_at >= From
+	To              *time.Time // created_at < To
+	Status          string
+	IncludeLines    bool
+	IncludePayments bool
+}
+
+// ExportRow lleva las líneas y los pagos ya serializados por PostgreSQL para no decodificarlos fila a fila
+type ExportRow struct {
+	Invoice
+	Lines    json.RawMessage `json:"lines,omitempty"`
+	Payments json.RawMessage `json:"payments,omitempty"`
+}
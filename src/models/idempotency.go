@@ -0,0 +1,21 @@
+package models
+
+import "time"
+
+// IdempotencyRecord guarda la primera respuesta a una petición con Idempotency-Key.
+// StatusCode es 0 mientras la petición original sigue en curso.
+type IdempotencyRecord struct {
+	TenantID     string
+	Principal    string
+	Key          string
+	Fingerprint  string
+	StatusCode   int
+	ContentType  string
+	ResponseBody []byte
+	CreatedAt    time.Time
+	ExpiresAt    time.Time
+}
+
+func (r *IdempotencyRecord) Completed() bool {
+	return r.StatusCode != 0
+}
@@ -1,21 +1,125 @@
 package models
 
-import "time"
+import (
+	"math"
+	"sass-billing-service/src/apperror"
+	"strings"
+	"time"
+)
 
 type Invoice struct {
-	ID            int       `json:"id"`
-	UserID        int       `json:"user_id"`
-	Amount        float64   `json:"amount"`
-	Description   string    `json:"description"`
-	Status        string    `json:"status"` // "pending", "paid", "cancelled"
-	PaymentMethod string    `json:"payment_method"`
-	CreatedAt     time.Time `json:"created_at"`
-	UpdatedAt     time.Time `json:"updated_at"`
+	ID            int           `json:"id"`
+	TenantID      string        `json:"tenant_id"`
+	InvoiceNumber string        `json:"invoice_number"`
+	UserID        int           `json:"user_id"`
+	CustomerName  string        `json:"customer_name"`
+	CustomerEmail string        `json:"customer_email"`
+	Amount        float64       `json:"amount"`
+	TaxAmount     float64       `json:"tax_amount"` // parte de Amount correspondiente a impuestos
+	Currency      string        `json:"currency"`   // ISO 4217
+	Description   string        `json:"description"`
+	Status        string        `json:"status"` // "pending", "paid", "cancelled", "refunded"
+	PaymentMethod string        `json:"payment_method"`
+	DueDate       time.Time     `json:"due_date"`
+	CreatedAt     time.Time     `json:"created_at"`
+	UpdatedAt     time.Time     `json:"updated_at"`
+	Lines         []InvoiceLine `json:"lines,omitempty"`
+}
+
+// Overdue indica si la factura ya venció en la fecha now; el propio día de vencimiento aún no cuenta
+func (i *Invoice) Overdue(now time.Time) bool {
+	return dayStart(now).After(dayStart(i.DueDate))
+}
+
+type InvoiceLine struct {
+	ID           int        `json:"id"`
+	InvoiceID    int        `json:"invoice_id"`
+	Product      string     `json:"product"`
+	Description  string     `json:"description"`
+	Amount       float64    `json:"amount"` // importe neto, sin impuestos
+	ServiceStart *time.Time `json:"service_start,omitempty"`
+	ServiceEnd   *time.Time `json:"service_end,omitempty"` // inclusivo
+}
+
+func (l *InvoiceLine) HasServicePeriod() bool {
+	return l.ServiceStart != nil && l.ServiceEnd != nil
 }
 
 type CreateInvoiceRequest struct {
-	UserID        int     `json:"user_id" validate:"required"`
-	Amount        float64 `json:"amount" validate:"required"`
-	Description   string  `json:"description" validate:"required"`
-	PaymentMethod string  `json:"payment_method" validate:"required"`
+	UserID        int                        `json:"user_id" validate:"required,min=1"`
+	CustomerName  string                     `json:"customer_name" validate:"max=255"`
+	CustomerEmail string                     `json:"customer_email" validate:"omitempty,email,max=255"`
+	Amount        float64                    `json:"amount" validate:"required,gt=0,max=99999999.99"`
+	TaxAmount     float64                    `json:"tax_amount" validate:"min=0"`
+	Currency      string                     `json:"currency" validate:"currency"` // "USD" si se omite
+	Description   string                     `json:"description" validate:"required,max=1000"`
+	PaymentMethod string                     `json:"payment_method" validate:"required,max=50"`
+	DueDate       *time.Time                 `json:"due_date"` // 30 días tras la emisión si se omite
+	Lines         []CreateInvoiceLineRequest `json:"lines" validate:"max=100,dive"`
+}
+
+// Normalize aplica los valores por defecto antes de validar
+func (r *CreateInvoiceRequest) Normalize() {
+	if r.Currency == "" {
+		r.Currency = DefaultCurrency
+	}
+	r.Currency = strings.ToUpper(strings.TrimSpace(r.Currency))
+}
+
+// Validate comprueba el impuesto y que la suma de las líneas coincida con el neto de la factura
+func (r *CreateInvoiceRequest) Validate() []apperror.FieldError {
+	var fields []apperror.FieldError
+	if r.Amount > 0 && r.TaxAmount > r.Amount {
+		fields = append(fields, apperror.Field("tax_amount", "must not exceed amount"))
+	}
+
+	if len(r.Lines) == 0 {
+		return fields
+	}
+
+	var net int64
+	for _, line := range r.Lines {
+		// Las líneas inválidas ya tienen su propio error
+		if line.Amount <= 0 {
+			return fields
+		}
+		net += int64(math.Round(line.Amount * 100))
+	}
+	if net != int64(math.Round((r.Amount-r.TaxAmount)*100)) {
+		fields = append(fields, apperror.Field("lines", "must add up to amount minus tax_amount"))
+	}
+
+	return fields
+}
+
+const (
+	DefaultCurrency = "USD"
+	DefaultTenantID = "default"
+	DefaultDueDays  = 30
+)
+
+type CreateInvoiceLineRequest struct {
+	Product      string     `json:"product" validate:"required,max=100"`
+	Description  string     `json:"description" validate:"max=1000"`
+	Amount       float64    `json:"amount" validate:"required,gt=0"`
+	ServiceStart *time.Time `json:"service_start"`
+	ServiceEnd   *time.Time `json:"service_end"`
+}
+
+// Validate comprueba que el periodo de servicio esté completo y ordenado
+func (l *CreateInvoiceLineRequest) Validate() []apperror.FieldError {
+	if (l.ServiceStart == nil) != (l.ServiceEnd == nil) {
+		return []apperror.FieldError{apperror.Field("service_end", "service period requires both start and end")}
+	}
+	if l.ServiceStart != nil && l.ServiceEnd.Before(*l.ServiceStart) {
+		return []apperror.FieldError{apperror.Field("service_end", "must not be before service_start")}
+	}
+
+	return nil
+}
+
+type InvoiceSearchResult struct {
+	Invoice   Invoice `json:"invoice"`
+	Rank      float64 `json:"rank"`
+	Highlight string  `json:"highlight"` // fragmentos con las coincidencias entre <mark></mark>; el resto va escapado como HTML
 }
@@ -0,0 +1,78 @@
+package models
+
+import "time"
+
+// Plan de cuentas del libro mayor interno
+const (
+	AccountCash               = "1000"
+	AccountAccountsReceivable = "1100"
+	AccountTaxPayable         = "2200"
+	AccountDeferredRevenue    = "2400"
+	AccountRevenue            = "4000"
+)
+
+// Tipos de asiento contable
+const (
+	EntryInvoiceFinalized = "invoice_finalized"
+	EntryPayment          = "payment"
+	EntryRefund           = "refund"
+	EntryCreditNote       = "credit_note"
+)
+
+type LedgerAccount struct {
+	Code string `json:"code"`
+	Name string `json:"name"`
+	Type string `json:"type"` // "asset", "liability", "revenue"
+}
+
+type JournalEntry struct {
+	ID            int           `json:"id"`
+	TenantID      string        `json:"tenant_id"`
+	EntryType     string        `json:"entry_type"`
+	ReferenceType string        `json:"reference_type"`
+	ReferenceID   int           `json:"reference_id"`
+	Description   string        `json:"description"`
+	PostedAt      time.Time     `json:"posted_at"`
+	Lines         []JournalLine `json:"lines"`
+}
+
+// Los importes de las líneas se guardan en céntimos para que el cuadre sea exacto
+type JournalLine struct {
+	AccountCode string `json:"account_code"`
+	DebitCents  int64  `json:"debit_cents"`
+	CreditCents int64  `json:"credit_cents"`
+}
+
+// Balanced indica si el asiento tiene líneas y el debe coincide con el haber
+func (e *JournalEntry) Balanced() bool {
+	if len(e.Lines) == 0 {
+		return false
+	}
+
+	var debit, credit int64
+	for _, line := range e.Lines {
+		if line.DebitCents < 0 || line.CreditCents < 0 {
+			return false
+		}
+		debit += line.DebitCents
+		credit += line.CreditCents
+	}
+
+	return debit == credit
+}
+
+type TrialBalanceAccount struct {
+	AccountCode string  `json:"account_code"`
+	AccountName string  `json:"account_name"`
+	AccountType string  `json:"account_type"`
+	Debit       float64 `json:"debit"`
+	Credit      float64 `json:"credit"`
+	Balance     float64 `json:"balance"` // debe - haber
+}
+
+type TrialBalance struct {
+	Accounts    []TrialBalanceAccount `json:"accounts"`
+	TotalDebit  float64               `json:"total_debit"`
+	TotalCredit float64               `json:"total_credit"`
+	Balance     float64               `json:"balance"` // siempre 0 si el libro está cuadrado
+}
@@ -0,0 +1,39 @@
+package models
+
+import (
+	"strconv"
+	"time"
+)
+
+const (
+	DefaultPageSize = 20
+	MaxPageSize     = 100
+)
+
+// PageLimitRules valida el tamaño de página pedido explícitamente (validation.Var)
+var PageLimitRules = "min=1,max=" + strconv.Itoa(MaxPageSize)
+
+// Ordenaciones admitidas en el listado de facturas; el prefijo "-" indica orden descendente
+var InvoiceSorts = []string{"-created_at", "created_at", "-amount", "amount"}
+
+type InvoiceFilter struct {
+	UserID        int // 0 = todos los usuarios
+	Status        string
+	CreatedFrom   *time.Time
+	CreatedTo     *time.Time
+	MinAmount     *float64
+	MaxAmount     *float64
+	Currency      string
+	PaymentMethod string
+}
+
+type PageRequest struct {
+	Limit  int
+	Cursor string // opaco, devuelto como next_cursor en la página anterior
+	Sort   string
+}
+
+type InvoicePage struct {
+	Invoices   []Invoice
+	NextCursor string
+}
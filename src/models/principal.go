@@ -0,0 +1,17 @@
+package models
+
+// Métodos de autenticación de un principal
+const (
+	AuthMethodJWT    = "jwt"
+	AuthMethodAPIKey = "api_key"
+)
+
+// Principal es la identidad autenticada de la petición, venga de donde venga la credencial
+type Principal struct {
+	Subject    string   `json:"subject"`
+	UserID     int      `json:"user_id"` // cliente al que pertenece la identidad; 0 para personal interno
+	TenantID   string   `json:"tenant_id"`
+	Roles      []string `json:"roles"`
+	AuthMethod string   `json:"auth_method"`
+	Scopes     []string `json:"scopes,omitempty"` // permisos concedidos a una API key
+}
@@ -0,0 +1,21 @@
+package models
+
+// ReceivableDiscrepancy es una factura cuyo saldo en cuentas por cobrar no corresponde a su estado:
+// una factura pendiente debe su importe completo y cualquier otra debe quedar a cero
+type ReceivableDiscrepancy struct {
+	InvoiceID     int     `json:"invoice_id"`
+	InvoiceNumber string  `json:"invoice_number"`
+	Status        string  `json:"status"`
+	Expected      float64 `json:"expected"`
+	Ledger        float64 `json:"ledger"`
+}
+
+type Reconciliation struct {
+	TrialBalance  float64                 `json:"trial_balance"` // 0 si el libro está cuadrado
+	Discrepancies []ReceivableDiscrepancy `json:"discrepancies"`
+}
+
+// Clean indica que el libro cuadra y concuerda con el estado de todas las facturas
+func (r *Reconciliation) Clean() bool {
+	return r.TrialBalance == 0 && len(r.Discrepancies) == 0
+}
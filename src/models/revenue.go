@@ -0,0 +1,105 @@
+package models
+
+import (
+	"math"
+	"time"
+)
+
+// Estados de una fila del calendario de reconocimiento
+const (
+	ScheduleScheduled  = "scheduled"
+	ScheduleRecognized = "recognized"
+	ScheduleCancelled  = "cancelled"
+)
+
+const EntryRevenueRecognition = "revenue_recognition"
+
+type RevenueScheduleEntry struct {
+	ID            int        `json:"id"`
+	InvoiceID     int        `json:"invoice_id"`
+	InvoiceLineID int        `json:"invoice_line_id"`
+	Product       string     `json:"product"`
+	Period        time.Time  `json:"period"` // primer día del mes
+	AmountCents   int64      `json:"amount_cents"`
+	Status        string     `json:"status"`
+	RecognizedAt  *time.Time `json:"recognized_at,omitempty"`
+}
+
+// RecognitionSchedule reparte el importe de la línea entre los meses de su periodo de servicio,
+// prorrateando por días. Las líneas sin periodo se reconocen enteras en el mes de emisión.
+func (l *InvoiceLine) RecognitionSchedule(issuedAt time.Time) []RevenueScheduleEntry {
+	total := int64(math.Round(l.Amount * 100))
+
+	if !l.HasServicePeriod() {
+		return []RevenueScheduleEntry{{
+			InvoiceLineID: l.ID,
+			Product:       l.Product,
+			Period:        MonthStart(issuedAt),
+			AmountCents:   total,
+			Status:        ScheduleRecognized,
+		}}
+	}
+
+	start := dayStart(*l.ServiceStart)
+	end := dayStart(*l.ServiceEnd)
+	totalDays := daysBetween(start, end) + 1
+
+	var schedule []RevenueScheduleEntry
+	var allocated int64
+	for month := MonthStart(start); !month.After(end); month = month.AddDate(0, 1, 0) {
+		from := month
+		if from.Before(start) {
+			from = start
+		}
+		to := month.AddDate(0, 1, -1)
+		if to.After(end) {
+			to = end
+		}
+
+		amount := total * int64(daysBetween(from, to)+1) / int64(totalDays)
+		allocated += amount
+		schedule = append(schedule, RevenueScheduleEntry{
+			InvoiceLineID: l.ID,
+			Product:       l.Product,
+			Period:        month,
+			AmountCents:   amount,
+			Status:        ScheduleScheduled,
+		})
+	}
+
+	// El redondeo se ajusta en el último mes para que el total cuadre al céntimo
+	schedule[len(schedule)-1].AmountCents += total - allocated
+
+	return schedule
+}
+
+func MonthStart(t time.Time) time.Time {
+	return time.Date(t.Year(), t.Month(), 1, 0, 0, 0, 0, time.UTC)
+}
+
+func dayStart(t time.Time) time.Time {
+	return time.Date(t.Year(), t.Month(), t.Day(), 0, 0, 0, 0, time.UTC)
+}
+
+func daysBetween(from, to time.Time) int {
+	return int(to.Sub(from).Hours() / 24)
+}
+
+type RevenueProductMonth struct {
+	Product    string  `json:"product"`
+	Recognized float64 `json:"recognized"`
+	Deferred   float64 `json:"deferred"`
+}
+
+type RevenueMonth struct {
+	Month      string                `json:"month"` // "2006-01"
+	Recognized float64               `json:"recognized"`
+	Deferred   float64               `json:"deferred"`
+	Products   []RevenueProductMonth `json:"products"`
+}
+
+type RevenueReport struct {
+	From   string         `json:"from"`
+	To     string         `json:"to"`
+	Months []RevenueMonth `json:"months"`
+}
@@ -0,0 +1,50 @@
+package models
+
+import "time"
+
+// Subscription es el periodo vigente de un producto recurrente de un cliente: la última línea con
+// periodo de servicio facturada para ese usuario y producto
+type Subscription struct {
+	InvoiceID     int
+	UserID        int
+	CustomerName  string
+	CustomerEmail string
+	Currency      string
+	PaymentMethod string
+	Product       string
+	Description   string
+	Amount        float64 // neto de la línea, sin impuesto
+	TaxRate       float64 // impuesto de la factura sobre su neto
+	ServiceStart  time.Time
+	ServiceEnd    time.Time // inclusivo
+}
+
+// NextPeriod devuelve el periodo que sigue al actual con la misma duración. Si el periodo abarca
+// meses completos a partir de su día de inicio (1–31 de enero, 15 de enero–14 de abril) el siguiente
+// abarca los mismos meses; si no, los mismos días.
+func (s *Subscription) NextPeriod() (time.Time, time.Time) {
+	start := dayStart(s.ServiceStart)
+	end := dayStart(s.ServiceEnd)
+	next := end.AddDate(0, 0, 1)
+
+	for months := 1; !start.AddDate(0, months, -1).After(end); months++ {
+		if start.AddDate(0, months, -1).Equal(end) {
+			return next, next.AddDate(0, months, -1)
+		}
+	}
+
+	return next, next.AddDate(0, 0, daysBetween(start, end))
+}
+
+// Renewal es la factura de un periodo nuevo de una suscripción; en modo dry-run InvoiceID es 0
+type Renewal struct {
+	UserID            int       `json:"user_id"`
+	Product           string    `json:"product"`
+	Currency          string    `json:"currency"`
+	Amount            float64   `json:"amount"` // con impuesto
+	TaxAmount         float64   `json:"tax_amount"`
+	ServiceStart      time.Time `json:"service_start"`
+	ServiceEnd        time.Time `json:"service_end"`
+	PreviousInvoiceID int       `json:"previous_invoice_id"`
+	InvoiceID         int       `json:"invoice_id,omitempty"`
+}
@@ -0,0 +1,148 @@
+// Visor de la referencia de la API: renderiza openapi.json sin dependencias ni peticiones externas,
+// para que /api/docs funcione sin conexión y con una CSP estricta. Todo el texto se inserta con
+// textContent, nunca como HTML.
+(function () {
+  "use strict";
+
+  var root = document.getElementById("docs");
+
+  function el(tag, className, text) {
+    var node = document.createElement(tag);
+    if (className) node.className = className;
+    if (text !== undefined && text !== null) node.textContent = String(text);
+    return node;
+  }
+
+  function append(parent) {
+    for (var i = 1; i < arguments.length; i++) {
+      if (arguments[i]) parent.appendChild(arguments[i]);
+    }
+    return parent;
+  }
+
+  function refName(ref) {
+    return ref.substring(ref.lastIndexOf("/") + 1);
+  }
+
+  function resolve(spec, node) {
+    if (!node || !node.$ref) return node || {};
+    var target = spec;
+    node.$ref.replace(/^#\//, "").split("/").forEach(function (part) {
+      target = target ? target[part] : undefined;
+    });
+    return target || {};
+  }
+
+  // schemaLabel resume un esquema en una línea: "Invoice", "array<Invoice>", "string (date-time)"
+  function schemaLabel(schema) {
+    if (!schema) return "";
+    if (schema.$ref) return refName(schema.$ref);
+    if (schema.type === "array") return "array<" + schemaLabel(schema.items) + ">";
+    if (schema.oneOf || schema.anyOf) {
+      return (schema.oneOf || schema.anyOf).map(schemaLabel).join(" | ");
+    }
+    var label = Array.isArray(schema.type) ? schema.type.join(" | ") : schema.type || "object";
+    if (schema.format) label += " (" + schema.format + ")";
+    if (schema.enum) label += ": " + schema.enum.join(", ");
+    return label;
+  }
+
+  function schemaTable(spec, schema) {
+    schema = resolve(spec, schema);
+    if (!schema.properties) return null;
+
+    var table = el("table");
+    append(table.appendChild(el("tr")), el("th", null, "Field"), el("th", null, "Type"), el("th", null, "Description"));
+    var required = schema.required || [];
+    Object.keys(schema.properties).forEach(function (name) {
+      var property = schema.properties[name];
+      var row = table.appendChild(el("tr"));
+      append(row,
+        el("td", "mono", name + (required.indexOf(name) >= 0 ? " *" : "")),
+        el("td", "mono", schemaLabel(property)),
+        el("td", null, property.description || ""));
+    });
+    return table;
+  }
+
+  function content(spec, body) {
+    var media = body.content || {};
+    var section = el("div");
+    Object.keys(media).forEach(function (type) {
+      var schema = media[type].schema;
+      append(section, el("p", "mono", type + " → " + schemaLabel(schema)), schemaTable(spec, schema));
+    });
+    return section;
+  }
+
+  function operation(spec, path, method, op, shared) {
+    var section = el("details", "operation");
+    var summary = section.appendChild(el("summary"));
+    append(summary, el("span", "method " + method, method.toUpperCase()), el("span", "mono", path), el("span", "muted", op.summary || ""));
+
+    if (op.description) section.appendChild(el("p", null, op.description));
+
+    var parameters = (shared || []).concat(op.parameters || []).map(function (p) { return resolve(spec, p); });
+    if (parameters.length) {
+      section.appendChild(el("h4", null, "Parameters"));
+      var table = section.appendChild(el("table"));
+      append(table.appendChild(el("tr")), el("th", null, "Name"), el("th", null, "In"), el("th", null, "Type"), el("th", null, "Description"));
+      parameters.forEach(function (p) {
+        append(table.appendChild(el("tr")),
+          el("td", "mono", p.name + (p.required ? " *" : "")),
+          el("td", null, p.in),
+          el("td", "mono", schemaLabel(p.schema)),
+          el("td", null, p.description || ""));
+      });
+    }
+
+    if (op.requestBody) {
+      section.appendChild(el("h4", null, "Request body"));
+      section.appendChild(content(spec, resolve(spec, op.requestBody)));
+    }
+
+    section.appendChild(el("h4", null, "Responses"));
+    Object.keys(op.responses || {}).forEach(function (status) {
+      var response = resolve(spec, op.responses[status]);
+      append(section, el("p", null, status + " " + (response.description || "")), content(spec, response));
+    });
+
+    return section;
+  }
+
+  function render(spec) {
+    root.textContent = "";
+    var info = spec.info || {};
+    append(root, el("h1", null, info.title + " " + (info.version || "")), el("p", null, info.description || ""));
+
+    // Las operaciones se agrupan por su primera etiqueta, en el orden de "tags" de la especificación
+    var groups = {};
+    var order = (spec.tags || []).map(function (tag) { return tag.name; });
+    Object.keys(spec.paths || {}).forEach(function (path) {
+      var item = spec.paths[path];
+      ["get", "post", "put", "patch", "delete"].forEach(function (method) {
+        if (!item[method]) return;
+        var tag = (item[method].tags || ["Other"])[0];
+        if (order.indexOf(tag) < 0) order.push(tag);
+        (groups[tag] = groups[tag] || []).push(operation(spec, path, method, item[method], item.parameters));
+      });
+    });
+
+    order.forEach(function (tag) {
+      if (!groups[tag]) return;
+      root.appendChild(el("h2", null, tag));
+      groups[tag].forEach(function (section) { root.appendChild(section); });
+    });
+  }
+
+  fetch(root.getAttribute("data-spec-url"))
+    .then(function (response) {
+      if (!response.ok) throw new Error("HTTP " + response.status);
+      return response.json();
+    })
+    .then(render)
+    .catch(function (err) {
+      root.textContent = "";
+      root.appendChild(el("p", "error", "Could not load the API specification: " + err.message));
+    });
+})();
@@ -0,0 +1,62 @@
+package openapi
+
+import (
+	"embed"
+	"path"
+
+	"github.com/gofiber/fiber/v2"
+)
+
+// Spec es la especificación OpenAPI 3.1 de la API. Se mantiene a mano junto a las rutas;
+// tests/openapi_test.go falla si se registra una ruta que no aparece aquí.
+//
+//go:embed openapi.json
+var Spec []byte
+
+//go:embed docs.html
+var docsPage []byte
+
+// El visor va embebido en el binario: /docs no hace peticiones fuera del servicio
+//
+//go:embed assets
+var assets embed.FS
+
+var assetTypes = map[string]string{
+	".js":  "text/javascript; charset=utf-8",
+	".css": "text/css; charset=utf-8",
+}
+
+// Handler sirve la especificación en JSON
+func Handler() fiber.Handler {
+	return func(c *fiber.Ctx) error {
+		c.Set(fiber.HeaderContentType, fiber.MIMEApplicationJSON)
+		return c.Send(Spec)
+	}
+}
+
+// DocsHandler sirve la referencia navegable de la API, que se renderiza a partir de openapi.json
+func DocsHandler() fiber.Handler {
+	return func(c *fiber.Ctx) error {
+		c.Set(fiber.HeaderContentType, fiber.MIMETextHTMLCharsetUTF8)
+		return c.Send(docsPage)
+	}
+}
+
+// AssetHandler sirve los ficheros estáticos del visor
+func AssetHandler() fiber.Handler {
+	return func(c *fiber.Ctx) error {
+		name := c.Params("file")
+		contentType, ok := assetTypes[path.Ext(name)]
+		if !ok {
+			return fiber.ErrNotFound
+		}
+
+		body, err := assets.ReadFile("assets/" + path.Base(name))
+		if err != nil {
+			return fiber.ErrNotFound
+		}
+
+		c.Set(fiber.HeaderContentType, contentType)
+		return c.Send(body)
+	}
+}
@@ -0,0 +1,106 @@
+package policy
+
+import "sass-billing-service/src/models"
+
+// Roles reconocidos en los tokens
+const (
+	RoleCustomer        = "customer"
+	RoleBillingAdmin    = "billing-admin"
+	RoleFinanceReadonly = "finance-readonly"
+	RoleSupport         = "support"
+)
+
+type Permission string
+
+const (
+	InvoicesRead   Permission = "invoices:read"
+	InvoicesCreate Permission = "invoices:create"
+	InvoicesPay    Permission = "invoices:pay"
+	InvoicesVoid   Permission = "invoices:void"
+	InvoicesRefund Permission = "invoices:refund"
+	InvoicesExport Permission = "invoices:export"
+	LedgerRead     Permission = "ledger:read"
+	ReportsRead    Permission = "reports:read"
+	APIKeysManage  Permission = "api-keys:manage"
+)
+
+var rolePermissions = map[string][]Permission{
+	RoleCustomer:        {InvoicesRead, InvoicesPay},
+	RoleSupport:         {InvoicesRead},
+	RoleFinanceReadonly: {InvoicesRead, InvoicesExport, LedgerRead, ReportsRead},
+	RoleBillingAdmin: {InvoicesRead, InvoicesCreate, InvoicesPay, InvoicesVoid, InvoicesRefund,
+		InvoicesExport, LedgerRead, ReportsRead, APIKeysManage},
+}
+
+// DelegablePermissions son los permisos que se pueden conceder a una API key. La gestión de claves
+// queda fuera para que una clave no pueda emitir otras con más alcance.
+var DelegablePermissions = []Permission{InvoicesRead, InvoicesCreate, InvoicesPay, InvoicesVoid, InvoicesRefund,
+	InvoicesExport, LedgerRead, ReportsRead}
+
+func Delegable(scope string) bool {
+	for _, permission := range DelegablePermissions {
+		if string(permission) == scope {
+			return true
+		}
+	}
+
+	return false
+}
+
+// Can indica si alguno de los roles del principal concede el permiso; una API key solo tiene sus scopes
+func Can(principal *models.Principal, permission Permission) bool {
+	if principal == nil {
+		return false
+	}
+
+	if principal.AuthMethod == models.AuthMethodAPIKey {
+		for _, scope := range principal.Scopes {
+			if scope == string(permission) {
+				return true
+			}
+		}
+		return false
+	}
+
+	for _, role := range principal.Roles {
+		for _, granted := range rolePermissions[role] {
+			if granted == permission {
+				return true
+			}
+		}
+	}
+
+	return false
+}
+
+// OwnResourcesOnly indica si el principal solo puede ver facturas de su propio user_id.
+// Cualquier rol interno levanta la restricción; las API keys son de servicio y abarcan todo su tenant.
+func OwnResourcesOnly(principal *models.Principal) bool {
+	if principal == nil {
+		return true
+	}
+	if principal.AuthMethod == models.AuthMethodAPIKey {
+		return false
+	}
+
+	for _, role := range principal.Roles {
+		if role != RoleCustomer {
+			return false
+		}
+	}
+
+	return true
+}
+
+// CanAccessInvoice combina permiso, tenant y propiedad del recurso
+func CanAccessInvoice(principal *models.Principal, permission Permission, invoice *models.Invoice) bool {
+	if !Can(principal, permission) || invoice.TenantID != principal.TenantID {
+		return false
+	}
+
+	if OwnResourcesOnly(principal) {
+		return principal.UserID != 0 && invoice.UserID == principal.UserID
+	}
+
+	return true
+}
@@ -0,0 +1,172 @@
+package ratelimit
+
+import (
+	"context"
+	"fmt"
+	"math"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+// Limit permite Requests peticiones por ventana Per, con una ráfaga del mismo tamaño
+type Limit struct {
+	Requests int
+	Per      time.Duration
+}
+
+// ParseLimit lee un límite con el formato "<peticiones>/<s|m|h>", por ejemplo "120/m"
+func ParseLimit(value string) (Limit, error) {
+	count, unit, found := strings.Cut(strings.TrimSpace(value), "/")
+	requests, err := strconv.Atoi(strings.TrimSpace(count))
+	if !found || err != nil || requests <= 0 {
+		return Limit{}, fmt.Errorf("invalid rate limit %q, expected <requests>/<s|m|h>", value)
+	}
+
+	per := map[string]time.Duration{"s": time.Second, "m": time.Minute, "h": time.Hour}[strings.TrimSpace(unit)]
+	if per == 0 {
+		return Limit{}, fmt.Errorf("invalid rate limit %q, expected <requests>/<s|m|h>", value)
+	}
+
+	return Limit{Requests: requests, Per: per}, nil
+}
+
+// Enabled es false para el valor cero, que significa "sin límite"
+func (l Limit) Enabled() bool {
+	return l.Requests > 0 && l.Per > 0
+}
+
+// Rate devuelve los tokens que se reponen por segundo
+func (l Limit) Rate() float64 {
+	return float64(l.Requests) / l.Per.Seconds()
+}
+
+// Result es el estado del bucket tras consumir (o intentar consumir) un token
+type Result struct {
+	Allowed    bool
+	Limit      int
+	Remaining  int
+	Reset      time.Duration // hasta que el bucket vuelve a estar lleno
+	RetryAfter time.Duration // hasta que hay un token disponible; cero si se permitió
+}
+
+// Bucket es un token bucket del backend en memoria. El de PostgreSQL hace la misma reposición
+// en SQL y construye el resultado con Limit.Result, así que ambos informan igual al cliente.
+type Bucket struct {
+	Tokens    float64
+	UpdatedAt time.Time
+}
+
+// NewBucket crea un bucket lleno
+func NewBucket(limit Limit, now time.Time) Bucket {
+	return Bucket{Tokens: float64(limit.Requests), UpdatedAt: now}
+}
+
+// Take repone los tokens acumulados desde la última petición y consume uno si lo hay.
+// Una petición rechazada no consume nada.
+func (b *Bucket) Take(limit Limit, now time.Time) Result {
+	if elapsed := now.Sub(b.UpdatedAt).Seconds(); elapsed > 0 {
+		b.Tokens = math.Min(float64(limit.Requests), b.Tokens+elapsed*limit.Rate())
+		b.UpdatedAt = now
+	}
+
+	allowed := b.Tokens >= 1
+	if allowed {
+		b.Tokens--
+	}
+
+	return limit.Result(b.Tokens, allowed)
+}
+
+// Result describe el bucket con tokens restantes después de consumir (allowed) o de rechazar la petición
+func (l Limit) Result(tokens float64, allowed bool) Result {
+	result := Result{Allowed: allowed, Limit: l.Requests, Remaining: int(tokens)}
+	if !allowed {
+		result.Remaining = 0
+		result.RetryAfter = seconds(math.Max(0, 1-tokens) / l.Rate())
+	}
+	result.Reset = seconds((float64(l.Requests) - tokens) / l.Rate())
+
+	return result
+}
+
+func seconds(value float64) time.Duration {
+	return time.Duration(value * float64(time.Second))
+}
+
+// Policy decide qué límites se aplican a una petición: uno por principal y ruta
+// y otro global para todo el tenant, ambos con excepciones configurables
+type Policy struct {
+	Default Limit            // por principal y ruta
+	Routes  map[string]Limit // por ruta, con clave "GET /api/invoices"
+	Tenant  Limit            // suma de todas las peticiones de un tenant
+	Tenants map[string]Limit // por tenant, sustituye a Tenant
+}
+
+// RouteLimit devuelve el límite por principal de la ruta ("GET /api/invoices/:id")
+func (p Policy) RouteLimit(route string) Limit {
+	if limit, ok := p.Routes[route]; ok {
+		return limit
+	}
+
+	return p.Default
+}
+
+// TenantLimit devuelve el límite global del tenant
+func (p Policy) TenantLimit(tenantID string) Limit {
+	if limit, ok := p.Tenants[tenantID]; ok {
+		return limit
+	}
+
+	return p.Tenant
+}
+
+// MemoryStore guarda los buckets en el proceso. Cada réplica limita por separado,
+// así que con varias réplicas el límite efectivo se multiplica por su número.
+type MemoryStore struct {
+	mu       sync.Mutex
+	buckets  map[string]*memoryBucket
+	prunedAt time.Time
+	now      func() time.Time
+}
+
+type memoryBucket struct {
+	Bucket
+	per time.Duration
+}
+
+func NewMemoryStore() *MemoryStore {
+	return &MemoryStore{buckets: make(map[string]*memoryBucket), now: time.Now}
+}
+
+func (s *MemoryStore) Take(ctx context.Context, key string, limit Limit) (Result, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	now := s.now()
+	s.prune(now)
+
+	bucket, ok := s.buckets[key]
+	if !ok {
+		bucket = &memoryBucket{Bucket: NewBucket(limit, now)}
+		s.buckets[key] = bucket
+	}
+	bucket.per = limit.Per
+
+	return bucket.Take(limit, now), nil
+}
+
+// prune descarta, como mucho una vez por minuto, los buckets que ya se habrían rellenado del todo
+func (s *MemoryStore) prune(now time.Time) {
+	if now.Sub(s.prunedAt) < time.Minute {
+		return
+	}
+	s.prunedAt = now
+
+	for key, bucket := range s.buckets {
+		if now.Sub(bucket.UpdatedAt) >= bucket.per {
+			delete(s.buckets, key)
+		}
+	}
+}
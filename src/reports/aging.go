@@ -0,0 +1,46 @@
+package reports
+
+import (
+	"encoding/csv"
+	"io"
+	"sass-billing-service/src/models"
+	"strconv"
+)
+
+// WriteAgingCSV escribe una fila por cliente y moneda seguida de los totales por moneda (user_id vacío)
+func WriteAgingCSV(w io.Writer, report *models.AgingReport) error {
+	writer := csv.NewWriter(w)
+
+	header := []string{"as_of", "user_id", "currency", "current", "days_1_30", "days_31_60",
+		"days_61_90", "days_over_90", "total", "invoices"}
+	if err := writer.Write(header); err != nil {
+		return err
+	}
+
+	rows := append(append([]models.AgingRow{}, report.Customers...), report.Totals...)
+	for _, row := range rows {
+		userID := ""
+		if row.UserID != 0 {
+			userID = strconv.Itoa(row.UserID)
+		}
+
+		record := []string{
+			report.AsOf,
+			userID,
+			row.Currency,
+			formatAmount(row.Current),
+			formatAmount(row.Days1To30),
+			formatAmount(row.Days31To60),
+			formatAmount(row.Days61To90),
+			formatAmount(row.Over90),
+			formatAmount(row.Total),
+			strconv.Itoa(row.Invoices),
+		}
+		if err := writer.Write(record); err != nil {
+			return err
+		}
+	}
+
+	writer.Flush()
+	return writer.Error()
+}
@@ -0,0 +1,201 @@
+package reports
+
+import (
+	"encoding/csv"
+	"io"
+	"math"
+	"sort"
+	"strconv"
+	"time"
+)
+
+// CustomerMonth es el MRR de un cliente en un mes, ya convertido a la moneda de reporte (en céntimos)
+type CustomerMonth struct {
+	UserID   int
+	Month    time.Time
+	MRRCents int64
+}
+
+type MRRMonth struct {
+	Month               string  `json:"month"` // "2006-01"
+	MRR                 float64 `json:"mrr"`
+	ARR                 float64 `json:"arr"`
+	New                 float64 `json:"new"`
+	Expansion           float64 `json:"expansion"`
+	Contraction         float64 `json:"contraction"` // negativo
+	Churn               float64 `json:"churn"`       // negativo
+	Reactivation        float64 `json:"reactivation"`
+	NetNew              float64 `json:"net_new"`
+	Customers           int     `json:"customers"`
+	ChurnedCustomers    int     `json:"churned_customers"`
+	LogoChurnRate       float64 `json:"logo_churn_rate"`       // clientes perdidos / clientes al inicio del mes
+	NetRevenueRetention float64 `json:"net_revenue_retention"` // MRR retenido de la cohorte inicial / MRR inicial
+}
+
+type MRRReport struct {
+	Currency string     `json:"currency"`
+	From     string     `json:"from"`
+	To       string     `json:"to"`
+	Months   []MRRMonth `json:"months"`
+}
+
+type mrrTotals struct {
+	mrr, newMRR, expansion, contraction, churn, reactivation int64
+	customers, churned, startingCustomers                    int
+	startingMRR                                              int64
+}
+
+// ComputeMRR clasifica la variación de MRR de cada cliente mes a mes. history debe contener
+// todos los meses anteriores disponibles para distinguir clientes nuevos de reactivaciones.
+func ComputeMRR(history []CustomerMonth, from, to time.Time, currency string) *MRRReport {
+	from = monthStart(from)
+	to = monthStart(to)
+
+	byCustomer := map[int]map[time.Time]int64{}
+	var first time.Time
+	for _, row := range history {
+		month := monthStart(row.Month)
+		if byCustomer[row.UserID] == nil {
+			byCustomer[row.UserID] = map[time.Time]int64{}
+		}
+		byCustomer[row.UserID][month] += row.MRRCents
+		if first.IsZero() || month.Before(first) {
+			first = month
+		}
+	}
+
+	customers := make([]int, 0, len(byCustomer))
+	for userID := range byCustomer {
+		customers = append(customers, userID)
+	}
+	sort.Ints(customers)
+
+	report := &MRRReport{
+		Currency: currency,
+		From:     from.Format("2006-01"),
+		To:       to.Format("2006-01"),
+		Months:   []MRRMonth{},
+	}
+
+	start := from
+	if !first.IsZero() && first.Before(start) {
+		start = first
+	}
+
+	everActive := map[int]bool{}
+	for month := start; !month.After(to); month = month.AddDate(0, 1, 0) {
+		previous := month.AddDate(0, -1, 0)
+
+		var totals mrrTotals
+		for _, userID := range customers {
+			prev := byCustomer[userID][previous]
+			cur := byCustomer[userID][month]
+
+			if prev > 0 {
+				totals.startingCustomers++
+				totals.startingMRR += prev
+			}
+			if cur > 0 {
+				totals.customers++
+				totals.mrr += cur
+			}
+
+			switch {
+			case prev == 0 && cur > 0 && everActive[userID]:
+				totals.reactivation += cur
+			case prev == 0 && cur > 0:
+				totals.newMRR += cur
+			case prev > 0 && cur == 0:
+				totals.churn -= prev
+				totals.churned++
+			case cur > prev:
+				totals.expansion += cur - prev
+			case cur < prev:
+				totals.contraction -= prev - cur
+			}
+
+			if cur > 0 {
+				everActive[userID] = true
+			}
+		}
+
+		if month.Before(from) {
+			continue
+		}
+
+		bucket := MRRMonth{
+			Month:            month.Format("2006-01"),
+			MRR:              fromCents(totals.mrr),
+			ARR:              fromCents(totals.mrr * 12),
+			New:              fromCents(totals.newMRR),
+			Expansion:        fromCents(totals.expansion),
+			Contraction:      fromCents(totals.contraction),
+			Churn:            fromCents(totals.churn),
+			Reactivation:     fromCents(totals.reactivation),
+			NetNew:           fromCents(totals.newMRR + totals.expansion + totals.contraction + totals.churn + totals.reactivation),
+			Customers:        totals.customers,
+			ChurnedCustomers: totals.churned,
+		}
+		if totals.startingCustomers > 0 {
+			bucket.LogoChurnRate = ratio(int64(totals.churned), int64(totals.startingCustomers))
+		}
+		if totals.startingMRR > 0 {
+			retained := totals.startingMRR + totals.expansion + totals.contraction + totals.churn
+			bucket.NetRevenueRetention = ratio(retained, totals.startingMRR)
+		}
+		report.Months = append(report.Months, bucket)
+	}
+
+	return report
+}
+
+func WriteMRRCSV(w io.Writer, report *MRRReport) error {
+	writer := csv.NewWriter(w)
+
+	header := []string{"month", "currency", "mrr", "arr", "new", "expansion", "contraction", "churn",
+		"reactivation", "net_new", "customers", "churned_customers", "logo_churn_rate", "net_revenue_retention"}
+	if err := writer.Write(header); err != nil {
+		return err
+	}
+
+	for _, m := range report.Months {
+		record := []string{
+			m.Month,
+			report.Currency,
+			formatAmount(m.MRR),
+			formatAmount(m.ARR),
+			formatAmount(m.New),
+			formatAmount(m.Expansion),
+			formatAmount(m.Contraction),
+			formatAmount(m.Churn),
+			formatAmount(m.Reactivation),
+			formatAmount(m.NetNew),
+			strconv.Itoa(m.Customers),
+			strconv.Itoa(m.ChurnedCustomers),
+			strconv.FormatFloat(m.LogoChurnRate, 'f', 4, 64),
+			strconv.FormatFloat(m.NetRevenueRetention, 'f', 4, 64),
+		}
+		if err := writer.Write(record); err != nil {
+			return err
+		}
+	}
+
+	writer.Flush()
+	return writer.Error()
+}
+
+func monthStart(t time.Time) time.Time {
+	return time.Date(t.Year(), t.Month(), 1, 0, 0, 0, 0, time.UTC)
+}
+
+func fromCents(cents int64) float64 {
+	return float64(cents) / 100
+}
+
+func ratio(numerator, denominator int64) float64 {
+	return math.Round(float64(numerator)/float64(denominator)*10000) / 10000
+}
+
+func formatAmount(amount float64) string {
+	return strconv.FormatFloat(amount, 'f', 2, 64)
+}
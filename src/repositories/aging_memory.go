@@ -0,0 +1,119 @@
+package repositories
+
+import (
+	"context"
+	"sass-billing-service/src/models"
+	"sass-billing-service/src/tenant"
+	"sort"
+	"time"
+)
+
+// MemoryAgingRepository es la versión en MemoryDB de AgingRepository
+type MemoryAgingRepository struct {
+	db *MemoryDB
+}
+
+func NewMemoryAgingRepository(db *MemoryDB) *MemoryAgingRepository {
+	return &MemoryAgingRepository{db: db}
+}
+
+// Report saca el saldo de cada factura de cuentas por cobrar, con los asientos registrados hasta el final
+// del día asOf, y lo reparte en los mismos tramos que AgingRepository.Report
+func (r *MemoryAgingRepository) Report(ctx context.Context, asOf time.Time) (*models.AgingReport, error) {
+	tenantID, ok := tenant.FromContext(ctx)
+	if !ok {
+		return nil, tenant.ErrMissingTenant
+	}
+
+	// Las fechas se comparan como columnas DATE
+	day := asOf.UTC().Truncate(24 * time.Hour)
+
+	r.db.mu.RLock()
+	defer r.db.mu.RUnlock()
+
+	type customerKey struct {
+		currency string
+		userID   int
+	}
+	customers := map[customerKey]*agingBalance{}
+	totals := map[string]*agingBalance{}
+
+	for _, invoice := range r.db.sortedInvoices() {
+		if invoice.TenantID != tenantID {
+			continue
+		}
+		balance := r.db.invoiceBalance(invoice, models.AccountAccountsReceivable, day.AddDate(0, 0, 1))
+		if balance == 0 {
+			continue
+		}
+
+		var bucket int
+		switch overdue := int(day.Sub(invoice.DueDate.UTC().Truncate(24*time.Hour)).Hours() / 24); {
+		case overdue <= 0:
+			bucket = 0
+		case overdue <= 30:
+			bucket = 1
+		case overdue <= 60:
+			bucket = 2
+		case overdue <= 90:
+			bucket = 3
+		default:
+			bucket = 4
+		}
+
+		key := customerKey{currency: invoice.Currency, userID: invoice.UserID}
+		if customers[key] == nil {
+			customers[key] = &agingBalance{row: models.AgingRow{UserID: invoice.UserID, Currency: invoice.Currency}}
+		}
+		if totals[invoice.Currency] == nil {
+			totals[invoice.Currency] = &agingBalance{row: models.AgingRow{Currency: invoice.Currency}}
+		}
+		customers[key].add(bucket, balance)
+		totals[invoice.Currency].add(bucket, balance)
+	}
+
+	report := &models.AgingReport{
+		AsOf:      asOf.Format("2006-01-02"),
+		Customers: []models.AgingRow{},
+		Totals:    []models.AgingRow{},
+	}
+	for _, balance := range customers {
+		report.Customers = append(report.Customers, balance.result())
+	}
+	for _, balance := range totals {
+		report.Totals = append(report.Totals, balance.result())
+	}
+	sort.Slice(report.Customers, func(i, j int) bool {
+		a, b := report.Customers[i], report.Customers[j]
+		if a.Currency != b.Currency {
+			return a.Currency < b.Currency
+		}
+		return a.UserID < b.UserID
+	})
+	sort.Slice(report.Totals, func(i, j int) bool { return report.Totals[i].Currency < report.Totals[j].Currency })
+
+	return report, nil
+}
+
+// agingBalance acumula en céntimos una fila del informe: los cinco tramos y el total
+type agingBalance struct {
+	row   models.AgingRow
+	cents [6]int64
+}
+
+func (b *agingBalance) add(bucket int, balance int64) {
+	b.cents[bucket] += balance
+	b.cents[5] += balance
+	b.row.Invoices++
+}
+
+func (b *agingBalance) result() models.AgingRow {
+	row := b.row
+	row.Current = fromCents(b.cents[0])
+	row.Days1To30 = fromCents(b.cents[1])
+	row.Days31To60 = fromCents(b.cents[2])
+	row.Days61To90 = fromCents(b.cents[3])
+	row.Over90 = fromCents(b.cents[4])
+	row.Total = fromCents(b.cents[5])
+	return row
+}
@@ -0,0 +1,90 @@
+package repositories
+
+import (
+	"context"
+	"database/sql"
+	"sass-billing-service/src/metrics"
+	"sass-billing-service/src/models"
+	"time"
+)
+
+type AgingRepository struct {
+	db *sql.DB
+}
+
+func NewAgingRepository(db *sql.DB) *AgingRepository {
+	return &AgingRepository{db: db}
+}
+
+// Report agrupa el saldo pendiente de cada factura a la fecha indicada por cliente y tramo de vencimiento.
+// El saldo sale de las cuentas por cobrar del libro mayor, así que refleja pagos y notas de crédito
+// registrados hasta esa fecha. Las filas con user_id NULL son los totales por moneda.
+func (r *AgingRepository) Report(ctx context.Context, asOf time.Time) (*models.AgingReport, error) {
+	defer metrics.ObserveQuery("aging", "Report", time.Now())
+	query := `WITH balances AS (
+		SELECT i.user_id, i.currency, $2::date - i.due_date AS days_overdue,
+			SUM(l.debit_cents - l.credit_cents) AS balance
+		FROM invoices i
+		JOIN journal_entries e ON e.reference_type = 'invoice' AND e.reference_id = i.id
+			AND e.posted_at < $2::date + INTERVAL '1 day'
+		JOIN journal_lines l ON l.entry_id = e.id AND l.account_code = $3
+		WHERE i.tenant_id = $1
+		GROUP BY i.id, i.user_id, i.currency, i.due_date
+		HAVING SUM(l.debit_cents - l.credit_cents) <> 0
+	)
+	SELECT user_id, currency,
+		COALESCE(SUM(balance) FILTER (WHERE days_overdue <= 0), 0),
+		COALESCE(SUM(balance) FILTER (WHERE days_overdue BETWEEN 1 AND 30), 0),
+		COALESCE(SUM(balance) FILTER (WHERE days_overdue BETWEEN 31 AND 60), 0),
+		COALESCE(SUM(balance) FILTER (WHERE days_overdue BETWEEN 61 AND 90), 0),
+		COALESCE(SUM(balance) FILTER (WHERE days_overdue > 90), 0),
+		SUM(balance),
+		COUNT(*)
+	FROM balances
+	GROUP BY GROUPING SETS ((currency, user_id), (currency))
+	ORDER BY currency, user_id NULLS LAST`
+
+	tx, tenantID, err := beginScoped(ctx, r.db, &sql.TxOptions{ReadOnly: true})
+	if err != nil {
+		return nil, err
+	}
+	defer tx.Rollback()
+
+	rows, err := tx.QueryContext(ctx, query, tenantID, asOf, models.AccountAccountsReceivable)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	report := &models.AgingReport{
+		AsOf:      asOf.Format("2006-01-02"),
+		Customers: []models.AgingRow{},
+		Totals:    []models.AgingRow{},
+	}
+	for rows.Next() {
+		var row models.AgingRow
+		var userID sql.NullInt64
+		var current, days1To30, days31To60, days61To90, over90, total int64
+		if err := rows.Scan(&userID, &row.Currency, &current, &days1To30, &days31To60, &days61To90, &over90, &total, &row.Invoices); err != nil {
+			return nil, err
+		}
+		row.Current = fromCents(current)
+		row.Days1To30 = fromCents(days1To30)
+		row.Days31To60 = fromCents(days31To60)
+		row.Days61To90 = fromCents(days61To90)
+		row.Over90 = fromCents(over90)
+		row.Total = fromCents(total)
+
+		if userID.Valid {
+			row.UserID = int(userID.Int64)
+			report.Customers = append(report.Customers, row)
+		} else {
+			report.Totals = append(report.Totals, row)
+		}
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+
+	return report, tx.Commit()
+}
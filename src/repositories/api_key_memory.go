@@ -0,0 +1,181 @@
+package repositories
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"sass-billing-service/src/models"
+	"sass-billing-service/src/tenant"
+	"time"
+)
+
+// errDuplicatePrefix hace de la restricción UNIQUE de api_keys.prefix
+var errDuplicatePrefix = errors.New("duplicate API key prefix")
+
+// MemoryAPIKeyRepository es la versión en MemoryDB de APIKeyRepository
+type MemoryAPIKeyRepository struct {
+	db *MemoryDB
+}
+
+func NewMemoryAPIKeyRepository(db *MemoryDB) *MemoryAPIKeyRepository {
+	return &MemoryAPIKeyRepository{db: db}
+}
+
+func (r *MemoryAPIKeyRepository) Create(ctx context.Context, key *models.APIKey) (*models.APIKey, error) {
+	tenantID, ok := tenant.FromContext(ctx)
+	if !ok {
+		return nil, tenant.ErrMissingTenant
+	}
+
+	r.db.mu.Lock()
+	defer r.db.mu.Unlock()
+
+	return r.insert(tenantID, key)
+}
+
+func (r *MemoryAPIKeyRepository) List(ctx context.Context) ([]models.APIKey, error) {
+	tenantID, ok := tenant.FromContext(ctx)
+	if !ok {
+		return nil, tenant.ErrMissingTenant
+	}
+
+	r.db.mu.RLock()
+	defer r.db.mu.RUnlock()
+
+	keys := []models.APIKey{}
+	for id := 1; id <= r.db.sequences["api_keys"]; id++ {
+		if key, ok := r.db.apiKeys[id]; ok && key.TenantID == tenantID {
+			keys = append(keys, *copyAPIKey(key))
+		}
+	}
+
+	return keys, nil
+}
+
+// Revoke devuelve sql.ErrNoRows si la clave no existe en el tenant o ya estaba revocada
+func (r *MemoryAPIKeyRepository) Revoke(ctx context.Context, id int) (*models.APIKey, error) {
+	tenantID, ok := tenant.FromContext(ctx)
+	if !ok {
+		return nil, tenant.ErrMissingTenant
+	}
+
+	r.db.mu.Lock()
+	defer r.db.mu.Unlock()
+
+	key, err := r.revoke(tenantID, id)
+	if err != nil {
+		return nil, err
+	}
+
+	return copyAPIKey(key), nil
+}
+
+// Rotate revoca la clave y crea su sustituta en el mismo paso, como APIKeyRepository.Rotate
+func (r *MemoryAPIKeyRepository) Rotate(ctx context.Context, id int, prefix, keyHash string) (*models.APIKey, error) {
+	tenantID, ok := tenant.FromContext(ctx)
+	if !ok {
+		return nil, tenant.ErrMissingTenant
+	}
+
+	r.db.mu.Lock()
+	defer r.db.mu.Unlock()
+
+	old, ok := r.db.apiKeys[id]
+	if !ok || old.TenantID != tenantID || old.RevokedAt != nil {
+		return nil, sql.ErrNoRows
+	}
+
+	created, err := r.insert(tenantID, &models.APIKey{
+		Name:      old.Name,
+		Prefix:    prefix,
+		KeyHash:   keyHash,
+		Scopes:    old.Scopes,
+		CreatedBy: old.CreatedBy,
+		ExpiresAt: old.ExpiresAt,
+	})
+	if err != nil {
+		return nil, err
+	}
+	if _, err := r.revoke(tenantID, id); err != nil {
+		return nil, err
+	}
+
+	return created, nil
+}
+
+// FindByPrefix busca en todos los tenants: el tenant sale de la clave encontrada
+func (r *MemoryAPIKeyRepository) FindByPrefix(ctx context.Context, prefix string) (*models.APIKey, error) {
+	r.db.mu.RLock()
+	defer r.db.mu.RUnlock()
+
+	for _, key := range r.db.apiKeys {
+		if key.Prefix == prefix {
+			return copyAPIKey(key), nil
+		}
+	}
+
+	return nil, sql.ErrNoRows
+}
+
+// TouchLastUsed anota el último uso como mucho una vez por minuto
+func (r *MemoryAPIKeyRepository) TouchLastUsed(ctx context.Context, id int, usedAt time.Time) error {
+	tenantID, ok := tenant.FromContext(ctx)
+	if !ok {
+		return tenant.ErrMissingTenant
+	}
+
+	r.db.mu.Lock()
+	defer r.db.mu.Unlock()
+
+	key, ok := r.db.apiKeys[id]
+	if ok && key.TenantID == tenantID && (key.LastUsedAt == nil || key.LastUsedAt.Before(usedAt.Add(-time.Minute))) {
+		key.LastUsedAt = &usedAt
+	}
+
+	return nil
+}
+
+// insert y revoke se llaman con el cerrojo de escritura tomado
+func (r *MemoryAPIKeyRepository) insert(tenantID string, key *models.APIKey) (*models.APIKey, error) {
+	for _, existing := range r.db.apiKeys {
+		if existing.Prefix == key.Prefix {
+			return nil, errDuplicatePrefix
+		}
+	}
+
+	stored := copyAPIKey(key)
+	stored.ID = r.db.nextval("api_keys")
+	stored.TenantID = tenantID
+	stored.CreatedAt = r.db.now()
+	stored.LastUsedAt = nil
+	stored.RevokedAt = nil
+	r.db.apiKeys[stored.ID] = stored
+
+	return copyAPIKey(stored), nil
+}
+
+func (r *MemoryAPIKeyRepository) revoke(tenantID string, id int) (*models.APIKey, error) {
+	key, ok := r.db.apiKeys[id]
+	if !ok || key.TenantID != tenantID || key.RevokedAt != nil {
+		return nil, sql.ErrNoRows
+	}
+
+	now := r.db.now()
+	key.RevokedAt = &now
+
+	return key, nil
+}
+
+// copyAPIKey evita compartir los scopes y las fechas con el estado guardado
+func copyAPIKey(key *models.APIKey) *models.APIKey {
+	copy := *key
+	copy.Scopes = append([]string(nil), key.Scopes...)
+	for _, date := range []**time.Time{&copy.ExpiresAt, &copy.LastUsedAt, &copy.RevokedAt} {
+		if *date != nil {
+			value := **date
+			*date = &value
+		}
+	}
+
+	return &copy
+}
@@ -0,0 +1,197 @@
+package repositories
+
+import (
+	"context"
+	"database/sql"
+	"sass-billing-service/src/metrics"
+	"sass-billing-service/src/models"
+	"sass-billing-service/src/tenant"
+	"time"
+
+	"github.com/lib/pq"
+)
+
+const apiKeyColumns = `id, tenant_id, name, prefix, key_hash, scopes, created_by, created_at, expires_at, last_used_at, revoked_at`
+
+type APIKeyRepository struct {
+	db *sql.DB
+}
+
+func NewAPIKeyRepository(db *sql.DB) *APIKeyRepository {
+	return &APIKeyRepository{db: db}
+}
+
+func scanAPIKey(row rowScanner, key *models.APIKey) error {
+	return row.Scan(
+		&key.ID,
+		&key.TenantID,
+		&key.Name,
+		&key.Prefix,
+		&key.KeyHash,
+		pq.Array(&key.Scopes),
+		&key.CreatedBy,
+		&key.CreatedAt,
+		&key.ExpiresAt,
+		&key.LastUsedAt,
+		&key.RevokedAt,
+	)
+}
+
+// Create guarda una clave nueva del tenant del contexto
+func (r *APIKeyRepository) Create(ctx context.Context, key *models.APIKey) (*models.APIKey, error) {
+	defer metrics.ObserveQuery("api_key", "Create", time.Now())
+	tx, tenantID, err := beginScoped(ctx, r.db, nil)
+	if err != nil {
+		return nil, err
+	}
+	defer tx.Rollback()
+
+	created, err := insertAPIKey(ctx, tx, tenantID, key)
+	if err != nil {
+		return nil, err
+	}
+
+	return created, tx.Commit()
+}
+
+func (r *APIKeyRepository) List(ctx context.Context) ([]models.APIKey, error) {
+	defer metrics.ObserveQuery("api_key", "List", time.Now())
+	tx, tenantID, err := beginScoped(ctx, r.db, &sql.TxOptions{ReadOnly: true})
+	if err != nil {
+		return nil, err
+	}
+	defer tx.Rollback()
+
+	rows, err := tx.QueryContext(ctx, `SELECT `+apiKeyColumns+` FROM api_keys WHERE tenant_id = $1 ORDER BY id`, tenantID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	keys := []models.APIKey{}
+	for rows.Next() {
+		var key models.APIKey
+		if err := scanAPIKey(rows, &key); err != nil {
+			return nil, err
+		}
+		keys = append(keys, key)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+
+	return keys, tx.Commit()
+}
+
+// Revoke anula una clave activa; devuelve sql.ErrNoRows si no existe o ya estaba revocada
+func (r *APIKeyRepository) Revoke(ctx context.Context, id int) (*models.APIKey, error) {
+	defer metrics.ObserveQuery("api_key", "Revoke", time.Now())
+	tx, tenantID, err := beginScoped(ctx, r.db, nil)
+	if err != nil {
+		return nil, err
+	}
+	defer tx.Rollback()
+
+	var key models.APIKey
+	row := tx.QueryRowContext(ctx, `UPDATE api_keys SET revoked_at = $3
+	WHERE tenant_id = $1 AND id = $2 AND revoked_at IS NULL
+	RETURNING `+apiKeyColumns, tenantID, id, time.Now())
+	if err := scanAPIKey(row, &key); err != nil {
+		return nil, err
+	}
+
+	return &key, tx.Commit()
+}
+
+// Rotate revoca la clave indicada y crea en la misma transacción su sustituta, con el mismo nombre,
+// scopes y caducidad pero con el prefijo y hash nuevos
+func (r *APIKeyRepository) Rotate(ctx context.Context, id int, prefix, keyHash string) (*models.APIKey, error) {
+	defer metrics.ObserveQuery("api_key", "Rotate", time.Now())
+	tx, tenantID, err := beginScoped(ctx, r.db, nil)
+	if err != nil {
+		return nil, err
+	}
+	defer tx.Rollback()
+
+	var old models.APIKey
+	row := tx.QueryRowContext(ctx, `UPDATE api_keys SET revoked_at = $3
+	WHERE tenant_id = $1 AND id = $2 AND revoked_at IS NULL
+	RETURNING `+apiKeyColumns, tenantID, id, time.Now())
+	if err := scanAPIKey(row, &old); err != nil {
+		return nil, err
+	}
+
+	created, err := insertAPIKey(ctx, tx, tenantID, &models.APIKey{
+		Name:      old.Name,
+		Prefix:    prefix,
+		KeyHash:   keyHash,
+		Scopes:    old.Scopes,
+		CreatedBy: old.CreatedBy,
+		ExpiresAt: old.ExpiresAt,
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return created, tx.Commit()
+}
+
+// FindByPrefix busca la clave presentada en una petición. Es el único punto en que el tenant
+// aún no se conoce, así que la búsqueda atraviesa las políticas RLS; el tenant sale después de la clave.
+func (r *APIKeyRepository) FindByPrefix(ctx context.Context, prefix string) (*models.APIKey, error) {
+	defer metrics.ObserveQuery("api_key", "FindByPrefix", time.Now())
+	tx, err := beginSystem(tenant.WithSystem(ctx), r.db)
+	if err != nil {
+		return nil, err
+	}
+	defer tx.Rollback()
+
+	var key models.APIKey
+	row := tx.QueryRowContext(ctx, `SELECT `+apiKeyColumns+` FROM api_keys WHERE prefix = $1`, prefix)
+	if err := scanAPIKey(row, &key); err != nil {
+		return nil, err
+	}
+
+	return &key, tx.Commit()
+}
+
+// TouchLastUsed anota el último uso sin escribir en cada petición: como mucho una vez por minuto
+func (r *APIKeyRepository) TouchLastUsed(ctx context.Context, id int, usedAt time.Time) error {
+	defer metrics.ObserveQuery("api_key", "TouchLastUsed", time.Now())
+	tx, tenantID, err := beginScoped(ctx, r.db, nil)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	if _, err := tx.ExecContext(ctx, `UPDATE api_keys SET last_used_at = $3
+	WHERE tenant_id = $1 AND id = $2 AND (last_used_at IS NULL OR last_used_at < $3 - INTERVAL '1 minute')`,
+		tenantID, id, usedAt); err != nil {
+		return err
+	}
+
+	return tx.Commit()
+}
+
+func insertAPIKey(ctx context.Context, tx dbtx, tenantID string, key *models.APIKey) (*models.APIKey, error) {
+	query := `INSERT INTO api_keys (tenant_id, name, prefix, key_hash, scopes, created_by, created_at, expires_at)
+	VALUES ($1, $2, $3, $4, $5, $6, $7, $8)
+	RETURNING ` + apiKeyColumns
+
+	var created models.APIKey
+	row := tx.QueryRowContext(ctx, query,
+		tenantID,
+		key.Name,
+		key.Prefix,
+		key.KeyHash,
+		pq.Array(key.Scopes),
+		key.CreatedBy,
+		time.Now(),
+		key.ExpiresAt,
+	)
+	if err := scanAPIKey(row, &created); err != nil {
+		return nil, err
+	}
+
+	return &created, nil
+}
@@ -0,0 +1,128 @@
+package repositories
+
+import (
+	"context"
+	"encoding/json"
+	"sass-billing-service/src/models"
+	"sass-billing-service/src/tenant"
+	"time"
+)
+
+// MemoryExportRepository es la versión en MemoryDB de ExportRepository
+type MemoryExportRepository struct {
+	db *MemoryDB
+}
+
+func NewMemoryExportRepository(db *MemoryDB) *MemoryExportRepository {
+	return &MemoryExportRepository{db: db}
+}
+
+// exportLine y exportPayment reproducen los objetos que arma json_build_object en la consulta de PostgreSQL
+type exportLine struct {
+	ID           int     `json:"id"`
+	Product      string  `json:"product"`
+	Description  string  `json:"description"`
+	Amount       float64 `json:"amount"`
+	ServiceStart *string `json:"service_start"`
+	ServiceEnd   *string `json:"service_end"`
+}
+
+type exportPayment struct {
+	Type     string    `json:"type"`
+	Amount   float64   `json:"amount"`
+	PostedAt time.Time `json:"posted_at"`
+}
+
+// Stream aplica los mismos filtros y columnas que ExportRepository.Stream. Las filas se preparan
+// con el cerrojo tomado y fn se llama después, para no bloquear la API mientras se escribe la respuesta.
+func (r *MemoryExportRepository) Stream(ctx context.Context, filter models.ExportFilter, fn func(*models.ExportRow) error) error {
+	rows, err := r.rows(ctx, filter)
+	if err != nil {
+		return err
+	}
+
+	for i := range rows {
+		if err := fn(&rows[i]); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+func (r *MemoryExportRepository) rows(ctx context.Context, filter models.ExportFilter) ([]models.ExportRow, error) {
+	tenantID, ok := tenant.FromContext(ctx)
+	if !ok {
+		return nil, tenant.ErrMissingTenant
+	}
+
+	r.db.mu.RLock()
+	defer r.db.mu.RUnlock()
+
+	var rows []models.ExportRow
+	var err error
+	for _, invoice := range r.db.sortedInvoices() {
+		if invoice.TenantID != tenantID ||
+			filter.From != nil && invoice.CreatedAt.Before(*filter.From) ||
+			filter.To != nil && !invoice.CreatedAt.Before(*filter.To) ||
+			filter.Status != "" && invoice.Status != filter.Status {
+			continue
+		}
+
+		row := models.ExportRow{Invoice: *copyInvoice(invoice, false)}
+		if filter.IncludeLines {
+			lines := []exportLine{}
+			for _, line := range invoice.Lines {
+				lines = append(lines, exportLine{
+					ID:           line.ID,
+					Product:      line.Product,
+					Description:  line.Description,
+					Amount:       line.Amount,
+					ServiceStart: exportDate(line.ServiceStart),
+					ServiceEnd:   exportDate(line.ServiceEnd),
+				})
+			}
+			if row.Lines, err = json.Marshal(lines); err != nil {
+				return nil, err
+			}
+		}
+		if filter.IncludePayments {
+			if row.Payments, err = json.Marshal(r.payments(invoice)); err != nil {
+				return nil, err
+			}
+		}
+		rows = append(rows, row)
+	}
+
+	return rows, nil
+}
+
+// payments devuelve los cobros y devoluciones de la factura con el importe que movieron en caja
+func (r *MemoryExportRepository) payments(invoice *models.Invoice) []exportPayment {
+	payments := []exportPayment{}
+	for _, entry := range r.db.entries {
+		if entry.TenantID != invoice.TenantID || entry.ReferenceType != "invoice" || entry.ReferenceID != invoice.ID ||
+			(entry.EntryType != models.EntryPayment && entry.EntryType != models.EntryRefund) {
+			continue
+		}
+
+		var cash int64
+		for _, line := range entry.Lines {
+			if line.AccountCode == models.AccountCash {
+				cash += line.DebitCents + line.CreditCents
+			}
+		}
+		payments = append(payments, exportPayment{Type: entry.EntryType, Amount: fromCents(cash), PostedAt: entry.PostedAt})
+	}
+
+	return payments
+}
+
+func exportDate(date *time.Time) *string {
+	if date == nil {
+		return nil
+	}
+
+	formatted := date.Format("2006-01-02")
+	return &formatted
+}
@@ -0,0 +1,129 @@
+package repositories
+
+import (
+	"context"
+	"database/sql"
+	"sass-billing-service/src/metrics"
+	"sass-billing-service/src/models"
+	"strconv"
+	"time"
+)
+
+const exportBatchSize = 1000
+
+type ExportRepository struct {
+	db *sql.DB
+}
+
+func NewExportRepository(db *sql.DB) *ExportRepository {
+	return &ExportRepository{db: db}
+}
+
+// Stream recorre las facturas del tenant del contexto con un cursor de servidor, de exportBatchSize en exportBatchSize,
+// llamando a fn por cada fila; nunca se carga el resultado completo en memoria
+func (r *ExportRepository) Stream(ctx context.Context, filter models.ExportFilter, fn func(*models.ExportRow) error) error {
+	defer metrics.ObserveQuery("export", "Stream", time.Now())
+	query := `DECLARE invoice_export NO SCROLL CURSOR FOR
+	SELECT i.id, i.tenant_id, i.invoice_number, i.user_id, i.customer_name, i.customer_email, i.amount, i.tax_amount, i.currency, i.description, i.status,
+		i.payment_method, i.due_date, i.created_at, i.updated_at,
+		CASE WHEN $5 THEN (
+			SELECT COALESCE(json_agg(json_build_object(
+				'id', l.id, 'product', l.product, 'description', l.description, 'amount', l.amount,
+				'service_start', l.service_start, 'service_end', l.service_end) ORDER BY l.id), '[]')
+			FROM invoice_lines l WHERE l.invoice_id = i.id
+		) END,
+		CASE WHEN $6 THEN (
+			SELECT COALESCE(json_agg(json_build_object(
+				'type', e.entry_type, 'amount', c.amount_cents / 100.0, 'posted_at', e.posted_at) ORDER BY e.id), '[]')
+			FROM journal_entries e
+			JOIN LATERAL (
+				SELECT SUM(debit_cents + credit_cents) AS amount_cents FROM journal_lines
+				WHERE entry_id = e.id AND account_code = '` + models.AccountCash + `'
+			) c ON true
+			WHERE e.reference_type = 'invoice' AND e.reference_id = i.id
+				AND e.entry_type IN ('` + models.EntryPayment + `', '` + models.EntryRefund + `')
+		) END
+	FROM invoices i
+	WHERE i.tenant_id = $1
+		AND ($2::timestamptz IS NULL OR i.created_at >= $2)
+		AND ($3::timestamptz IS NULL OR i.created_at < $3)
+		AND ($4 = '' OR i.status = $4)
+	ORDER BY i.id`
+
+	tx, tenantID, err := beginScoped(ctx, r.db, &sql.TxOptions{ReadOnly: true})
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	if _, err := tx.ExecContext(ctx, query,
+		tenantID,
+		filter.From,
+		filter.To,
+		filter.Status,
+		filter.IncludeLines,
+		filter.IncludePayments,
+	); err != nil {
+		return err
+	}
+
+	for {
+		fetched, err := r.fetchBatch(ctx, tx, fn)
+		if err != nil {
+			return err
+		}
+		if fetched < exportBatchSize {
+			break
+		}
+	}
+
+	if _, err := tx.ExecContext(ctx, `CLOSE invoice_export`); err != nil {
+		return err
+	}
+
+	return tx.Commit()
+}
+
+func (r *ExportRepository) fetchBatch(ctx context.Context, tx dbtx, fn func(*models.ExportRow) error) (int, error) {
+	rows, err := tx.QueryContext(ctx, "FETCH FORWARD "+strconv.Itoa(exportBatchSize)+" FROM invoice_export")
+	if err != nil {
+		return 0, err
+	}
+	defer rows.Close()
+
+	fetched := 0
+	for rows.Next() {
+		var row models.ExportRow
+		var lines, payments []byte
+		if err := rows.Scan(
+			&row.ID,
+			&row.TenantID,
+			&row.InvoiceNumber,
+			&row.UserID,
+			&row.CustomerName,
+			&row.CustomerEmail,
+			&row.Amount,
+			&row.TaxAmount,
+			&row.Currency,
+			&row.Description,
+			&row.Status,
+			&row.PaymentMethod,
+			&row.DueDate,
+			&row.CreatedAt,
+			&row.UpdatedAt,
+			&lines,
+			&payments,
+		); err != nil {
+			return fetched, err
+		}
+		row.Lines = lines
+		row.Payments = payments
+
+		if err := fn(&row); err != nil {
+			return fetched, err
+		}
+		fetched++
+	}
+
+	return fetched, rows.Err()
+}
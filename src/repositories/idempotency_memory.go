@@ -0,0 +1,99 @@
+package repositories
+
+import (
+	"context"
+	"sass-billing-service/src/models"
+	"sass-billing-service/src/tenant"
+	"sync"
+	"time"
+)
+
+// MemoryIdempotencyRepository es la versión en proceso de IdempotencyRepository, con las claves
+// igualmente aisladas por tenant y principal
+type MemoryIdempotencyRepository struct {
+	mu      sync.Mutex
+	records map[[3]string]*models.IdempotencyRecord
+	now     func() time.Time
+}
+
+func NewMemoryIdempotencyRepository() *MemoryIdempotencyRepository {
+	return &MemoryIdempotencyRepository{records: make(map[[3]string]*models.IdempotencyRecord), now: time.Now}
+}
+
+func (r *MemoryIdempotencyRepository) Reserve(ctx context.Context, principal, key, fingerprint string, ttl time.Duration) (*models.IdempotencyRecord, bool, error) {
+	tenantID, ok := tenant.FromContext(ctx)
+	if !ok {
+		return nil, false, tenant.ErrMissingTenant
+	}
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	now := r.now()
+	if record, ok := r.records[[3]string{tenantID, principal, key}]; ok && record.ExpiresAt.After(now) {
+		copy := *record
+		return &copy, false, nil
+	}
+
+	r.records[[3]string{tenantID, principal, key}] = &models.IdempotencyRecord{
+		TenantID:    tenantID,
+		Principal:   principal,
+		Key:         key,
+		Fingerprint: fingerprint,
+		CreatedAt:   now,
+		ExpiresAt:   now.Add(ttl),
+	}
+
+	return nil, true, nil
+}
+
+func (r *MemoryIdempotencyRepository) Complete(ctx context.Context, principal, key string, statusCode int, contentType string, body []byte) error {
+	tenantID, ok := tenant.FromContext(ctx)
+	if !ok {
+		return tenant.ErrMissingTenant
+	}
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if record, ok := r.records[[3]string{tenantID, principal, key}]; ok {
+		record.StatusCode = statusCode
+		record.ContentType = contentType
+		record.ResponseBody = append([]byte(nil), body...)
+	}
+
+	return nil
+}
+
+func (r *MemoryIdempotencyRepository) Release(ctx context.Context, principal, key string) error {
+	tenantID, ok := tenant.FromContext(ctx)
+	if !ok {
+		return tenant.ErrMissingTenant
+	}
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	delete(r.records, [3]string{tenantID, principal, key})
+	return nil
+}
+
+func (r *MemoryIdempotencyRepository) DeleteExpired(ctx context.Context) (int64, error) {
+	if !tenant.IsSystem(ctx) {
+		return 0, tenant.ErrMissingTenant
+	}
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	var deleted int64
+	now := r.now()
+	for id, record := range r.records {
+		if !record.ExpiresAt.After(now) {
+			delete(r.records, id)
+			deleted++
+		}
+	}
+
+	return deleted, nil
+}
@@ -0,0 +1,115 @@
+package repositories
+
+import (
+	"context"
+	"database/sql"
+	"sass-billing-service/src/metrics"
+	"sass-billing-service/src/models"
+	"time"
+)
+
+type IdempotencyRepository struct {
+	db *sql.DB
+}
+
+func NewIdempotencyRepository(db *sql.DB) *IdempotencyRepository {
+	return &IdempotencyRepository{db: db}
+}
+
+// Reserve intenta quedarse con la clave del principal; una clave caducada se reutiliza como si no existiera.
+// Si la clave ya está tomada devuelve el registro existente y reserved = false.
+func (r *IdempotencyRepository) Reserve(ctx context.Context, principal, key, fingerprint string, ttl time.Duration) (*models.IdempotencyRecord, bool, error) {
+	defer metrics.ObserveQuery("idempotency", "Reserve", time.Now())
+	query := `INSERT INTO idempotency_keys (tenant_id, principal, idempotency_key, fingerprint, created_at, expires_at)
+	VALUES ($1, $2, $3, $4, $5, $6)
+	ON CONFLICT (tenant_id, principal, idempotency_key) DO UPDATE
+	SET fingerprint = EXCLUDED.fingerprint, status_code = NULL, content_type = NULL, response_body = NULL,
+		created_at = EXCLUDED.created_at, expires_at = EXCLUDED.expires_at
+	WHERE idempotency_keys.expires_at <= EXCLUDED.created_at
+	RETURNING tenant_id`
+
+	tx, tenantID, err := beginScoped(ctx, r.db, nil)
+	if err != nil {
+		return nil, false, err
+	}
+	defer tx.Rollback()
+
+	now := time.Now()
+	var inserted string
+	err = tx.QueryRowContext(ctx, query, tenantID, principal, key, fingerprint, now, now.Add(ttl)).Scan(&inserted)
+	if err == nil {
+		return nil, true, tx.Commit()
+	}
+	if err != sql.ErrNoRows {
+		return nil, false, err
+	}
+
+	record := models.IdempotencyRecord{TenantID: tenantID, Principal: principal, Key: key}
+	var statusCode sql.NullInt64
+	var contentType sql.NullString
+	err = tx.QueryRowContext(ctx, `SELECT fingerprint, status_code, content_type, response_body, created_at, expires_at
+	FROM idempotency_keys WHERE tenant_id = $1 AND principal = $2 AND idempotency_key = $3`, tenantID, principal, key).Scan(
+		&record.Fingerprint,
+		&statusCode,
+		&contentType,
+		&record.ResponseBody,
+		&record.CreatedAt,
+		&record.ExpiresAt,
+	)
+	if err != nil {
+		return nil, false, err
+	}
+	record.StatusCode = int(statusCode.Int64)
+	record.ContentType = contentType.String
+
+	return &record, false, tx.Commit()
+}
+
+func (r *IdempotencyRepository) Complete(ctx context.Context, principal, key string, statusCode int, contentType string, body []byte) error {
+	defer metrics.ObserveQuery("idempotency", "Complete", time.Now())
+	return r.exec(ctx, `UPDATE idempotency_keys SET status_code = $2, content_type = $3, response_body = $4
+	WHERE tenant_id = $1 AND principal = $5 AND idempotency_key = $6`, statusCode, contentType, body, principal, key)
+}
+
+// Release libera una clave cuya petición falló sin respuesta definitiva, para que el reintento se procese
+func (r *IdempotencyRepository) Release(ctx context.Context, principal, key string) error {
+	defer metrics.ObserveQuery("idempotency", "Release", time.Now())
+	return r.exec(ctx, `DELETE FROM idempotency_keys WHERE tenant_id = $1 AND principal = $2 AND idempotency_key = $3`, principal, key)
+}
+
+// exec ejecuta una sentencia del tenant del contexto, que siempre ocupa $1
+func (r *IdempotencyRepository) exec(ctx context.Context, query string, args ...interface{}) error {
+	tx, tenantID, err := beginScoped(ctx, r.db, nil)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	if _, err := tx.ExecContext(ctx, query, append([]interface{}{tenantID}, args...)...); err != nil {
+		return err
+	}
+
+	return tx.Commit()
+}
+
+// DeleteExpired purga las claves caducadas de todos los tenants; requiere un contexto de sistema
+func (r *IdempotencyRepository) DeleteExpired(ctx context.Context) (int64, error) {
+	defer metrics.ObserveQuery("idempotency", "DeleteExpired", time.Now())
+	tx, err := beginSystem(ctx, r.db)
+	if err != nil {
+		return 0, err
+	}
+	defer tx.Rollback()
+
+	result, err := tx.ExecContext(ctx, `DELETE FROM idempotency_keys WHERE expires_at <= $1`, time.Now())
+	if err != nil {
+		return 0, err
+	}
+
+	deleted, err := result.RowsAffected()
+	if err != nil {
+		return 0, err
+	}
+
+	return deleted, tx.Commit()
+}
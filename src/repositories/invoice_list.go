@@ -0,0 +1,251 @@
+package repositories
+
+import (
+	"context"
+	"database/sql"
+	"encoding/base64"
+	"encoding/json"
+	"html"
+	"sass-billing-service/src/apperror"
+	"sass-billing-service/src/metrics"
+	"sass-billing-service/src/models"
+	"sass-billing-service/src/tenant"
+	"sass-billing-service/src/tracing"
+	"strconv"
+	"strings"
+	"time"
+)
+
+var (
+	ErrInvalidCursor = apperror.Validation("Invalid pagination cursor", apperror.Field("cursor", "must be a next_cursor returned with the same sort"))
+	ErrInvalidSort   = apperror.Validation("Invalid sort option", apperror.Field("sort", "must be one of "+strings.Join(models.InvoiceSorts, ", ")))
+)
+
+// pageCursor se serializa en base64 para que el cliente lo trate como opaco
+type pageCursor struct {
+	Sort  string `json:"s"`
+	Value string `json:"v"`
+	ID    int    `json:"id"`
+}
+
+func encodeCursor(sort string, invoice *models.Invoice) string {
+	cursor := pageCursor{Sort: sort, ID: invoice.ID}
+	if strings.TrimPrefix(sort, "-") == "amount" {
+		cursor.Value = strconv.FormatFloat(invoice.Amount, 'f', 2, 64)
+	} else {
+		cursor.Value = invoice.CreatedAt.Format(time.RFC3339Nano)
+	}
+
+	data, _ := json.Marshal(cursor)
+	return base64.RawURLEncoding.EncodeToString(data)
+}
+
+func decodeCursor(encoded, sort string) (*pageCursor, error) {
+	data, err := base64.RawURLEncoding.DecodeString(encoded)
+	if err != nil {
+		return nil, ErrInvalidCursor
+	}
+
+	var cursor pageCursor
+	if err := json.Unmarshal(data, &cursor); err != nil || cursor.Sort != sort {
+		return nil, ErrInvalidCursor
+	}
+
+	return &cursor, nil
+}
+
+func validSort(sort string) bool {
+	for _, option := range models.InvoiceSorts {
+		if option == sort {
+			return true
+		}
+	}
+
+	return false
+}
+
+// List pagina por keyset sobre (columna de orden, id), de modo que el coste no depende de la página pedida
+func (r *InvoiceRepository) List(ctx context.Context, filter models.InvoiceFilter, page models.PageRequest) (*models.InvoicePage, error) {
+	defer metrics.ObserveQuery("invoice", "List", time.Now())
+	if page.Sort == "" {
+		page.Sort = models.InvoiceSorts[0]
+	}
+	if !validSort(page.Sort) {
+		return nil, ErrInvalidSort
+	}
+	if page.Limit <= 0 {
+		page.Limit = models.DefaultPageSize
+	}
+	if page.Limit > models.MaxPageSize {
+		page.Limit = models.MaxPageSize
+	}
+
+	tenantID, ok := tenant.FromContext(ctx)
+	if !ok {
+		return nil, tenant.ErrMissingTenant
+	}
+
+	var conditions []string
+	var args []interface{}
+	where := func(condition string, value interface{}) {
+		args = append(args, value)
+		conditions = append(conditions, strings.ReplaceAll(condition, "?", "$"+strconv.Itoa(len(args))))
+	}
+
+	where("tenant_id = ?", tenantID)
+	if filter.UserID != 0 {
+		where("user_id = ?", filter.UserID)
+	}
+	if filter.Status != "" {
+		where("status = ?", filter.Status)
+	}
+	if filter.CreatedFrom != nil {
+		where("created_at >= ?", *filter.CreatedFrom)
+	}
+	if filter.CreatedTo != nil {
+		where("created_at < ?", *filter.CreatedTo)
+	}
+	if filter.MinAmount != nil {
+		where("amount >= ?", *filter.MinAmount)
+	}
+	if filter.MaxAmount != nil {
+		where("amount <= ?", *filter.MaxAmount)
+	}
+	if filter.Currency != "" {
+		where("currency = ?", filter.Currency)
+	}
+	if filter.PaymentMethod != "" {
+		where("payment_method = ?", filter.PaymentMethod)
+	}
+
+	column := strings.TrimPrefix(page.Sort, "-")
+	direction, comparator := "ASC", ">"
+	if strings.HasPrefix(page.Sort, "-") {
+		direction, comparator = "DESC", "<"
+	}
+
+	if page.Cursor != "" {
+		cursor, err := decodeCursor(page.Cursor, page.Sort)
+		if err != nil {
+			return nil, err
+		}
+
+		cast := "::timestamptz"
+		if column == "amount" {
+			cast = "::numeric"
+			_, err = strconv.ParseFloat(cursor.Value, 64)
+		} else {
+			_, err = time.Parse(time.RFC3339Nano, cursor.Value)
+		}
+		if err != nil {
+			return nil, ErrInvalidCursor
+		}
+
+		args = append(args, cursor.Value, cursor.ID)
+		conditions = append(conditions, "("+column+", id) "+comparator+
+			" ($"+strconv.Itoa(len(args)-1)+cast+", $"+strconv.Itoa(len(args))+")")
+	}
+
+	// Se pide una fila de más para saber si hay página siguiente
+	args = append(args, page.Limit+1)
+	query := `SELECT ` + invoiceColumns + ` FROM invoices
+	WHERE ` + strings.Join(conditions, " AND ") + `
+	ORDER BY ` + column + ` ` + direction + `, id ` + direction + `
+	LIMIT $` + strconv.Itoa(len(args))
+	ctx, span := tracing.Query(ctx, "invoice", "List", query)
+	defer span.End()
+
+	tx, _, err := beginScoped(ctx, r.db, &sql.TxOptions{ReadOnly: true})
+	if err != nil {
+		return nil, err
+	}
+	defer tx.Rollback()
+
+	rows, err := tx.QueryContext(ctx, query, args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	result := &models.InvoicePage{Invoices: []models.Invoice{}}
+	for rows.Next() {
+		var invoice models.Invoice
+		if err := scanInvoice(rows, &invoice); err != nil {
+			return nil, err
+		}
+		result.Invoices = append(result.Invoices, invoice)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+
+	if len(result.Invoices) > page.Limit {
+		result.Invoices = result.Invoices[:page.Limit]
+		result.NextCursor = encodeCursor(page.Sort, &result.Invoices[page.Limit-1])
+	}
+
+	return result, tx.Commit()
+}
+
+// ts_headline marca las coincidencias con estos caracteres de control, que antes se eliminan del texto.
+// Así el resto del texto, que escribe el cliente, se escapa como HTML y solo las marcas pasan a <mark>.
+const (
+	highlightStart = "\x02"
+	highlightStop  = "\x03"
+)
+
+var highlightTags = strings.NewReplacer(highlightStart, "<mark>", highlightStop, "</mark>")
+
+// renderHighlight convierte el fragmento con marcas en HTML seguro de mostrar
+func renderHighlight(marked string) string {
+	return highlightTags.Replace(html.EscapeString(marked))
+}
+
+// Search busca en el documento de texto completo de las facturas del tenant del contexto (número, cliente,
+// descripción y líneas), ordenando por relevancia y resaltando las coincidencias.
+// Un userID distinto de cero restringe la búsqueda a las facturas de ese usuario.
+func (r *InvoiceRepository) Search(ctx context.Context, userID int, q string, limit int) ([]models.InvoiceSearchResult, error) {
+	defer metrics.ObserveQuery("invoice", "Search", time.Now())
+	if limit <= 0 || limit > models.MaxPageSize {
+		limit = models.DefaultPageSize
+	}
+
+	query := `SELECT ` + invoiceColumns + `, ts_rank_cd(search_vector, q) AS rank,
+		ts_headline('english', translate(concat_ws(' ', invoice_number, customer_name, customer_email, description,
+			(SELECT string_agg(l.product || ' ' || l.description, ' ') FROM invoice_lines l WHERE l.invoice_id = invoices.id)), chr(2) || chr(3), ''),
+			q, 'StartSel=' || chr(2) || ', StopSel=' || chr(3) || ', MaxFragments=2, MaxWords=20, MinWords=5') AS highlight
+	FROM invoices, websearch_to_tsquery('english', $2) q
+	WHERE tenant_id = $1 AND search_vector @@ q AND ($4::int = 0 OR user_id = $4)
+	ORDER BY rank DESC, id DESC
+	LIMIT $3`
+	ctx, span := tracing.Query(ctx, "invoice", "Search", query)
+	defer span.End()
+
+	tx, tenantID, err := beginScoped(ctx, r.db, &sql.TxOptions{ReadOnly: true})
+	if err != nil {
+		return nil, err
+	}
+	defer tx.Rollback()
+
+	rows, err := tx.QueryContext(ctx, query, tenantID, q, limit, userID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	results := []models.InvoiceSearchResult{}
+	for rows.Next() {
+		var result models.InvoiceSearchResult
+		fields := append(invoiceFields(&result.Invoice), &result.Rank, &result.Highlight)
+		if err := rows.Scan(fields...); err != nil {
+			return nil, err
+		}
+		result.Highlight = renderHighlight(result.Highlight)
+		results = append(results, result)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+
+	return results, tx.Commit()
+}
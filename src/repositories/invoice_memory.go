@@ -0,0 +1,412 @@
+package repositories
+
+import (
+	"cmp"
+	"context"
+	"database/sql"
+	"fmt"
+	"math"
+	"regexp"
+	"sass-billing-service/src/models"
+	"sass-billing-service/src/tenant"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// MemoryInvoiceRepository guarda las facturas en MemoryDB con la misma semántica que InvoiceRepository:
+// tenant obligatorio en el contexto, sql.ErrNoRows para las facturas de otro tenant, las mismas transiciones
+// de estado con sus asientos y calendarios de reconocimiento, paginación por cursor y búsqueda
+type MemoryInvoiceRepository struct {
+	db *MemoryDB
+}
+
+func NewMemoryInvoiceRepository(db *MemoryDB) *MemoryInvoiceRepository {
+	return &MemoryInvoiceRepository{db: db}
+}
+
+func (r *MemoryInvoiceRepository) GetByID(ctx context.Context, id int) (*models.Invoice, error) {
+	tenantID, ok := tenant.FromContext(ctx)
+	if !ok {
+		return nil, tenant.ErrMissingTenant
+	}
+
+	r.db.mu.RLock()
+	defer r.db.mu.RUnlock()
+
+	invoice, ok := r.db.invoices[id]
+	if !ok || invoice.TenantID != tenantID {
+		return nil, sql.ErrNoRows
+	}
+
+	return copyInvoice(invoice, true), nil
+}
+
+func (r *MemoryInvoiceRepository) GetByUserID(ctx context.Context, userID int) ([]models.Invoice, error) {
+	tenantID, ok := tenant.FromContext(ctx)
+	if !ok {
+		return nil, tenant.ErrMissingTenant
+	}
+
+	r.db.mu.RLock()
+	defer r.db.mu.RUnlock()
+
+	var invoices []models.Invoice
+	for _, invoice := range r.db.sortedInvoices() {
+		if invoice.TenantID == tenantID && invoice.UserID == userID {
+			invoices = append(invoices, *copyInvoice(invoice, false))
+		}
+	}
+
+	return invoices, nil
+}
+
+func (r *MemoryInvoiceRepository) Create(ctx context.Context, req *models.CreateInvoiceRequest) (*models.Invoice, error) {
+	tenantID, ok := tenant.FromContext(ctx)
+	if !ok {
+		return nil, tenant.ErrMissingTenant
+	}
+
+	r.db.mu.Lock()
+	defer r.db.mu.Unlock()
+
+	now := r.db.now()
+	dueDate := now.AddDate(0, 0, models.DefaultDueDays)
+	if req.DueDate != nil {
+		dueDate = *req.DueDate
+	}
+
+	id := r.db.nextval("invoices")
+	invoice := &models.Invoice{
+		ID:            id,
+		TenantID:      tenantID,
+		InvoiceNumber: fmt.Sprintf("INV-%06d", id),
+		UserID:        req.UserID,
+		CustomerName:  req.CustomerName,
+		CustomerEmail: req.CustomerEmail,
+		Amount:        req.Amount,
+		TaxAmount:     req.TaxAmount,
+		Currency:      req.Currency,
+		Description:   req.Description,
+		Status:        "pending",
+		PaymentMethod: req.PaymentMethod,
+		DueDate:       dueDate,
+		CreatedAt:     now,
+		UpdatedAt:     now,
+	}
+	var schedules []*memorySchedule
+	for _, item := range req.Lines {
+		line := models.InvoiceLine{
+			ID:           r.db.nextval("invoice_lines"),
+			InvoiceID:    invoice.ID,
+			Product:      item.Product,
+			Description:  item.Description,
+			Amount:       item.Amount,
+			ServiceStart: item.ServiceStart,
+			ServiceEnd:   item.ServiceEnd,
+		}
+		for _, entry := range line.RecognitionSchedule(now) {
+			entry.InvoiceID = invoice.ID
+			if entry.Status == models.ScheduleRecognized {
+				entry.RecognizedAt = &now
+			}
+			schedules = append(schedules, &memorySchedule{RevenueScheduleEntry: entry, TenantID: tenantID, BookedAt: now})
+		}
+		invoice.Lines = append(invoice.Lines, line)
+	}
+
+	if err := r.db.postEntries(invoiceFinalizedEntry(invoice)); err != nil {
+		return nil, err
+	}
+	for _, schedule := range schedules {
+		schedule.ID = r.db.nextval("revenue_schedules")
+	}
+	r.db.schedules = append(r.db.schedules, schedules...)
+	r.db.invoices[invoice.ID] = invoice
+
+	return copyInvoice(invoice, true), nil
+}
+
+func (r *MemoryInvoiceRepository) MarkPaid(ctx context.Context, id int) (*models.Invoice, error) {
+	return r.transition(ctx, id, "pending", "paid", false, func(invoice *models.Invoice, _ int64) []*models.JournalEntry {
+		return []*models.JournalEntry{paymentEntry(invoice)}
+	})
+}
+
+func (r *MemoryInvoiceRepository) Void(ctx context.Context, id int) (*models.Invoice, error) {
+	return r.transition(ctx, id, "pending", "cancelled", true, func(invoice *models.Invoice, deferred int64) []*models.JournalEntry {
+		return []*models.JournalEntry{creditNoteEntry(invoice, deferred)}
+	})
+}
+
+func (r *MemoryInvoiceRepository) Refund(ctx context.Context, id int) (*models.Invoice, error) {
+	return r.transition(ctx, id, "paid", "refunded", true, func(invoice *models.Invoice, deferred int64) []*models.JournalEntry {
+		return []*models.JournalEntry{creditNoteEntry(invoice, deferred), refundEntry(invoice)}
+	})
+}
+
+// transition sigue los mismos pasos que InvoiceRepository.transition y devuelve, como ella, la factura
+// sin sus líneas. Nada cambia hasta que los asientos se han registrado.
+func (r *MemoryInvoiceRepository) transition(ctx context.Context, id int, from, to string, cancelSchedules bool, entries func(*models.Invoice, int64) []*models.JournalEntry) (*models.Invoice, error) {
+	tenantID, ok := tenant.FromContext(ctx)
+	if !ok {
+		return nil, tenant.ErrMissingTenant
+	}
+
+	r.db.mu.Lock()
+	defer r.db.mu.Unlock()
+
+	invoice, ok := r.db.invoices[id]
+	if !ok || invoice.TenantID != tenantID {
+		return nil, sql.ErrNoRows
+	}
+	if invoice.Status != from {
+		return nil, ErrInvalidInvoiceStatus
+	}
+
+	updated := copyInvoice(invoice, false)
+	updated.Status = to
+	updated.UpdatedAt = r.db.now()
+
+	var pending []*memorySchedule
+	var deferred int64
+	if cancelSchedules {
+		for _, schedule := range r.db.schedules {
+			if schedule.InvoiceID == invoice.ID && schedule.Status == models.ScheduleScheduled {
+				pending = append(pending, schedule)
+				deferred += schedule.AmountCents
+			}
+		}
+	}
+
+	if err := r.db.postEntries(entries(updated, deferred)...); err != nil {
+		return nil, err
+	}
+	cancelledAt := updated.UpdatedAt
+	for _, schedule := range pending {
+		schedule.Status = models.ScheduleCancelled
+		schedule.CancelledAt = &cancelledAt
+	}
+	invoice.Status = updated.Status
+	invoice.UpdatedAt = updated.UpdatedAt
+
+	return updated, nil
+}
+
+// List aplica los mismos filtros, ordenaciones y cursores que InvoiceRepository.List
+func (r *MemoryInvoiceRepository) List(ctx context.Context, filter models.InvoiceFilter, page models.PageRequest) (*models.InvoicePage, error) {
+	if page.Sort == "" {
+		page.Sort = models.InvoiceSorts[0]
+	}
+	if !validSort(page.Sort) {
+		return nil, ErrInvalidSort
+	}
+	if page.Limit <= 0 {
+		page.Limit = models.DefaultPageSize
+	}
+	if page.Limit > models.MaxPageSize {
+		page.Limit = models.MaxPageSize
+	}
+
+	tenantID, ok := tenant.FromContext(ctx)
+	if !ok {
+		return nil, tenant.ErrMissingTenant
+	}
+
+	byAmount := strings.TrimPrefix(page.Sort, "-") == "amount"
+	descending := strings.HasPrefix(page.Sort, "-")
+
+	// after indica si la factura va detrás de (value, id) en el orden pedido
+	after := func(invoice *models.Invoice, amount float64, created time.Time, id int) bool {
+		var order int
+		if byAmount {
+			order = cmp.Compare(roundCents(invoice.Amount), roundCents(amount))
+		} else {
+			order = invoice.CreatedAt.Compare(created)
+		}
+		if order == 0 {
+			order = cmp.Compare(invoice.ID, id)
+		}
+		if descending {
+			return order < 0
+		}
+		return order > 0
+	}
+
+	var cursor func(*models.Invoice) bool
+	if page.Cursor != "" {
+		decoded, err := decodeCursor(page.Cursor, page.Sort)
+		if err != nil {
+			return nil, err
+		}
+
+		var amount float64
+		var created time.Time
+		if byAmount {
+			amount, err = strconv.ParseFloat(decoded.Value, 64)
+		} else {
+			created, err = time.Parse(time.RFC3339Nano, decoded.Value)
+		}
+		if err != nil {
+			return nil, ErrInvalidCursor
+		}
+		cursor = func(invoice *models.Invoice) bool {
+			return after(invoice, amount, created, decoded.ID)
+		}
+	}
+
+	r.db.mu.RLock()
+	defer r.db.mu.RUnlock()
+
+	var matches []*models.Invoice
+	for _, invoice := range r.db.invoices {
+		if invoice.TenantID == tenantID && matchesFilter(invoice, filter) && (cursor == nil || cursor(invoice)) {
+			matches = append(matches, invoice)
+		}
+	}
+	sort.Slice(matches, func(i, j int) bool {
+		return after(matches[j], matches[i].Amount, matches[i].CreatedAt, matches[i].ID)
+	})
+
+	result := &models.InvoicePage{Invoices: []models.Invoice{}}
+	for _, invoice := range matches {
+		if len(result.Invoices) == page.Limit {
+			result.NextCursor = encodeCursor(page.Sort, &result.Invoices[page.Limit-1])
+			break
+		}
+		result.Invoices = append(result.Invoices, *copyInvoice(invoice, false))
+	}
+
+	return result, nil
+}
+
+func matchesFilter(invoice *models.Invoice, filter models.InvoiceFilter) bool {
+	switch {
+	case filter.UserID != 0 && invoice.UserID != filter.UserID,
+		filter.Status != "" && invoice.Status != filter.Status,
+		filter.CreatedFrom != nil && invoice.CreatedAt.Before(*filter.CreatedFrom),
+		filter.CreatedTo != nil && !invoice.CreatedAt.Before(*filter.CreatedTo),
+		filter.MinAmount != nil && invoice.Amount < *filter.MinAmount,
+		filter.MaxAmount != nil && invoice.Amount > *filter.MaxAmount,
+		filter.Currency != "" && invoice.Currency != filter.Currency,
+		filter.PaymentMethod != "" && invoice.PaymentMethod != filter.PaymentMethod:
+		return false
+	}
+
+	return true
+}
+
+// searchTerm es un término de la sintaxis web: "frase exacta", palabra o -exclusión
+var searchTerm = regexp.MustCompile(`(-?)(?:"([^"]*)"|(\S+))`)
+
+// Search entiende la misma sintaxis que websearch_to_tsquery, aunque sin stemming: cada término debe
+// aparecer en el documento (número, cliente, descripción y líneas) y la relevancia es el número de apariciones
+func (r *MemoryInvoiceRepository) Search(ctx context.Context, userID int, q string, limit int) ([]models.InvoiceSearchResult, error) {
+	if limit <= 0 || limit > models.MaxPageSize {
+		limit = models.DefaultPageSize
+	}
+
+	tenantID, ok := tenant.FromContext(ctx)
+	if !ok {
+		return nil, tenant.ErrMissingTenant
+	}
+
+	var include, exclude []string
+	for _, match := range searchTerm.FindAllStringSubmatch(strings.ToLower(q), -1) {
+		term := strings.TrimSpace(match[2] + match[3])
+		switch {
+		case term == "" || term == "or":
+			continue
+		case match[1] == "-":
+			exclude = append(exclude, term)
+		default:
+			include = append(include, term)
+		}
+	}
+
+	r.db.mu.RLock()
+	defer r.db.mu.RUnlock()
+
+	results := []models.InvoiceSearchResult{}
+	if len(include) == 0 {
+		return results, nil
+	}
+
+	for _, invoice := range r.db.sortedInvoices() {
+		if invoice.TenantID != tenantID || (userID != 0 && invoice.UserID != userID) {
+			continue
+		}
+
+		document := searchDocument(invoice)
+		lower := strings.ToLower(document)
+		rank, matched := 0, true
+		for _, term := range include {
+			count := strings.Count(lower, term)
+			matched = matched && count > 0
+			rank += count
+		}
+		for _, term := range exclude {
+			matched = matched && !strings.Contains(lower, term)
+		}
+		if !matched {
+			continue
+		}
+
+		results = append(results, models.InvoiceSearchResult{
+			Invoice:   *copyInvoice(invoice, false),
+			Rank:      float64(rank),
+			Highlight: highlight(document, include),
+		})
+	}
+
+	sort.SliceStable(results, func(i, j int) bool {
+		if results[i].Rank != results[j].Rank {
+			return results[i].Rank > results[j].Rank
+		}
+		return results[i].Invoice.ID > results[j].Invoice.ID
+	})
+	if len(results) > limit {
+		results = results[:limit]
+	}
+
+	return results, nil
+}
+
+func searchDocument(invoice *models.Invoice) string {
+	parts := []string{invoice.InvoiceNumber, invoice.CustomerName, invoice.CustomerEmail, invoice.Description}
+	for _, line := range invoice.Lines {
+		parts = append(parts, line.Product, line.Description)
+	}
+
+	return strings.Join(strings.Fields(strings.Join(parts, " ")), " ")
+}
+
+// highlight marca las coincidencias con <mark></mark> y escapa el resto, como la versión PostgreSQL
+func highlight(document string, terms []string) string {
+	quoted := make([]string, len(terms))
+	for i, term := range terms {
+		quoted[i] = regexp.QuoteMeta(term)
+	}
+
+	document = strings.NewReplacer(highlightStart, "", highlightStop, "").Replace(document)
+	marked := regexp.MustCompile(`(?i)(`+strings.Join(quoted, "|")+`)`).ReplaceAllString(document, highlightStart+"$1"+highlightStop)
+	return renderHighlight(marked)
+}
+
+// copyInvoice evita que el llamante modifique el estado guardado; withLines imita las consultas
+// que no cargan las líneas
+func copyInvoice(invoice *models.Invoice, withLines bool) *models.Invoice {
+	copy := *invoice
+	copy.Lines = nil
+	if withLines {
+		copy.Lines = append([]models.InvoiceLine(nil), invoice.Lines...)
+	}
+
+	return &copy
+}
+
+// roundCents compara importes como la columna NUMERIC(10, 2)
+func roundCents(amount float64) float64 {
+	return math.Round(amount*100) / 100
+}
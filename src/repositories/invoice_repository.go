@@ -3,48 +3,104 @@ package repositories
 import (
 	"context"
 	"database/sql"
+	"sass-billing-service/src/apperror"
+	"sass-billing-service/src/metrics"
 	"sass-billing-service/src/models"
+	"sass-billing-service/src/tracing"
 	"time"
 )
 
+var ErrInvalidInvoiceStatus = apperror.Conflict("Invoice status does not allow this operation")
+
+const invoiceColumns = `id, tenant_id, invoice_number, user_id, customer_name, customer_email, amount, tax_amount, currency, description, status, payment_method, due_date, created_at, updated_at`
+
+const updateInvoiceStatusQuery = `UPDATE invoices SET status = $1, updated_at = $2 WHERE tenant_id = $3 AND id = $4`
+
+type rowScanner interface {
+	Scan(dest ...interface{}) error
+}
+
 type InvoiceRepository struct {
-	db *sql.DB
+	db     *sql.DB
+	ledger *LedgerRepository
 }
 
 func NewInvoiceRepository(db *sql.DB) *InvoiceRepository {
-	return &InvoiceRepository{db: db}
+	return &InvoiceRepository{db: db, ledger: NewLedgerRepository(db)}
 }
 
-func (r *InvoiceRepository) GetByID(ctx context.Context, id int) (*models.Invoice, error) {
-	query := `SELECT id, user_id, amount, description, status, payment_method, created_at, updated_at 
-	FROM invoices WHERE id = $1`
-
-	row := r.db.QueryRowContext(ctx, query, id)
+func scanInvoice(row rowScanner, invoice *models.Invoice) error {
+	return row.Scan(invoiceFields(invoice)...)
+}
 
-	var invoice models.Invoice
-	err := row.Scan(
+// invoiceFields devuelve los destinos de Scan en el orden de invoiceColumns
+func invoiceFields(invoice *models.Invoice) []interface{} {
+	return []interface{}{
 		&invoice.ID,
+		&invoice.TenantID,
+		&invoice.InvoiceNumber,
 		&invoice.UserID,
+		&invoice.CustomerName,
+		&invoice.CustomerEmail,
 		&invoice.Amount,
+		&invoice.TaxAmount,
+		&invoice.Currency,
 		&invoice.Description,
 		&invoice.Status,
 		&invoice.PaymentMethod,
+		&invoice.DueDate,
 		&invoice.CreatedAt,
 		&invoice.UpdatedAt,
-	)
+	}
+}
+
+func (r *InvoiceRepository) GetByID(ctx context.Context, id int) (*models.Invoice, error) {
+	defer metrics.ObserveQuery("invoice", "GetByID", time.Now())
+	query := `SELECT ` + invoiceColumns + ` 
+	FROM invoices WHERE tenant_id = $1 AND id = $2`
+	ctx, span := tracing.Query(ctx, "invoice", "GetByID", query)
+	defer span.End()
+
+	tx, tenantID, err := beginScoped(ctx, r.db, &sql.TxOptions{ReadOnly: true})
+	if err != nil {
+		return nil, err
+	}
+	defer tx.Rollback()
+
+	row := tx.QueryRowContext(ctx, query, tenantID, id)
+
+	var invoice models.Invoice
+	if err := scanInvoice(row, &invoice); err != nil {
+		return nil, err
+	}
 
+	lines, err := getInvoiceLines(ctx, tx, invoice.ID)
 	if err != nil {
 		return nil, err
 	}
+	invoice.Lines = lines
+
+	if err := tx.Commit(); err != nil {
+		return nil, err
+	}
 
 	return &invoice, nil
 }
 
 func (r *InvoiceRepository) GetByUserID(ctx context.Context, userID int) ([]models.Invoice, error) {
-	query := `SELECT id, user_id, amount, description, status, payment_method, created_at, updated_at 
-	FROM invoices WHERE user_id = $1`
+	defer metrics.ObserveQuery("invoice", "GetByUserID", time.Now())
+	query := `SELECT ` + invoiceColumns + ` 
+	FROM invoices WHERE tenant_id = $1 AND user_id = $2`
+	ctx, span := tracing.Query(ctx, "invoice", "GetByUserID", query)
+	defer span.End()
+
+	tx, tenantID, err := beginScoped(ctx, r.db, &sql.TxOptions{ReadOnly: true})
+	if err != nil {
+		return nil, err
+	}
+	defer tx.Rollback()
 
-	rows, err := r.db.QueryContext(ctx, query, userID)
+	rows, err := tx.QueryContext(ctx, query, tenantID, userID)
 	if err != nil {
 		return nil, err
 	}
@@ -53,54 +109,145 @@ func (r *InvoiceRepository) GetByUserID(ctx context.Context, userID int) ([]mode
 	var invoices []models.Invoice
 	for rows.Next() {
 		var invoice models.Invoice
-		err := rows.Scan(
-			&invoice.ID,
-			&invoice.UserID,
-			&invoice.Amount,
-			&invoice.Description,
-			&invoice.Status,
-			&invoice.PaymentMethod,
-			&invoice.CreatedAt,
-			&invoice.UpdatedAt,
-		)
-		if err != nil {
+		if err := scanInvoice(rows, &invoice); err != nil {
 			return nil, err
 		}
 		invoices = append(invoices, invoice)
 	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
 
-	return invoices, nil
+	return invoices, tx.Commit()
 }
 
+// Create inserta la factura, sus líneas y calendario de reconocimiento y registra su asiento de emisión
+// en la misma transacción. El tenant sale siempre del contexto, nunca de la petición.
 func (r *InvoiceRepository) Create(ctx context.Context, invoice *models.CreateInvoiceRequest) (*models.Invoice, error) {
-	query := `INSERT INTO invoices (user_id, amount, description, status, payment_method, created_at, updated_at)
-	VALUES ($1, $2, $3, 'pending', $4, $5, $5) 
-	RETURNING id, user_id, amount, description, status, payment_method, created_at, updated_at`
+	defer metrics.ObserveQuery("invoice", "Create", time.Now())
+	query := `INSERT INTO invoices (tenant_id, user_id, customer_name, customer_email, amount, tax_amount, currency, description, status, payment_method, due_date, created_at, updated_at)
+	VALUES ($1, $2, $3, $4, $5, $6, $7, $8, 'pending', $9, $10, $11, $11) 
+	RETURNING ` + invoiceColumns
+	ctx, span := tracing.Query(ctx, "invoice", "Create", query)
+	defer span.End()
+
+	tx, tenantID, err := beginScoped(ctx, r.db, nil)
+	if err != nil {
+		return nil, err
+	}
+	defer tx.Rollback()
 
 	now := time.Now()
-	row := r.db.QueryRowContext(ctx, query,
+	dueDate := now.AddDate(0, 0, models.DefaultDueDays)
+	if invoice.DueDate != nil {
+		dueDate = *invoice.DueDate
+	}
+
+	row := tx.QueryRowContext(ctx, query,
+		tenantID,
 		invoice.UserID,
+		invoice.CustomerName,
+		invoice.CustomerEmail,
 		invoice.Amount,
+		invoice.TaxAmount,
+		invoice.Currency,
 		invoice.Description,
 		invoice.PaymentMethod,
+		dueDate,
 		now,
 	)
 
 	var createdInvoice models.Invoice
-	err := row.Scan(
-		&createdInvoice.ID,
-		&createdInvoice.UserID,
-		&createdInvoice.Amount,
-		&createdInvoice.Description,
-		&createdInvoice.Status,
-		&createdInvoice.PaymentMethod,
-		&createdInvoice.CreatedAt,
-		&createdInvoice.UpdatedAt,
-	)
+	if err := scanInvoice(row, &createdInvoice); err != nil {
+		return nil, err
+	}
 
-	if err != nil {
+	if err := insertInvoiceLines(ctx, tx, &createdInvoice, invoice.Lines); err != nil {
+		return nil, err
+	}
+
+	if err := r.ledger.PostEntry(ctx, tx.Tx, invoiceFinalizedEntry(&createdInvoice)); err != nil {
+		return nil, err
+	}
+
+	if err := tx.Commit(); err != nil {
 		return nil, err
 	}
 
 	return &createdInvoice, nil
 }
+
+// MarkPaid registra el cobro completo de una factura pendiente
+func (r *InvoiceRepository) MarkPaid(ctx context.Context, id int) (*models.Invoice, error) {
+	defer metrics.ObserveQuery("invoice", "MarkPaid", time.Now())
+	ctx, span := tracing.Query(ctx, "invoice", "MarkPaid", updateInvoiceStatusQuery)
+	defer span.End()
+	return r.transition(ctx, id, "pending", "paid", false, func(invoice *models.Invoice, _ int64) []*models.JournalEntry {
+		return []*models.JournalEntry{paymentEntry(invoice)}
+	})
+}
+
+// Void anula una factura pendiente emitiendo una nota de crédito por el total
+func (r *InvoiceRepository) Void(ctx context.Context, id int) (*models.Invoice, error) {
+	defer metrics.ObserveQuery("invoice", "Void", time.Now())
+	ctx, span := tracing.Query(ctx, "invoice", "Void", updateInvoiceStatusQuery)
+	defer span.End()
+	return r.transition(ctx, id, "pending", "cancelled", true, func(invoice *models.Invoice, deferred int64) []*models.JournalEntry {
+		return []*models.JournalEntry{creditNoteEntry(invoice, deferred)}
+	})
+}
+
+// Refund devuelve el importe de una factura pagada: nota de crédito más salida de caja
+func (r *InvoiceRepository) Refund(ctx context.Context, id int) (*models.Invoice, error) {
+	defer metrics.ObserveQuery("invoice", "Refund", time.Now())
+	ctx, span := tracing.Query(ctx, "invoice", "Refund", updateInvoiceStatusQuery)
+	defer span.End()
+	return r.transition(ctx, id, "paid", "refunded", true, func(invoice *models.Invoice, deferred int64) []*models.JournalEntry {
+		return []*models.JournalEntry{creditNoteEntry(invoice, deferred), refundEntry(invoice)}
+	})
+}
+
+// transition cambia el estado de la factura y registra sus asientos; si cancelSchedules es true,
+// lo pendiente de reconocer se anula y se pasa al constructor de asientos para revertirlo
+func (r *InvoiceRepository) transition(ctx context.Context, id int, from, to string, cancelSchedules bool, entries func(*models.Invoice, int64) []*models.JournalEntry) (*models.Invoice, error) {
+	tx, tenantID, err := beginScoped(ctx, r.db, nil)
+	if err != nil {
+		return nil, err
+	}
+	defer tx.Rollback()
+
+	var invoice models.Invoice
+	row := tx.QueryRowContext(ctx, `SELECT `+invoiceColumns+` FROM invoices WHERE tenant_id = $1 AND id = $2 FOR UPDATE`, tenantID, id)
+	if err := scanInvoice(row, &invoice); err != nil {
+		return nil, err
+	}
+
+	if invoice.Status != from {
+		return nil, ErrInvalidInvoiceStatus
+	}
+
+	invoice.Status = to
+	invoice.UpdatedAt = time.Now()
+	if _, err := tx.ExecContext(ctx, updateInvoiceStatusQuery, invoice.Status, invoice.UpdatedAt, tenantID, invoice.ID); err != nil {
+		return nil, err
+	}
+
+	var deferred int64
+	if cancelSchedules {
+		if deferred, err = cancelPendingSchedules(ctx, tx, invoice.ID, invoice.UpdatedAt); err != nil {
+			return nil, err
+		}
+	}
+
+	for _, entry := range entries(&invoice, deferred) {
+		if err := r.ledger.PostEntry(ctx, tx.Tx, entry); err != nil {
+			return nil, err
+		}
+	}
+
+	if err := tx.Commit(); err != nil {
+		return nil, err
+	}
+
+	return &invoice, nil
+}
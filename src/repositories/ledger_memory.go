@@ -0,0 +1,101 @@
+package repositories
+
+import (
+	"context"
+	"sass-billing-service/src/models"
+	"sass-billing-service/src/tenant"
+	"time"
+)
+
+// MemoryLedgerRepository lee el libro mayor de MemoryDB; los asientos los registran las operaciones
+// de facturas y el cierre de ingresos, como en PostgreSQL
+type MemoryLedgerRepository struct {
+	db *MemoryDB
+}
+
+func NewMemoryLedgerRepository(db *MemoryDB) *MemoryLedgerRepository {
+	return &MemoryLedgerRepository{db: db}
+}
+
+func (r *MemoryLedgerRepository) TrialBalance(ctx context.Context) (*models.TrialBalance, error) {
+	tenantID, ok := tenant.FromContext(ctx)
+	if !ok {
+		return nil, tenant.ErrMissingTenant
+	}
+
+	r.db.mu.RLock()
+	defer r.db.mu.RUnlock()
+
+	debits := map[string]int64{}
+	credits := map[string]int64{}
+	for _, entry := range r.db.entries {
+		if entry.TenantID != tenantID {
+			continue
+		}
+		for _, line := range entry.Lines {
+			debits[line.AccountCode] += line.DebitCents
+			credits[line.AccountCode] += line.CreditCents
+		}
+	}
+
+	balance := &models.TrialBalance{Accounts: []models.TrialBalanceAccount{}}
+	var totalDebit, totalCredit int64
+	for _, account := range memoryAccounts {
+		debit, credit := debits[account.Code], credits[account.Code]
+		balance.Accounts = append(balance.Accounts, models.TrialBalanceAccount{
+			AccountCode: account.Code,
+			AccountName: account.Name,
+			AccountType: account.Type,
+			Debit:       fromCents(debit),
+			Credit:      fromCents(credit),
+			Balance:     fromCents(debit - credit),
+		})
+
+		totalDebit += debit
+		totalCredit += credit
+	}
+
+	balance.TotalDebit = fromCents(totalDebit)
+	balance.TotalCredit = fromCents(totalCredit)
+	balance.Balance = fromCents(totalDebit - totalCredit)
+
+	return balance, nil
+}
+
+// ReceivableDiscrepancies aplica la misma regla que la consulta de LedgerRepository: una factura pendiente
+// debe su total en cuentas por cobrar y cualquier otra no debe nada
+func (r *MemoryLedgerRepository) ReceivableDiscrepancies(ctx context.Context) ([]models.ReceivableDiscrepancy, error) {
+	tenantID, ok := tenant.FromContext(ctx)
+	if !ok {
+		return nil, tenant.ErrMissingTenant
+	}
+
+	r.db.mu.RLock()
+	defer r.db.mu.RUnlock()
+
+	discrepancies := []models.ReceivableDiscrepancy{}
+	for _, invoice := range r.db.sortedInvoices() {
+		if invoice.TenantID != tenantID {
+			continue
+		}
+
+		var expected int64
+		if invoice.Status == "pending" {
+			expected = toCents(invoice.Amount)
+		}
+		ledger := r.db.invoiceBalance(invoice, models.AccountAccountsReceivable, time.Time{})
+		if expected == ledger {
+			continue
+		}
+
+		discrepancies = append(discrepancies, models.ReceivableDiscrepancy{
+			InvoiceID:     invoice.ID,
+			InvoiceNumber: invoice.InvoiceNumber,
+			Status:        invoice.Status,
+			Expected:      fromCents(expected),
+			Ledger:        fromCents(ledger),
+		})
+	}
+
+	return discrepancies, nil
+}
@@ -0,0 +1,272 @@
+package repositories
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"math"
+	"sass-billing-service/src/metrics"
+	"sass-billing-service/src/models"
+	"time"
+)
+
+var ErrUnbalancedEntry = errors.New("journal entry is not balanced")
+
+type LedgerRepository struct {
+	db *sql.DB
+}
+
+func NewLedgerRepository(db *sql.DB) *LedgerRepository {
+	return &LedgerRepository{db: db}
+}
+
+// PostEntry registra un asiento dentro de la transacción de la operación que lo origina
+func (r *LedgerRepository) PostEntry(ctx context.Context, tx *sql.Tx, entry *models.JournalEntry) error {
+	if !entry.Balanced() {
+		return ErrUnbalancedEntry
+	}
+
+	if entry.PostedAt.IsZero() {
+		entry.PostedAt = time.Now()
+	}
+
+	query := `INSERT INTO journal_entries (tenant_id, entry_type, reference_type, reference_id, description, posted_at)
+	VALUES ($1, $2, $3, $4, $5, $6) RETURNING id`
+
+	err := tx.QueryRowContext(ctx, query,
+		entry.TenantID,
+		entry.EntryType,
+		entry.ReferenceType,
+		entry.ReferenceID,
+		entry.Description,
+		entry.PostedAt,
+	).Scan(&entry.ID)
+	if err != nil {
+		return err
+	}
+
+	lineQuery := `INSERT INTO journal_lines (entry_id, account_code, debit_cents, credit_cents)
+	VALUES ($1, $2, $3, $4)`
+
+	for _, line := range entry.Lines {
+		if _, err := tx.ExecContext(ctx, lineQuery, entry.ID, line.AccountCode, line.DebitCents, line.CreditCents); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+func (r *LedgerRepository) TrialBalance(ctx context.Context) (*models.TrialBalance, error) {
+	defer metrics.ObserveQuery("ledger", "TrialBalance", time.Now())
+	query := `SELECT a.code, a.name, a.type, COALESCE(SUM(l.debit_cents), 0), COALESCE(SUM(l.credit_cents), 0)
+	FROM ledger_accounts a
+	LEFT JOIN (journal_lines l JOIN journal_entries e ON e.id = l.entry_id AND e.tenant_id = $1) ON l.account_code = a.code
+	GROUP BY a.code, a.name, a.type ORDER BY a.code`
+
+	tx, tenantID, err := beginScoped(ctx, r.db, &sql.TxOptions{ReadOnly: true})
+	if err != nil {
+		return nil, err
+	}
+	defer tx.Rollback()
+
+	rows, err := tx.QueryContext(ctx, query, tenantID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	balance := &models.TrialBalance{Accounts: []models.TrialBalanceAccount{}}
+	var totalDebit, totalCredit int64
+	for rows.Next() {
+		var account models.TrialBalanceAccount
+		var debit, credit int64
+		if err := rows.Scan(&account.AccountCode, &account.AccountName, &account.AccountType, &debit, &credit); err != nil {
+			return nil, err
+		}
+		account.Debit = fromCents(debit)
+		account.Credit = fromCents(credit)
+		account.Balance = fromCents(debit - credit)
+		balance.Accounts = append(balance.Accounts, account)
+
+		totalDebit += debit
+		totalCredit += credit
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+
+	balance.TotalDebit = fromCents(totalDebit)
+	balance.TotalCredit = fromCents(totalCredit)
+	balance.Balance = fromCents(totalDebit - totalCredit)
+
+	return balance, tx.Commit()
+}
+
+func toCents(amount float64) int64 {
+	return int64(math.Round(amount * 100))
+}
+
+func fromCents(cents int64) float64 {
+	return float64(cents) / 100
+}
+
+// Asientos generados por cada operación de facturación
+
+// invoiceFinalizedEntry abona a ingresos diferidos la parte de las líneas con periodo de servicio
+func invoiceFinalizedEntry(invoice *models.Invoice) *models.JournalEntry {
+	total := toCents(invoice.Amount)
+	tax := toCents(invoice.TaxAmount)
+
+	var deferred int64
+	for _, line := range invoice.Lines {
+		if line.HasServicePeriod() {
+			deferred += toCents(line.Amount)
+		}
+	}
+
+	lines := []models.JournalLine{
+		{AccountCode: models.AccountAccountsReceivable, DebitCents: total},
+	}
+	if revenue := total - tax - deferred; revenue > 0 {
+		lines = append(lines, models.JournalLine{AccountCode: models.AccountRevenue, CreditCents: revenue})
+	}
+	if deferred > 0 {
+		lines = append(lines, models.JournalLine{AccountCode: models.AccountDeferredRevenue, CreditCents: deferred})
+	}
+	if tax > 0 {
+		lines = append(lines, models.JournalLine{AccountCode: models.AccountTaxPayable, CreditCents: tax})
+	}
+
+	return &models.JournalEntry{
+		TenantID:      invoice.TenantID,
+		EntryType:     models.EntryInvoiceFinalized,
+		ReferenceType: "invoice",
+		ReferenceID:   invoice.ID,
+		Description:   "Invoice finalized",
+		PostedAt:      invoice.CreatedAt,
+		Lines:         lines,
+	}
+}
+
+func paymentEntry(invoice *models.Invoice) *models.JournalEntry {
+	total := toCents(invoice.Amount)
+
+	return &models.JournalEntry{
+		TenantID:      invoice.TenantID,
+		EntryType:     models.EntryPayment,
+		ReferenceType: "invoice",
+		ReferenceID:   invoice.ID,
+		Description:   "Payment received via " + invoice.PaymentMethod,
+		PostedAt:      invoice.UpdatedAt,
+		Lines: []models.JournalLine{
+			{AccountCode: models.AccountCash, DebitCents: total},
+			{AccountCode: models.AccountAccountsReceivable, CreditCents: total},
+		},
+	}
+}
+
+// creditNoteEntry revierte el ingreso, la parte aún diferida y el impuesto contra cuentas por cobrar
+func creditNoteEntry(invoice *models.Invoice, deferred int64) *models.JournalEntry {
+	total := toCents(invoice.Amount)
+	tax := toCents(invoice.TaxAmount)
+
+	var lines []models.JournalLine
+	if revenue := total - tax - deferred; revenue > 0 {
+		lines = append(lines, models.JournalLine{AccountCode: models.AccountRevenue, DebitCents: revenue})
+	}
+	if deferred > 0 {
+		lines = append(lines, models.JournalLine{AccountCode: models.AccountDeferredRevenue, DebitCents: deferred})
+	}
+	if tax > 0 {
+		lines = append(lines, models.JournalLine{AccountCode: models.AccountTaxPayable, DebitCents: tax})
+	}
+	lines = append(lines, models.JournalLine{AccountCode: models.AccountAccountsReceivable, CreditCents: total})
+
+	return &models.JournalEntry{
+		TenantID:      invoice.TenantID,
+		EntryType:     models.EntryCreditNote,
+		ReferenceType: "invoice",
+		ReferenceID:   invoice.ID,
+		Description:   "Credit note issued",
+		PostedAt:      invoice.UpdatedAt,
+		Lines:         lines,
+	}
+}
+
+func refundEntry(invoice *models.Invoice) *models.JournalEntry {
+	total := toCents(invoice.Amount)
+
+	return &models.JournalEntry{
+		TenantID:      invoice.TenantID,
+		EntryType:     models.EntryRefund,
+		ReferenceType: "invoice",
+		ReferenceID:   invoice.ID,
+		Description:   "Refund via " + invoice.PaymentMethod,
+		PostedAt:      invoice.UpdatedAt,
+		Lines: []models.JournalLine{
+			{AccountCode: models.AccountAccountsReceivable, DebitCents: total},
+			{AccountCode: models.AccountCash, CreditCents: total},
+		},
+	}
+}
+
+func revenueRecognitionEntry(tenantID string, period time.Time, amount int64, postedAt time.Time) *models.JournalEntry {
+	return &models.JournalEntry{
+		TenantID:      tenantID,
+		EntryType:     models.EntryRevenueRecognition,
+		ReferenceType: "revenue_period",
+		ReferenceID:   period.Year()*100 + int(period.Month()),
+		Description:   "Revenue recognized for " + period.Format("2006-01"),
+		PostedAt:      postedAt,
+		Lines: []models.JournalLine{
+			{AccountCode: models.AccountDeferredRevenue, DebitCents: amount},
+			{AccountCode: models.AccountRevenue, CreditCents: amount},
+		},
+	}
+}
+
+// ReceivableDiscrepancies compara en SQL el saldo de cuentas por cobrar de cada factura con el que le corresponde por su estado
+func (r *LedgerRepository) ReceivableDiscrepancies(ctx context.Context) ([]models.ReceivableDiscrepancy, error) {
+	defer metrics.ObserveQuery("ledger", "ReceivableDiscrepancies", time.Now())
+	query := `SELECT i.id, i.invoice_number, i.status, expected.cents, COALESCE(ar.balance_cents, 0)
+	FROM invoices i
+	CROSS JOIN LATERAL (SELECT CASE WHEN i.status = 'pending' THEN ROUND(i.amount * 100)::BIGINT ELSE 0 END AS cents) expected
+	LEFT JOIN (
+		SELECT e.reference_id, SUM(l.debit_cents - l.credit_cents) AS balance_cents
+		FROM journal_entries e JOIN journal_lines l ON l.entry_id = e.id
+		WHERE e.tenant_id = $1 AND e.reference_type = 'invoice' AND l.account_code = $2
+		GROUP BY e.reference_id
+	) ar ON ar.reference_id = i.id
+	WHERE i.tenant_id = $1 AND expected.cents <> COALESCE(ar.balance_cents, 0)
+	ORDER BY i.id`
+
+	tx, tenantID, err := beginScoped(ctx, r.db, &sql.TxOptions{ReadOnly: true})
+	if err != nil {
+		return nil, err
+	}
+	defer tx.Rollback()
+
+	rows, err := tx.QueryContext(ctx, query, tenantID, models.AccountAccountsReceivable)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	discrepancies := []models.ReceivableDiscrepancy{}
+	for rows.Next() {
+		var discrepancy models.ReceivableDiscrepancy
+		var expected, ledger int64
+		if err := rows.Scan(&discrepancy.InvoiceID, &discrepancy.InvoiceNumber, &discrepancy.Status, &expected, &ledger); err != nil {
+			return nil, err
+		}
+		discrepancy.Expected = fromCents(expected)
+		discrepancy.Ledger = fromCents(ledger)
+		discrepancies = append(discrepancies, discrepancy)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+
+	return discrepancies, tx.Commit()
+}
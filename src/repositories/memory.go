@@ -0,0 +1,124 @@
+package repositories
+
+import (
+	"context"
+	"sass-billing-service/src/models"
+	"sort"
+	"sync"
+	"time"
+)
+
+// memoryAccounts es el plan de cuentas que la migración 002 carga en ledger_accounts
+var memoryAccounts = []models.LedgerAccount{
+	{Code: models.AccountCash, Name: "Cash", Type: "asset"},
+	{Code: models.AccountAccountsReceivable, Name: "Accounts receivable", Type: "asset"},
+	{Code: models.AccountTaxPayable, Name: "Tax payable", Type: "liability"},
+	{Code: models.AccountDeferredRevenue, Name: "Deferred revenue", Type: "liability"},
+	{Code: models.AccountRevenue, Name: "Revenue", Type: "revenue"},
+}
+
+// memorySchedule es una fila de revenue_schedules
+type memorySchedule struct {
+	models.RevenueScheduleEntry
+	TenantID       string
+	BookedAt       time.Time
+	CancelledAt    *time.Time
+	JournalEntryID int
+}
+
+// MemoryDB sustituye a PostgreSQL para los repositorios en memoria. Guarda las mismas tablas
+// (facturas y sus líneas, calendarios de reconocimiento, libro mayor y API keys) bajo un único
+// cerrojo, de modo que una factura y sus asientos cambian a la vez, como en una transacción.
+// No hay tipos de cambio: el MRR en otra moneda falla igual que con la tabla exchange_rates vacía.
+type MemoryDB struct {
+	mu        sync.RWMutex
+	invoices  map[int]*models.Invoice
+	schedules []*memorySchedule
+	entries   []*models.JournalEntry
+	apiKeys   map[int]*models.APIKey
+	sequences map[string]int
+	now       func() time.Time
+}
+
+func NewMemoryDB() *MemoryDB {
+	return &MemoryDB{
+		invoices:  make(map[int]*models.Invoice),
+		apiKeys:   make(map[int]*models.APIKey),
+		sequences: make(map[string]int),
+		now:       time.Now,
+	}
+}
+
+// PingContext cumple controllers.Pinger: la memoria del proceso siempre está disponible
+func (db *MemoryDB) PingContext(ctx context.Context) error {
+	return nil
+}
+
+// Version y Latest cumplen controllers.SchemaVersioner: no hay esquema que migrar
+func (db *MemoryDB) Version(ctx context.Context) (int, error) {
+	return 0, nil
+}
+
+func (db *MemoryDB) Latest() int {
+	return 0
+}
+
+// nextval imita las columnas SERIAL; se llama con el cerrojo de escritura tomado
+func (db *MemoryDB) nextval(table string) int {
+	db.sequences[table]++
+	return db.sequences[table]
+}
+
+// postEntries registra los asientos solo si todos cuadran, para no dejar la operación a medias
+func (db *MemoryDB) postEntries(entries ...*models.JournalEntry) error {
+	for _, entry := range entries {
+		if !entry.Balanced() {
+			return ErrUnbalancedEntry
+		}
+	}
+
+	for _, entry := range entries {
+		if entry.PostedAt.IsZero() {
+			entry.PostedAt = db.now()
+		}
+		entry.ID = db.nextval("journal_entries")
+
+		stored := *entry
+		stored.Lines = append([]models.JournalLine(nil), entry.Lines...)
+		db.entries = append(db.entries, &stored)
+	}
+
+	return nil
+}
+
+// sortedInvoices devuelve las facturas por id, el orden de inserción
+func (db *MemoryDB) sortedInvoices() []*models.Invoice {
+	invoices := make([]*models.Invoice, 0, len(db.invoices))
+	for _, invoice := range db.invoices {
+		invoices = append(invoices, invoice)
+	}
+	sort.Slice(invoices, func(i, j int) bool { return invoices[i].ID < invoices[j].ID })
+
+	return invoices
+}
+
+// invoiceBalance es el saldo de una cuenta en los asientos de una factura registrados antes de before
+// (sin límite si before es cero)
+func (db *MemoryDB) invoiceBalance(invoice *models.Invoice, account string, before time.Time) int64 {
+	var balance int64
+	for _, entry := range db.entries {
+		if entry.TenantID != invoice.TenantID || entry.ReferenceType != "invoice" || entry.ReferenceID != invoice.ID {
+			continue
+		}
+		if !before.IsZero() && !entry.PostedAt.Before(before) {
+			continue
+		}
+		for _, line := range entry.Lines {
+			if line.AccountCode == account {
+				balance += line.DebitCents - line.CreditCents
+			}
+		}
+	}
+
+	return balance
+}
@@ -0,0 +1,75 @@
+package repositories
+
+import (
+	"context"
+	"math"
+	"sass-billing-service/src/models"
+	"sass-billing-service/src/reports"
+	"sass-billing-service/src/tenant"
+	"sort"
+	"time"
+)
+
+// MemoryMetricsRepository es la versión en MemoryDB de MetricsRepository
+type MemoryMetricsRepository struct {
+	db *MemoryDB
+}
+
+func NewMemoryMetricsRepository(db *MemoryDB) *MemoryMetricsRepository {
+	return &MemoryMetricsRepository{db: db}
+}
+
+// CustomerMRR reparte cada línea recurrente entre sus meses con el mismo redondeo que MetricsRepository.CustomerMRR.
+// MemoryDB no guarda tipos de cambio, así que una línea en otra moneda da ErrMissingExchangeRate.
+func (r *MemoryMetricsRepository) CustomerMRR(ctx context.Context, through time.Time, currency string) ([]reports.CustomerMonth, error) {
+	tenantID, ok := tenant.FromContext(ctx)
+	if !ok {
+		return nil, tenant.ErrMissingTenant
+	}
+
+	r.db.mu.RLock()
+	defer r.db.mu.RUnlock()
+
+	type customerMonth struct {
+		userID int
+		month  time.Time
+	}
+	mrr := map[customerMonth]float64{}
+	for _, invoice := range r.db.sortedInvoices() {
+		if invoice.TenantID != tenantID || invoice.Status == "cancelled" || invoice.Status == "refunded" {
+			continue
+		}
+
+		for _, line := range invoice.Lines {
+			if !line.HasServicePeriod() {
+				continue
+			}
+
+			days := line.ServiceEnd.UTC().Truncate(24*time.Hour).Sub(line.ServiceStart.UTC().Truncate(24*time.Hour)).Hours()/24 + 1
+			months := max(int(math.Round(days/30.4375)), 1)
+			for n := 0; n < months; n++ {
+				month := models.MonthStart(*line.ServiceStart).AddDate(0, n, 0)
+				if month.After(through) {
+					break
+				}
+				if invoice.Currency != currency {
+					return nil, ErrMissingExchangeRate
+				}
+				mrr[customerMonth{userID: invoice.UserID, month: month}] += line.Amount / float64(months)
+			}
+		}
+	}
+
+	history := make([]reports.CustomerMonth, 0, len(mrr))
+	for key, amount := range mrr {
+		history = append(history, reports.CustomerMonth{UserID: key.userID, Month: key.month, MRRCents: int64(math.Round(amount * 100))})
+	}
+	sort.Slice(history, func(i, j int) bool {
+		if history[i].UserID != history[j].UserID {
+			return history[i].UserID < history[j].UserID
+		}
+		return history[i].Month.Before(history[j].Month)
+	})
+
+	return history, nil
+}
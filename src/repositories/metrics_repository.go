@@ -0,0 +1,78 @@
+package repositories
+
+import (
+	"context"
+	"database/sql"
+	"sass-billing-service/src/apperror"
+	"sass-billing-service/src/metrics"
+	"sass-billing-service/src/reports"
+	"time"
+)
+
+var ErrMissingExchangeRate = apperror.Unprocessable("Missing exchange rate for reporting currency")
+
+type MetricsRepository struct {
+	db *sql.DB
+}
+
+func NewMetricsRepository(db *sql.DB) *MetricsRepository {
+	return &MetricsRepository{db: db}
+}
+
+// CustomerMRR normaliza a mensual las líneas recurrentes (las que tienen periodo de servicio) de facturas
+// vigentes y devuelve el MRR por cliente y mes en la moneda de reporte, hasta el mes indicado
+func (r *MetricsRepository) CustomerMRR(ctx context.Context, through time.Time, currency string) ([]reports.CustomerMonth, error) {
+	defer metrics.ObserveQuery("metrics", "CustomerMRR", time.Now())
+	query := `WITH recurring AS (
+		SELECT i.user_id, i.currency, l.amount, l.service_start,
+			GREATEST(ROUND((l.service_end - l.service_start + 1) / 30.4375), 1)::int AS months
+		FROM invoice_lines l JOIN invoices i ON i.id = l.invoice_id
+		WHERE i.tenant_id = $3 AND l.service_start IS NOT NULL AND i.status NOT IN ('cancelled', 'refunded')
+	), expanded AS (
+		SELECT r.user_id, r.currency, r.amount / r.months AS mrr,
+			(date_trunc('month', r.service_start) + make_interval(months => gs.n))::date AS month
+		FROM recurring r CROSS JOIN LATERAL generate_series(0, r.months - 1) AS gs(n)
+	)
+	SELECT e.user_id, e.month,
+		COALESCE(ROUND(SUM(e.mrr * CASE WHEN e.currency = $2 THEN 1 ELSE x.rate END) * 100), 0)::bigint,
+		BOOL_OR(e.currency <> $2 AND x.rate IS NULL)
+	FROM expanded e
+	LEFT JOIN LATERAL (
+		SELECT rate FROM exchange_rates
+		WHERE from_currency = e.currency AND to_currency = $2 AND effective_date < e.month + interval '1 month'
+		ORDER BY effective_date DESC LIMIT 1
+	) x ON true
+	WHERE e.month <= $1
+	GROUP BY e.user_id, e.month
+	ORDER BY e.user_id, e.month`
+
+	tx, tenantID, err := beginScoped(ctx, r.db, &sql.TxOptions{ReadOnly: true})
+	if err != nil {
+		return nil, err
+	}
+	defer tx.Rollback()
+
+	rows, err := tx.QueryContext(ctx, query, through, currency, tenantID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var history []reports.CustomerMonth
+	for rows.Next() {
+		var row reports.CustomerMonth
+		var missingRate bool
+		if err := rows.Scan(&row.UserID, &row.Month, &row.MRRCents, &missingRate); err != nil {
+			return nil, err
+		}
+		if missingRate {
+			return nil, ErrMissingExchangeRate
+		}
+		history = append(history, row)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+
+	return history, tx.Commit()
+}
@@ -0,0 +1,71 @@
+package repositories
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"sass-billing-service/src/metrics"
+	"sass-billing-service/src/ratelimit"
+	"time"
+)
+
+// RateLimitRepository guarda los buckets del limitador en PostgreSQL para que todas las réplicas
+// compartan el mismo límite. El reloj es el de la base de datos, no el de cada réplica.
+type RateLimitRepository struct {
+	db *sql.DB
+}
+
+func NewRateLimitRepository(db *sql.DB) *RateLimitRepository {
+	return &RateLimitRepository{db: db}
+}
+
+// refilledTokens son los tokens del bucket b tras reponer los acumulados desde updated_at,
+// con $2 la ráfaga y $3 los tokens por segundo
+const refilledTokens = `LEAST($2::float8, b.tokens + GREATEST(EXTRACT(EPOCH FROM now() - b.updated_at)::float8, 0) * $3::float8)`
+
+// takeQuery repone y consume en una sola sentencia: ON CONFLICT bloquea la fila durante el
+// UPDATE, así que dos réplicas nunca gastan el mismo token. Un bucket nuevo empieza lleno y
+// sale ya con un token gastado. Si no hay token no se escribe nada (reponer sin consumir no
+// cambia el estado) y la segunda rama devuelve los tokens tal como los ve la sentencia, solo
+// para calcular Retry-After.
+const takeQuery = `WITH taken AS (
+	INSERT INTO rate_limit_buckets AS b (bucket_key, tokens, updated_at)
+	VALUES ($1, $2::float8 - 1, now())
+	ON CONFLICT (bucket_key) DO UPDATE
+	SET tokens = ` + refilledTokens + ` - 1, updated_at = GREATEST(b.updated_at, now())
+	WHERE ` + refilledTokens + ` >= 1
+	RETURNING tokens
+)
+SELECT true, tokens FROM taken
+UNION ALL
+SELECT false, ` + refilledTokens + ` FROM rate_limit_buckets b
+WHERE b.bucket_key = $1 AND NOT EXISTS (SELECT 1 FROM taken)`
+
+// Take consume un token del bucket con una única sentencia atómica; el reloj es el de la base de datos
+func (r *RateLimitRepository) Take(ctx context.Context, key string, limit ratelimit.Limit) (ratelimit.Result, error) {
+	defer metrics.ObserveQuery("rate_limit", "Take", time.Now())
+	var allowed bool
+	var tokens float64
+	err := r.db.QueryRowContext(ctx, takeQuery, key, limit.Requests, limit.Rate()).Scan(&allowed, &tokens)
+	// Sin fila, el bucket lo creó otra réplica después de la instantánea de la sentencia y ya está vacío
+	if errors.Is(err, sql.ErrNoRows) {
+		return limit.Result(0, false), nil
+	}
+	if err != nil {
+		return ratelimit.Result{}, err
+	}
+
+	return limit.Result(tokens, allowed), nil
+}
+
+// DeleteStale borra los buckets sin uso desde before; cualquier bucket que no se haya tocado
+// en una ventana completa está lleno y equivale a no tener fila
+func (r *RateLimitRepository) DeleteStale(ctx context.Context, before time.Time) (int64, error) {
+	defer metrics.ObserveQuery("rate_limit", "DeleteStale", time.Now())
+	result, err := r.db.ExecContext(ctx, `DELETE FROM rate_limit_buckets WHERE updated_at < $1`, before)
+	if err != nil {
+		return 0, err
+	}
+
+	return result.RowsAffected()
+}
@@ -0,0 +1,138 @@
+package repositories
+
+import (
+	"context"
+	"sass-billing-service/src/models"
+	"sass-billing-service/src/tenant"
+	"sort"
+	"time"
+)
+
+// MemoryRevenueRepository es la versión en MemoryDB de RevenueRepository
+type MemoryRevenueRepository struct {
+	db *MemoryDB
+}
+
+func NewMemoryRevenueRepository(db *MemoryDB) *MemoryRevenueRepository {
+	return &MemoryRevenueRepository{db: db}
+}
+
+// RecognizeThrough agrupa lo programado por tenant y mes igual que RevenueRepository.RecognizeThrough
+// y, como ella, solo se admite desde un contexto de sistema
+func (r *MemoryRevenueRepository) RecognizeThrough(ctx context.Context, through time.Time) ([]models.JournalEntry, error) {
+	if !tenant.IsSystem(ctx) {
+		return nil, tenant.ErrMissingTenant
+	}
+
+	r.db.mu.Lock()
+	defer r.db.mu.Unlock()
+
+	type tenantPeriod struct {
+		tenantID string
+		period   time.Time
+	}
+	totals := map[tenantPeriod]int64{}
+	pending := map[tenantPeriod][]*memorySchedule{}
+	for _, schedule := range r.db.schedules {
+		if schedule.Status != models.ScheduleScheduled || schedule.Period.After(models.MonthStart(through)) {
+			continue
+		}
+		key := tenantPeriod{tenantID: schedule.TenantID, period: models.MonthStart(schedule.Period)}
+		totals[key] += schedule.AmountCents
+		pending[key] = append(pending[key], schedule)
+	}
+
+	keys := make([]tenantPeriod, 0, len(totals))
+	for key := range totals {
+		keys = append(keys, key)
+	}
+	sort.Slice(keys, func(i, j int) bool {
+		if !keys[i].period.Equal(keys[j].period) {
+			return keys[i].period.Before(keys[j].period)
+		}
+		return keys[i].tenantID < keys[j].tenantID
+	})
+
+	now := r.db.now()
+	entries := []models.JournalEntry{}
+	for _, key := range keys {
+		entry := revenueRecognitionEntry(key.tenantID, key.period, totals[key], now)
+		if totals[key] > 0 {
+			if err := r.db.postEntries(entry); err != nil {
+				return nil, err
+			}
+		}
+
+		for _, schedule := range pending[key] {
+			schedule.Status = models.ScheduleRecognized
+			schedule.RecognizedAt = &now
+			schedule.JournalEntryID = entry.ID
+		}
+		entries = append(entries, *entry)
+	}
+
+	return entries, nil
+}
+
+// Report calcula por mes y producto lo mismo que la consulta de RevenueRepository.Report
+func (r *MemoryRevenueRepository) Report(ctx context.Context, from, to time.Time) (*models.RevenueReport, error) {
+	tenantID, ok := tenant.FromContext(ctx)
+	if !ok {
+		return nil, tenant.ErrMissingTenant
+	}
+
+	from = models.MonthStart(from)
+	to = models.MonthStart(to)
+
+	r.db.mu.RLock()
+	defer r.db.mu.RUnlock()
+
+	report := &models.RevenueReport{
+		From:   from.Format("2006-01"),
+		To:     to.Format("2006-01"),
+		Months: []models.RevenueMonth{},
+	}
+	for month := from; !month.After(to); month = month.AddDate(0, 1, 0) {
+		bucket := models.RevenueMonth{Month: month.Format("2006-01"), Products: []models.RevenueProductMonth{}}
+		next := month.AddDate(0, 1, 0)
+
+		recognized := map[string]int64{}
+		deferred := map[string]int64{}
+		var products []string
+		for _, schedule := range r.db.schedules {
+			// Como en SQL, un calendario anulado solo desaparece de los cierres posteriores a su anulación
+			cancelled := schedule.CancelledAt != nil && schedule.CancelledAt.Before(next)
+			if schedule.TenantID != tenantID || cancelled || !schedule.BookedAt.Before(next) {
+				continue
+			}
+			if _, seen := recognized[schedule.Product]; !seen {
+				products = append(products, schedule.Product)
+				recognized[schedule.Product] = 0
+			}
+			switch period := models.MonthStart(schedule.Period); {
+			case period.Equal(month):
+				recognized[schedule.Product] += schedule.AmountCents
+			case period.After(month):
+				deferred[schedule.Product] += schedule.AmountCents
+			}
+		}
+
+		sort.Strings(products)
+		for _, product := range products {
+			if recognized[product] == 0 && deferred[product] == 0 {
+				continue
+			}
+			bucket.Products = append(bucket.Products, models.RevenueProductMonth{
+				Product:    product,
+				Recognized: fromCents(recognized[product]),
+				Deferred:   fromCents(deferred[product]),
+			})
+			bucket.Recognized = fromCents(toCents(bucket.Recognized) + recognized[product])
+			bucket.Deferred = fromCents(toCents(bucket.Deferred) + deferred[product])
+		}
+
+		report.Months = append(report.Months, bucket)
+	}
+
+	return report, nil
+}
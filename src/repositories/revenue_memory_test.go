@@ -0,0 +1,46 @@
+package repositories
+
+import (
+	"testing"
+	"time"
+
+	"sass-billing-service/src/models"
+	"sass-billing-service/src/tenant"
+
+	"github.com/stretchr/testify/assert"
+)
+
+// TestMemoryRevenueReportKeepsClosedMonths anula en marzo una factura anual de un euro al día y comprueba que enero y
+// febrero siguen mostrando lo que había a su cierre. Vive en el paquete repositories para mover el reloj
+// de MemoryDB entre la emisión y la anulación.
+func TestMemoryRevenueReportKeepsClosedMonths(t *testing.T) {
+	db := NewMemoryDB()
+	clock := time.Date(2026, time.January, 10, 12, 0, 0, 0, time.UTC)
+	db.now = func() time.Time { return clock }
+
+	ctx := tenant.WithTenant(t.Context(), "acme")
+	start := time.Date(2026, time.January, 1, 0, 0, 0, 0, time.UTC)
+	end := time.Date(2026, time.December, 31, 0, 0, 0, 0, time.UTC)
+	invoices := NewMemoryInvoiceRepository(db)
+	invoice, err := invoices.Create(ctx, &models.CreateInvoiceRequest{
+		UserID: 7, Amount: 365, Currency: "EUR",
+		Lines: []models.CreateInvoiceLineRequest{{Product: "pro", Amount: 365, ServiceStart: &start, ServiceEnd: &end}},
+	})
+	assert.NoError(t, err)
+
+	clock = time.Date(2026, time.March, 15, 12, 0, 0, 0, time.UTC)
+	_, err = invoices.Void(ctx, invoice.ID)
+	assert.NoError(t, err)
+
+	report, err := NewMemoryRevenueRepository(db).Report(ctx, start, time.Date(2026, time.April, 1, 0, 0, 0, 0, time.UTC))
+
+	assert.NoError(t, err)
+	if assert.Len(t, report.Months, 4) {
+		assert.Equal(t, 31.0, report.Months[0].Recognized)
+		assert.Equal(t, 334.0, report.Months[0].Deferred)
+		assert.Equal(t, 28.0, report.Months[1].Recognized)
+		assert.Equal(t, 306.0, report.Months[1].Deferred)
+		assert.Empty(t, report.Months[2].Products)
+		assert.Empty(t, report.Months[3].Products)
+	}
+}
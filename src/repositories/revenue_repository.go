@@ -0,0 +1,263 @@
+package repositories
+
+import (
+	"context"
+	"database/sql"
+	"sass-billing-service/src/metrics"
+	"sass-billing-service/src/models"
+	"sort"
+	"time"
+)
+
+type RevenueRepository struct {
+	db     *sql.DB
+	ledger *LedgerRepository
+}
+
+func NewRevenueRepository(db *sql.DB) *RevenueRepository {
+	return &RevenueRepository{db: db, ledger: NewLedgerRepository(db)}
+}
+
+// RecognizeThrough traspasa de ingresos diferidos a ingresos todo lo programado hasta el mes indicado,
+// con un asiento por tenant y mes. Es idempotente: las filas ya reconocidas no se vuelven a tocar.
+// Recorre todos los tenants, así que solo se admite desde un contexto de sistema.
+func (r *RevenueRepository) RecognizeThrough(ctx context.Context, through time.Time) ([]models.JournalEntry, error) {
+	defer metrics.ObserveQuery("revenue", "RecognizeThrough", time.Now())
+	tx, err := beginSystem(ctx, r.db)
+	if err != nil {
+		return nil, err
+	}
+	defer tx.Rollback()
+
+	rows, err := tx.QueryContext(ctx, `SELECT tenant_id, period, amount_cents FROM revenue_schedules
+	WHERE status = 'scheduled' AND period <= $1 FOR UPDATE`, models.MonthStart(through))
+	if err != nil {
+		return nil, err
+	}
+
+	type tenantPeriod struct {
+		tenantID string
+		period   time.Time
+	}
+	totals := map[tenantPeriod]int64{}
+	for rows.Next() {
+		var key tenantPeriod
+		var amount int64
+		if err := rows.Scan(&key.tenantID, &key.period, &amount); err != nil {
+			rows.Close()
+			return nil, err
+		}
+		key.period = models.MonthStart(key.period)
+		totals[key] += amount
+	}
+	rows.Close()
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+
+	keys := make([]tenantPeriod, 0, len(totals))
+	for key := range totals {
+		keys = append(keys, key)
+	}
+	sort.Slice(keys, func(i, j int) bool {
+		if !keys[i].period.Equal(keys[j].period) {
+			return keys[i].period.Before(keys[j].period)
+		}
+		return keys[i].tenantID < keys[j].tenantID
+	})
+
+	now := time.Now()
+	entries := []models.JournalEntry{}
+	for _, key := range keys {
+		entry := revenueRecognitionEntry(key.tenantID, key.period, totals[key], now)
+		if totals[key] > 0 {
+			if err := r.ledger.PostEntry(ctx, tx.Tx, entry); err != nil {
+				return nil, err
+			}
+		}
+
+		_, err := tx.ExecContext(ctx, `UPDATE revenue_schedules
+		SET status = 'recognized', recognized_at = $1, journal_entry_id = NULLIF($2, 0)
+		WHERE status = 'scheduled' AND tenant_id = $3 AND period = $4`, now, entry.ID, key.tenantID, key.period)
+		if err != nil {
+			return nil, err
+		}
+		entries = append(entries, *entry)
+	}
+
+	if err := tx.Commit(); err != nil {
+		return nil, err
+	}
+
+	return entries, nil
+}
+
+// Report devuelve, para cada mes del rango, el ingreso reconocido en el mes y el saldo diferido al cierre.
+// Cada mes ve los calendarios como estaban a su cierre: uno anulado después sigue contando en los meses
+// anteriores a la anulación, así que un informe ya cerrado no cambia al anular una factura.
+func (r *RevenueRepository) Report(ctx context.Context, from, to time.Time) (*models.RevenueReport, error) {
+	defer metrics.ObserveQuery("revenue", "Report", time.Now())
+	query := `WITH months AS (
+		SELECT generate_series($1::date, $2::date, interval '1 month')::date AS month
+	)
+	SELECT m.month, s.product,
+		COALESCE(SUM(s.amount_cents) FILTER (WHERE s.period = m.month), 0) AS recognized,
+		COALESCE(SUM(s.amount_cents) FILTER (WHERE s.period > m.month), 0) AS deferred
+	FROM months m
+	JOIN revenue_schedules s ON s.tenant_id = $3 AND s.booked_at < m.month + interval '1 month'
+		AND (s.cancelled_at IS NULL OR s.cancelled_at >= m.month + interval '1 month')
+	GROUP BY m.month, s.product
+	ORDER BY m.month, s.product`
+
+	from = models.MonthStart(from)
+	to = models.MonthStart(to)
+
+	tx, tenantID, err := beginScoped(ctx, r.db, &sql.TxOptions{ReadOnly: true})
+	if err != nil {
+		return nil, err
+	}
+	defer tx.Rollback()
+
+	rows, err := tx.QueryContext(ctx, query, from, to, tenantID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	report := &models.RevenueReport{
+		From:   from.Format("2006-01"),
+		To:     to.Format("2006-01"),
+		Months: []models.RevenueMonth{},
+	}
+	index := map[string]int{}
+	for month := from; !month.After(to); month = month.AddDate(0, 1, 0) {
+		key := month.Format("2006-01")
+		index[key] = len(report.Months)
+		report.Months = append(report.Months, models.RevenueMonth{Month: key, Products: []models.RevenueProductMonth{}})
+	}
+
+	for rows.Next() {
+		var month time.Time
+		var product models.RevenueProductMonth
+		var recognized, deferred int64
+		if err := rows.Scan(&month, &product.Product, &recognized, &deferred); err != nil {
+			return nil, err
+		}
+		if recognized == 0 && deferred == 0 {
+			continue
+		}
+
+		i, ok := index[month.Format("2006-01")]
+		if !ok {
+			continue
+		}
+		product.Recognized = fromCents(recognized)
+		product.Deferred = fromCents(deferred)
+
+		bucket := &report.Months[i]
+		bucket.Products = append(bucket.Products, product)
+		bucket.Recognized = fromCents(toCents(bucket.Recognized) + recognized)
+		bucket.Deferred = fromCents(toCents(bucket.Deferred) + deferred)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+
+	return report, tx.Commit()
+}
+
+func insertInvoiceLines(ctx context.Context, tx dbtx, invoice *models.Invoice, lines []models.CreateInvoiceLineRequest) error {
+	query := `INSERT INTO invoice_lines (tenant_id, invoice_id, product, description, amount, service_start, service_end)
+	VALUES ($1, $2, $3, $4, $5, $6, $7) RETURNING id`
+
+	scheduleQuery := `INSERT INTO revenue_schedules (tenant_id, invoice_id, invoice_line_id, product, period, amount_cents, status, booked_at, recognized_at)
+	VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9)`
+
+	for _, req := range lines {
+		line := models.InvoiceLine{
+			InvoiceID:    invoice.ID,
+			Product:      req.Product,
+			Description:  req.Description,
+			Amount:       req.Amount,
+			ServiceStart: req.ServiceStart,
+			ServiceEnd:   req.ServiceEnd,
+		}
+
+		err := tx.QueryRowContext(ctx, query,
+			invoice.TenantID,
+			line.InvoiceID,
+			line.Product,
+			line.Description,
+			line.Amount,
+			line.ServiceStart,
+			line.ServiceEnd,
+		).Scan(&line.ID)
+		if err != nil {
+			return err
+		}
+
+		for _, entry := range line.RecognitionSchedule(invoice.CreatedAt) {
+			var recognizedAt *time.Time
+			if entry.Status == models.ScheduleRecognized {
+				recognizedAt = &invoice.CreatedAt
+			}
+			if _, err := tx.ExecContext(ctx, scheduleQuery,
+				invoice.TenantID,
+				invoice.ID,
+				line.ID,
+				entry.Product,
+				entry.Period,
+				entry.AmountCents,
+				entry.Status,
+				invoice.CreatedAt,
+				recognizedAt,
+			); err != nil {
+				return err
+			}
+		}
+
+		invoice.Lines = append(invoice.Lines, line)
+	}
+
+	return nil
+}
+
+// cancelPendingSchedules anula en cancelledAt lo que queda por reconocer de una factura y devuelve ese importe
+func cancelPendingSchedules(ctx context.Context, tx dbtx, invoiceID int, cancelledAt time.Time) (int64, error) {
+	var deferred int64
+	err := tx.QueryRowContext(ctx, `WITH cancelled AS (
+		UPDATE revenue_schedules SET status = 'cancelled', cancelled_at = $2
+		WHERE invoice_id = $1 AND status = 'scheduled'
+		RETURNING amount_cents
+	) SELECT COALESCE(SUM(amount_cents), 0) FROM cancelled`, invoiceID, cancelledAt).Scan(&deferred)
+
+	return deferred, err
+}
+
+func getInvoiceLines(ctx context.Context, db queryer, invoiceID int) ([]models.InvoiceLine, error) {
+	rows, err := db.QueryContext(ctx, `SELECT id, invoice_id, product, description, amount, service_start, service_end
+	FROM invoice_lines WHERE invoice_id = $1 ORDER BY id`, invoiceID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var lines []models.InvoiceLine
+	for rows.Next() {
+		var line models.InvoiceLine
+		if err := rows.Scan(
+			&line.ID,
+			&line.InvoiceID,
+			&line.Product,
+			&line.Description,
+			&line.Amount,
+			&line.ServiceStart,
+			&line.ServiceEnd,
+		); err != nil {
+			return nil, err
+		}
+		lines = append(lines, line)
+	}
+
+	return lines, rows.Err()
+}
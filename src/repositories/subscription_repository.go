@@ -0,0 +1,65 @@
+package repositories
+
+import (
+	"context"
+	"database/sql"
+	"sass-billing-service/src/metrics"
+	"sass-billing-service/src/models"
+	"time"
+)
+
+type SubscriptionRepository struct {
+	db *sql.DB
+}
+
+func NewSubscriptionRepository(db *sql.DB) *SubscriptionRepository {
+	return &SubscriptionRepository{db: db}
+}
+
+// DueRenewals devuelve las suscripciones del tenant cuyo periodo vigente termina como tarde en through.
+// El periodo vigente es la última línea con periodo de servicio de cada usuario y producto; si su factura
+// se anuló o se devolvió la suscripción se considera terminada. Una vez renovada, la línea nueva pasa
+// a ser la vigente, así que repetir la renovación no vuelve a facturar el mismo periodo.
+func (r *SubscriptionRepository) DueRenewals(ctx context.Context, through time.Time) ([]models.Subscription, error) {
+	defer metrics.ObserveQuery("subscriptions", "DueRenewals", time.Now())
+	query := `SELECT invoice_id, user_id, customer_name, customer_email, currency, payment_method,
+		product, description, amount, tax_rate, service_start, service_end
+	FROM (
+		SELECT DISTINCT ON (i.user_id, l.product) i.id AS invoice_id, i.user_id, i.customer_name, i.customer_email,
+			i.currency, i.payment_method, i.status, l.product, l.description, l.amount,
+			COALESCE(i.tax_amount / NULLIF(i.amount - i.tax_amount, 0), 0) AS tax_rate, l.service_start, l.service_end
+		FROM invoice_lines l
+		JOIN invoices i ON i.id = l.invoice_id
+		WHERE i.tenant_id = $1 AND l.service_start IS NOT NULL
+		ORDER BY i.user_id, l.product, l.service_end DESC, i.id DESC
+	) current_periods
+	WHERE status NOT IN ('cancelled', 'refunded') AND service_end <= $2::date
+	ORDER BY user_id, product`
+
+	tx, tenantID, err := beginScoped(ctx, r.db, &sql.TxOptions{ReadOnly: true})
+	if err != nil {
+		return nil, err
+	}
+	defer tx.Rollback()
+
+	rows, err := tx.QueryContext(ctx, query, tenantID, through)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	subscriptions := []models.Subscription{}
+	for rows.Next() {
+		var s models.Subscription
+		if err := rows.Scan(&s.InvoiceID, &s.UserID, &s.CustomerName, &s.CustomerEmail, &s.Currency, &s.PaymentMethod,
+			&s.Product, &s.Description, &s.Amount, &s.TaxRate, &s.ServiceStart, &s.ServiceEnd); err != nil {
+			return nil, err
+		}
+		subscriptions = append(subscriptions, s)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+
+	return subscriptions, tx.Commit()
+}
@@ -0,0 +1,118 @@
+package repositories
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"sass-billing-service/src/tenant"
+)
+
+// ErrTenantMismatch indica que una operación de un tenant intentó unirse a la transacción de otro
+var ErrTenantMismatch = errors.New("tenant does not match the transaction in context")
+
+// queryer lo cumplen tanto *sql.DB como *sql.Tx
+type queryer interface {
+	QueryContext(ctx context.Context, query string, args ...interface{}) (*sql.Rows, error)
+}
+
+// dbtx es lo que necesitan los helpers que trabajan dentro de la transacción de su llamador;
+// lo cumplen *sql.Tx y scopedTx
+type dbtx interface {
+	ExecContext(ctx context.Context, query string, args ...interface{}) (sql.Result, error)
+	QueryContext(ctx context.Context, query string, args ...interface{}) (*sql.Rows, error)
+	QueryRowContext(ctx context.Context, query string, args ...interface{}) *sql.Row
+}
+
+// scopedTx es la transacción que usa un método de repositorio. Si el contexto ya trae una abierta
+// por el TxManager, el método se une a ella y su Commit y Rollback no hacen nada: decide quien la abrió.
+type scopedTx struct {
+	*sql.Tx
+	joined bool
+}
+
+func (t *scopedTx) Commit() error {
+	if t.joined {
+		return nil
+	}
+	return t.Tx.Commit()
+}
+
+func (t *scopedTx) Rollback() error {
+	if t.joined {
+		return nil
+	}
+	return t.Tx.Rollback()
+}
+
+// beginScoped abre una transacción limitada al tenant del contexto. Además de filtrar por tenant_id
+// en cada consulta, fija app.tenant_id para que las políticas RLS de PostgreSQL actúen como segunda
+// barrera. Sin tenant en el contexto no se llega a tocar la base de datos.
+// Dentro de TxManager.WithinTx reutiliza la transacción del contexto e ignora opts.
+func beginScoped(ctx context.Context, db *sql.DB, opts *sql.TxOptions) (*scopedTx, string, error) {
+	tenantID, ok := tenant.FromContext(ctx)
+	if !ok {
+		return nil, "", tenant.ErrMissingTenant
+	}
+
+	if ambient := ambientFrom(ctx); ambient != nil && !ambient.system {
+		if ambient.tenantID != tenantID {
+			return nil, "", ErrTenantMismatch
+		}
+		return &scopedTx{Tx: ambient.tx, joined: true}, tenantID, nil
+	}
+
+	tx, err := openScoped(ctx, db, opts, tenantID)
+	if err != nil {
+		return nil, "", err
+	}
+
+	return &scopedTx{Tx: tx}, tenantID, nil
+}
+
+// beginSystem abre una transacción que atraviesa las políticas RLS, reservada a las tareas
+// de mantenimiento que trabajan sobre todos los tenants. Solo se une a transacciones también de sistema.
+// Cambia al rol billing_system (BYPASSRLS) solo durante la transacción; las peticiones nunca lo usan.
+func beginSystem(ctx context.Context, db *sql.DB) (*scopedTx, error) {
+	if !tenant.IsSystem(ctx) {
+		return nil, tenant.ErrMissingTenant
+	}
+
+	if ambient := ambientFrom(ctx); ambient != nil && ambient.system {
+		return &scopedTx{Tx: ambient.tx, joined: true}, nil
+	}
+
+	tx, err := openSystem(ctx, db, nil)
+	if err != nil {
+		return nil, err
+	}
+
+	return &scopedTx{Tx: tx}, nil
+}
+
+func openScoped(ctx context.Context, db *sql.DB, opts *sql.TxOptions, tenantID string) (*sql.Tx, error) {
+	tx, err := db.BeginTx(ctx, opts)
+	if err != nil {
+		return nil, err
+	}
+
+	if _, err := tx.ExecContext(ctx, `SELECT set_config('app.tenant_id', $1, true)`, tenantID); err != nil {
+		tx.Rollback()
+		return nil, err
+	}
+
+	return tx, nil
+}
+
+func openSystem(ctx context.Context, db *sql.DB, opts *sql.TxOptions) (*sql.Tx, error) {
+	tx, err := db.BeginTx(ctx, opts)
+	if err != nil {
+		return nil, err
+	}
+
+	if _, err := tx.ExecContext(ctx, `SET LOCAL ROLE billing_system`); err != nil {
+		tx.Rollback()
+		return nil, err
+	}
+
+	return tx, nil
+}
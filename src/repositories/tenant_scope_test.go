@@ -0,0 +1,168 @@
+package repositories
+
+import (
+	"context"
+	"database/sql"
+	"os"
+	"sort"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+
+	"sass-billing-service/src/migrations"
+	"sass-billing-service/src/models"
+	"sass-billing-service/src/tenant"
+
+	"github.com/stretchr/testify/assert"
+)
+
+// TestRowLevelSecurity comprueba contra un PostgreSQL real que las políticas RLS ocultan las filas
+// de otros tenants aunque la consulta no filtre por tenant_id. Vive en el paquete repositories para
+// usar beginScoped, la misma transacción que abren los repositorios. Se salta si TEST_DATABASE_DSN
+// no apunta a una base de datos desechable.
+func TestRowLevelSecurity(t *testing.T) {
+	dsn := os.Getenv("TEST_DATABASE_DSN")
+	if dsn == "" {
+		t.Skip("TEST_DATABASE_DSN is not set")
+	}
+
+	db, err := sql.Open("postgres", dsn)
+	if err != nil {
+		t.Fatalf("an error '%s' was not expected when opening the database", err)
+	}
+	defer db.Close()
+	// Una sola conexión para que el SET ROLE de más abajo afecte a todas las transacciones del test
+	db.SetMaxOpenConns(1)
+
+	ctx := context.Background()
+	runner, err := migrations.NewRunner(db, migrations.Files)
+	if err != nil {
+		t.Fatalf("an error '%s' was not expected when loading the migrations", err)
+	}
+	if _, err := runner.Up(ctx); err != nil {
+		t.Fatalf("an error '%s' was not expected when applying the migrations", err)
+	}
+
+	// Tenants nuevos en cada ejecución para no depender de lo que dejaron las anteriores
+	suffix := strconv.FormatInt(time.Now().UnixNano(), 36)
+	tenants := []string{"acme-" + suffix, "globex-" + suffix}
+	invoiceIDs := map[string]int{}
+	entryIDs := map[string][]int{}
+	for i, tenantID := range tenants {
+		scope := tenant.WithTenant(ctx, tenantID)
+		start := models.MonthStart(time.Now())
+		end := start.AddDate(0, 3, -1)
+
+		invoice, err := NewInvoiceRepository(db).Create(scope, &models.CreateInvoiceRequest{
+			UserID: 7, Amount: 300, Currency: "USD", Description: "Pro quarterly", PaymentMethod: "card",
+			Lines: []models.CreateInvoiceLineRequest{{Product: "pro", Amount: 300, ServiceStart: &start, ServiceEnd: &end}},
+		})
+		if err != nil {
+			t.Fatalf("an error '%s' was not expected when creating an invoice", err)
+		}
+		invoiceIDs[tenantID] = invoice.ID
+
+		_, err = NewAPIKeyRepository(db).Create(scope, &models.APIKey{
+			Name: "ci", Prefix: "bk_" + strconv.Itoa(i) + suffix, KeyHash: strings.Repeat(strconv.Itoa(i), 64), Scopes: []string{"invoices:read"},
+		})
+		if err != nil {
+			t.Fatalf("an error '%s' was not expected when creating an API key", err)
+		}
+
+		tx, _, err := beginScoped(scope, db, &sql.TxOptions{ReadOnly: true})
+		if err != nil {
+			t.Fatalf("an error '%s' was not expected when opening a scoped transaction", err)
+		}
+		entryIDs[tenantID] = queryInts(t, tx, `SELECT id FROM journal_entries WHERE reference_type = 'invoice' AND reference_id = $1`, invoice.ID)
+		tx.Rollback()
+	}
+
+	// Un superusuario o un rol BYPASSRLS no pasa por las políticas: las consultas se hacen con un rol corriente
+	var bypass bool
+	if err := db.QueryRowContext(ctx, `SELECT rolsuper OR rolbypassrls FROM pg_roles WHERE rolname = current_user`).Scan(&bypass); err != nil {
+		t.Fatalf("an error '%s' was not expected when reading the current role", err)
+	}
+	if bypass {
+		for _, statement := range []string{
+			`DO $$ BEGIN IF NOT EXISTS (SELECT 1 FROM pg_roles WHERE rolname = 'billing_rls_test') THEN CREATE ROLE billing_rls_test NOLOGIN; END IF; END $$`,
+			`GRANT SELECT, UPDATE ON ALL TABLES IN SCHEMA public TO billing_rls_test`,
+			`SET ROLE billing_rls_test`,
+		} {
+			if _, err := db.ExecContext(ctx, statement); err != nil {
+				t.Fatalf("an error '%s' was not expected when preparing the test role", err)
+			}
+		}
+		defer db.ExecContext(ctx, `RESET ROLE`)
+	}
+
+	for i, tenantID := range tenants {
+		other := tenants[1-i]
+
+		t.Run(tenantID[:strings.Index(tenantID, "-")], func(t *testing.T) {
+			tx, _, err := beginScoped(tenant.WithTenant(ctx, tenantID), db, nil)
+			if err != nil {
+				t.Fatalf("an error '%s' was not expected when opening a scoped transaction", err)
+			}
+			defer tx.Rollback()
+
+			// Ninguna de estas consultas filtra por tenant: lo hace la política
+			for _, table := range []string{"invoices", "invoice_lines", "revenue_schedules", "journal_entries", "api_keys"} {
+				assert.Equal(t, []string{tenantID}, queryStrings(t, tx, `SELECT DISTINCT tenant_id FROM `+table), table)
+			}
+
+			assert.Empty(t, queryInts(t, tx, `SELECT id FROM invoices WHERE id = $1`, invoiceIDs[other]))
+			assert.NotEmpty(t, queryInts(t, tx, `SELECT id FROM journal_lines WHERE entry_id = ANY($1::int[])`, intArray(entryIDs[tenantID])))
+			assert.Empty(t, queryInts(t, tx, `SELECT id FROM journal_lines WHERE entry_id = ANY($1::int[])`, intArray(entryIDs[other])))
+
+			// Tampoco se pueden modificar
+			result, err := tx.ExecContext(ctx, `UPDATE invoices SET status = status WHERE id = $1`, invoiceIDs[other])
+			assert.NoError(t, err)
+			affected, _ := result.RowsAffected()
+			assert.Zero(t, affected)
+		})
+	}
+}
+
+func queryStrings(t *testing.T, tx dbtx, query string, args ...interface{}) []string {
+	rows, err := tx.QueryContext(context.Background(), query, args...)
+	if err != nil {
+		t.Fatalf("an error '%s' was not expected when querying %q", err, query)
+	}
+	defer rows.Close()
+
+	values := []string{}
+	for rows.Next() {
+		var value string
+		if err := rows.Scan(&value); err != nil {
+			t.Fatalf("an error '%s' was not expected when scanning %q", err, query)
+		}
+		values = append(values, value)
+	}
+	if err := rows.Err(); err != nil {
+		t.Fatalf("an error '%s' was not expected when reading %q", err, query)
+	}
+	sort.Strings(values)
+
+	return values
+}
+
+func queryInts(t *testing.T, tx dbtx, query string, args ...interface{}) []int {
+	values := []int{}
+	for _, value := range queryStrings(t, tx, query, args...) {
+		n, _ := strconv.Atoi(value)
+		values = append(values, n)
+	}
+
+	return values
+}
+
+// intArray da el literal de un int[] de PostgreSQL
+func intArray(values []int) string {
+	parts := make([]string, len(values))
+	for i, value := range values {
+		parts[i] = strconv.Itoa(value)
+	}
+
+	return "{" + strings.Join(parts, ",") + "}"
+}
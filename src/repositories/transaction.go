@@ -0,0 +1,133 @@
+package repositories
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"math/rand/v2"
+	"sass-billing-service/src/metrics"
+	"sass-billing-service/src/tenant"
+	"time"
+
+	"github.com/lib/pq"
+)
+
+// Espera antes del primer reintento; se duplica en cada intento y se reparte al azar para que
+// las transacciones que chocaron no vuelvan a coincidir
+const retryBaseDelay = 10 * time.Millisecond
+
+type txKey struct{}
+
+// ambientTx es la transacción que WithinTx deja en el contexto para los repositorios
+type ambientTx struct {
+	tx       *sql.Tx
+	tenantID string
+	system   bool
+}
+
+func ambientFrom(ctx context.Context) *ambientTx {
+	ambient, _ := ctx.Value(txKey{}).(*ambientTx)
+	return ambient
+}
+
+// TxManager agrupa varias operaciones de repositorio en una única transacción. La transacción viaja
+// en el contexto y los repositorios la usan sin cambiar su firma; fuera de WithinTx cada método
+// sigue abriendo la suya.
+type TxManager struct {
+	db        *sql.DB
+	isolation sql.IsolationLevel
+	retries   int
+}
+
+// NewTxManager crea el gestor con el nivel de aislamiento de sus transacciones y el número máximo
+// de reintentos ante fallos de serialización o interbloqueos
+func NewTxManager(db *sql.DB, isolation sql.IsolationLevel, retries int) *TxManager {
+	return &TxManager{db: db, isolation: isolation, retries: retries}
+}
+
+// WithIsolation devuelve una copia del gestor con otro nivel de aislamiento, para las operaciones
+// que necesitan más garantías que el resto
+func (m *TxManager) WithIsolation(isolation sql.IsolationLevel) *TxManager {
+	copy := *m
+	copy.isolation = isolation
+	return &copy
+}
+
+// WithinTx ejecuta fn en una transacción limitada al tenant del contexto (o de sistema en las tareas
+// de mantenimiento). Se confirma si fn no devuelve error y se deshace en otro caso. Si ya hay una
+// transacción en el contexto fn se une a ella. Un fallo de serialización repite fn entera, así que
+// fn no debe tener efectos fuera de la base de datos.
+func (m *TxManager) WithinTx(ctx context.Context, fn func(ctx context.Context) error) error {
+	if ambientFrom(ctx) != nil {
+		return fn(ctx)
+	}
+
+	for attempt := 0; ; attempt++ {
+		err := m.run(ctx, fn)
+		reason := retryReason(err)
+		if reason == "" || attempt >= m.retries {
+			return err
+		}
+
+		metrics.TxRetry(reason)
+		delay := retryBaseDelay << attempt
+		timer := time.NewTimer(delay/2 + rand.N(delay/2+1))
+		select {
+		case <-ctx.Done():
+			timer.Stop()
+			return err
+		case <-timer.C:
+		}
+	}
+}
+
+func (m *TxManager) run(ctx context.Context, fn func(ctx context.Context) error) error {
+	opts := &sql.TxOptions{Isolation: m.isolation}
+
+	var (
+		ambient = &ambientTx{}
+		err     error
+	)
+	if tenantID, ok := tenant.FromContext(ctx); ok {
+		ambient.tenantID = tenantID
+		ambient.tx, err = openScoped(ctx, m.db, opts, tenantID)
+	} else if tenant.IsSystem(ctx) {
+		ambient.system = true
+		ambient.tx, err = openSystem(ctx, m.db, opts)
+	} else {
+		return tenant.ErrMissingTenant
+	}
+	if err != nil {
+		return err
+	}
+	defer ambient.tx.Rollback()
+
+	if err := fn(context.WithValue(ctx, txKey{}, ambient)); err != nil {
+		return err
+	}
+
+	return ambient.tx.Commit()
+}
+
+// retryReason devuelve el nombre del error de PostgreSQL si merece repetir la transacción
+func retryReason(err error) string {
+	var pqErr *pq.Error
+	if !errors.As(err, &pqErr) {
+		return ""
+	}
+
+	switch name := pqErr.Code.Name(); name {
+	case "serialization_failure", "deadlock_detected":
+		return name
+	}
+
+	return ""
+}
+
+// NoTx cumple el mismo contrato que TxManager para los repositorios en memoria, que ya aplican
+// cada operación de forma atómica
+type NoTx struct{}
+
+func (NoTx) WithinTx(ctx context.Context, fn func(ctx context.Context) error) error {
+	return fn(ctx)
+}
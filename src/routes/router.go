@@ -3,15 +3,61 @@ package router
 import (
 	"sass-billing-service/src/controllers"
 	"sass-billing-service/src/helpers"
+	"sass-billing-service/src/metrics"
+	"sass-billing-service/src/openapi"
+	"sass-billing-service/src/policy"
 
 	"github.com/gofiber/fiber/v2"
 )
 
-func SetupRoutes(app fiber.Router, invoiceController *controllers.InvoiceController) {
+func SetupRoutes(app fiber.Router, invoiceController *controllers.InvoiceController, ledgerController *controllers.LedgerController, reportController *controllers.ReportController, exportController *controllers.ExportController, apiKeyController *controllers.APIKeyController, auth, rateLimit, idempotency fiber.Handler) {
 	invoices := app.Group("/invoices")
 	{
-		invoices.Get("/", helpers.AuthMiddleware, invoiceController.GetInvoices)
-		invoices.Post("/", helpers.AuthMiddleware, invoiceController.CreateInvoice)
-		invoices.Get("/:id", helpers.AuthMiddleware, invoiceController.GetInvoice)
+		invoices.Get("/", auth, rateLimit, helpers.RequirePermission(policy.InvoicesRead), invoiceController.GetInvoices)
+		invoices.Post("/", auth, rateLimit, helpers.RequirePermission(policy.InvoicesCreate), idempotency, invoiceController.CreateInvoice)
+		invoices.Get("/export", auth, rateLimit, helpers.RequirePermission(policy.InvoicesExport), exportController.ExportInvoices)
+		invoices.Get("/search", auth, rateLimit, helpers.RequirePermission(policy.InvoicesRead), invoiceController.SearchInvoices)
+		invoices.Get("/:id", auth, rateLimit, helpers.RequirePermission(policy.InvoicesRead), invoiceController.GetInvoice)
+		invoices.Post("/:id/pay", auth, rateLimit, helpers.RequirePermission(policy.InvoicesPay), idempotency, invoiceController.PayInvoice)
+		invoices.Post("/:id/void", auth, rateLimit, helpers.RequirePermission(policy.InvoicesVoid), idempotency, invoiceController.VoidInvoice)
+		invoices.Post("/:id/refund", auth, rateLimit, helpers.RequirePermission(policy.InvoicesRefund), idempotency, invoiceController.RefundInvoice)
 	}
+
+	ledger := app.Group("/ledger")
+	{
+		ledger.Get("/trial-balance", auth, rateLimit, helpers.RequirePermission(policy.LedgerRead), ledgerController.GetTrialBalance)
+	}
+
+	reports := app.Group("/reports")
+	{
+		reports.Get("/revenue", auth, rateLimit, helpers.RequirePermission(policy.ReportsRead), reportController.GetRevenueReport)
+		reports.Get("/mrr", auth, rateLimit, helpers.RequirePermission(policy.ReportsRead), reportController.GetMRRReport)
+		reports.Get("/ar-aging", auth, rateLimit, helpers.RequirePermission(policy.ReportsRead), reportController.GetAgingReport)
+	}
+
+	apiKeys := app.Group("/api-keys")
+	{
+		apiKeys.Get("/", auth, rateLimit, helpers.RequirePermission(policy.APIKeysManage), apiKeyController.ListAPIKeys)
+		apiKeys.Post("/", auth, rateLimit, helpers.RequirePermission(policy.APIKeysManage), apiKeyController.CreateAPIKey)
+		apiKeys.Post("/:id/rotate", auth, rateLimit, helpers.RequirePermission(policy.APIKeysManage), apiKeyController.RotateAPIKey)
+		apiKeys.Delete("/:id", auth, rateLimit, helpers.RequirePermission(policy.APIKeysManage), apiKeyController.RevokeAPIKey)
+	}
+}
+
+// SetupHealthRoutes registra las sondas de Kubernetes fuera de /api y sin autenticación
+func SetupHealthRoutes(app fiber.Router, healthController *controllers.HealthController) {
+	app.Get("/healthz", healthController.Liveness)
+	app.Get("/readyz", healthController.Readiness)
+}
+
+// SetupMetricsRoutes publica las métricas de Prometheus fuera de /api y sin autenticación
+func SetupMetricsRoutes(app fiber.Router) {
+	app.Get("/metrics", metrics.Handler())
+}
+
+// SetupDocsRoutes publica la especificación OpenAPI y su visor, sin autenticación
+func SetupDocsRoutes(app fiber.Router) {
+	app.Get("/openapi.json", openapi.Handler())
+	app.Get("/docs", openapi.DocsHandler())
+	app.Get("/docs/assets/:file", openapi.AssetHandler())
 }
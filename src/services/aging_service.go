@@ -0,0 +1,30 @@
+package services
+
+import (
+	"context"
+	"sass-billing-service/src/models"
+	"sass-billing-service/src/repositories"
+	"time"
+)
+
+// AgingStore calcula la antigüedad de saldos; lo cumplen repositories.AgingRepository y repositories.MemoryAgingRepository
+type AgingStore interface {
+	Report(ctx context.Context, asOf time.Time) (*models.AgingReport, error)
+}
+
+var (
+	_ AgingStore = (*repositories.AgingRepository)(nil)
+	_ AgingStore = (*repositories.MemoryAgingRepository)(nil)
+)
+
+type AgingService struct {
+	repo AgingStore
+}
+
+func NewAgingService(repo AgingStore) *AgingService {
+	return &AgingService{repo: repo}
+}
+
+func (s *AgingService) GetAgingReport(ctx context.Context, asOf time.Time) (*models.AgingReport, error) {
+	return s.repo.Report(ctx, asOf)
+}
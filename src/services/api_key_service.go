@@ -0,0 +1,188 @@
+package services
+
+import (
+	"context"
+	"crypto/rand"
+	"crypto/sha256"
+	"crypto/subtle"
+	"database/sql"
+	"encoding/base64"
+	"encoding/hex"
+	"errors"
+	"log/slog"
+	"sass-billing-service/src/apperror"
+	"sass-billing-service/src/models"
+	"sass-billing-service/src/policy"
+	"sass-billing-service/src/repositories"
+	"sass-billing-service/src/tenant"
+	"strings"
+	"time"
+)
+
+var (
+	ErrInvalidAPIKey = apperror.Unauthorized("Invalid API key")
+	ErrInvalidScope  = apperror.Validation("Invalid API key scope", apperror.Field("scopes", "must list at least one delegable permission"))
+)
+
+// Formato de clave: "bk_" + 16 caracteres hex de prefijo público + "_" + secreto de 32 bytes en base64url.
+// El prefijo es la clave única de búsqueda: con 64 bits una colisión es despreciable. Las claves emitidas
+// antes tienen 8 caracteres de prefijo y se siguen aceptando.
+const (
+	apiKeyMarker          = "bk_"
+	apiKeyPrefixBytes     = 8
+	apiKeyLegacyPrefixLen = len(apiKeyMarker) + 8
+	apiKeyPrefixLen       = len(apiKeyMarker) + 2*apiKeyPrefixBytes
+)
+
+// APIKeyStore guarda las API keys; lo cumplen repositories.APIKeyRepository y repositories.MemoryAPIKeyRepository
+type APIKeyStore interface {
+	Create(ctx context.Context, key *models.APIKey) (*models.APIKey, error)
+	List(ctx context.Context) ([]models.APIKey, error)
+	Revoke(ctx context.Context, id int) (*models.APIKey, error)
+	Rotate(ctx context.Context, id int, prefix, keyHash string) (*models.APIKey, error)
+	FindByPrefix(ctx context.Context, prefix string) (*models.APIKey, error)
+	TouchLastUsed(ctx context.Context, id int, usedAt time.Time) error
+}
+
+var (
+	_ APIKeyStore = (*repositories.APIKeyRepository)(nil)
+	_ APIKeyStore = (*repositories.MemoryAPIKeyRepository)(nil)
+)
+
+type APIKeyService struct {
+	repo APIKeyStore
+}
+
+func NewAPIKeyService(repo APIKeyStore) *APIKeyService {
+	return &APIKeyService{repo: repo}
+}
+
+// CreateAPIKey emite una clave para el tenant del contexto; la clave completa solo viaja en esta respuesta
+func (s *APIKeyService) CreateAPIKey(ctx context.Context, req *models.CreateAPIKeyRequest, createdBy string) (*models.IssuedAPIKey, error) {
+	scopes, err := normalizeScopes(req.Scopes)
+	if err != nil {
+		return nil, err
+	}
+
+	key, prefix, hash, err := generateAPIKey()
+	if err != nil {
+		return nil, err
+	}
+
+	created, err := s.repo.Create(ctx, &models.APIKey{
+		Name:      req.Name,
+		Prefix:    prefix,
+		KeyHash:   hash,
+		Scopes:    scopes,
+		CreatedBy: createdBy,
+		ExpiresAt: req.ExpiresAt,
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return &models.IssuedAPIKey{APIKey: *created, Key: key}, nil
+}
+
+func (s *APIKeyService) ListAPIKeys(ctx context.Context) ([]models.APIKey, error) {
+	return s.repo.List(ctx)
+}
+
+// RotateAPIKey sustituye la clave por otra con los mismos permisos; la anterior deja de valer al instante
+func (s *APIKeyService) RotateAPIKey(ctx context.Context, id int) (*models.IssuedAPIKey, error) {
+	key, prefix, hash, err := generateAPIKey()
+	if err != nil {
+		return nil, err
+	}
+
+	created, err := s.repo.Rotate(ctx, id, prefix, hash)
+	if err != nil {
+		return nil, err
+	}
+
+	return &models.IssuedAPIKey{APIKey: *created, Key: key}, nil
+}
+
+func (s *APIKeyService) RevokeAPIKey(ctx context.Context, id int) (*models.APIKey, error) {
+	return s.repo.Revoke(ctx, id)
+}
+
+// Authenticate valida la clave presentada y devuelve el principal con los scopes de la clave.
+// Cualquier fallo se reporta como ErrInvalidAPIKey para no revelar si el prefijo existe.
+func (s *APIKeyService) Authenticate(ctx context.Context, presented string) (*models.Principal, error) {
+	// El secreto en base64url puede contener "_", el prefijo hex no
+	end := -1
+	if strings.HasPrefix(presented, apiKeyMarker) {
+		end = strings.IndexByte(presented[len(apiKeyMarker):], '_') + len(apiKeyMarker)
+	}
+	if (end != apiKeyPrefixLen && end != apiKeyLegacyPrefixLen) || end == len(presented)-1 {
+		return nil, ErrInvalidAPIKey
+	}
+
+	key, err := s.repo.FindByPrefix(ctx, presented[:end])
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil, ErrInvalidAPIKey
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	now := time.Now()
+	if subtle.ConstantTimeCompare([]byte(hashAPIKey(presented)), []byte(key.KeyHash)) != 1 || !key.Active(now) {
+		return nil, ErrInvalidAPIKey
+	}
+
+	if err := s.repo.TouchLastUsed(tenant.WithTenant(ctx, key.TenantID), key.ID, now); err != nil {
+		slog.WarnContext(ctx, "error updating API key last use", "api_key_id", key.ID, "error", err)
+	}
+
+	return &models.Principal{
+		Subject:    "api-key:" + key.Prefix,
+		TenantID:   key.TenantID,
+		AuthMethod: models.AuthMethodAPIKey,
+		Scopes:     key.Scopes,
+	}, nil
+}
+
+func normalizeScopes(scopes []string) ([]string, error) {
+	seen := map[string]bool{}
+	normalized := []string{}
+	for _, scope := range scopes {
+		scope = strings.TrimSpace(scope)
+		if !policy.Delegable(scope) {
+			return nil, ErrInvalidScope
+		}
+		if !seen[scope] {
+			seen[scope] = true
+			normalized = append(normalized, scope)
+		}
+	}
+
+	if len(normalized) == 0 {
+		return nil, ErrInvalidScope
+	}
+
+	return normalized, nil
+}
+
+func generateAPIKey() (key, prefix, hash string, err error) {
+	public := make([]byte, apiKeyPrefixBytes)
+	secret := make([]byte, 32)
+	if _, err := rand.Read(public); err != nil {
+		return "", "", "", err
+	}
+	if _, err := rand.Read(secret); err != nil {
+		return "", "", "", err
+	}
+
+	prefix = apiKeyMarker + hex.EncodeToString(public)
+	key = prefix + "_" + base64.RawURLEncoding.EncodeToString(secret)
+
+	return key, prefix, hashAPIKey(key), nil
+}
+
+// hashAPIKey basta con SHA-256: la clave tiene 256 bits de entropía y no necesita un hash lento
+func hashAPIKey(key string) string {
+	sum := sha256.Sum256([]byte(key))
+	return hex.EncodeToString(sum[:])
+}
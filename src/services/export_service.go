@@ -0,0 +1,40 @@
+package services
+
+import (
+	"context"
+	"io"
+	"sass-billing-service/src/export"
+	"sass-billing-service/src/models"
+	"sass-billing-service/src/repositories"
+)
+
+// ExportStore recorre las facturas a exportar; lo cumplen repositories.ExportRepository y repositories.MemoryExportRepository
+type ExportStore interface {
+	Stream(ctx context.Context, filter models.ExportFilter, fn func(*models.ExportRow) error) error
+}
+
+var (
+	_ ExportStore = (*repositories.ExportRepository)(nil)
+	_ ExportStore = (*repositories.MemoryExportRepository)(nil)
+)
+
+type ExportService struct {
+	repo ExportStore
+}
+
+func NewExportService(repo ExportStore) *ExportService {
+	return &ExportService{repo: repo}
+}
+
+func (s *ExportService) ExportInvoices(ctx context.Context, filter models.ExportFilter, format string, w io.Writer) error {
+	writer, err := export.NewWriter(format, w)
+	if err != nil {
+		return err
+	}
+
+	if err := s.repo.Stream(ctx, filter, writer.Write); err != nil {
+		return err
+	}
+
+	return writer.Close()
+}
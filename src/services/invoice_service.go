@@ -2,26 +2,160 @@ package services
 
 import (
 	"context"
+	"database/sql"
+	"errors"
+	"sass-billing-service/src/metrics"
 	"sass-billing-service/src/models"
 	"sass-billing-service/src/repositories"
+	"sass-billing-service/src/tracing"
+	"time"
+
+	"go.opentelemetry.io/otel/attribute"
+)
+
+// InvoiceStore es el almacén de facturas; lo cumplen repositories.InvoiceRepository (PostgreSQL)
+// y repositories.MemoryInvoiceRepository
+type InvoiceStore interface {
+	GetByID(ctx context.Context, id int) (*models.Invoice, error)
+	GetByUserID(ctx context.Context, userID int) ([]models.Invoice, error)
+	List(ctx context.Context, filter models.InvoiceFilter, page models.PageRequest) (*models.InvoicePage, error)
+	Search(ctx context.Context, userID int, q string, limit int) ([]models.InvoiceSearchResult, error)
+	Create(ctx context.Context, req *models.CreateInvoiceRequest) (*models.Invoice, error)
+	MarkPaid(ctx context.Context, id int) (*models.Invoice, error)
+	Void(ctx context.Context, id int) (*models.Invoice, error)
+	Refund(ctx context.Context, id int) (*models.Invoice, error)
+}
+
+var (
+	_ InvoiceStore = (*repositories.InvoiceRepository)(nil)
+	_ InvoiceStore = (*repositories.MemoryInvoiceRepository)(nil)
 )
 
 type InvoiceService struct {
-	repo *repositories.InvoiceRepository
+	repo InvoiceStore
+	tx   Transactor
 }
 
-func NewInvoiceService(repo *repositories.InvoiceRepository) *InvoiceService {
-	return &InvoiceService{repo: repo}
+func NewInvoiceService(repo InvoiceStore, tx Transactor) *InvoiceService {
+	return &InvoiceService{repo: repo, tx: tx}
+}
+
+// inTx ejecuta una operación de escritura en una transacción del Transactor, que la repite
+// si choca con otra concurrente
+func (s *InvoiceService) inTx(ctx context.Context, op func(ctx context.Context) (*models.Invoice, error)) (*models.Invoice, error) {
+	var invoice *models.Invoice
+	err := s.tx.WithinTx(ctx, func(ctx context.Context) error {
+		var err error
+		invoice, err = op(ctx)
+		return err
+	})
+	return invoice, err
 }
 
 func (s *InvoiceService) GetInvoiceByID(ctx context.Context, id int) (*models.Invoice, error) {
-	return s.repo.GetByID(ctx, id)
+	ctx, span := tracing.Start(ctx, "InvoiceService.GetInvoiceByID", attribute.Int("invoice.id", id))
+	invoice, err := s.repo.GetByID(ctx, id)
+	tracing.End(span, err)
+	return invoice, err
 }
 
 func (s *InvoiceService) GetInvoicesByUserID(ctx context.Context, userID int) ([]models.Invoice, error) {
-	return s.repo.GetByUserID(ctx, userID)
+	ctx, span := tracing.Start(ctx, "InvoiceService.GetInvoicesByUserID", attribute.Int("user.id", userID))
+	invoices, err := s.repo.GetByUserID(ctx, userID)
+	tracing.End(span, err)
+	return invoices, err
+}
+
+func (s *InvoiceService) ListInvoices(ctx context.Context, filter models.InvoiceFilter, page models.PageRequest) (*models.InvoicePage, error) {
+	ctx, span := tracing.Start(ctx, "InvoiceService.ListInvoices", attribute.String("page.sort", page.Sort), attribute.Int("page.limit", page.Limit))
+	result, err := s.repo.List(ctx, filter, page)
+	tracing.End(span, err)
+	return result, err
+}
+
+// El texto buscado no se añade al span: puede contener datos del cliente
+func (s *InvoiceService) SearchInvoices(ctx context.Context, userID int, q string, limit int) ([]models.InvoiceSearchResult, error) {
+	ctx, span := tracing.Start(ctx, "InvoiceService.SearchInvoices")
+	results, err := s.repo.Search(ctx, userID, q, limit)
+	tracing.End(span, err)
+	return results, err
 }
 
+// CreateInvoice emite la factura ya finalizada: se crea y se contabiliza en la misma operación
 func (s *InvoiceService) CreateInvoice(ctx context.Context, req *models.CreateInvoiceRequest) (*models.Invoice, error) {
-	return s.repo.Create(ctx, req)
+	ctx, span := tracing.Start(ctx, "InvoiceService.CreateInvoice", attribute.String("invoice.currency", req.Currency))
+	invoice, err := s.inTx(ctx, func(ctx context.Context) (*models.Invoice, error) {
+		return s.repo.Create(ctx, req)
+	})
+	if err == nil {
+		span.SetAttributes(attribute.Int("invoice.id", invoice.ID))
+		metrics.InvoiceEvent(metrics.InvoiceCreated)
+		metrics.InvoiceEvent(metrics.InvoiceFinalized)
+	}
+	tracing.End(span, err)
+	return invoice, err
+}
+
+func (s *InvoiceService) PayInvoice(ctx context.Context, id int) (*models.Invoice, error) {
+	ctx, span := tracing.Start(ctx, "InvoiceService.PayInvoice", attribute.Int("invoice.id", id))
+	invoice, err := s.inTx(ctx, func(ctx context.Context) (*models.Invoice, error) {
+		return s.repo.MarkPaid(ctx, id)
+	})
+	if err == nil {
+		metrics.InvoiceEvent(metrics.InvoicePaid)
+		if invoice.Overdue(time.Now()) {
+			metrics.DunningAttempt(metrics.DunningCollected)
+		}
+	} else {
+		s.paymentFailed(ctx, id, err)
+	}
+	tracing.End(span, err)
+	return invoice, err
+}
+
+// paymentFailed cuenta el cobro fallido por pasarela y motivo. El error de MarkPaid no trae la factura,
+// así que se vuelve a leer para saber su medio de pago y si estaba vencida.
+func (s *InvoiceService) paymentFailed(ctx context.Context, id int, err error) {
+	reason := metrics.PaymentError
+	switch {
+	case errors.Is(err, sql.ErrNoRows):
+		reason = metrics.PaymentNotFound
+	case errors.Is(err, repositories.ErrInvalidInvoiceStatus):
+		reason = metrics.PaymentInvalidStatus
+	}
+
+	invoice, lookupErr := s.repo.GetByID(ctx, id)
+	if lookupErr != nil {
+		metrics.PaymentFailure("", reason)
+		return
+	}
+
+	metrics.PaymentFailure(invoice.PaymentMethod, reason)
+	if invoice.Status == "pending" && invoice.Overdue(time.Now()) {
+		metrics.DunningAttempt(metrics.DunningFailed)
+	}
+}
+
+func (s *InvoiceService) VoidInvoice(ctx context.Context, id int) (*models.Invoice, error) {
+	ctx, span := tracing.Start(ctx, "InvoiceService.VoidInvoice", attribute.Int("invoice.id", id))
+	invoice, err := s.inTx(ctx, func(ctx context.Context) (*models.Invoice, error) {
+		return s.repo.Void(ctx, id)
+	})
+	if err == nil {
+		metrics.InvoiceEvent(metrics.InvoiceVoided)
+	}
+	tracing.End(span, err)
+	return invoice, err
+}
+
+func (s *InvoiceService) RefundInvoice(ctx context.Context, id int) (*models.Invoice, error) {
+	ctx, span := tracing.Start(ctx, "InvoiceService.RefundInvoice", attribute.Int("invoice.id", id))
+	invoice, err := s.inTx(ctx, func(ctx context.Context) (*models.Invoice, error) {
+		return s.repo.Refund(ctx, id)
+	})
+	if err == nil {
+		metrics.InvoiceEvent(metrics.InvoiceRefunded)
+	}
+	tracing.End(span, err)
+	return invoice, err
 }
@@ -0,0 +1,45 @@
+package services
+
+import (
+	"context"
+	"sass-billing-service/src/models"
+	"sass-billing-service/src/repositories"
+)
+
+// LedgerStore lee el libro mayor; lo cumplen repositories.LedgerRepository y repositories.MemoryLedgerRepository
+type LedgerStore interface {
+	TrialBalance(ctx context.Context) (*models.TrialBalance, error)
+	ReceivableDiscrepancies(ctx context.Context) ([]models.ReceivableDiscrepancy, error)
+}
+
+var (
+	_ LedgerStore = (*repositories.LedgerRepository)(nil)
+	_ LedgerStore = (*repositories.MemoryLedgerRepository)(nil)
+)
+
+type LedgerService struct {
+	repo LedgerStore
+}
+
+func NewLedgerService(repo LedgerStore) *LedgerService {
+	return &LedgerService{repo: repo}
+}
+
+func (s *LedgerService) GetTrialBalance(ctx context.Context) (*models.TrialBalance, error) {
+	return s.repo.TrialBalance(ctx)
+}
+
+// Reconcile comprueba que el libro cuadra y que cuentas por cobrar concuerda con el estado de las facturas
+func (s *LedgerService) Reconcile(ctx context.Context) (*models.Reconciliation, error) {
+	balance, err := s.repo.TrialBalance(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	discrepancies, err := s.repo.ReceivableDiscrepancies(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	return &models.Reconciliation{TrialBalance: balance.Balance, Discrepancies: discrepancies}, nil
+}
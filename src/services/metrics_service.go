@@ -0,0 +1,36 @@
+package services
+
+import (
+	"context"
+	"sass-billing-service/src/reports"
+	"sass-billing-service/src/repositories"
+	"time"
+)
+
+// MetricsStore da el MRR por cliente y mes; lo cumplen repositories.MetricsRepository y repositories.MemoryMetricsRepository
+type MetricsStore interface {
+	CustomerMRR(ctx context.Context, through time.Time, currency string) ([]reports.CustomerMonth, error)
+}
+
+var (
+	_ MetricsStore = (*repositories.MetricsRepository)(nil)
+	_ MetricsStore = (*repositories.MemoryMetricsRepository)(nil)
+)
+
+type MetricsService struct {
+	repo              MetricsStore
+	reportingCurrency string
+}
+
+func NewMetricsService(repo MetricsStore, reportingCurrency string) *MetricsService {
+	return &MetricsService{repo: repo, reportingCurrency: reportingCurrency}
+}
+
+func (s *MetricsService) GetMRRReport(ctx context.Context, from, to time.Time) (*reports.MRRReport, error) {
+	history, err := s.repo.CustomerMRR(ctx, to, s.reportingCurrency)
+	if err != nil {
+		return nil, err
+	}
+
+	return reports.ComputeMRR(history, from, to, s.reportingCurrency), nil
+}
@@ -0,0 +1,36 @@
+package services
+
+import (
+	"context"
+	"sass-billing-service/src/models"
+	"sass-billing-service/src/repositories"
+	"time"
+)
+
+// RevenueStore reconoce ingresos y los resume; lo cumplen repositories.RevenueRepository y repositories.MemoryRevenueRepository
+type RevenueStore interface {
+	RecognizeThrough(ctx context.Context, through time.Time) ([]models.JournalEntry, error)
+	Report(ctx context.Context, from, to time.Time) (*models.RevenueReport, error)
+}
+
+var (
+	_ RevenueStore = (*repositories.RevenueRepository)(nil)
+	_ RevenueStore = (*repositories.MemoryRevenueRepository)(nil)
+)
+
+type RevenueService struct {
+	repo RevenueStore
+}
+
+func NewRevenueService(repo RevenueStore) *RevenueService {
+	return &RevenueService{repo: repo}
+}
+
+// CloseMonth reconoce todo lo programado hasta el mes indicado, incluidos meses anteriores sin cerrar
+func (s *RevenueService) CloseMonth(ctx context.Context, month time.Time) ([]models.JournalEntry, error) {
+	return s.repo.RecognizeThrough(ctx, models.MonthStart(month))
+}
+
+func (s *RevenueService) GetRevenueReport(ctx context.Context, from, to time.Time) (*models.RevenueReport, error) {
+	return s.repo.Report(ctx, from, to)
+}
@@ -0,0 +1,81 @@
+package services
+
+import (
+	"context"
+	"fmt"
+	"math"
+	"sass-billing-service/src/models"
+	"sass-billing-service/src/repositories"
+	"time"
+)
+
+// SubscriptionStore localiza las suscripciones pendientes de renovar; lo cumple repositories.SubscriptionRepository
+type SubscriptionStore interface {
+	DueRenewals(ctx context.Context, through time.Time) ([]models.Subscription, error)
+}
+
+var _ SubscriptionStore = (*repositories.SubscriptionRepository)(nil)
+
+type SubscriptionService struct {
+	repo     SubscriptionStore
+	invoices *InvoiceService
+}
+
+func NewSubscriptionService(repo SubscriptionStore, invoices *InvoiceService) *SubscriptionService {
+	return &SubscriptionService{repo: repo, invoices: invoices}
+}
+
+// Renew factura el periodo siguiente de cada suscripción cuyo periodo vigente termina como tarde en through,
+// con el mismo importe neto y el mismo tipo de impuesto. Con dryRun solo calcula las renovaciones.
+// Cada factura va en su propia transacción: si una falla se devuelven las ya emitidas junto al error,
+// y volver a lanzar la renovación solo factura las que faltan.
+func (s *SubscriptionService) Renew(ctx context.Context, through time.Time, dryRun bool) ([]models.Renewal, error) {
+	subscriptions, err := s.repo.DueRenewals(ctx, through)
+	if err != nil {
+		return nil, err
+	}
+
+	renewals := []models.Renewal{}
+	for _, subscription := range subscriptions {
+		start, end := subscription.NextPeriod()
+		tax := math.Round(subscription.Amount*subscription.TaxRate*100) / 100
+		renewal := models.Renewal{
+			UserID:            subscription.UserID,
+			Product:           subscription.Product,
+			Currency:          subscription.Currency,
+			Amount:            math.Round((subscription.Amount+tax)*100) / 100,
+			TaxAmount:         tax,
+			ServiceStart:      start,
+			ServiceEnd:        end,
+			PreviousInvoiceID: subscription.InvoiceID,
+		}
+
+		if !dryRun {
+			invoice, err := s.invoices.CreateInvoice(ctx, &models.CreateInvoiceRequest{
+				UserID:        subscription.UserID,
+				CustomerName:  subscription.CustomerName,
+				CustomerEmail: subscription.CustomerEmail,
+				Amount:        renewal.Amount,
+				TaxAmount:     tax,
+				Currency:      subscription.Currency,
+				Description:   fmt.Sprintf("%s renewal %s to %s", subscription.Product, start.Format("2006-01-02"), end.Format("2006-01-02")),
+				PaymentMethod: subscription.PaymentMethod,
+				Lines: []models.CreateInvoiceLineRequest{{
+					Product:      subscription.Product,
+					Description:  subscription.Description,
+					Amount:       subscription.Amount,
+					ServiceStart: &start,
+					ServiceEnd:   &end,
+				}},
+			})
+			if err != nil {
+				return renewals, fmt.Errorf("error renewing %s for user %d: %w", subscription.Product, subscription.UserID, err)
+			}
+			renewal.InvoiceID = invoice.ID
+		}
+
+		renewals = append(renewals, renewal)
+	}
+
+	return renewals, nil
+}
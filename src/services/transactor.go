@@ -0,0 +1,17 @@
+package services
+
+import (
+	"context"
+	"sass-billing-service/src/repositories"
+)
+
+// Transactor ejecuta fn en una transacción que los repositorios toman del contexto;
+// lo cumplen repositories.TxManager (PostgreSQL) y repositories.NoTx (repositorios en memoria)
+type Transactor interface {
+	WithinTx(ctx context.Context, fn func(ctx context.Context) error) error
+}
+
+var (
+	_ Transactor = (*repositories.TxManager)(nil)
+	_ Transactor = repositories.NoTx{}
+)
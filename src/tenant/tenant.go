@@ -0,0 +1,34 @@
+package tenant
+
+import (
+	"context"
+	"errors"
+)
+
+// ErrMissingTenant indica que una operación llegó a la capa de datos sin tenant en el contexto
+var ErrMissingTenant = errors.New("tenant missing from context")
+
+type tenantKey struct{}
+
+type systemKey struct{}
+
+// WithTenant devuelve un contexto que limita todas las operaciones de datos al tenant indicado
+func WithTenant(ctx context.Context, tenantID string) context.Context {
+	return context.WithValue(ctx, tenantKey{}, tenantID)
+}
+
+func FromContext(ctx context.Context) (string, bool) {
+	tenantID, ok := ctx.Value(tenantKey{}).(string)
+	return tenantID, ok && tenantID != ""
+}
+
+// WithSystem marca el contexto de las tareas de mantenimiento que recorren todos los tenants.
+// Nunca debe derivarse de una petición HTTP.
+func WithSystem(ctx context.Context) context.Context {
+	return context.WithValue(ctx, systemKey{}, true)
+}
+
+func IsSystem(ctx context.Context) bool {
+	system, _ := ctx.Value(systemKey{}).(bool)
+	return system
+}
@@ -0,0 +1,59 @@
+package tracing
+
+import (
+	"github.com/gofiber/fiber/v2"
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/codes"
+	semconv "go.opentelemetry.io/otel/semconv/v1.26.0"
+	"go.opentelemetry.io/otel/trace"
+)
+
+// headerCarrier adapta las cabeceras de Fiber al propagador de OpenTelemetry
+type headerCarrier struct {
+	c *fiber.Ctx
+}
+
+func (h headerCarrier) Get(key string) string { return h.c.Get(key) }
+
+func (h headerCarrier) Set(key, value string) { h.c.Set(key, value) }
+
+func (h headerCarrier) Keys() []string {
+	keys := make([]string, 0)
+	h.c.Request().Header.VisitAll(func(key, _ []byte) {
+		keys = append(keys, string(key))
+	})
+	return keys
+}
+
+// Middleware abre el span de servidor de cada petición continuando el traceparent entrante.
+// El span se renombra con el patrón de la ruta al terminar, cuando Fiber ya la ha resuelto.
+// El estado se lee de la respuesta: helpers.HandleErrors, por dentro, ya ha convertido los errores.
+func Middleware() fiber.Handler {
+	return func(c *fiber.Ctx) error {
+		ctx := otel.GetTextMapPropagator().Extract(c.UserContext(), headerCarrier{c})
+		ctx, span := tracer().Start(ctx, c.Method(),
+			trace.WithSpanKind(trace.SpanKindServer),
+			trace.WithAttributes(
+				semconv.HTTPRequestMethodKey.String(c.Method()),
+				semconv.URLPath(c.Path()),
+			),
+		)
+		defer span.End()
+
+		own := c.Route()
+		c.SetUserContext(ctx)
+		err := c.Next()
+		status := c.Response().StatusCode()
+
+		if route := c.Route(); route != own {
+			span.SetName(c.Method() + " " + route.Path)
+			span.SetAttributes(semconv.HTTPRoute(route.Path))
+		}
+		span.SetAttributes(semconv.HTTPResponseStatusCode(status))
+		if status >= fiber.StatusInternalServerError {
+			span.SetStatus(codes.Error, "")
+		}
+
+		return err
+	}
+}
@@ -0,0 +1,166 @@
+package tracing
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"net/http"
+	"regexp"
+	"strings"
+
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	"go.opentelemetry.io/otel/exporters/otlp/otlptrace/otlptracehttp"
+	"go.opentelemetry.io/otel/exporters/stdout/stdouttrace"
+	"go.opentelemetry.io/otel/propagation"
+	"go.opentelemetry.io/otel/sdk/resource"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	semconv "go.opentelemetry.io/otel/semconv/v1.26.0"
+	"go.opentelemetry.io/otel/trace"
+)
+
+const ServiceName = "sass-billing-service"
+
+// Exporters
+const (
+	ExporterNone   = "none"
+	ExporterStdout = "stdout"
+	ExporterOTLP   = "otlp"
+)
+
+// maxStatementLength evita que una consulta generada dinámicamente infle cada span
+const maxStatementLength = 2048
+
+var (
+	stringLiteral  = regexp.MustCompile(`'(?:[^']|'')*'`)
+	numericLiteral = regexp.MustCompile(`(^|[^$\w.])\d+(?:\.\d+)?\b`)
+	whitespace     = regexp.MustCompile(`\s+`)
+)
+
+// Setup instala el proveedor de trazas global y el propagador W3C (traceparent, baggage).
+// Con ExporterNone solo se propaga el contexto entrante: no se generan ni exportan spans.
+// El endpoint OTLP es opcional; sin él se usan las variables OTEL_EXPORTER_OTLP_* o localhost:4318.
+func Setup(ctx context.Context, exporter, endpoint string, stdout io.Writer) (func(context.Context) error, error) {
+	otel.SetTextMapPropagator(propagation.NewCompositeTextMapPropagator(propagation.TraceContext{}, propagation.Baggage{}))
+
+	var spanExporter sdktrace.SpanExporter
+	var err error
+	switch exporter {
+	case ExporterNone:
+		return func(context.Context) error { return nil }, nil
+	case ExporterStdout:
+		spanExporter, err = stdouttrace.New(stdouttrace.WithWriter(stdout))
+	case ExporterOTLP:
+		var options []otlptracehttp.Option
+		if endpoint != "" {
+			options = append(options, otlptracehttp.WithEndpointURL(endpoint))
+		}
+		spanExporter, err = otlptracehttp.New(ctx, options...)
+	default:
+		return nil, fmt.Errorf("unknown trace exporter %q", exporter)
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	res, err := resource.Merge(resource.Default(), resource.NewWithAttributes(semconv.SchemaURL, semconv.ServiceName(ServiceName)))
+	if err != nil {
+		return nil, err
+	}
+
+	provider := sdktrace.NewTracerProvider(
+		sdktrace.WithBatcher(spanExporter),
+		sdktrace.WithResource(res),
+	)
+	otel.SetTracerProvider(provider)
+
+	return provider.Shutdown, nil
+}
+
+func tracer() trace.Tracer {
+	return otel.Tracer(ServiceName)
+}
+
+// Start abre un span interno hijo del que haya en el contexto
+func Start(ctx context.Context, name string, attrs ...attribute.KeyValue) (context.Context, trace.Span) {
+	return tracer().Start(ctx, name, trace.WithAttributes(attrs...))
+}
+
+// End registra el error, si lo hay, y cierra el span
+func End(span trace.Span, err error) {
+	if err != nil {
+		span.RecordError(err)
+		span.SetStatus(codes.Error, err.Error())
+	}
+	span.End()
+}
+
+// Query abre el span de cliente de una consulta de repositorio. La sentencia se guarda sin literales
+// para que ningún dato de cliente llegue al backend de trazas; los valores van siempre como parámetros.
+func Query(ctx context.Context, repository, method, statement string) (context.Context, trace.Span) {
+	return tracer().Start(ctx, repository+"."+method,
+		trace.WithSpanKind(trace.SpanKindClient),
+		trace.WithAttributes(
+			semconv.DBSystemPostgreSQL,
+			semconv.DBOperationName(method),
+			semconv.DBQueryText(SanitizeSQL(statement)),
+		),
+	)
+}
+
+// SanitizeSQL sustituye los literales de texto y numéricos por '?' y compacta los espacios.
+// Los parámetros ($1, $2...) se conservan.
+func SanitizeSQL(statement string) string {
+	statement = stringLiteral.ReplaceAllString(statement, "?")
+	statement = numericLiteral.ReplaceAllString(statement, "${1}?")
+	statement = strings.TrimSpace(whitespace.ReplaceAllString(statement, " "))
+
+	if len(statement) > maxStatementLength {
+		statement = statement[:maxStatementLength]
+	}
+
+	return statement
+}
+
+// Transport envuelve un http.RoundTripper para las llamadas salientes: abre un span de cliente
+// y propaga traceparent al servicio remoto
+func Transport(base http.RoundTripper) http.RoundTripper {
+	if base == nil {
+		base = http.DefaultTransport
+	}
+
+	return &transport{base: base}
+}
+
+type transport struct {
+	base http.RoundTripper
+}
+
+func (t *transport) RoundTrip(req *http.Request) (*http.Response, error) {
+	ctx, span := tracer().Start(req.Context(), "HTTP "+req.Method,
+		trace.WithSpanKind(trace.SpanKindClient),
+		trace.WithAttributes(
+			semconv.HTTPRequestMethodKey.String(req.Method),
+			semconv.ServerAddress(req.URL.Hostname()),
+		),
+	)
+
+	// RoundTrip no debe modificar la petición original
+	req = req.Clone(ctx)
+	otel.GetTextMapPropagator().Inject(ctx, propagation.HeaderCarrier(req.Header))
+
+	resp, err := t.base.RoundTrip(req)
+	if err != nil {
+		End(span, err)
+		return nil, err
+	}
+
+	span.SetAttributes(semconv.HTTPResponseStatusCode(resp.StatusCode))
+	if resp.StatusCode >= http.StatusInternalServerError {
+		span.SetStatus(codes.Error, resp.Status)
+	}
+	span.End()
+
+	return resp, nil
+}
@@ -0,0 +1,27 @@
+package utils
+
+import "time"
+
+// ParseDateRange interpreta fechas "2006-01-02"; to es inclusivo, así que se devuelve el día siguiente
+func ParseDateRange(from, to string) (*time.Time, *time.Time, error) {
+	var fromDate, toDate *time.Time
+
+	if from != "" {
+		parsed, err := time.Parse("2006-01-02", from)
+		if err != nil {
+			return nil, nil, err
+		}
+		fromDate = &parsed
+	}
+
+	if to != "" {
+		parsed, err := time.Parse("2006-01-02", to)
+		if err != nil {
+			return nil, nil, err
+		}
+		parsed = parsed.AddDate(0, 0, 1)
+		toDate = &parsed
+	}
+
+	return fromDate, toDate, nil
+}
@@ -3,9 +3,10 @@ package utils
 import "github.com/gofiber/fiber/v2"
 
 type Response struct {
-	Success bool        `json:"success"`
-	Message string      `json:"message,omitempty"`
-	Data    interface{} `json:"data,omitempty"`
+	Success    bool        `json:"success"`
+	Message    string      `json:"message,omitempty"`
+	Data       interface{} `json:"data,omitempty"`
+	NextCursor string      `json:"next_cursor,omitempty"`
 }
 
 func SuccessResponse(c *fiber.Ctx, status int, data interface{}) error {
@@ -15,6 +16,15 @@ func SuccessResponse(c *fiber.Ctx, status int, data interface{}) error {
 	})
 }
 
+// PaginatedResponse incluye el cursor de la página siguiente; vacío cuando no hay más resultados
+func PaginatedResponse(c *fiber.Ctx, status int, data interface{}, nextCursor string) error {
+	return c.Status(status).JSON(Response{
+		Success:    true,
+		Data:       data,
+		NextCursor: nextCursor,
+	})
+}
+
 func ErrorResponse(c *fiber.Ctx, status int, message string) error {
 	return c.Status(status).JSON(Response{
 		Success: false,
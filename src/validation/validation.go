@@ -0,0 +1,271 @@
+package validation
+
+import (
+	"fmt"
+	"net/mail"
+	"reflect"
+	"strconv"
+	"strings"
+	"time"
+
+	"sass-billing-service/src/apperror"
+)
+
+// Validator lo implementan los modelos con reglas entre campos que no caben en una etiqueta;
+// se invoca después de las etiquetas y sus errores se suman a los de estas
+type Validator interface {
+	Validate() []apperror.FieldError
+}
+
+// Struct evalúa las etiquetas `validate` de v (y de sus structs anidados) y devuelve un error
+// de validación con todos los campos inválidos a la vez, o nil si no hay ninguno.
+//
+// Reglas: required, omitempty, min=, max=, gt=, len=, oneof=a b c, email, currency y dive,
+// que aplica las reglas siguientes a cada elemento de un slice y valida sus structs.
+func Struct(v any) error {
+	if fields := Fields(v); len(fields) > 0 {
+		return apperror.Validation("Request validation failed", fields...)
+	}
+
+	return nil
+}
+
+// Fields es como Struct pero devuelve los errores sin envolver
+func Fields(v any) []apperror.FieldError {
+	value := reflect.ValueOf(v)
+	for value.Kind() == reflect.Pointer {
+		if value.IsNil() {
+			return nil
+		}
+		value = value.Elem()
+	}
+	if value.Kind() != reflect.Struct {
+		panic(fmt.Sprintf("validation: %T is not a struct", v))
+	}
+
+	return validateStruct(value, "")
+}
+
+// Var aplica unas reglas sueltas a un valor; sirve para parámetros de query y flags
+func Var(field string, value any, rules string) []apperror.FieldError {
+	return validateValue(reflect.ValueOf(value), field, strings.Split(rules, ","))
+}
+
+var timeType = reflect.TypeOf(time.Time{})
+
+func validateStruct(value reflect.Value, prefix string) []apperror.FieldError {
+	var fields []apperror.FieldError
+
+	t := value.Type()
+	for i := 0; i < t.NumField(); i++ {
+		field := t.Field(i)
+		if !field.IsExported() {
+			continue
+		}
+
+		name := prefix + fieldName(field)
+		if tag, ok := field.Tag.Lookup("validate"); ok && tag != "-" {
+			fields = append(fields, validateValue(value.Field(i), name, strings.Split(tag, ","))...)
+		} else if nested := indirect(value.Field(i)); nested.Kind() == reflect.Struct && nested.Type() != timeType {
+			fields = append(fields, validateStruct(nested, name+".")...)
+		}
+	}
+
+	if validator, ok := addressable(value).Interface().(Validator); ok {
+		for _, fieldErr := range validator.Validate() {
+			fieldErr.Field = prefix + fieldErr.Field
+			fields = append(fields, fieldErr)
+		}
+	}
+
+	return fields
+}
+
+func validateValue(value reflect.Value, name string, rules []string) []apperror.FieldError {
+	var fields []apperror.FieldError
+
+	for i, rule := range rules {
+		rule = strings.TrimSpace(rule)
+		key, param, _ := strings.Cut(rule, "=")
+
+		switch key {
+		case "":
+			continue
+		case "omitempty":
+			if isZero(value) {
+				return fields
+			}
+			continue
+		case "dive":
+			elements := indirect(value)
+			if elements.Kind() != reflect.Slice && elements.Kind() != reflect.Array {
+				panic(fmt.Sprintf("validation: dive on %s, which is not a slice", name))
+			}
+			for j := 0; j < elements.Len(); j++ {
+				fields = append(fields, validateElement(elements.Index(j), name+"["+strconv.Itoa(j)+"]", rules[i+1:])...)
+			}
+			return fields
+		}
+
+		if message := check(key, param, value); message != "" {
+			fields = append(fields, apperror.Field(name, message))
+			// Una regla fallida basta: no tiene sentido informar de max si falta el valor
+			return fields
+		}
+	}
+
+	// Los structs anidados sin dive se validan igualmente
+	if nested := indirect(value); nested.Kind() == reflect.Struct && nested.Type() != timeType {
+		fields = append(fields, validateStruct(nested, name+".")...)
+	}
+
+	return fields
+}
+
+func validateElement(value reflect.Value, name string, rules []string) []apperror.FieldError {
+	if len(rules) > 0 {
+		return validateValue(value, name, rules)
+	}
+	if nested := indirect(value); nested.Kind() == reflect.Struct && nested.Type() != timeType {
+		return validateStruct(nested, name+".")
+	}
+
+	return nil
+}
+
+// check devuelve el mensaje de error de una regla, o "" si se cumple
+func check(key, param string, value reflect.Value) string {
+	if key == "required" {
+		if isZero(value) {
+			return "is required"
+		}
+		return ""
+	}
+
+	value = indirect(value)
+	if !value.IsValid() {
+		return ""
+	}
+
+	switch key {
+	case "min", "max", "gt", "len":
+		limit, err := strconv.ParseFloat(param, 64)
+		if err != nil {
+			panic(fmt.Sprintf("validation: invalid %s parameter %q", key, param))
+		}
+		return checkBound(key, param, limit, value)
+	case "oneof":
+		options := strings.Fields(param)
+		text := fmt.Sprint(value.Interface())
+		for _, option := range options {
+			if text == option {
+				return ""
+			}
+		}
+		return "must be one of " + strings.Join(options, ", ")
+	case "email":
+		address, err := mail.ParseAddress(value.String())
+		if err != nil || address.Address != value.String() {
+			return "must be a valid email address"
+		}
+		return ""
+	case "currency":
+		if !isCurrency(value.String()) {
+			return "must be a 3-letter ISO 4217 code"
+		}
+		return ""
+	default:
+		panic(fmt.Sprintf("validation: unknown rule %q", key))
+	}
+}
+
+// checkBound compara la longitud de textos y slices o el valor de los números
+func checkBound(key, param string, limit float64, value reflect.Value) string {
+	var size float64
+	var unit string
+	switch value.Kind() {
+	case reflect.String:
+		size, unit = float64(len([]rune(value.String()))), " characters"
+	case reflect.Slice, reflect.Array, reflect.Map:
+		size, unit = float64(value.Len()), " items"
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		size = float64(value.Int())
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		size = float64(value.Uint())
+	case reflect.Float32, reflect.Float64:
+		size = value.Float()
+	default:
+		panic(fmt.Sprintf("validation: %s does not apply to %s", key, value.Kind()))
+	}
+
+	switch {
+	case key == "min" && size < limit:
+		return "must be at least " + param + unit
+	case key == "max" && size > limit:
+		return "must be at most " + param + unit
+	case key == "gt" && size <= limit:
+		return "must be greater than " + param + unit
+	case key == "len" && size != limit:
+		return "must be exactly " + param + unit
+	}
+
+	return ""
+}
+
+func isCurrency(code string) bool {
+	if len(code) != 3 {
+		return false
+	}
+	for _, r := range code {
+		if r < 'A' || r > 'Z' {
+			return false
+		}
+	}
+
+	return true
+}
+
+func isZero(value reflect.Value) bool {
+	if !value.IsValid() {
+		return true
+	}
+	switch value.Kind() {
+	case reflect.Pointer, reflect.Interface:
+		return value.IsNil()
+	case reflect.Slice, reflect.Map:
+		return value.Len() == 0
+	}
+
+	return value.IsZero()
+}
+
+func indirect(value reflect.Value) reflect.Value {
+	for value.IsValid() && (value.Kind() == reflect.Pointer || value.Kind() == reflect.Interface) {
+		if value.IsNil() {
+			return reflect.Value{}
+		}
+		value = value.Elem()
+	}
+
+	return value
+}
+
+// addressable devuelve un puntero al struct para encontrar los métodos con receptor puntero
+func addressable(value reflect.Value) reflect.Value {
+	if value.CanAddr() {
+		return value.Addr()
+	}
+
+	copy := reflect.New(value.Type())
+	copy.Elem().Set(value)
+	return copy
+}
+
+// fieldName usa el nombre JSON del campo, que es el que conoce el cliente
+func fieldName(field reflect.StructField) string {
+	if name, _, _ := strings.Cut(field.Tag.Get("json"), ","); name != "" && name != "-" {
+		return name
+	}
+
+	return field.Name
+}
@@ -0,0 +1,57 @@
+package tests
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+	"time"
+
+	"sass-billing-service/src/models"
+	"sass-billing-service/src/reports"
+	"sass-billing-service/src/repositories"
+
+	"github.com/DATA-DOG/go-sqlmock"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestAgingReport(t *testing.T) {
+	db, mock, err := sqlmock.New()
+	if err != nil {
+		t.Fatalf("an error '%s' was not expected when opening a stub database connection", err)
+	}
+	defer db.Close()
+
+	asOf := time.Date(2026, time.June, 30, 0, 0, 0, 0, time.UTC)
+	columns := []string{"user_id", "currency", "current", "days_1_30", "days_31_60", "days_61_90", "days_over_90", "total", "invoices"}
+
+	expectTenantScope(mock, "acme")
+	mock.ExpectQuery(`WITH balances AS (.+) WHERE i.tenant_id = \$1 (.+) GROUPING SETS`).
+		WithArgs("acme", asOf, models.AccountAccountsReceivable).
+		WillReturnRows(sqlmock.NewRows(columns).
+			AddRow(7, "USD", int64(10000), int64(0), int64(2550), int64(0), int64(0), int64(12550), 2).
+			AddRow(9, "USD", int64(0), int64(0), int64(0), int64(0), int64(40000), int64(40000), 1).
+			AddRow(nil, "USD", int64(10000), int64(0), int64(2550), int64(0), int64(40000), int64(52550), 3))
+	mock.ExpectCommit()
+
+	repo := repositories.NewAgingRepository(db)
+	report, err := repo.Report(tenantContext("acme"), asOf)
+
+	assert.NoError(t, err)
+	assert.Equal(t, "2026-06-30", report.AsOf)
+	assert.Len(t, report.Customers, 2)
+	assert.Equal(t, 7, report.Customers[0].UserID)
+	assert.Equal(t, 25.5, report.Customers[0].Days31To60)
+	assert.Equal(t, 400.0, report.Customers[1].Over90)
+	assert.Len(t, report.Totals, 1)
+	assert.Equal(t, 525.5, report.Totals[0].Total)
+	assert.Equal(t, 3, report.Totals[0].Invoices)
+	assert.NoError(t, mock.ExpectationsWereMet())
+
+	var buf bytes.Buffer
+	assert.NoError(t, reports.WriteAgingCSV(&buf, report))
+
+	lines := strings.Split(strings.TrimSpace(buf.String()), "\n")
+	assert.Len(t, lines, 4)
+	assert.Equal(t, "2026-06-30,7,USD,100.00,0.00,25.50,0.00,0.00,125.50,2", lines[1])
+	assert.Equal(t, "2026-06-30,,USD,100.00,0.00,25.50,0.00,400.00,525.50,3", lines[3])
+}
@@ -0,0 +1,197 @@
+package tests
+
+import (
+	"context"
+	"crypto/sha256"
+	"database/sql/driver"
+	"encoding/hex"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"sass-billing-service/src/helpers"
+	"sass-billing-service/src/models"
+	"sass-billing-service/src/policy"
+	"sass-billing-service/src/repositories"
+	"sass-billing-service/src/services"
+	"sass-billing-service/src/tenant"
+
+	"github.com/DATA-DOG/go-sqlmock"
+	"github.com/gofiber/fiber/v2"
+	"github.com/stretchr/testify/assert"
+)
+
+var apiKeyColumns = []string{"id", "tenant_id", "name", "prefix", "key_hash", "scopes", "created_by", "created_at", "expires_at", "last_used_at", "revoked_at"}
+
+// capturedArg acepta cualquier valor y lo guarda para usarlo en expectativas posteriores
+type capturedArg struct {
+	value driver.Value
+}
+
+func (a *capturedArg) Match(v driver.Value) bool {
+	a.value = v
+	return true
+}
+
+func TestAPIKeyService(t *testing.T) {
+	db, mock, err := sqlmock.New()
+	if err != nil {
+		t.Fatalf("an error '%s' was not expected when opening a stub database connection", err)
+	}
+	defer db.Close()
+
+	service := services.NewAPIKeyService(repositories.NewAPIKeyRepository(db))
+	now := time.Now()
+	prefix, hash := &capturedArg{}, &capturedArg{}
+
+	expectTenantScope(mock, "acme")
+	mock.ExpectQuery(`INSERT INTO api_keys`).
+		WithArgs("acme", "ci", prefix, hash, `{"invoices:read","invoices:create"}`, "root", sqlmock.AnyArg(), nil).
+		WillReturnRows(sqlmock.NewRows(apiKeyColumns).
+			AddRow(5, "acme", "ci", "bk_00000000", "", "{invoices:read,invoices:create}", "root", now, nil, nil, nil))
+	mock.ExpectCommit()
+
+	issued, err := service.CreateAPIKey(tenantContext("acme"), &models.CreateAPIKeyRequest{
+		Name:   "ci",
+		Scopes: []string{"invoices:read", "invoices:create", "invoices:read"},
+	}, "root")
+
+	assert.NoError(t, err)
+	assert.True(t, strings.HasPrefix(issued.Key, prefix.value.(string)+"_"))
+	assert.Len(t, prefix.value.(string), len("bk_")+16)
+	assert.NotContains(t, hash.value.(string), issued.Key)
+	assert.Len(t, hash.value.(string), 64)
+
+	t.Run("AuthenticatesIssuedKey", func(t *testing.T) {
+		mock.ExpectBegin()
+		mock.ExpectExec(`SET LOCAL ROLE billing_system`).WillReturnResult(sqlmock.NewResult(0, 0))
+		mock.ExpectQuery(`SELECT (.+) FROM api_keys WHERE prefix = \$1`).
+			WithArgs(prefix.value).
+			WillReturnRows(sqlmock.NewRows(apiKeyColumns).
+				AddRow(5, "acme", "ci", prefix.value, hash.value, "{invoices:read,invoices:create}", "root", now, nil, nil, nil))
+		mock.ExpectCommit()
+		expectTenantScope(mock, "acme")
+		mock.ExpectExec(`UPDATE api_keys SET last_used_at = \$3`).
+			WithArgs("acme", 5, sqlmock.AnyArg()).
+			WillReturnResult(sqlmock.NewResult(0, 1))
+		mock.ExpectCommit()
+
+		principal, err := service.Authenticate(context.Background(), issued.Key)
+
+		assert.NoError(t, err)
+		assert.Equal(t, "acme", principal.TenantID)
+		assert.Equal(t, models.AuthMethodAPIKey, principal.AuthMethod)
+		assert.Equal(t, []string{"invoices:read", "invoices:create"}, principal.Scopes)
+		assert.NoError(t, mock.ExpectationsWereMet())
+	})
+
+	t.Run("RejectsWrongSecretAndRevokedKeys", func(t *testing.T) {
+		for _, revokedAt := range []interface{}{nil, now.Add(-time.Minute)} {
+			mock.ExpectBegin()
+			mock.ExpectExec(`SET LOCAL ROLE billing_system`).WillReturnResult(sqlmock.NewResult(0, 0))
+			mock.ExpectQuery(`SELECT (.+) FROM api_keys WHERE prefix = \$1`).
+				WillReturnRows(sqlmock.NewRows(apiKeyColumns).
+					AddRow(5, "acme", "ci", prefix.value, hash.value, "{invoices:read}", "root", now, nil, nil, revokedAt))
+			mock.ExpectCommit()
+		}
+
+		_, err := service.Authenticate(context.Background(), issued.Key+"x")
+		assert.ErrorIs(t, err, services.ErrInvalidAPIKey)
+		_, err = service.Authenticate(context.Background(), issued.Key)
+		assert.ErrorIs(t, err, services.ErrInvalidAPIKey)
+
+		// Una clave mal formada ni siquiera llega a la base de datos
+		_, err = service.Authenticate(context.Background(), "not-a-key")
+		assert.ErrorIs(t, err, services.ErrInvalidAPIKey)
+		assert.NoError(t, mock.ExpectationsWereMet())
+	})
+
+	t.Run("AcceptsLegacyPrefix", func(t *testing.T) {
+		legacy := "bk_0123abcd_c2VjcmV0LXdpdGhfdW5kZXJzY29yZQ"
+		sum := sha256.Sum256([]byte(legacy))
+		mock.ExpectBegin()
+		mock.ExpectExec(`SET LOCAL ROLE billing_system`).WillReturnResult(sqlmock.NewResult(0, 0))
+		mock.ExpectQuery(`SELECT (.+) FROM api_keys WHERE prefix = \$1`).
+			WithArgs("bk_0123abcd").
+			WillReturnRows(sqlmock.NewRows(apiKeyColumns).
+				AddRow(6, "acme", "old", "bk_0123abcd", hex.EncodeToString(sum[:]), "{invoices:read}", "root", now, nil, nil, nil))
+		mock.ExpectCommit()
+		expectTenantScope(mock, "acme")
+		mock.ExpectExec(`UPDATE api_keys SET last_used_at = \$3`).
+			WithArgs("acme", 6, sqlmock.AnyArg()).
+			WillReturnResult(sqlmock.NewResult(0, 1))
+		mock.ExpectCommit()
+
+		principal, err := service.Authenticate(context.Background(), legacy)
+
+		assert.NoError(t, err)
+		assert.Equal(t, "api-key:bk_0123abcd", principal.Subject)
+		assert.NoError(t, mock.ExpectationsWereMet())
+	})
+
+	t.Run("RejectsUndelegableScopes", func(t *testing.T) {
+		for _, scopes := range [][]string{{string(policy.APIKeysManage)}, {"invoices:delete"}, {}} {
+			_, err := service.CreateAPIKey(tenantContext("acme"), &models.CreateAPIKeyRequest{Name: "ci", Scopes: scopes}, "root")
+			assert.ErrorIs(t, err, services.ErrInvalidScope)
+		}
+		assert.NoError(t, mock.ExpectationsWereMet())
+	})
+}
+
+type stubAPIKeyAuthenticator map[string]*models.Principal
+
+func (s stubAPIKeyAuthenticator) Authenticate(ctx context.Context, key string) (*models.Principal, error) {
+	if principal, ok := s[key]; ok {
+		return principal, nil
+	}
+	return nil, services.ErrInvalidAPIKey
+}
+
+func TestAuthMiddlewareAPIKey(t *testing.T) {
+	auth := helpers.NewAuthMiddleware(nil, stubAPIKeyAuthenticator{
+		"bk_valid": {Subject: "api-key:bk_valid", TenantID: "acme", AuthMethod: models.AuthMethodAPIKey, Scopes: []string{"invoices:read"}},
+	})
+
+	app := fiber.New(fiber.Config{ErrorHandler: helpers.ErrorHandler})
+	app.Get("/invoices", auth, helpers.RequirePermission(policy.InvoicesRead), func(c *fiber.Ctx) error {
+		tenantID, _ := tenant.FromContext(c.UserContext())
+		return c.SendString(helpers.CurrentPrincipal(c).Subject + " " + tenantID)
+	})
+	app.Get("/reports", auth, helpers.RequirePermission(policy.ReportsRead), func(c *fiber.Ctx) error {
+		return c.SendStatus(fiber.StatusOK)
+	})
+
+	send := func(path, key string) *httptest.ResponseRecorder {
+		req := httptest.NewRequest("GET", path, nil)
+		req.Header.Set(helpers.APIKeyHeader, key)
+		resp, err := app.Test(req)
+		assert.NoError(t, err)
+
+		recorder := httptest.NewRecorder()
+		recorder.Code = resp.StatusCode
+		recorder.Body.ReadFrom(resp.Body)
+		return recorder
+	}
+
+	resp := send("/invoices", "bk_valid")
+	assert.Equal(t, fiber.StatusOK, resp.Code)
+	assert.Equal(t, "api-key:bk_valid acme", resp.Body.String())
+
+	assert.Equal(t, fiber.StatusForbidden, send("/reports", "bk_valid").Code)
+	assert.Equal(t, fiber.StatusUnauthorized, send("/invoices", "bk_revoked").Code)
+}
+
+func TestAPIKeyPolicy(t *testing.T) {
+	principal := &models.Principal{TenantID: "acme", AuthMethod: models.AuthMethodAPIKey, Scopes: []string{"invoices:read"}}
+
+	assert.True(t, policy.Can(principal, policy.InvoicesRead))
+	assert.False(t, policy.Can(principal, policy.InvoicesRefund))
+	assert.False(t, policy.OwnResourcesOnly(principal))
+	assert.True(t, policy.CanAccessInvoice(principal, policy.InvoicesRead, &models.Invoice{TenantID: "acme", UserID: 7}))
+	assert.False(t, policy.CanAccessInvoice(principal, policy.InvoicesRead, &models.Invoice{TenantID: "globex", UserID: 7}))
+
+	// Los roles de un token nunca se mezclan con scopes
+	withRoles := &models.Principal{TenantID: "acme", AuthMethod: models.AuthMethodAPIKey, Roles: []string{policy.RoleBillingAdmin}}
+	assert.False(t, policy.Can(withRoles, policy.InvoicesRead))
+}
@@ -0,0 +1,71 @@
+package tests
+
+import (
+	"context"
+	"errors"
+	"io"
+	"net/http/httptest"
+	"testing"
+
+	"sass-billing-service/src/helpers"
+	"sass-billing-service/src/tenant"
+
+	"github.com/gofiber/fiber/v2"
+	"github.com/golang-jwt/jwt/v5"
+	"github.com/stretchr/testify/assert"
+)
+
+// stubTokenVerifier devuelve los claims registrados para cada token
+type stubTokenVerifier map[string]*helpers.Claims
+
+func (s stubTokenVerifier) Verify(ctx context.Context, tokenString string) (*helpers.Claims, error) {
+	if claims, ok := s[tokenString]; ok {
+		return claims, nil
+	}
+	return nil, errors.New("invalid token")
+}
+
+func TestAuthMiddlewareJWT(t *testing.T) {
+	auth := helpers.NewAuthMiddleware(stubTokenVerifier{
+		"valid":      {UserID: 7, TenantID: "acme", Roles: []string{"customer"}, RegisteredClaims: jwt.RegisteredClaims{Subject: "ada"}},
+		"no-tenant":  {UserID: 7, Roles: []string{"customer"}, RegisteredClaims: jwt.RegisteredClaims{Subject: "ada"}},
+		"no-subject": {UserID: 7, TenantID: "acme", Roles: []string{"customer"}},
+	}, stubAPIKeyAuthenticator{})
+
+	app := fiber.New(fiber.Config{ErrorHandler: helpers.ErrorHandler})
+	app.Get("/whoami", auth, func(c *fiber.Ctx) error {
+		tenantID, _ := tenant.FromContext(c.UserContext())
+		return c.SendString(helpers.CurrentPrincipal(c).Subject + " " + tenantID)
+	})
+
+	send := func(token string) (int, string, string) {
+		req := httptest.NewRequest("GET", "/whoami", nil)
+		req.Header.Set("Authorization", "Bearer "+token)
+		resp, err := app.Test(req)
+		assert.NoError(t, err)
+		body, _ := io.ReadAll(resp.Body)
+		return resp.StatusCode, resp.Header.Get(fiber.HeaderContentType), string(body)
+	}
+
+	status, _, body := send("valid")
+	assert.Equal(t, fiber.StatusOK, status)
+	assert.Equal(t, "ada acme", body)
+
+	t.Run("MissingTenantIsRejected", func(t *testing.T) {
+		status, contentType, body := send("no-tenant")
+		assert.Equal(t, fiber.StatusUnauthorized, status)
+		assert.Equal(t, helpers.ProblemContentType, contentType)
+		assert.Contains(t, body, `"code":"unauthorized"`)
+	})
+
+	t.Run("MissingSubjectIsRejected", func(t *testing.T) {
+		status, contentType, _ := send("no-subject")
+		assert.Equal(t, fiber.StatusUnauthorized, status)
+		assert.Equal(t, helpers.ProblemContentType, contentType)
+	})
+
+	t.Run("InvalidToken", func(t *testing.T) {
+		status, _, _ := send("forged")
+		assert.Equal(t, fiber.StatusUnauthorized, status)
+	})
+}
@@ -0,0 +1,129 @@
+package tests
+
+import (
+	"bytes"
+	"context"
+	"database/sql"
+	"encoding/json"
+	"testing"
+	"time"
+
+	"sass-billing-service/src/cli"
+	"sass-billing-service/src/models"
+	"sass-billing-service/src/repositories"
+	"sass-billing-service/src/services"
+
+	"github.com/DATA-DOG/go-sqlmock"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestInvoiceCommand(t *testing.T) {
+	db, mock, err := sqlmock.New()
+	if err != nil {
+		t.Fatalf("an error '%s' was not expected when opening a stub database connection", err)
+	}
+	defer db.Close()
+
+	service := services.NewInvoiceService(repositories.NewInvoiceRepository(db), repositories.NewTxManager(db, sql.LevelReadCommitted, 3))
+	created := time.Date(2026, 3, 2, 10, 0, 0, 0, time.UTC)
+	expectGet := func() {
+		expectTenantScope(mock, "acme")
+		mock.ExpectQuery(`SELECT (.+) FROM invoices WHERE tenant_id = \$1 AND id = \$2`).
+			WithArgs("acme", 42).
+			WillReturnRows(sqlmock.NewRows(invoiceColumns).
+				AddRow(42, "acme", "INV-000042", 7, "Ada", "ada@example.com", 121.0, 21.0, "EUR", "Pro plan", "pending", "card", created.AddDate(0, 0, 30), created, created))
+		mock.ExpectQuery(`SELECT (.+) FROM invoice_lines WHERE invoice_id = \$1`).
+			WithArgs(42).
+			WillReturnRows(sqlmock.NewRows([]string{"id", "invoice_id", "product", "description", "amount", "service_start", "service_end"}))
+		mock.ExpectCommit()
+	}
+
+	t.Run("GetPrintsTable", func(t *testing.T) {
+		expectGet()
+		var out bytes.Buffer
+
+		err := cli.RunInvoice(context.Background(), service, []string{"get", "42", "--tenant", "acme"}, &out)
+
+		assert.NoError(t, err)
+		assert.Contains(t, out.String(), "NUMBER")
+		assert.Regexp(t, `42\s+INV-000042\s+7\s+pending\s+121.00\s+EUR\s+2026-04-01`, out.String())
+		assert.NoError(t, mock.ExpectationsWereMet())
+	})
+
+	t.Run("GetPrintsJSON", func(t *testing.T) {
+		expectGet()
+		var out bytes.Buffer
+
+		err := cli.RunInvoice(context.Background(), service, []string{"get", "--json", "--tenant=acme", "42"}, &out)
+
+		var invoice models.Invoice
+		assert.NoError(t, err)
+		assert.NoError(t, json.Unmarshal(out.Bytes(), &invoice))
+		assert.Equal(t, "INV-000042", invoice.InvoiceNumber)
+		assert.NoError(t, mock.ExpectationsWereMet())
+	})
+
+	t.Run("RejectsBadArguments", func(t *testing.T) {
+		var out bytes.Buffer
+		for _, args := range [][]string{
+			{},
+			{"delete", "42"},
+			{"get"},
+			{"void", "abc"},
+			{"create", "--user", "7", "--amount", "10"},
+			{"create", "--user", "7", "--amount", "10", "--description", "Pro", "--payment-method", "card", "--tax", "11"},
+			{"list", "--limit", "500"},
+		} {
+			assert.Error(t, cli.RunInvoice(context.Background(), service, args, &out), args)
+		}
+		assert.NoError(t, mock.ExpectationsWereMet())
+	})
+}
+
+func TestReconcileCommand(t *testing.T) {
+	db, mock, err := sqlmock.New()
+	if err != nil {
+		t.Fatalf("an error '%s' was not expected when opening a stub database connection", err)
+	}
+	defer db.Close()
+
+	service := services.NewLedgerService(repositories.NewLedgerRepository(db))
+	expectReconcile := func(discrepancies *sqlmock.Rows) {
+		expectTenantScope(mock, "acme")
+		mock.ExpectQuery(`SELECT a.code, a.name, a.type, (.+) AND e.tenant_id = \$1`).
+			WillReturnRows(sqlmock.NewRows([]string{"code", "name", "type", "debit", "credit"}).
+				AddRow(models.AccountCash, "Cash", "asset", int64(12100), int64(0)).
+				AddRow(models.AccountRevenue, "Revenue", "revenue", int64(0), int64(12100)))
+		mock.ExpectCommit()
+		expectTenantScope(mock, "acme")
+		mock.ExpectQuery(`SELECT i.id, i.invoice_number, i.status, (.+) WHERE i.tenant_id = \$1 AND expected.cents <> COALESCE\(ar.balance_cents, 0\)`).
+			WithArgs("acme", models.AccountAccountsReceivable).
+			WillReturnRows(discrepancies)
+		mock.ExpectCommit()
+	}
+	discrepancyColumns := []string{"id", "invoice_number", "status", "expected", "ledger"}
+
+	t.Run("Clean", func(t *testing.T) {
+		expectReconcile(sqlmock.NewRows(discrepancyColumns))
+		var out bytes.Buffer
+
+		err := cli.RunReconcile(context.Background(), service, []string{"--tenant", "acme"}, &out)
+
+		assert.NoError(t, err)
+		assert.Contains(t, out.String(), "trial balance: 0.00")
+		assert.NoError(t, mock.ExpectationsWereMet())
+	})
+
+	t.Run("DiscrepanciesFailTheCommand", func(t *testing.T) {
+		expectReconcile(sqlmock.NewRows(discrepancyColumns).AddRow(42, "INV-000042", "paid", int64(0), int64(12100)))
+		var out bytes.Buffer
+
+		err := cli.RunReconcile(context.Background(), service, []string{"--tenant", "acme", "--json"}, &out)
+
+		var result models.Reconciliation
+		assert.ErrorIs(t, err, cli.ErrReconciliationFailed)
+		assert.NoError(t, json.Unmarshal(out.Bytes(), &result))
+		assert.Equal(t, []models.ReceivableDiscrepancy{{InvoiceID: 42, InvoiceNumber: "INV-000042", Status: "paid", Expected: 0, Ledger: 121}}, result.Discrepancies)
+		assert.NoError(t, mock.ExpectationsWereMet())
+	})
+}
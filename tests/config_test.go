@@ -0,0 +1,169 @@
+package tests
+
+import (
+	"database/sql"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"sass-billing-service/src/config"
+
+	"github.com/stretchr/testify/assert"
+)
+
+var configKeys = []string{
+	"STORE", "DB_HOST", "DB_PORT", "DB_USER", "DB_PASSWORD", "DB_NAME", "DB_SSLMODE", "DB_ISOLATION_LEVEL", "DB_TX_RETRIES",
+	"SERVER_PORT", "AUTO_MIGRATE", "SHUTDOWN_TIMEOUT", "SHUTDOWN_DRAIN_DELAY",
+	"LOG_LEVEL", "LOG_FORMAT", "TRACING_EXPORTER", "TRACING_ENDPOINT", "REPORTING_CURRENCY", "IDEMPOTENCY_KEY_TTL",
+	"RATE_LIMIT_BACKEND", "RATE_LIMIT_DEFAULT", "RATE_LIMIT_ROUTES", "RATE_LIMIT_TENANT", "RATE_LIMIT_TENANTS",
+	"JWT_SECRET", "JWT_JWKS", "JWT_JWKS_REFRESH", "JWT_JWKS_MIN_REFRESH", "JWT_ALGORITHMS", "JWT_ISSUER", "JWT_AUDIENCE", "JWT_CLOCK_SKEW",
+}
+
+// clearConfigEnv aísla el test del entorno y del .env del directorio de trabajo
+func clearConfigEnv(t *testing.T) {
+	for _, key := range configKeys {
+		t.Setenv(key, "")
+	}
+	dir, err := os.Getwd()
+	assert.NoError(t, err)
+	assert.NoError(t, os.Chdir(t.TempDir()))
+	t.Cleanup(func() { os.Chdir(dir) })
+}
+
+func TestConfigLoad(t *testing.T) {
+	t.Run("EnvironmentOnlyWithoutDotEnv", func(t *testing.T) {
+		clearConfigEnv(t)
+		t.Setenv("DB_HOST", "db")
+		t.Setenv("DB_USER", "billing")
+		t.Setenv("DB_NAME", "billing")
+		t.Setenv("JWT_SECRET", "secret")
+		t.Setenv("RATE_LIMIT_TENANT", "off")
+		t.Setenv("RATE_LIMIT_ROUTES", "GET /api/invoices = 60 / m")
+
+		cfg, err := config.Load("", nil)
+
+		assert.NoError(t, err)
+		assert.Equal(t, "5432", cfg.DBPort)
+		assert.Equal(t, "8080", cfg.ServerPort)
+		assert.Equal(t, 24*time.Hour, cfg.IdempotencyKeyTTL)
+		assert.Equal(t, 15*time.Second, cfg.ShutdownDrainDelay)
+		assert.Equal(t, sql.LevelReadCommitted, cfg.DBIsolation)
+		assert.Equal(t, 3, cfg.DBTxRetries)
+		assert.Equal(t, []string{"HS256"}, cfg.JWTAlgorithms)
+		assert.Equal(t, config.RateLimits{
+			Default: "120/m",
+			Routes:  map[string]string{"GET /api/invoices": "60/m"},
+			Tenants: map[string]string{},
+		}, cfg.RateLimit)
+		assert.Equal(t, "host=db port=5432 user=billing password= dbname=billing sslmode=disable", cfg.DSN())
+	})
+
+	t.Run("EnvironmentOverridesFileOverridesDefaults", func(t *testing.T) {
+		clearConfigEnv(t)
+		file := filepath.Join(t.TempDir(), "billing.yaml")
+		assert.NoError(t, os.WriteFile(file, []byte("db_host: file-db\ndb_user: billing\ndb_name: billing\nserver_port: 9000\n"+
+			"jwt_jwks: /etc/billing/jwks.json\njwt_algorithms: [RS256, ES384]\n"), 0o600))
+		t.Setenv("DB_HOST", "env-db")
+
+		cfg, err := config.Load(file, nil)
+
+		assert.NoError(t, err)
+		assert.Equal(t, "env-db", cfg.DBHost)
+		assert.Equal(t, "9000", cfg.ServerPort)
+		assert.Equal(t, "USD", cfg.ReportingCurrency)
+		assert.Equal(t, []string{"RS256", "ES384"}, cfg.JWTAlgorithms)
+	})
+
+	t.Run("ReadsDefaultDotEnv", func(t *testing.T) {
+		clearConfigEnv(t)
+		assert.NoError(t, os.WriteFile(".env", []byte("DB_HOST=localhost\nDB_USER=billing\nDB_NAME=billing\nJWT_JWKS=https://id.example.com/jwks\nCOMPOSE_PROJECT_NAME=billing\n"), 0o600))
+
+		cfg, err := config.Load("", nil)
+
+		assert.NoError(t, err)
+		assert.Equal(t, "localhost", cfg.DBHost)
+		assert.Equal(t, []string{"RS256", "ES256"}, cfg.JWTAlgorithms)
+	})
+
+	t.Run("ReportsEveryProblemAtOnce", func(t *testing.T) {
+		clearConfigEnv(t)
+		t.Setenv("DB_PORT", "postgres")
+		t.Setenv("REPORTING_CURRENCY", "dollars")
+		t.Setenv("JWT_CLOCK_SKEW", "soon")
+		t.Setenv("DB_ISOLATION_LEVEL", "snapshot")
+		t.Setenv("DB_TX_RETRIES", "-1")
+		t.Setenv("RATE_LIMIT_DEFAULT", "0/m")
+		t.Setenv("RATE_LIMIT_TENANTS", "globex")
+
+		_, err := config.Load("", nil)
+
+		var validation *config.ValidationError
+		assert.ErrorAs(t, err, &validation)
+		assert.ElementsMatch(t, []string{
+			"DB_HOST is required",
+			`DB_PORT must be a port number, got "postgres"`,
+			"DB_USER is required",
+			"DB_NAME is required",
+			"REPORTING_CURRENCY must be a 3-letter ISO 4217 code",
+			`JWT_CLOCK_SKEW must be a duration such as 30s or 15m, got "soon"`,
+			"JWT_SECRET or JWT_JWKS is required",
+			"DB_ISOLATION_LEVEL must be one of read_committed, repeatable_read, serializable",
+			`DB_TX_RETRIES must be a non-negative integer, got "-1"`,
+			`RATE_LIMIT_DEFAULT must be <requests>/<s|m|h> or off, got "0/m"`,
+			`RATE_LIMIT_TENANTS must be a list of <name>=<requests>/<s|m|h>, got "globex"`,
+		}, validation.Problems)
+	})
+
+	t.Run("MemoryStoreNeedsNoDatabase", func(t *testing.T) {
+		clearConfigEnv(t)
+		t.Setenv("STORE", "memory")
+		t.Setenv("JWT_SECRET", "secret")
+
+		cfg, err := config.Load("", nil)
+
+		assert.NoError(t, err)
+		assert.Equal(t, "memory", cfg.Store)
+		assert.Empty(t, cfg.DBHost)
+
+		// Los buckets del limitador no pueden ir a una base de datos que no existe
+		t.Setenv("RATE_LIMIT_BACKEND", "postgres")
+		_, err = config.Load("", nil)
+
+		var validation *config.ValidationError
+		assert.ErrorAs(t, err, &validation)
+		assert.Equal(t, []string{"RATE_LIMIT_BACKEND must be memory when STORE is memory"}, validation.Problems)
+	})
+
+	t.Run("OverridesTakePrecedenceOverEnvironment", func(t *testing.T) {
+		clearConfigEnv(t)
+		t.Setenv("STORE", "postgres")
+		t.Setenv("JWT_SECRET", "secret")
+
+		cfg, err := config.Load("", map[string]string{"STORE": "memory"})
+
+		assert.NoError(t, err)
+		assert.Equal(t, "memory", cfg.Store)
+		assert.Equal(t, "postgres", os.Getenv("STORE"))
+
+		// Un override vacío es un flag que no se indicó
+		cfg, err = config.Load("", map[string]string{"STORE": ""})
+		assert.ErrorContains(t, err, "DB_HOST is required")
+		assert.Nil(t, cfg)
+
+		_, err = config.Load("", map[string]string{"STROE": "memory"})
+		assert.ErrorContains(t, err, "unknown setting STROE")
+	})
+
+	t.Run("RejectsUnknownYAMLKeysAndMissingFile", func(t *testing.T) {
+		clearConfigEnv(t)
+		file := filepath.Join(t.TempDir(), "billing.yml")
+		assert.NoError(t, os.WriteFile(file, []byte("db_hots: localhost\n"), 0o600))
+
+		_, err := config.Load(file, nil)
+		assert.ErrorContains(t, err, "unknown setting db_hots")
+
+		_, err = config.Load(filepath.Join(t.TempDir(), "missing.env"), nil)
+		assert.Error(t, err)
+	})
+}
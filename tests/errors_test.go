@@ -0,0 +1,121 @@
+package tests
+
+import (
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"io"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"sass-billing-service/src/apperror"
+	"sass-billing-service/src/controllers"
+	"sass-billing-service/src/helpers"
+	"sass-billing-service/src/models"
+	"sass-billing-service/src/policy"
+	"sass-billing-service/src/repositories"
+	"sass-billing-service/src/services"
+	"sass-billing-service/src/tenant"
+
+	"github.com/DATA-DOG/go-sqlmock"
+	"github.com/gofiber/fiber/v2"
+	"github.com/lib/pq"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestAppErrorClassification(t *testing.T) {
+	assert.Equal(t, apperror.CodeNotFound, apperror.From(sql.ErrNoRows).Code)
+	assert.Equal(t, apperror.CodeUpstreamUnavailable, apperror.From(&pq.Error{Code: "08006"}).Code)
+	assert.Equal(t, apperror.CodeUpstreamUnavailable, apperror.From(&pq.Error{Code: "57P01"}).Code)
+	assert.Equal(t, apperror.CodeConflict, apperror.From(&pq.Error{Code: "23505"}).Code)
+	assert.Equal(t, apperror.CodeInternal, apperror.From(errors.New("boom")).Code)
+
+	// Los errores de dominio se conservan aunque vengan envueltos
+	wrapped := apperror.From(errors.Join(errors.New("context"), repositories.ErrInvalidInvoiceStatus))
+	assert.Equal(t, apperror.CodeConflict, wrapped.Code)
+	assert.Equal(t, fiber.StatusConflict, wrapped.Status())
+}
+
+func TestProblemResponses(t *testing.T) {
+	db, mock, err := sqlmock.New()
+	if err != nil {
+		t.Fatalf("an error '%s' was not expected when opening a stub database connection", err)
+	}
+	defer db.Close()
+
+	controller := controllers.NewInvoiceController(services.NewInvoiceService(repositories.NewInvoiceRepository(db), repositories.NewTxManager(db, sql.LevelReadCommitted, 3)))
+
+	app := fiber.New(fiber.Config{ErrorHandler: helpers.ErrorHandler})
+	app.Use(helpers.RequestID())
+	app.Use(func(c *fiber.Ctx) error {
+		c.Locals("tenant_id", "acme")
+		c.Locals("principal", &models.Principal{Subject: "root", TenantID: "acme", Roles: []string{policy.RoleBillingAdmin}})
+		c.SetUserContext(tenant.WithTenant(c.UserContext(), "acme"))
+		return c.Next()
+	})
+	app.Get("/invoices/:id", controller.GetInvoice)
+	app.Post("/invoices", controller.CreateInvoice)
+
+	send := func(method, path, body string) (int, string, helpers.Problem) {
+		req := httptest.NewRequest(method, path, strings.NewReader(body))
+		req.Header.Set(fiber.HeaderContentType, fiber.MIMEApplicationJSON)
+		req.Header.Set(helpers.RequestIDHeader, "req-1")
+		resp, err := app.Test(req)
+		assert.NoError(t, err)
+
+		raw, _ := io.ReadAll(resp.Body)
+		var problem helpers.Problem
+		assert.NoError(t, json.Unmarshal(raw, &problem), string(raw))
+		assert.Equal(t, helpers.ProblemContentType, resp.Header.Get(fiber.HeaderContentType))
+		return resp.StatusCode, string(raw), problem
+	}
+
+	t.Run("NotFound", func(t *testing.T) {
+		expectTenantScope(mock, "acme")
+		mock.ExpectQuery(`SELECT (.+) FROM invoices WHERE tenant_id = \$1 AND id = \$2`).
+			WithArgs("acme", 42).
+			WillReturnError(sql.ErrNoRows)
+		mock.ExpectRollback()
+
+		status, _, problem := send("GET", "/invoices/42", "")
+		assert.Equal(t, fiber.StatusNotFound, status)
+		assert.Equal(t, apperror.CodeNotFound, problem.Code)
+		assert.Equal(t, "/problems/not_found", problem.Type)
+		assert.Equal(t, "/invoices/42", problem.Instance)
+		assert.Equal(t, "req-1", problem.RequestID)
+	})
+
+	t.Run("DatabaseOutageIsNotANotFound", func(t *testing.T) {
+		expectTenantScope(mock, "acme")
+		mock.ExpectQuery(`SELECT (.+) FROM invoices WHERE tenant_id = \$1 AND id = \$2`).
+			WithArgs("acme", 42).
+			WillReturnError(&pq.Error{Code: "08006", Message: "connection to 10.0.0.5 lost"})
+		mock.ExpectRollback()
+
+		status, raw, problem := send("GET", "/invoices/42", "")
+		assert.Equal(t, fiber.StatusServiceUnavailable, status)
+		assert.Equal(t, apperror.CodeUpstreamUnavailable, problem.Code)
+		assert.NotContains(t, raw, "10.0.0.5")
+	})
+
+	t.Run("ValidationListsEveryField", func(t *testing.T) {
+		status, _, problem := send("POST", "/invoices", `{"amount": -1, "currency": "EURO"}`)
+		assert.Equal(t, fiber.StatusBadRequest, status)
+		assert.Equal(t, apperror.CodeValidationFailed, problem.Code)
+
+		var fields []string
+		for _, field := range problem.Errors {
+			fields = append(fields, field.Field)
+		}
+		assert.ElementsMatch(t, []string{"user_id", "amount", "description", "payment_method", "currency"}, fields)
+	})
+
+	t.Run("InvalidID", func(t *testing.T) {
+		status, _, problem := send("GET", "/invoices/abc", "")
+		assert.Equal(t, fiber.StatusBadRequest, status)
+		assert.Equal(t, []apperror.FieldError{{Field: "id", Message: "must be an integer"}}, problem.Errors)
+	})
+
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
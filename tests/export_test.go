@@ -0,0 +1,228 @@
+package tests
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"io"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"sass-billing-service/src/controllers"
+	"sass-billing-service/src/export"
+	"sass-billing-service/src/helpers"
+	"sass-billing-service/src/models"
+	"sass-billing-service/src/repositories"
+	"sass-billing-service/src/utils"
+
+	"github.com/DATA-DOG/go-sqlmock"
+	"github.com/gofiber/fiber/v2"
+	"github.com/parquet-go/parquet-go"
+	"github.com/stretchr/testify/assert"
+)
+
+func exportRows() []models.ExportRow {
+	created := time.Date(2026, time.March, 3, 12, 0, 0, 0, time.UTC)
+	return []models.ExportRow{
+		{
+			Invoice: models.Invoice{
+				ID: 1, TenantID: "acme", UserID: 7, Amount: 121, TaxAmount: 21, Currency: "EUR",
+				Description: "Pro, annual", Status: "paid", PaymentMethod: "card",
+				DueDate: created.AddDate(0, 0, 30), CreatedAt: created, UpdatedAt: created,
+			},
+			Lines:    json.RawMessage(`[{"id":1,"product":"pro","amount":100}]`),
+			Payments: json.RawMessage(`[{"type":"payment","amount":121}]`),
+		},
+		{
+			Invoice: models.Invoice{
+				ID: 2, TenantID: "acme", UserID: 8, Amount: 10, Currency: "EUR",
+				Description: "Setup", Status: "pending", PaymentMethod: "transfer",
+				DueDate: created, CreatedAt: created, UpdatedAt: created,
+			},
+		},
+	}
+}
+
+func writeAll(t *testing.T, format string) []byte {
+	var buf bytes.Buffer
+	writer, err := export.NewWriter(format, &buf)
+	assert.NoError(t, err)
+
+	rows := exportRows()
+	for i := range rows {
+		assert.NoError(t, writer.Write(&rows[i]))
+	}
+	assert.NoError(t, writer.Close())
+
+	return buf.Bytes()
+}
+
+func TestExportWriters(t *testing.T) {
+	t.Run("CSV", func(t *testing.T) {
+		lines := strings.Split(strings.TrimSpace(string(writeAll(t, export.FormatCSV))), "\n")
+
+		assert.Len(t, lines, 3)
+		assert.True(t, strings.HasPrefix(lines[0], "id,tenant_id,invoice_number,user_id,customer_name,customer_email,amount"))
+		assert.Contains(t, lines[1], `"Pro, annual"`)
+		assert.Contains(t, lines[1], `"[{""id"":1,""product"":""pro"",""amount"":100}]"`)
+	})
+
+	t.Run("JSONL", func(t *testing.T) {
+		lines := strings.Split(strings.TrimSpace(string(writeAll(t, export.FormatJSONL))), "\n")
+		assert.Len(t, lines, 2)
+
+		var first map[string]interface{}
+		assert.NoError(t, json.Unmarshal([]byte(lines[0]), &first))
+		assert.Equal(t, "acme", first["tenant_id"])
+		assert.Len(t, first["lines"], 1)
+		assert.Len(t, first["payments"], 1)
+
+		var second map[string]interface{}
+		assert.NoError(t, json.Unmarshal([]byte(lines[1]), &second))
+		assert.NotContains(t, second, "lines")
+	})
+
+	t.Run("Parquet", func(t *testing.T) {
+		data := writeAll(t, export.FormatParquet)
+
+		type row struct {
+			ID       int64  `parquet:"id"`
+			Currency string `parquet:"currency"`
+			Lines    string `parquet:"lines,optional"`
+		}
+		rows, err := parquet.Read[row](bytes.NewReader(data), int64(len(data)))
+
+		assert.NoError(t, err)
+		assert.Len(t, rows, 2)
+		assert.Equal(t, int64(1), rows[0].ID)
+		assert.Equal(t, "EUR", rows[1].Currency)
+		assert.Contains(t, rows[0].Lines, `"product":"pro"`)
+	})
+
+	t.Run("UnsupportedFormat", func(t *testing.T) {
+		_, err := export.NewWriter("xml", &bytes.Buffer{})
+		assert.ErrorIs(t, err, export.ErrUnsupportedFormat)
+	})
+}
+
+func TestExportStream(t *testing.T) {
+	db, mock, err := sqlmock.New()
+	if err != nil {
+		t.Fatalf("an error '%s' was not expected when opening a stub database connection", err)
+	}
+	defer db.Close()
+
+	from, to, err := utils.ParseDateRange("2026-03-01", "2026-03-31")
+	assert.NoError(t, err)
+	assert.Equal(t, time.Date(2026, time.April, 1, 0, 0, 0, 0, time.UTC), *to)
+
+	filter := models.ExportFilter{From: from, To: to, Status: "paid", IncludeLines: true}
+	created := time.Date(2026, time.March, 3, 0, 0, 0, 0, time.UTC)
+
+	expectTenantScope(mock, "acme")
+	mock.ExpectExec(`DECLARE invoice_export NO SCROLL CURSOR FOR`).
+		WithArgs("acme", from, to, "paid", true, false).
+		WillReturnResult(sqlmock.NewResult(0, 0))
+	mock.ExpectQuery(`FETCH FORWARD 1000 FROM invoice_export`).
+		WillReturnRows(sqlmock.NewRows([]string{"id", "tenant_id", "invoice_number", "user_id", "customer_name", "customer_email", "amount", "tax_amount", "currency", "description",
+			"status", "payment_method", "due_date", "created_at", "updated_at", "lines", "payments"}).
+			AddRow(1, "acme", "INV-000001", 7, "Ada", "ada@example.com", 121.0, 21.0, "EUR", "Pro", "paid", "card", created, created, created, []byte(`[]`), nil))
+	mock.ExpectExec(`CLOSE invoice_export`).WillReturnResult(sqlmock.NewResult(0, 0))
+	mock.ExpectCommit()
+
+	var exported []models.ExportRow
+	repo := repositories.NewExportRepository(db)
+	err = repo.Stream(tenantContext("acme"), filter, func(row *models.ExportRow) error {
+		exported = append(exported, *row)
+		return nil
+	})
+
+	assert.NoError(t, err)
+	assert.Len(t, exported, 1)
+	assert.Equal(t, json.RawMessage(`[]`), exported[0].Lines)
+	assert.Nil(t, exported[0].Payments)
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
+
+// stubExportService escribe las filas de exportRows y devuelve err al terminar
+type stubExportService struct {
+	filter models.ExportFilter
+	err    error
+}
+
+func (s *stubExportService) ExportInvoices(_ context.Context, filter models.ExportFilter, format string, w io.Writer) error {
+	s.filter = filter
+	writer, err := export.NewWriter(format, w)
+	if err != nil {
+		return err
+	}
+	rows := exportRows()
+	for i := range rows {
+		if err := writer.Write(&rows[i]); err != nil {
+			return err
+		}
+	}
+	if s.err != nil {
+		return s.err
+	}
+	return writer.Close()
+}
+
+func TestExportController(t *testing.T) {
+	exportApp := func(service controllers.ExportService) *fiber.App {
+		app := fiber.New(fiber.Config{ErrorHandler: helpers.ErrorHandler})
+		app.Use(func(c *fiber.Ctx) error {
+			c.Locals("tenant_id", "acme")
+			return c.Next()
+		})
+		app.Get("/invoices/export", controllers.NewExportController(service).ExportInvoices)
+		return app
+	}
+
+	t.Run("StreamsRequestedFormat", func(t *testing.T) {
+		service := &stubExportService{}
+
+		resp, err := exportApp(service).Test(httptest.NewRequest("GET", "/invoices/export?format=jsonl&status=paid&include=lines,+payments", nil))
+
+		assert.NoError(t, err)
+		assert.Equal(t, fiber.StatusOK, resp.StatusCode)
+		body, err := io.ReadAll(resp.Body)
+		assert.NoError(t, err)
+		assert.Len(t, strings.Split(strings.TrimSpace(string(body)), "\n"), 2)
+		assert.Equal(t, models.ExportFilter{Status: "paid", IncludeLines: true, IncludePayments: true}, service.filter)
+	})
+
+	t.Run("RejectsUnknownStatusAndInclude", func(t *testing.T) {
+		for query, field := range map[string]string{
+			"status=unpaid":        "status",
+			"include=lines,refund": "include",
+			"include=line":         "include",
+		} {
+			service := &stubExportService{}
+
+			resp, err := exportApp(service).Test(httptest.NewRequest("GET", "/invoices/export?"+query, nil))
+
+			assert.NoError(t, err)
+			assert.Equal(t, fiber.StatusBadRequest, resp.StatusCode, query)
+			assert.Equal(t, helpers.ProblemContentType, resp.Header.Get(fiber.HeaderContentType), query)
+			body, _ := io.ReadAll(resp.Body)
+			assert.Contains(t, string(body), `"field":"`+field+`"`, query)
+		}
+	})
+
+	t.Run("FailureMidStreamTruncatesResponse", func(t *testing.T) {
+		service := &stubExportService{err: errors.New("connection reset")}
+
+		resp, err := exportApp(service).Test(httptest.NewRequest("GET", "/invoices/export?format=csv", nil))
+
+		// La conexión se corta sin el chunk final: o falla la petición o falla la lectura del cuerpo
+		if err == nil {
+			assert.Equal(t, fiber.StatusOK, resp.StatusCode)
+			_, err = io.ReadAll(resp.Body)
+		}
+		assert.Error(t, err)
+	})
+}
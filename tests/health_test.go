@@ -0,0 +1,140 @@
+package tests
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"sass-billing-service/src/apperror"
+	"sass-billing-service/src/controllers"
+	"sass-billing-service/src/helpers"
+	"sass-billing-service/src/jobs"
+	"sass-billing-service/src/repositories"
+	router "sass-billing-service/src/routes"
+	"sass-billing-service/src/utils"
+
+	"github.com/DATA-DOG/go-sqlmock"
+	"github.com/gofiber/fiber/v2"
+	"github.com/stretchr/testify/assert"
+)
+
+type stubSchema struct {
+	version int
+	err     error
+}
+
+func (s stubSchema) Version(ctx context.Context) (int, error) { return s.version, s.err }
+func (s stubSchema) Latest() int                              { return 10 }
+
+type stubWorker jobs.Status
+
+func (w stubWorker) Status() jobs.Status { return jobs.Status(w) }
+
+func TestHealthEndpoints(t *testing.T) {
+	db, mock, err := sqlmock.New(sqlmock.MonitorPingsOption(true))
+	if err != nil {
+		t.Fatalf("an error '%s' was not expected when opening a stub database connection", err)
+	}
+	defer db.Close()
+
+	probe := func(health *controllers.HealthController, path string) (int, utils.Response) {
+		app := fiber.New(fiber.Config{ErrorHandler: helpers.ErrorHandler})
+		router.SetupHealthRoutes(app, health)
+		resp, err := app.Test(httptest.NewRequest("GET", path, nil))
+		assert.NoError(t, err)
+		assert.Equal(t, fiber.MIMEApplicationJSON, resp.Header.Get(fiber.HeaderContentType))
+
+		var body utils.Response
+		assert.NoError(t, json.NewDecoder(resp.Body).Decode(&body))
+		return resp.StatusCode, body
+	}
+	// probeFailure espera el 503 como problem+json, igual que el resto de errores de la API
+	probeFailure := func(health *controllers.HealthController) helpers.Problem {
+		app := fiber.New(fiber.Config{ErrorHandler: helpers.ErrorHandler})
+		router.SetupHealthRoutes(app, health)
+		resp, err := app.Test(httptest.NewRequest("GET", "/readyz", nil))
+		assert.NoError(t, err)
+		assert.Equal(t, fiber.StatusServiceUnavailable, resp.StatusCode)
+		assert.Equal(t, helpers.ProblemContentType, resp.Header.Get(fiber.HeaderContentType))
+
+		var problem helpers.Problem
+		assert.NoError(t, json.NewDecoder(resp.Body).Decode(&problem))
+		return problem
+	}
+	running := stubWorker{Name: "revenue-close", Running: true}
+
+	t.Run("LivenessIgnoresDatabase", func(t *testing.T) {
+		health := controllers.NewHealthController(db, stubSchema{err: errors.New("down")})
+		status, _ := probe(health, "/healthz")
+		assert.Equal(t, fiber.StatusOK, status)
+	})
+
+	t.Run("Ready", func(t *testing.T) {
+		mock.ExpectPing()
+		health := controllers.NewHealthController(db, stubSchema{version: 10}, running)
+
+		status, body := probe(health, "/readyz")
+
+		assert.Equal(t, fiber.StatusOK, status)
+		assert.True(t, body.Success)
+		assert.NoError(t, mock.ExpectationsWereMet())
+	})
+
+	t.Run("NotReady", func(t *testing.T) {
+		for name, tc := range map[string]struct {
+			health *controllers.HealthController
+			failed apperror.FieldError
+		}{
+			"database":   {controllers.NewHealthController(db, stubSchema{version: 10}, running), apperror.Field("database", "database unreachable")},
+			"migrations": {controllers.NewHealthController(db, stubSchema{version: 9}, running), apperror.Field("migrations", "pending migrations")},
+			"worker":     {controllers.NewHealthController(db, stubSchema{version: 10}, stubWorker{Name: "revenue-close"}), apperror.Field("workers.revenue-close", "not running")},
+		} {
+			if name == "database" {
+				mock.ExpectPing().WillReturnError(errors.New("connection refused"))
+			} else {
+				mock.ExpectPing()
+			}
+
+			problem := probeFailure(tc.health)
+
+			assert.Equal(t, apperror.CodeUpstreamUnavailable, problem.Code, name)
+			assert.Equal(t, "Not ready", problem.Detail, name)
+			assert.Equal(t, []apperror.FieldError{tc.failed}, problem.Errors, name)
+		}
+		assert.NoError(t, mock.ExpectationsWereMet())
+	})
+
+	t.Run("NotReadyWhileShuttingDown", func(t *testing.T) {
+		health := controllers.NewHealthController(db, stubSchema{version: 10}, running)
+		health.ShuttingDown()
+
+		problem := probeFailure(health)
+
+		assert.Equal(t, apperror.CodeUpstreamUnavailable, problem.Code)
+		assert.Equal(t, "Shutting down", problem.Detail)
+	})
+}
+
+func TestJobsStopWhenContextIsCancelled(t *testing.T) {
+	db, _, err := sqlmock.New()
+	if err != nil {
+		t.Fatalf("an error '%s' was not expected when opening a stub database connection", err)
+	}
+	defer db.Close()
+
+	job := jobs.NewIdempotencyCleanupJob(repositories.NewIdempotencyRepository(db), time.Hour)
+	ctx, cancel := context.WithCancel(context.Background())
+	job.Start(ctx)
+	assert.True(t, job.Status().Running)
+
+	cancel()
+	select {
+	case <-job.Done():
+	case <-time.After(time.Second):
+		t.Fatal("job did not stop after its context was cancelled")
+	}
+	assert.False(t, job.Status().Running)
+}
@@ -0,0 +1,158 @@
+package tests
+
+import (
+	"context"
+	"io"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"sass-billing-service/src/helpers"
+	"sass-billing-service/src/models"
+	"sass-billing-service/src/tenant"
+
+	"github.com/gofiber/fiber/v2"
+	"github.com/stretchr/testify/assert"
+)
+
+type memoryIdempotencyStore struct {
+	mu      sync.Mutex
+	records map[string]*models.IdempotencyRecord
+}
+
+func newMemoryIdempotencyStore() *memoryIdempotencyStore {
+	return &memoryIdempotencyStore{records: map[string]*models.IdempotencyRecord{}}
+}
+
+func (s *memoryIdempotencyStore) Reserve(ctx context.Context, principal, key, fingerprint string, ttl time.Duration) (*models.IdempotencyRecord, bool, error) {
+	tenantID, _ := tenant.FromContext(ctx)
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if record, ok := s.records[tenantID+"/"+principal+"/"+key]; ok && record.ExpiresAt.After(time.Now()) {
+		return record, false, nil
+	}
+
+	s.records[tenantID+"/"+principal+"/"+key] = &models.IdempotencyRecord{
+		TenantID: tenantID, Principal: principal, Key: key, Fingerprint: fingerprint, ExpiresAt: time.Now().Add(ttl),
+	}
+	return nil, true, nil
+}
+
+func (s *memoryIdempotencyStore) Complete(ctx context.Context, principal, key string, statusCode int, contentType string, body []byte) error {
+	tenantID, _ := tenant.FromContext(ctx)
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	record := s.records[tenantID+"/"+principal+"/"+key]
+	record.StatusCode, record.ContentType, record.ResponseBody = statusCode, contentType, body
+	return nil
+}
+
+func (s *memoryIdempotencyStore) Release(ctx context.Context, principal, key string) error {
+	tenantID, _ := tenant.FromContext(ctx)
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	delete(s.records, tenantID+"/"+principal+"/"+key)
+	return nil
+}
+
+func TestIdempotencyMiddleware(t *testing.T) {
+	store := newMemoryIdempotencyStore()
+	calls := 0
+	failNext := false
+
+	// El principal llega en una cabecera en lugar de en un token
+	auth := func(c *fiber.Ctx) error {
+		c.Locals("principal", &models.Principal{Subject: c.Get("X-Subject", "ada"), TenantID: "acme"})
+		return c.Next()
+	}
+
+	app := fiber.New(fiber.Config{ErrorHandler: helpers.ErrorHandler})
+	app.Post("/invoices", auth, helpers.IdempotencyMiddleware(store, time.Hour), func(c *fiber.Ctx) error {
+		calls++
+		if failNext {
+			failNext = false
+			return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"error": "boom"})
+		}
+		return c.Status(fiber.StatusCreated).JSON(fiber.Map{"id": calls})
+	})
+
+	send := func(key, body string, headers ...string) (int, string, string) {
+		req := httptest.NewRequest("POST", "/invoices", strings.NewReader(body))
+		req.Header.Set("Content-Type", "application/json")
+		for i := 0; i+1 < len(headers); i += 2 {
+			req.Header.Set(headers[i], headers[i+1])
+		}
+		if key != "" {
+			req.Header.Set(helpers.IdempotencyKeyHeader, key)
+		}
+		resp, err := app.Test(req)
+		assert.NoError(t, err)
+		data, _ := io.ReadAll(resp.Body)
+		return resp.StatusCode, string(data), resp.Header.Get("Idempotent-Replayed")
+	}
+
+	status, body, replayed := send("key-1", `{"amount":10}`)
+	assert.Equal(t, fiber.StatusCreated, status)
+	assert.Equal(t, `{"id":1}`, body)
+	assert.Empty(t, replayed)
+
+	// Un reintento con la misma clave y el mismo cuerpo repite la respuesta sin volver a crear
+	status, body, replayed = send("key-1", `{"amount":10}`)
+	assert.Equal(t, fiber.StatusCreated, status)
+	assert.Equal(t, `{"id":1}`, body)
+	assert.Equal(t, "true", replayed)
+	assert.Equal(t, 1, calls)
+
+	// Otro principal del mismo tenant con la misma clave no recibe la respuesta del primero
+	status, body, replayed = send("key-1", `{"amount":10}`, "X-Subject", "grace")
+	assert.Equal(t, fiber.StatusCreated, status)
+	assert.Equal(t, `{"id":2}`, body)
+	assert.Empty(t, replayed)
+	assert.Equal(t, 2, calls)
+
+	// Misma clave con otro cuerpo
+	status, _, _ = send("key-1", `{"amount":11}`)
+	assert.Equal(t, fiber.StatusUnprocessableEntity, status)
+	assert.Equal(t, 2, calls)
+
+	// Los errores de servidor liberan la clave
+	failNext = true
+	status, _, _ = send("key-2", `{"amount":10}`)
+	assert.Equal(t, fiber.StatusInternalServerError, status)
+	status, body, _ = send("key-2", `{"amount":10}`)
+	assert.Equal(t, fiber.StatusCreated, status)
+	assert.Equal(t, `{"id":4}`, body)
+
+	// Sin cabecera no hay deduplicación
+	send("", `{"amount":10}`)
+	send("", `{"amount":10}`)
+	assert.Equal(t, 6, calls)
+}
+
+// inProgressStore simula una petición original con la misma huella que todavía no ha terminado
+type inProgressStore struct {
+	memoryIdempotencyStore
+}
+
+func (s *inProgressStore) Reserve(ctx context.Context, principal, key, fingerprint string, ttl time.Duration) (*models.IdempotencyRecord, bool, error) {
+	return &models.IdempotencyRecord{Key: key, Fingerprint: fingerprint}, false, nil
+}
+
+func TestIdempotencyMiddlewareInProgress(t *testing.T) {
+	app := fiber.New(fiber.Config{ErrorHandler: helpers.ErrorHandler})
+	app.Post("/invoices", helpers.IdempotencyMiddleware(&inProgressStore{}, time.Hour), func(c *fiber.Ctx) error {
+		return c.SendStatus(fiber.StatusCreated)
+	})
+
+	req := httptest.NewRequest("POST", "/invoices", strings.NewReader(`{"amount":10}`))
+	req.Header.Set(helpers.IdempotencyKeyHeader, "key-1")
+
+	resp, err := app.Test(req)
+	assert.NoError(t, err)
+	assert.Equal(t, fiber.StatusConflict, resp.StatusCode)
+}
@@ -1,12 +1,21 @@
-package controllers
+package tests
 
 import (
 	"context"
+	"database/sql"
 	"errors"
+	"io"
+	"net/http"
+	"net/http/httptest"
 	"strconv"
+	"strings"
 	"testing"
 
+	"sass-billing-service/src/controllers"
+	"sass-billing-service/src/helpers"
 	"sass-billing-service/src/models"
+	"sass-billing-service/src/policy"
+	"sass-billing-service/src/tenant"
 
 	"github.com/gofiber/fiber/v2"
 	"github.com/stretchr/testify/assert"
@@ -18,14 +27,21 @@ type MockInvoiceService struct {
 	mock.Mock
 }
 
+var _ controllers.InvoiceService = (*MockInvoiceService)(nil)
+
 func (m *MockInvoiceService) GetInvoiceByID(ctx context.Context, id int) (*models.Invoice, error) {
 	args := m.Called(ctx, id)
 	return args.Get(0).(*models.Invoice), args.Error(1)
 }
 
-func (m *MockInvoiceService) GetInvoicesByUserID(ctx context.Context, userID int) ([]models.Invoice, error) {
-	args := m.Called(ctx, userID)
-	return args.Get(0).([]models.Invoice), args.Error(1)
+func (m *MockInvoiceService) ListInvoices(ctx context.Context, filter models.InvoiceFilter, page models.PageRequest) (*models.InvoicePage, error) {
+	args := m.Called(ctx, filter, page)
+	return args.Get(0).(*models.InvoicePage), args.Error(1)
+}
+
+func (m *MockInvoiceService) SearchInvoices(ctx context.Context, userID int, q string, limit int) ([]models.InvoiceSearchResult, error) {
+	args := m.Called(ctx, userID, q, limit)
+	return args.Get(0).([]models.InvoiceSearchResult), args.Error(1)
 }
 
 func (m *MockInvoiceService) CreateInvoice(ctx context.Context, req *models.CreateInvoiceRequest) (*models.Invoice, error) {
@@ -33,18 +49,56 @@ func (m *MockInvoiceService) CreateInvoice(ctx context.Context, req *models.Crea
 	return args.Get(0).(*models.Invoice), args.Error(1)
 }
 
-func TestNewInvoiceController(t *testing.T) {
-	mockService := new(MockInvoiceService)
-	controller := NewInvoiceController(mockService)
+func (m *MockInvoiceService) PayInvoice(ctx context.Context, id int) (*models.Invoice, error) {
+	args := m.Called(ctx, id)
+	return args.Get(0).(*models.Invoice), args.Error(1)
+}
+
+func (m *MockInvoiceService) VoidInvoice(ctx context.Context, id int) (*models.Invoice, error) {
+	args := m.Called(ctx, id)
+	return args.Get(0).(*models.Invoice), args.Error(1)
+}
+
+func (m *MockInvoiceService) RefundInvoice(ctx context.Context, id int) (*models.Invoice, error) {
+	args := m.Called(ctx, id)
+	return args.Get(0).(*models.Invoice), args.Error(1)
+}
+
+// invoiceControllerApp monta el controlador con el ErrorHandler de la aplicación y un
+// administrador de facturación del tenant "acme" ya autenticado
+func invoiceControllerApp(service controllers.InvoiceService) *fiber.App {
+	controller := controllers.NewInvoiceController(service)
+
+	app := fiber.New(fiber.Config{ErrorHandler: helpers.ErrorHandler})
+	app.Use(func(c *fiber.Ctx) error {
+		c.Locals("tenant_id", "acme")
+		c.Locals("principal", &models.Principal{Subject: "root", TenantID: "acme", Roles: []string{policy.RoleBillingAdmin}})
+		c.SetUserContext(tenant.WithTenant(c.UserContext(), "acme"))
+		return c.Next()
+	})
+	app.Get("/invoices", controller.GetInvoices)
+	app.Post("/invoices", controller.CreateInvoice)
+	app.Get("/invoices/:id", controller.GetInvoice)
+
+	return app
+}
+
+func sendJSON(t *testing.T, app *fiber.App, method, path, body string) *http.Response {
+	req := httptest.NewRequest(method, path, strings.NewReader(body))
+	req.Header.Set(fiber.HeaderContentType, fiber.MIMEApplicationJSON)
+	resp, err := app.Test(req)
+	assert.NoError(t, err)
+	return resp
+}
 
-	assert.NotNil(t, controller)
-	assert.Equal(t, mockService, controller.service)
+func TestNewInvoiceController(t *testing.T) {
+	assert.NotNil(t, controllers.NewInvoiceController(new(MockInvoiceService)))
 }
 
 func TestInvoiceController_GetInvoices(t *testing.T) {
 	t.Run("Success", func(t *testing.T) {
 		mockService := new(MockInvoiceService)
-		controller := NewInvoiceController(mockService)
+		app := invoiceControllerApp(mockService)
 
 		// Configurar el mock
 		expectedUserID := 123
@@ -53,65 +107,46 @@ func TestInvoiceController_GetInvoices(t *testing.T) {
 			{ID: 2, UserID: expectedUserID, Amount: 200.75},
 		}
 
-		mockService.On("GetInvoicesByUserID", mock.Anything, expectedUserID).
-			Return(expectedInvoices, nil)
-
-		// Crear contexto de prueba
-		app := fiber.New()
-		ctx := app.AcquireCtx(&fiber.Ctx{})
-		defer app.ReleaseCtx(ctx)
-		ctx.Request().URI().SetQueryString("user_id=" + strconv.Itoa(expectedUserID))
+		mockService.On("ListInvoices", mock.Anything, models.InvoiceFilter{UserID: expectedUserID}, models.PageRequest{}).
+			Return(&models.InvoicePage{Invoices: expectedInvoices}, nil)
 
 		// Ejecutar
-		err := controller.GetInvoices(ctx)
+		resp := sendJSON(t, app, "GET", "/invoices?user_id="+strconv.Itoa(expectedUserID), "")
 
 		// Validar
-		assert.NoError(t, err)
-		assert.Equal(t, fiber.StatusOK, ctx.Response().StatusCode())
+		assert.Equal(t, fiber.StatusOK, resp.StatusCode)
 		mockService.AssertExpectations(t)
 	})
 
 	t.Run("InvalidUserID", func(t *testing.T) {
 		mockService := new(MockInvoiceService)
-		controller := NewInvoiceController(mockService)
-
-		// Crear contexto de prueba con user_id inválido
-		app := fiber.New()
-		ctx := app.AcquireCtx(&fiber.Ctx{})
-		defer app.ReleaseCtx(ctx)
-		ctx.Request().URI().SetQueryString("user_id=abc")
+		app := invoiceControllerApp(mockService)
 
-		// Ejecutar
-		err := controller.GetInvoices(ctx)
+		// Ejecutar con user_id inválido
+		resp := sendJSON(t, app, "GET", "/invoices?user_id=abc", "")
 
 		// Validar
-		assert.NoError(t, err)
-		assert.Equal(t, fiber.StatusBadRequest, ctx.Response().StatusCode())
-		mockService.AssertNotCalled(t, "GetInvoicesByUserID")
+		assert.Equal(t, fiber.StatusBadRequest, resp.StatusCode)
+		mockService.AssertNotCalled(t, "ListInvoices")
 	})
 
 	t.Run("ServiceError", func(t *testing.T) {
 		mockService := new(MockInvoiceService)
-		controller := NewInvoiceController(mockService)
+		app := invoiceControllerApp(mockService)
 
 		expectedUserID := 123
 		expectedError := errors.New("service error")
 
-		mockService.On("GetInvoicesByUserID", mock.Anything, expectedUserID).
-			Return([]models.Invoice{}, expectedError)
-
-		// Crear contexto de prueba
-		app := fiber.New()
-		ctx := app.AcquireCtx(&fiber.Ctx{})
-		defer app.ReleaseCtx(ctx)
-		ctx.Request().URI().SetQueryString("user_id=" + strconv.Itoa(expectedUserID))
+		mockService.On("ListInvoices", mock.Anything, models.InvoiceFilter{UserID: expectedUserID}, models.PageRequest{}).
+			Return((*models.InvoicePage)(nil), expectedError)
 
 		// Ejecutar
-		err := controller.GetInvoices(ctx)
+		resp := sendJSON(t, app, "GET", "/invoices?user_id="+strconv.Itoa(expectedUserID), "")
 
-		// Validar
-		assert.NoError(t, err)
-		assert.Equal(t, fiber.StatusInternalServerError, ctx.Response().StatusCode())
+		// Validar: el detalle del error interno no llega al cliente
+		body, _ := io.ReadAll(resp.Body)
+		assert.Equal(t, fiber.StatusInternalServerError, resp.StatusCode)
+		assert.NotContains(t, string(body), expectedError.Error())
 		mockService.AssertExpectations(t)
 	})
 }
@@ -119,84 +154,72 @@ func TestInvoiceController_GetInvoices(t *testing.T) {
 func TestInvoiceController_GetInvoice(t *testing.T) {
 	t.Run("Success", func(t *testing.T) {
 		mockService := new(MockInvoiceService)
-		controller := NewInvoiceController(mockService)
+		app := invoiceControllerApp(mockService)
 
 		expectedID := 1
 		expectedInvoice := &models.Invoice{
-			ID:     expectedID,
-			UserID: 123,
-			Amount: 100.50,
+			ID:       expectedID,
+			TenantID: "acme",
+			UserID:   123,
+			Amount:   100.50,
 		}
 
 		mockService.On("GetInvoiceByID", mock.Anything, expectedID).
 			Return(expectedInvoice, nil)
 
-		// Crear contexto de prueba
-		app := fiber.New()
-		ctx := app.AcquireCtx(&fiber.Ctx{})
-		defer app.ReleaseCtx(ctx)
-		ctx.SetParams("id", strconv.Itoa(expectedID))
-
 		// Ejecutar
-		err := controller.GetInvoice(ctx)
+		resp := sendJSON(t, app, "GET", "/invoices/"+strconv.Itoa(expectedID), "")
 
 		// Validar
-		assert.NoError(t, err)
-		assert.Equal(t, fiber.StatusOK, ctx.Response().StatusCode())
+		assert.Equal(t, fiber.StatusOK, resp.StatusCode)
 		mockService.AssertExpectations(t)
 	})
 
 	t.Run("InvalidInvoiceID", func(t *testing.T) {
 		mockService := new(MockInvoiceService)
-		controller := NewInvoiceController(mockService)
-
-		// Crear contexto de prueba con ID inválido
-		app := fiber.New()
-		ctx := app.AcquireCtx(&fiber.Ctx{})
-		defer app.ReleaseCtx(ctx)
-		ctx.SetParams("id", "abc")
+		app := invoiceControllerApp(mockService)
 
-		// Ejecutar
-		err := controller.GetInvoice(ctx)
+		// Ejecutar con ID inválido
+		resp := sendJSON(t, app, "GET", "/invoices/abc", "")
 
 		// Validar
-		assert.NoError(t, err)
-		assert.Equal(t, fiber.StatusBadRequest, ctx.Response().StatusCode())
+		assert.Equal(t, fiber.StatusBadRequest, resp.StatusCode)
 		mockService.AssertNotCalled(t, "GetInvoiceByID")
 	})
 
 	t.Run("NotFound", func(t *testing.T) {
 		mockService := new(MockInvoiceService)
-		controller := NewInvoiceController(mockService)
+		app := invoiceControllerApp(mockService)
 
 		expectedID := 999
 		mockService.On("GetInvoiceByID", mock.Anything, expectedID).
-			Return(&models.Invoice{}, errors.New("not found"))
-
-		// Crear contexto de prueba
-		app := fiber.New()
-		ctx := app.AcquireCtx(&fiber.Ctx{})
-		defer app.ReleaseCtx(ctx)
-		ctx.SetParams("id", strconv.Itoa(expectedID))
+			Return((*models.Invoice)(nil), sql.ErrNoRows)
 
 		// Ejecutar
-		err := controller.GetInvoice(ctx)
+		resp := sendJSON(t, app, "GET", "/invoices/"+strconv.Itoa(expectedID), "")
 
 		// Validar
-		assert.NoError(t, err)
-		assert.Equal(t, fiber.StatusNotFound, ctx.Response().StatusCode())
+		assert.Equal(t, fiber.StatusNotFound, resp.StatusCode)
 		mockService.AssertExpectations(t)
 	})
 }
 
 func TestInvoiceController_CreateInvoice(t *testing.T) {
+	body := `{
+		"user_id": 123,
+		"amount": 100.50,
+		"description": "Test invoice",
+		"payment_method": "credit_card"
+	}`
+
 	t.Run("Success", func(t *testing.T) {
 		mockService := new(MockInvoiceService)
-		controller := NewInvoiceController(mockService)
+		app := invoiceControllerApp(mockService)
 
 		req := &models.CreateInvoiceRequest{
 			UserID:        123,
 			Amount:        100.50,
+			Currency:      "USD",
 			Description:   "Test invoice",
 			PaymentMethod: "credit_card",
 		}
@@ -213,104 +236,55 @@ func TestInvoiceController_CreateInvoice(t *testing.T) {
 		mockService.On("CreateInvoice", mock.Anything, req).
 			Return(expectedInvoice, nil)
 
-		// Crear contexto de prueba con body
-		app := fiber.New()
-		ctx := app.AcquireCtx(&fiber.Ctx{})
-		defer app.ReleaseCtx(ctx)
-		ctx.Request().Header.SetContentType("application/json")
-		ctx.Request().SetBody([]byte(`{
-			"user_id": 123,
-			"amount": 100.50,
-			"description": "Test invoice",
-			"payment_method": "credit_card"
-		}`))
-
 		// Ejecutar
-		err := controller.CreateInvoice(ctx)
+		resp := sendJSON(t, app, "POST", "/invoices", body)
 
 		// Validar
-		assert.NoError(t, err)
-		assert.Equal(t, fiber.StatusCreated, ctx.Response().StatusCode())
+		assert.Equal(t, fiber.StatusCreated, resp.StatusCode)
 		mockService.AssertExpectations(t)
 	})
 
 	t.Run("InvalidBody", func(t *testing.T) {
 		mockService := new(MockInvoiceService)
-		controller := NewInvoiceController(mockService)
-
-		// Crear contexto de prueba con body inválido
-		app := fiber.New()
-		ctx := app.AcquireCtx(&fiber.Ctx{})
-		defer app.ReleaseCtx(ctx)
-		ctx.Request().Header.SetContentType("application/json")
-		ctx.Request().SetBody([]byte(`{ invalid json }`))
+		app := invoiceControllerApp(mockService)
 
-		// Ejecutar
-		err := controller.CreateInvoice(ctx)
+		// Ejecutar con body inválido
+		resp := sendJSON(t, app, "POST", "/invoices", `{ invalid json }`)
 
 		// Validar
-		assert.NoError(t, err)
-		assert.Equal(t, fiber.StatusBadRequest, ctx.Response().StatusCode())
+		assert.Equal(t, fiber.StatusBadRequest, resp.StatusCode)
 		mockService.AssertNotCalled(t, "CreateInvoice")
 	})
 
 	t.Run("MissingRequiredFields", func(t *testing.T) {
 		mockService := new(MockInvoiceService)
-		controller := NewInvoiceController(mockService)
-
-		// Crear contexto de prueba con campos faltantes
-		app := fiber.New()
-		ctx := app.AcquireCtx(&fiber.Ctx{})
-		defer app.ReleaseCtx(ctx)
-		ctx.Request().Header.SetContentType("application/json")
-		ctx.Request().SetBody([]byte(`{
+		app := invoiceControllerApp(mockService)
+
+		// Faltan description y payment_method
+		resp := sendJSON(t, app, "POST", "/invoices", `{
 			"user_id": 123,
 			"amount": 100.50
-		}`)) // Faltan description y payment_method
-
-		// Ejecutar
-		err := controller.CreateInvoice(ctx)
+		}`)
 
 		// Validar
-		assert.NoError(t, err)
-		assert.Equal(t, fiber.StatusBadRequest, ctx.Response().StatusCode())
+		assert.Equal(t, fiber.StatusBadRequest, resp.StatusCode)
 		mockService.AssertNotCalled(t, "CreateInvoice")
 	})
 
 	t.Run("ServiceError", func(t *testing.T) {
 		mockService := new(MockInvoiceService)
-		controller := NewInvoiceController(mockService)
-
-		req := &models.CreateInvoiceRequest{
-			UserID:        123,
-			Amount:        100.50,
-			Description:   "Test invoice",
-			PaymentMethod: "credit_card",
-		}
+		app := invoiceControllerApp(mockService)
 
 		expectedError := errors.New("service error")
 
-		mockService.On("CreateInvoice", mock.Anything, req).
-			Return(&models.Invoice{}, expectedError)
-
-		// Crear contexto de prueba
-		app := fiber.New()
-		ctx := app.AcquireCtx(&fiber.Ctx{})
-		defer app.ReleaseCtx(ctx)
-		ctx.Request().Header.SetContentType("application/json")
-		ctx.Request().SetBody([]byte(`{
-			"user_id": 123,
-			"amount": 100.50,
-			"description": "Test invoice",
-			"payment_method": "credit_card"
-		}`))
+		mockService.On("CreateInvoice", mock.Anything, mock.AnythingOfType("*models.CreateInvoiceRequest")).
+			Return((*models.Invoice)(nil), expectedError)
 
 		// Ejecutar
-		err := controller.CreateInvoice(ctx)
+		resp := sendJSON(t, app, "POST", "/invoices", body)
 
 		// Validar
-		assert.NoError(t, err)
-		assert.Equal(t, fiber.StatusInternalServerError, ctx.Response().StatusCode())
+		assert.Equal(t, fiber.StatusInternalServerError, resp.StatusCode)
 		mockService.AssertExpectations(t)
 	})
 }
@@ -0,0 +1,143 @@
+package tests
+
+import (
+	"testing"
+	"time"
+
+	"sass-billing-service/src/models"
+	"sass-billing-service/src/repositories"
+
+	"github.com/DATA-DOG/go-sqlmock"
+	"github.com/stretchr/testify/assert"
+)
+
+func invoiceRows(count int, start time.Time) *sqlmock.Rows {
+	rows := sqlmock.NewRows(invoiceColumns)
+	for i := 0; i < count; i++ {
+		created := start.Add(-time.Duration(i) * time.Hour)
+		rows.AddRow(100-i, "acme", "INV-000100", 7, "Ada", "ada@example.com", 10.0, 0.0, "USD", "Seat", "pending", "card", created, created, created)
+	}
+	return rows
+}
+
+func TestInvoiceList(t *testing.T) {
+	start := time.Date(2026, time.May, 10, 12, 0, 0, 0, time.UTC)
+
+	t.Run("FiltersAndNextCursor", func(t *testing.T) {
+		db, mock, err := sqlmock.New()
+		if err != nil {
+			t.Fatalf("an error '%s' was not expected when opening a stub database connection", err)
+		}
+		defer db.Close()
+
+		minAmount := 5.0
+		filter := models.InvoiceFilter{UserID: 7, Status: "pending", MinAmount: &minAmount, Currency: "USD"}
+
+		expectTenantScope(mock, "acme")
+		mock.ExpectQuery(`SELECT (.+) FROM invoices
+			WHERE tenant_id = \$1 AND user_id = \$2 AND status = \$3 AND amount >= \$4 AND currency = \$5
+			ORDER BY created_at DESC, id DESC
+			LIMIT \$6`).
+			WithArgs("acme", 7, "pending", 5.0, "USD", 3).
+			WillReturnRows(invoiceRows(3, start))
+		mock.ExpectCommit()
+
+		repo := repositories.NewInvoiceRepository(db)
+		page, err := repo.List(tenantContext("acme"), filter, models.PageRequest{Limit: 2})
+
+		assert.NoError(t, err)
+		assert.Len(t, page.Invoices, 2)
+		assert.NotEmpty(t, page.NextCursor)
+		assert.NoError(t, mock.ExpectationsWereMet())
+
+		// La página siguiente continúa justo después de la última factura devuelta
+		expectTenantScope(mock, "acme")
+		mock.ExpectQuery(`SELECT (.+) FROM invoices
+			WHERE tenant_id = \$1 AND user_id = \$2 AND status = \$3 AND amount >= \$4 AND currency = \$5
+			AND \(created_at, id\) < \(\$6::timestamptz, \$7\)
+			ORDER BY created_at DESC, id DESC
+			LIMIT \$8`).
+			WithArgs("acme", 7, "pending", 5.0, "USD", start.Add(-time.Hour).Format(time.RFC3339Nano), 99, 3).
+			WillReturnRows(invoiceRows(1, start.Add(-2*time.Hour)))
+		mock.ExpectCommit()
+
+		next, err := repo.List(tenantContext("acme"), filter, models.PageRequest{Limit: 2, Cursor: page.NextCursor})
+
+		assert.NoError(t, err)
+		assert.Len(t, next.Invoices, 1)
+		assert.Empty(t, next.NextCursor)
+		assert.NoError(t, mock.ExpectationsWereMet())
+	})
+
+	t.Run("AmountSortAndLimitCap", func(t *testing.T) {
+		db, mock, err := sqlmock.New()
+		if err != nil {
+			t.Fatalf("an error '%s' was not expected when opening a stub database connection", err)
+		}
+		defer db.Close()
+
+		expectTenantScope(mock, "acme")
+		mock.ExpectQuery(`ORDER BY amount ASC, id ASC\s+LIMIT \$2`).
+			WithArgs("acme", models.MaxPageSize+1).
+			WillReturnRows(invoiceRows(0, start))
+		mock.ExpectCommit()
+
+		repo := repositories.NewInvoiceRepository(db)
+		page, err := repo.List(tenantContext("acme"), models.InvoiceFilter{}, models.PageRequest{Limit: 1000, Sort: "amount"})
+
+		assert.NoError(t, err)
+		assert.Empty(t, page.Invoices)
+		assert.NoError(t, mock.ExpectationsWereMet())
+	})
+
+	t.Run("RejectsInvalidCursorAndSort", func(t *testing.T) {
+		db, mock, err := sqlmock.New()
+		if err != nil {
+			t.Fatalf("an error '%s' was not expected when opening a stub database connection", err)
+		}
+		defer db.Close()
+
+		repo := repositories.NewInvoiceRepository(db)
+		filter := models.InvoiceFilter{}
+
+		_, err = repo.List(tenantContext("acme"), filter, models.PageRequest{Cursor: "not-a-cursor"})
+		assert.ErrorIs(t, err, repositories.ErrInvalidCursor)
+
+		_, err = repo.List(tenantContext("acme"), filter, models.PageRequest{Sort: "description"})
+		assert.ErrorIs(t, err, repositories.ErrInvalidSort)
+
+		assert.NoError(t, mock.ExpectationsWereMet())
+	})
+}
+
+func TestInvoiceSearch(t *testing.T) {
+	db, mock, err := sqlmock.New()
+	if err != nil {
+		t.Fatalf("an error '%s' was not expected when opening a stub database connection", err)
+	}
+	defer db.Close()
+
+	created := time.Date(2026, time.March, 14, 0, 0, 0, 0, time.UTC)
+	columns := append(append([]string{}, invoiceColumns...), "rank", "highlight")
+
+	expectTenantScope(mock, "acme")
+	mock.ExpectQuery(`FROM invoices, websearch_to_tsquery\('english', \$2\) q
+		WHERE tenant_id = \$1 AND search_vector @@ q AND \(\$4::int = 0 OR user_id = \$4\)
+		ORDER BY rank DESC, id DESC`).
+		WithArgs("acme", "march pro upgrade", 20, 0).
+		WillReturnRows(sqlmock.NewRows(columns).
+			AddRow(42, "acme", "INV-000042", 7, "Ada", "ada@example.com", 49.0, 0.0, "USD", "March Pro upgrade", "paid", "card",
+				created, created, created, 0.8, "\x02March\x03 \x02Pro\x03 \x02upgrade\x03 <img src=x onerror=alert(1)>"))
+	mock.ExpectCommit()
+
+	repo := repositories.NewInvoiceRepository(db)
+	results, err := repo.Search(tenantContext("acme"), 0, "march pro upgrade", 20)
+
+	assert.NoError(t, err)
+	assert.Len(t, results, 1)
+	assert.Equal(t, "INV-000042", results[0].Invoice.InvoiceNumber)
+	assert.Equal(t, 0.8, results[0].Rank)
+	// Solo las coincidencias llegan como etiquetas; el texto del cliente se escapa
+	assert.Equal(t, "<mark>March</mark> <mark>Pro</mark> <mark>upgrade</mark> &lt;img src=x onerror=alert(1)&gt;", results[0].Highlight)
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
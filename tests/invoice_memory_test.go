@@ -0,0 +1,162 @@
+package tests
+
+import (
+	"encoding/json"
+	"io"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"sass-billing-service/src/controllers"
+	"sass-billing-service/src/helpers"
+	"sass-billing-service/src/models"
+	"sass-billing-service/src/policy"
+	"sass-billing-service/src/repositories"
+	router "sass-billing-service/src/routes"
+	"sass-billing-service/src/services"
+	"sass-billing-service/src/tenant"
+
+	"github.com/gofiber/fiber/v2"
+	"github.com/stretchr/testify/assert"
+)
+
+// invoiceEnvelope es el sobre utils.Response con las facturas ya decodificadas
+type invoiceEnvelope[T any] struct {
+	Success    bool   `json:"success"`
+	Data       T      `json:"data"`
+	NextCursor string `json:"next_cursor"`
+}
+
+// TestInvoiceAPIInMemory recorre la API de facturas de extremo a extremo con las rutas reales
+// y los repositorios en memoria, sin PostgreSQL
+func TestInvoiceAPIInMemory(t *testing.T) {
+	invoiceController := controllers.NewInvoiceController(services.NewInvoiceService(repositories.NewMemoryInvoiceRepository(repositories.NewMemoryDB()), repositories.NoTx{}))
+
+	// El tenant y el rol llegan en cabeceras en lugar de en un token
+	auth := func(c *fiber.Ctx) error {
+		principal := &models.Principal{Subject: "root", TenantID: c.Get("X-Tenant"), Roles: []string{policy.RoleBillingAdmin}}
+		c.Locals("tenant_id", principal.TenantID)
+		c.Locals("principal", principal)
+		c.SetUserContext(tenant.WithTenant(c.UserContext(), principal.TenantID))
+		return c.Next()
+	}
+	noop := func(c *fiber.Ctx) error { return c.Next() }
+	idempotency := helpers.IdempotencyMiddleware(repositories.NewMemoryIdempotencyRepository(), time.Hour)
+
+	app := fiber.New(fiber.Config{ErrorHandler: helpers.ErrorHandler})
+	router.SetupRoutes(app.Group("/api"), invoiceController, nil, nil, nil, nil, auth, noop, idempotency)
+
+	send := func(method, path, tenantID, body string, headers ...string) (int, []byte) {
+		req := httptest.NewRequest(method, path, strings.NewReader(body))
+		req.Header.Set(fiber.HeaderContentType, fiber.MIMEApplicationJSON)
+		req.Header.Set("X-Tenant", tenantID)
+		for i := 0; i+1 < len(headers); i += 2 {
+			req.Header.Set(headers[i], headers[i+1])
+		}
+		resp, err := app.Test(req)
+		assert.NoError(t, err)
+		raw, _ := io.ReadAll(resp.Body)
+		return resp.StatusCode, raw
+	}
+
+	create := `{"user_id": 7, "customer_name": "Ada", "amount": 121, "tax_amount": 21, "currency": "eur",
+		"description": "March Pro upgrade", "payment_method": "card",
+		"lines": [{"product": "pro", "amount": 100}]}`
+
+	status, first := send("POST", "/api/invoices", "acme", create, helpers.IdempotencyKeyHeader, "create-1")
+	assert.Equal(t, fiber.StatusCreated, status, string(first))
+
+	var created invoiceEnvelope[models.Invoice]
+	assert.NoError(t, json.Unmarshal(first, &created))
+	assert.Equal(t, "INV-000001", created.Data.InvoiceNumber)
+	assert.Equal(t, "EUR", created.Data.Currency)
+	assert.Equal(t, "pending", created.Data.Status)
+	assert.Len(t, created.Data.Lines, 1)
+
+	t.Run("IdempotentReplay", func(t *testing.T) {
+		status, replay := send("POST", "/api/invoices", "acme", create, helpers.IdempotencyKeyHeader, "create-1")
+		assert.Equal(t, fiber.StatusCreated, status)
+		assert.JSONEq(t, string(first), string(replay))
+	})
+
+	for _, body := range []string{
+		`{"user_id": 8, "amount": 50, "description": "Starter trial", "payment_method": "card"}`,
+		`{"user_id": 7, "amount": 300, "description": "Enterprise seats", "payment_method": "transfer"}`,
+	} {
+		status, raw := send("POST", "/api/invoices", "acme", body)
+		assert.Equal(t, fiber.StatusCreated, status, string(raw))
+	}
+	status, _ = send("POST", "/api/invoices", "globex", `{"user_id": 7, "amount": 10, "description": "Pro", "payment_method": "card"}`)
+	assert.Equal(t, fiber.StatusCreated, status)
+
+	t.Run("PaginatesWithCursor", func(t *testing.T) {
+		var ids []int
+		path := "/api/invoices?sort=amount&limit=2"
+		for path != "" {
+			status, raw := send("GET", path, "acme", "")
+			assert.Equal(t, fiber.StatusOK, status, string(raw))
+
+			var page invoiceEnvelope[[]models.Invoice]
+			assert.NoError(t, json.Unmarshal(raw, &page))
+			for _, invoice := range page.Data {
+				assert.Empty(t, invoice.Lines)
+				ids = append(ids, invoice.ID)
+			}
+
+			path = ""
+			if page.NextCursor != "" {
+				path = "/api/invoices?sort=amount&limit=2&cursor=" + page.NextCursor
+			}
+		}
+		assert.Equal(t, []int{2, 1, 3}, ids)
+
+		status, _ := send("GET", "/api/invoices?sort=-created_at&cursor=bogus", "acme", "")
+		assert.Equal(t, fiber.StatusBadRequest, status)
+	})
+
+	t.Run("StatusTransitions", func(t *testing.T) {
+		status, raw := send("POST", "/api/invoices/1/pay", "acme", "")
+		assert.Equal(t, fiber.StatusOK, status, string(raw))
+
+		status, raw = send("POST", "/api/invoices/1/void", "acme", "")
+		assert.Equal(t, fiber.StatusConflict, status)
+		assert.Contains(t, string(raw), `"code":"conflict"`)
+
+		status, _ = send("POST", "/api/invoices/1/refund", "acme", "")
+		assert.Equal(t, fiber.StatusOK, status)
+
+		status, raw = send("GET", "/api/invoices/1", "acme", "")
+		var invoice invoiceEnvelope[models.Invoice]
+		assert.NoError(t, json.Unmarshal(raw, &invoice))
+		assert.Equal(t, fiber.StatusOK, status)
+		assert.Equal(t, "refunded", invoice.Data.Status)
+		assert.Len(t, invoice.Data.Lines, 1)
+	})
+
+	t.Run("Search", func(t *testing.T) {
+		status, raw := send("GET", "/api/invoices/search?q=pro+-trial", "acme", "")
+		assert.Equal(t, fiber.StatusOK, status, string(raw))
+
+		var results invoiceEnvelope[[]models.InvoiceSearchResult]
+		assert.NoError(t, json.Unmarshal(raw, &results))
+		if assert.Len(t, results.Data, 1) {
+			assert.Equal(t, 1, results.Data[0].Invoice.ID)
+			assert.Contains(t, results.Data[0].Highlight, "<mark>Pro</mark>")
+		}
+	})
+
+	t.Run("TenantIsolation", func(t *testing.T) {
+		status, _ := send("GET", "/api/invoices/1", "globex", "")
+		assert.Equal(t, fiber.StatusNotFound, status)
+
+		status, _ = send("POST", "/api/invoices/2/void", "globex", "")
+		assert.Equal(t, fiber.StatusNotFound, status)
+
+		status, raw := send("GET", "/api/invoices", "globex", "")
+		var page invoiceEnvelope[[]models.Invoice]
+		assert.NoError(t, json.Unmarshal(raw, &page))
+		assert.Equal(t, fiber.StatusOK, status)
+		assert.Len(t, page.Data, 1)
+	})
+}
@@ -1,7 +1,6 @@
 package tests
 
 import (
-	"context"
 	"database/sql"
 	"errors"
 	"testing"
@@ -23,7 +22,7 @@ func TestNewInvoiceRepository(t *testing.T) {
 
 	repo := repositories.NewInvoiceRepository(db)
 	assert.NotNil(t, repo)
-	assert.Equal(t, db, repo.db)
+	assert.Equal(t, repositories.NewInvoiceRepository(db), repo)
 }
 
 func TestGetByID(t *testing.T) {
@@ -50,25 +49,37 @@ func TestGetByID(t *testing.T) {
 		}
 
 		// Set up expectations
-		rows := sqlmock.NewRows([]string{"id", "user_id", "amount", "description", "status", "payment_method", "created_at", "updated_at"}).
+		rows := sqlmock.NewRows([]string{"id", "tenant_id", "invoice_number", "user_id", "customer_name", "customer_email", "amount", "tax_amount", "currency", "description", "status", "payment_method", "due_date", "created_at", "updated_at"}).
 			AddRow(
 				expectedInvoice.ID,
+				expectedInvoice.TenantID,
+				expectedInvoice.InvoiceNumber,
 				expectedInvoice.UserID,
+				expectedInvoice.CustomerName,
+				expectedInvoice.CustomerEmail,
 				expectedInvoice.Amount,
+				expectedInvoice.TaxAmount,
+				expectedInvoice.Currency,
 				expectedInvoice.Description,
 				expectedInvoice.Status,
 				expectedInvoice.PaymentMethod,
+				expectedInvoice.DueDate,
 				expectedInvoice.CreatedAt,
 				expectedInvoice.UpdatedAt,
 			)
 
-		mock.ExpectQuery(`SELECT id, user_id, amount, description, status, payment_method, created_at, updated_at 
-			FROM invoices WHERE id = \$1`).
-			WithArgs(expectedID).
+		expectTenantScope(mock, models.DefaultTenantID)
+		mock.ExpectQuery(`SELECT id, tenant_id, invoice_number, user_id, customer_name, customer_email, amount, tax_amount, currency, description, status, payment_method, due_date, created_at, updated_at 
+			FROM invoices WHERE tenant_id = \$1 AND id = \$2`).
+			WithArgs(models.DefaultTenantID, expectedID).
 			WillReturnRows(rows)
+		mock.ExpectQuery(`SELECT (.+) FROM invoice_lines WHERE invoice_id = \$1`).
+			WithArgs(expectedID).
+			WillReturnRows(sqlmock.NewRows([]string{"id", "invoice_id", "product", "description", "amount", "service_start", "service_end"}))
+		mock.ExpectCommit()
 
 		// Execute
-		ctx := context.Background()
+		ctx := tenantContext(models.DefaultTenantID)
 		result, err := repo.GetByID(ctx, expectedID)
 
 		// Validate
@@ -87,12 +98,14 @@ func TestGetByID(t *testing.T) {
 		repo := repositories.NewInvoiceRepository(db)
 		expectedID := 999
 
-		mock.ExpectQuery(`SELECT id, user_id, amount, description, status, payment_method, created_at, updated_at 
-			FROM invoices WHERE id = \$1`).
-			WithArgs(expectedID).
+		expectTenantScope(mock, models.DefaultTenantID)
+		mock.ExpectQuery(`SELECT id, tenant_id, invoice_number, user_id, customer_name, customer_email, amount, tax_amount, currency, description, status, payment_method, due_date, created_at, updated_at 
+			FROM invoices WHERE tenant_id = \$1 AND id = \$2`).
+			WithArgs(models.DefaultTenantID, expectedID).
 			WillReturnError(sql.ErrNoRows)
+		mock.ExpectRollback()
 
-		ctx := context.Background()
+		ctx := tenantContext(models.DefaultTenantID)
 		result, err := repo.GetByID(ctx, expectedID)
 
 		assert.Nil(t, result)
@@ -112,12 +125,14 @@ func TestGetByID(t *testing.T) {
 		expectedID := 1
 		expectedError := errors.New("database error")
 
-		mock.ExpectQuery(`SELECT id, user_id, amount, description, status, payment_method, created_at, updated_at 
-			FROM invoices WHERE id = \$1`).
-			WithArgs(expectedID).
+		expectTenantScope(mock, models.DefaultTenantID)
+		mock.ExpectQuery(`SELECT id, tenant_id, invoice_number, user_id, customer_name, customer_email, amount, tax_amount, currency, description, status, payment_method, due_date, created_at, updated_at 
+			FROM invoices WHERE tenant_id = \$1 AND id = \$2`).
+			WithArgs(models.DefaultTenantID, expectedID).
 			WillReturnError(expectedError)
+		mock.ExpectRollback()
 
-		ctx := context.Background()
+		ctx := tenantContext(models.DefaultTenantID)
 		result, err := repo.GetByID(ctx, expectedID)
 
 		assert.Nil(t, result)
@@ -162,27 +177,36 @@ func TestGetByUserID(t *testing.T) {
 		}
 
 		// Set up expectations
-		rows := sqlmock.NewRows([]string{"id", "user_id", "amount", "description", "status", "payment_method", "created_at", "updated_at"})
+		rows := sqlmock.NewRows([]string{"id", "tenant_id", "invoice_number", "user_id", "customer_name", "customer_email", "amount", "tax_amount", "currency", "description", "status", "payment_method", "due_date", "created_at", "updated_at"})
 		for _, inv := range expectedInvoices {
 			rows.AddRow(
 				inv.ID,
+				inv.TenantID,
+				inv.InvoiceNumber,
 				inv.UserID,
+				inv.CustomerName,
+				inv.CustomerEmail,
 				inv.Amount,
+				inv.TaxAmount,
+				inv.Currency,
 				inv.Description,
 				inv.Status,
 				inv.PaymentMethod,
+				inv.DueDate,
 				inv.CreatedAt,
 				inv.UpdatedAt,
 			)
 		}
 
-		mock.ExpectQuery(`SELECT id, user_id, amount, description, status, payment_method, created_at, updated_at 
-			FROM invoices WHERE user_id = \$1`).
-			WithArgs(userID).
+		expectTenantScope(mock, models.DefaultTenantID)
+		mock.ExpectQuery(`SELECT id, tenant_id, invoice_number, user_id, customer_name, customer_email, amount, tax_amount, currency, description, status, payment_method, due_date, created_at, updated_at 
+			FROM invoices WHERE tenant_id = \$1 AND user_id = \$2`).
+			WithArgs(models.DefaultTenantID, userID).
 			WillReturnRows(rows)
+		mock.ExpectCommit()
 
 		// Execute
-		ctx := context.Background()
+		ctx := tenantContext(models.DefaultTenantID)
 		result, err := repo.GetByUserID(ctx, userID)
 
 		// Validate
@@ -202,15 +226,17 @@ func TestGetByUserID(t *testing.T) {
 		userID := 999
 
 		// Set up expectations
-		rows := sqlmock.NewRows([]string{"id", "user_id", "amount", "description", "status", "payment_method", "created_at", "updated_at"})
+		rows := sqlmock.NewRows([]string{"id", "tenant_id", "invoice_number", "user_id", "customer_name", "customer_email", "amount", "tax_amount", "currency", "description", "status", "payment_method", "due_date", "created_at", "updated_at"})
 
-		mock.ExpectQuery(`SELECT id, user_id, amount, description, status, payment_method, created_at, updated_at 
-			FROM invoices WHERE user_id = \$1`).
-			WithArgs(userID).
+		expectTenantScope(mock, models.DefaultTenantID)
+		mock.ExpectQuery(`SELECT id, tenant_id, invoice_number, user_id, customer_name, customer_email, amount, tax_amount, currency, description, status, payment_method, due_date, created_at, updated_at 
+			FROM invoices WHERE tenant_id = \$1 AND user_id = \$2`).
+			WithArgs(models.DefaultTenantID, userID).
 			WillReturnRows(rows)
+		mock.ExpectCommit()
 
 		// Execute
-		ctx := context.Background()
+		ctx := tenantContext(models.DefaultTenantID)
 		result, err := repo.GetByUserID(ctx, userID)
 
 		// Validate
@@ -230,12 +256,14 @@ func TestGetByUserID(t *testing.T) {
 		userID := 123
 		expectedError := errors.New("database error")
 
-		mock.ExpectQuery(`SELECT id, user_id, amount, description, status, payment_method, created_at, updated_at 
-			FROM invoices WHERE user_id = \$1`).
-			WithArgs(userID).
+		expectTenantScope(mock, models.DefaultTenantID)
+		mock.ExpectQuery(`SELECT id, tenant_id, invoice_number, user_id, customer_name, customer_email, amount, tax_amount, currency, description, status, payment_method, due_date, created_at, updated_at 
+			FROM invoices WHERE tenant_id = \$1 AND user_id = \$2`).
+			WithArgs(models.DefaultTenantID, userID).
 			WillReturnError(expectedError)
+		mock.ExpectRollback()
 
-		ctx := context.Background()
+		ctx := tenantContext(models.DefaultTenantID)
 		result, err := repo.GetByUserID(ctx, userID)
 
 		assert.Nil(t, result)
@@ -257,12 +285,14 @@ func TestGetByUserID(t *testing.T) {
 		rows := sqlmock.NewRows([]string{"id", "user_id", "amount"}).
 			AddRow(1, userID, 100.50)
 
-		mock.ExpectQuery(`SELECT id, user_id, amount, description, status, payment_method, created_at, updated_at 
-			FROM invoices WHERE user_id = \$1`).
-			WithArgs(userID).
+		expectTenantScope(mock, models.DefaultTenantID)
+		mock.ExpectQuery(`SELECT id, tenant_id, invoice_number, user_id, customer_name, customer_email, amount, tax_amount, currency, description, status, payment_method, due_date, created_at, updated_at 
+			FROM invoices WHERE tenant_id = \$1 AND user_id = \$2`).
+			WithArgs(models.DefaultTenantID, userID).
 			WillReturnRows(rows)
+		mock.ExpectRollback()
 
-		ctx := context.Background()
+		ctx := tenantContext(models.DefaultTenantID)
 		result, err := repo.GetByUserID(ctx, userID)
 
 		assert.Nil(t, result)
@@ -291,6 +321,7 @@ func TestCreate(t *testing.T) {
 
 		expectedInvoice := &models.Invoice{
 			ID:            1,
+			TenantID:      models.DefaultTenantID,
 			UserID:        request.UserID,
 			Amount:        request.Amount,
 			Description:   request.Description,
@@ -301,32 +332,56 @@ func TestCreate(t *testing.T) {
 		}
 
 		// Set up expectations
-		mock.ExpectQuery(`INSERT INTO invoices \(user_id, amount, description, status, payment_method, created_at, updated_at\)
-			VALUES \(\$1, \$2, \$3, 'pending', \$4, \$5, \$5\) 
-			RETURNING id, user_id, amount, description, status, payment_method, created_at, updated_at`).
+		expectTenantScope(mock, models.DefaultTenantID)
+		mock.ExpectQuery(`INSERT INTO invoices \(tenant_id, user_id, customer_name, customer_email, amount, tax_amount, currency, description, status, payment_method, due_date, created_at, updated_at\)
+			VALUES \(\$1, \$2, \$3, \$4, \$5, \$6, \$7, \$8, 'pending', \$9, \$10, \$11, \$11\) 
+			RETURNING id, tenant_id, invoice_number, user_id, customer_name, customer_email, amount, tax_amount, currency, description, status, payment_method, due_date, created_at, updated_at`).
 			WithArgs(
+				models.DefaultTenantID,
 				request.UserID,
+				request.CustomerName,
+				request.CustomerEmail,
 				request.Amount,
+				request.TaxAmount,
+				request.Currency,
 				request.Description,
 				request.PaymentMethod,
+				sqlmock.AnyArg(), // For due date
 				sqlmock.AnyArg(), // For timestamp
 			).
 			WillReturnRows(
-				sqlmock.NewRows([]string{"id", "user_id", "amount", "description", "status", "payment_method", "created_at", "updated_at"}).
+				sqlmock.NewRows([]string{"id", "tenant_id", "invoice_number", "user_id", "customer_name", "customer_email", "amount", "tax_amount", "currency", "description", "status", "payment_method", "due_date", "created_at", "updated_at"}).
 					AddRow(
 						expectedInvoice.ID,
+						expectedInvoice.TenantID,
+						expectedInvoice.InvoiceNumber,
 						expectedInvoice.UserID,
+						expectedInvoice.CustomerName,
+						expectedInvoice.CustomerEmail,
 						expectedInvoice.Amount,
+						expectedInvoice.TaxAmount,
+						expectedInvoice.Currency,
 						expectedInvoice.Description,
 						expectedInvoice.Status,
 						expectedInvoice.PaymentMethod,
+						expectedInvoice.DueDate,
 						expectedInvoice.CreatedAt,
 						expectedInvoice.UpdatedAt,
 					),
 			)
+		mock.ExpectQuery(`INSERT INTO journal_entries`).
+			WithArgs(models.DefaultTenantID, models.EntryInvoiceFinalized, "invoice", expectedInvoice.ID, sqlmock.AnyArg(), sqlmock.AnyArg()).
+			WillReturnRows(sqlmock.NewRows([]string{"id"}).AddRow(10))
+		mock.ExpectExec(`INSERT INTO journal_lines`).
+			WithArgs(10, models.AccountAccountsReceivable, int64(10050), int64(0)).
+			WillReturnResult(sqlmock.NewResult(1, 1))
+		mock.ExpectExec(`INSERT INTO journal_lines`).
+			WithArgs(10, models.AccountRevenue, int64(0), int64(10050)).
+			WillReturnResult(sqlmock.NewResult(2, 1))
+		mock.ExpectCommit()
 
 		// Execute
-		ctx := context.Background()
+		ctx := tenantContext(models.DefaultTenantID)
 		result, err := repo.Create(ctx, request)
 
 		// Validate
@@ -358,19 +413,27 @@ func TestCreate(t *testing.T) {
 
 		expectedError := errors.New("database error")
 
-		mock.ExpectQuery(`INSERT INTO invoices \(user_id, amount, description, status, payment_method, created_at, updated_at\)
-			VALUES \(\$1, \$2, \$3, 'pending', \$4, \$5, \$5\) 
-			RETURNING id, user_id, amount, description, status, payment_method, created_at, updated_at`).
+		expectTenantScope(mock, models.DefaultTenantID)
+		mock.ExpectQuery(`INSERT INTO invoices \(tenant_id, user_id, customer_name, customer_email, amount, tax_amount, currency, description, status, payment_method, due_date, created_at, updated_at\)
+			VALUES \(\$1, \$2, \$3, \$4, \$5, \$6, \$7, \$8, 'pending', \$9, \$10, \$11, \$11\) 
+			RETURNING id, tenant_id, invoice_number, user_id, customer_name, customer_email, amount, tax_amount, currency, description, status, payment_method, due_date, created_at, updated_at`).
 			WithArgs(
+				models.DefaultTenantID,
 				request.UserID,
+				request.CustomerName,
+				request.CustomerEmail,
 				request.Amount,
+				request.TaxAmount,
+				request.Currency,
 				request.Description,
 				request.PaymentMethod,
 				sqlmock.AnyArg(),
+				sqlmock.AnyArg(),
 			).
 			WillReturnError(expectedError)
+		mock.ExpectRollback()
 
-		ctx := context.Background()
+		ctx := tenantContext(models.DefaultTenantID)
 		result, err := repo.Create(ctx, request)
 
 		assert.Nil(t, result)
@@ -395,22 +458,30 @@ func TestCreate(t *testing.T) {
 		}
 
 		// Set up expectations with incomplete data
-		mock.ExpectQuery(`INSERT INTO invoices \(user_id, amount, description, status, payment_method, created_at, updated_at\)
-			VALUES \(\$1, \$2, \$3, 'pending', \$4, \$5, \$5\) 
-			RETURNING id, user_id, amount, description, status, payment_method, created_at, updated_at`).
+		expectTenantScope(mock, models.DefaultTenantID)
+		mock.ExpectQuery(`INSERT INTO invoices \(tenant_id, user_id, customer_name, customer_email, amount, tax_amount, currency, description, status, payment_method, due_date, created_at, updated_at\)
+			VALUES \(\$1, \$2, \$3, \$4, \$5, \$6, \$7, \$8, 'pending', \$9, \$10, \$11, \$11\) 
+			RETURNING id, tenant_id, invoice_number, user_id, customer_name, customer_email, amount, tax_amount, currency, description, status, payment_method, due_date, created_at, updated_at`).
 			WithArgs(
+				models.DefaultTenantID,
 				request.UserID,
+				request.CustomerName,
+				request.CustomerEmail,
 				request.Amount,
+				request.TaxAmount,
+				request.Currency,
 				request.Description,
 				request.PaymentMethod,
 				sqlmock.AnyArg(),
+				sqlmock.AnyArg(),
 			).
 			WillReturnRows(
 				sqlmock.NewRows([]string{"id", "user_id"}). // Missing columns
 										AddRow(1, request.UserID),
 			)
+		mock.ExpectRollback()
 
-		ctx := context.Background()
+		ctx := tenantContext(models.DefaultTenantID)
 		result, err := repo.Create(ctx, request)
 
 		assert.Nil(t, result)
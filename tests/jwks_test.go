@@ -0,0 +1,214 @@
+package tests
+
+import (
+	"context"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/rsa"
+	"encoding/base64"
+	"encoding/json"
+	"math/big"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"sass-billing-service/src/helpers"
+	"sass-billing-service/src/jwks"
+
+	"github.com/golang-jwt/jwt/v5"
+	"github.com/stretchr/testify/assert"
+)
+
+func encodeInt(value *big.Int) string {
+	return base64.RawURLEncoding.EncodeToString(value.Bytes())
+}
+
+func rsaJWK(kid string, key *rsa.PrivateKey) map[string]string {
+	return map[string]string{"kty": "RSA", "kid": kid, "use": "sig", "alg": "RS256",
+		"n": encodeInt(key.N), "e": encodeInt(big.NewInt(int64(key.E)))}
+}
+
+func ecJWK(kid string, key *ecdsa.PrivateKey) map[string]string {
+	return map[string]string{"kty": "EC", "kid": kid, "crv": "P-256",
+		"x": encodeInt(key.X), "y": encodeInt(key.Y)}
+}
+
+func writeJWKS(t *testing.T, path string, keys ...map[string]string) {
+	document, err := json.Marshal(map[string]interface{}{"keys": keys})
+	assert.NoError(t, err)
+	assert.NoError(t, os.WriteFile(path, document, 0o600))
+}
+
+func signToken(t *testing.T, method jwt.SigningMethod, kid string, key interface{}, claims jwt.MapClaims) string {
+	token := jwt.NewWithClaims(method, claims)
+	if kid != "" {
+		token.Header["kid"] = kid
+	}
+	signed, err := token.SignedString(key)
+	assert.NoError(t, err)
+	return signed
+}
+
+func TestJWTVerifierJWKS(t *testing.T) {
+	rsaKey, err := rsa.GenerateKey(rand.Reader, 2048)
+	assert.NoError(t, err)
+	ecKey, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	assert.NoError(t, err)
+
+	path := filepath.Join(t.TempDir(), "jwks.json")
+	// La clave Ed25519 no se admite y se ignora sin afectar a las demás
+	writeJWKS(t, path, rsaJWK("rsa-1", rsaKey), ecJWK("ec-1", ecKey), map[string]string{"kty": "OKP", "crv": "Ed25519", "kid": "ed-1", "x": "11qYAYKxCrfVS_7TyWQHOg7hcvPapiMlrwIaaPcHURo"})
+
+	verifier, err := helpers.NewJWTVerifier(helpers.JWTVerifierOptions{
+		KeySet:     jwks.NewKeySet(path, time.Hour, 0),
+		Algorithms: []string{"RS256", "ES256"},
+		Issuer:     "https://id.example.com",
+		Audience:   "billing",
+		ClockSkew:  30 * time.Second,
+	})
+	assert.NoError(t, err)
+
+	claims := func(overrides jwt.MapClaims) jwt.MapClaims {
+		base := jwt.MapClaims{"sub": "ada", "user_id": 7, "tenant_id": "acme", "roles": []string{"customer"},
+			"iss": "https://id.example.com", "aud": "billing", "exp": time.Now().Add(time.Hour).Unix()}
+		for k, v := range overrides {
+			base[k] = v
+		}
+		return base
+	}
+	ctx := context.Background()
+
+	t.Run("SelectsKeyByKid", func(t *testing.T) {
+		parsed, err := verifier.Verify(ctx, signToken(t, jwt.SigningMethodRS256, "rsa-1", rsaKey, claims(nil)))
+		assert.NoError(t, err)
+		assert.Equal(t, "ada", parsed.Subject)
+		assert.Equal(t, "acme", parsed.TenantID)
+
+		_, err = verifier.Verify(ctx, signToken(t, jwt.SigningMethodES256, "ec-1", ecKey, claims(nil)))
+		assert.NoError(t, err)
+
+		// La firma RSA no cuadra con la clave EC del kid anunciado
+		_, err = verifier.Verify(ctx, signToken(t, jwt.SigningMethodRS256, "ec-1", rsaKey, claims(nil)))
+		assert.Error(t, err)
+		_, err = verifier.Verify(ctx, signToken(t, jwt.SigningMethodRS256, "unknown", rsaKey, claims(nil)))
+		assert.ErrorContains(t, err, jwks.ErrKeyNotFound.Error())
+		_, err = verifier.Verify(ctx, signToken(t, jwt.SigningMethodRS256, "", rsaKey, claims(nil)))
+		assert.Error(t, err)
+	})
+
+	t.Run("EnforcesAllowedAlgorithms", func(t *testing.T) {
+		_, err := verifier.Verify(ctx, signToken(t, jwt.SigningMethodRS384, "rsa-1", rsaKey, claims(nil)))
+		assert.Error(t, err)
+		_, err = verifier.Verify(ctx, signToken(t, jwt.SigningMethodHS256, "rsa-1", []byte("secret"), claims(nil)))
+		assert.Error(t, err)
+		_, err = verifier.Verify(ctx, signToken(t, jwt.SigningMethodNone, "rsa-1", jwt.UnsafeAllowNoneSignatureType, claims(nil)))
+		assert.Error(t, err)
+
+		_, err = helpers.NewJWTVerifier(helpers.JWTVerifierOptions{KeySet: jwks.NewKeySet(path, time.Hour, 0), Algorithms: []string{"HS256"}})
+		assert.Error(t, err)
+	})
+
+	t.Run("ChecksIssuerAudienceAndClockSkew", func(t *testing.T) {
+		for _, override := range []jwt.MapClaims{
+			{"iss": "https://evil.example.com"},
+			{"aud": "other"},
+			{"exp": time.Now().Add(-time.Minute).Unix()},
+			{"nbf": time.Now().Add(time.Minute).Unix()},
+		} {
+			_, err := verifier.Verify(ctx, signToken(t, jwt.SigningMethodRS256, "rsa-1", rsaKey, claims(override)))
+			assert.Error(t, err, override)
+		}
+
+		for _, override := range []jwt.MapClaims{
+			{"exp": time.Now().Add(-10 * time.Second).Unix()},
+			{"nbf": time.Now().Add(10 * time.Second).Unix(), "iat": time.Now().Add(10 * time.Second).Unix()},
+		} {
+			_, err := verifier.Verify(ctx, signToken(t, jwt.SigningMethodRS256, "rsa-1", rsaKey, claims(override)))
+			assert.NoError(t, err, override)
+		}
+	})
+
+	t.Run("PicksUpRotatedKeys", func(t *testing.T) {
+		rotated, err := rsa.GenerateKey(rand.Reader, 2048)
+		assert.NoError(t, err)
+		writeJWKS(t, path, rsaJWK("rsa-2", rotated))
+
+		_, err = verifier.Verify(ctx, signToken(t, jwt.SigningMethodRS256, "rsa-2", rotated, claims(nil)))
+		assert.NoError(t, err)
+		_, err = verifier.Verify(ctx, signToken(t, jwt.SigningMethodRS256, "rsa-1", rsaKey, claims(nil)))
+		assert.Error(t, err)
+	})
+}
+
+func TestKeySetCachesRemoteDocument(t *testing.T) {
+	rsaKey, err := rsa.GenerateKey(rand.Reader, 2048)
+	assert.NoError(t, err)
+
+	fetches := 0
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		fetches++
+		json.NewEncoder(w).Encode(map[string]interface{}{"keys": []map[string]string{rsaJWK("rsa-1", rsaKey)}})
+	}))
+	defer server.Close()
+
+	keySet := jwks.NewKeySet(server.URL, time.Hour, time.Hour)
+	for i := 0; i < 3; i++ {
+		key, err := keySet.Key(context.Background(), "rsa-1")
+		assert.NoError(t, err)
+		assert.Equal(t, "RS256", key.Algorithm)
+	}
+
+	// Un kid desconocido no fuerza otra descarga dentro del intervalo mínimo
+	_, err = keySet.Key(context.Background(), "forged")
+	assert.ErrorIs(t, err, jwks.ErrKeyNotFound)
+	assert.Equal(t, 1, fetches)
+}
+
+func TestKeySetRefreshesOutsideTheLock(t *testing.T) {
+	rsaKey, err := rsa.GenerateKey(rand.Reader, 2048)
+	assert.NoError(t, err)
+
+	// A partir de la segunda descarga el proveedor no responde hasta que se libera
+	release := make(chan struct{})
+	var fetches atomic.Int32
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if fetches.Add(1) > 1 {
+			<-release
+		}
+		json.NewEncoder(w).Encode(map[string]interface{}{"keys": []map[string]string{rsaJWK("rsa-1", rsaKey)}})
+	}))
+	defer server.Close()
+	defer close(release)
+
+	keySet := jwks.NewKeySet(server.URL, time.Nanosecond, 0)
+	_, err = keySet.Key(context.Background(), "rsa-1")
+	assert.NoError(t, err)
+
+	// La caché caducada se sigue sirviendo mientras la recarga espera al proveedor
+	key, err := keySet.Key(context.Background(), "rsa-1")
+	assert.NoError(t, err)
+	assert.Equal(t, "rsa-1", key.ID)
+
+	// Quien espera un kid nuevo se rinde con su contexto sin cancelar la recarga de los demás
+	ctx, cancel := context.WithTimeout(context.Background(), 50*time.Millisecond)
+	defer cancel()
+	_, err = keySet.Key(ctx, "rsa-2")
+	assert.ErrorIs(t, err, context.DeadlineExceeded)
+	assert.Equal(t, int32(2), fetches.Load())
+}
+
+func TestJWTVerifierSecret(t *testing.T) {
+	verifier, err := helpers.NewJWTVerifier(helpers.JWTVerifierOptions{Secret: []byte("secret"), Algorithms: []string{"HS256"}})
+	assert.NoError(t, err)
+
+	claims := jwt.MapClaims{"sub": "ada", "exp": time.Now().Add(time.Hour).Unix()}
+	_, err = verifier.Verify(context.Background(), signToken(t, jwt.SigningMethodHS256, "", []byte("secret"), claims))
+	assert.NoError(t, err)
+	_, err = verifier.Verify(context.Background(), signToken(t, jwt.SigningMethodHS256, "", []byte("other"), claims))
+	assert.Error(t, err)
+}
@@ -0,0 +1,144 @@
+package tests
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"sass-billing-service/src/models"
+	"sass-billing-service/src/repositories"
+
+	"github.com/DATA-DOG/go-sqlmock"
+	"github.com/stretchr/testify/assert"
+)
+
+var invoiceColumns = []string{"id", "tenant_id", "invoice_number", "user_id", "customer_name", "customer_email", "amount", "tax_amount", "currency", "description", "status", "payment_method", "due_date", "created_at", "updated_at"}
+
+func TestJournalEntryBalanced(t *testing.T) {
+	balanced := &models.JournalEntry{Lines: []models.JournalLine{
+		{AccountCode: models.AccountAccountsReceivable, DebitCents: 12100},
+		{AccountCode: models.AccountRevenue, CreditCents: 10000},
+		{AccountCode: models.AccountTaxPayable, CreditCents: 2100},
+	}}
+	assert.True(t, balanced.Balanced())
+
+	unbalanced := &models.JournalEntry{Lines: []models.JournalLine{
+		{AccountCode: models.AccountCash, DebitCents: 100},
+		{AccountCode: models.AccountRevenue, CreditCents: 99},
+	}}
+	assert.False(t, unbalanced.Balanced())
+
+	empty := &models.JournalEntry{}
+	assert.False(t, empty.Balanced())
+}
+
+func TestPostEntryRejectsUnbalanced(t *testing.T) {
+	db, mock, err := sqlmock.New()
+	if err != nil {
+		t.Fatalf("an error '%s' was not expected when opening a stub database connection", err)
+	}
+	defer db.Close()
+
+	mock.ExpectBegin()
+	tx, err := db.Begin()
+	assert.NoError(t, err)
+
+	repo := repositories.NewLedgerRepository(db)
+	err = repo.PostEntry(context.Background(), tx, &models.JournalEntry{Lines: []models.JournalLine{
+		{AccountCode: models.AccountCash, DebitCents: 100},
+	}})
+
+	assert.ErrorIs(t, err, repositories.ErrUnbalancedEntry)
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
+
+func TestTrialBalance(t *testing.T) {
+	db, mock, err := sqlmock.New()
+	if err != nil {
+		t.Fatalf("an error '%s' was not expected when opening a stub database connection", err)
+	}
+	defer db.Close()
+
+	rows := sqlmock.NewRows([]string{"code", "name", "type", "debit", "credit"}).
+		AddRow(models.AccountCash, "Cash", "asset", int64(12100), int64(0)).
+		AddRow(models.AccountAccountsReceivable, "Accounts receivable", "asset", int64(12100), int64(12100)).
+		AddRow(models.AccountTaxPayable, "Tax payable", "liability", int64(0), int64(2100)).
+		AddRow(models.AccountRevenue, "Revenue", "revenue", int64(0), int64(10000))
+
+	expectTenantScope(mock, "acme")
+	mock.ExpectQuery(`SELECT a.code, a.name, a.type, (.+) AND e.tenant_id = \$1`).
+		WithArgs("acme").
+		WillReturnRows(rows)
+	mock.ExpectCommit()
+
+	repo := repositories.NewLedgerRepository(db)
+	balance, err := repo.TrialBalance(tenantContext("acme"))
+
+	assert.NoError(t, err)
+	assert.Len(t, balance.Accounts, 4)
+	assert.Equal(t, 242.0, balance.TotalDebit)
+	assert.Equal(t, 242.0, balance.TotalCredit)
+	assert.Equal(t, 0.0, balance.Balance)
+	assert.Equal(t, -100.0, balance.Accounts[3].Balance)
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
+
+func TestInvoiceStatusTransitions(t *testing.T) {
+	now := time.Now()
+
+	t.Run("PayPostsPaymentEntry", func(t *testing.T) {
+		db, mock, err := sqlmock.New()
+		if err != nil {
+			t.Fatalf("an error '%s' was not expected when opening a stub database connection", err)
+		}
+		defer db.Close()
+
+		expectTenantScope(mock, "default")
+		mock.ExpectQuery(`SELECT (.+) FROM invoices WHERE tenant_id = \$1 AND id = \$2 FOR UPDATE`).
+			WithArgs("default", 1).
+			WillReturnRows(sqlmock.NewRows(invoiceColumns).
+				AddRow(1, "default", "INV-000001", 123, "Ada Lovelace", "ada@example.com", 121.00, 21.00, "USD", "Pro plan", "pending", "credit_card", now, now, now))
+		mock.ExpectExec(`UPDATE invoices SET status = \$1`).
+			WithArgs("paid", sqlmock.AnyArg(), "default", 1).
+			WillReturnResult(sqlmock.NewResult(0, 1))
+		mock.ExpectQuery(`INSERT INTO journal_entries`).
+			WithArgs("default", models.EntryPayment, "invoice", 1, sqlmock.AnyArg(), sqlmock.AnyArg()).
+			WillReturnRows(sqlmock.NewRows([]string{"id"}).AddRow(7))
+		mock.ExpectExec(`INSERT INTO journal_lines`).
+			WithArgs(7, models.AccountCash, int64(12100), int64(0)).
+			WillReturnResult(sqlmock.NewResult(1, 1))
+		mock.ExpectExec(`INSERT INTO journal_lines`).
+			WithArgs(7, models.AccountAccountsReceivable, int64(0), int64(12100)).
+			WillReturnResult(sqlmock.NewResult(2, 1))
+		mock.ExpectCommit()
+
+		repo := repositories.NewInvoiceRepository(db)
+		invoice, err := repo.MarkPaid(tenantContext("default"), 1)
+
+		assert.NoError(t, err)
+		assert.Equal(t, "paid", invoice.Status)
+		assert.NoError(t, mock.ExpectationsWereMet())
+	})
+
+	t.Run("RefundRequiresPaidInvoice", func(t *testing.T) {
+		db, mock, err := sqlmock.New()
+		if err != nil {
+			t.Fatalf("an error '%s' was not expected when opening a stub database connection", err)
+		}
+		defer db.Close()
+
+		expectTenantScope(mock, "default")
+		mock.ExpectQuery(`SELECT (.+) FROM invoices WHERE tenant_id = \$1 AND id = \$2 FOR UPDATE`).
+			WithArgs("default", 1).
+			WillReturnRows(sqlmock.NewRows(invoiceColumns).
+				AddRow(1, "default", "INV-000001", 123, "Ada Lovelace", "ada@example.com", 121.00, 21.00, "USD", "Pro plan", "pending", "credit_card", now, now, now))
+		mock.ExpectRollback()
+
+		repo := repositories.NewInvoiceRepository(db)
+		invoice, err := repo.Refund(tenantContext("default"), 1)
+
+		assert.Nil(t, invoice)
+		assert.ErrorIs(t, err, repositories.ErrInvalidInvoiceStatus)
+		assert.NoError(t, mock.ExpectationsWereMet())
+	})
+}
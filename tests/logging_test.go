@@ -0,0 +1,77 @@
+package tests
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"log/slog"
+	"net/http/httptest"
+	"testing"
+
+	"sass-billing-service/src/helpers"
+	"sass-billing-service/src/logging"
+	"sass-billing-service/src/tenant"
+
+	"github.com/gofiber/fiber/v2"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestLoggerAddsContextAndRedacts(t *testing.T) {
+	var buf bytes.Buffer
+	logger, err := logging.New(&buf, "json", "debug")
+	assert.NoError(t, err)
+
+	ctx := logging.WithRequestID(context.Background(), "req-123")
+	ctx = logging.WithPrincipal(tenant.WithTenant(ctx, "tenant-a"), "user-1")
+	logger.InfoContext(ctx, "paying invoice",
+		"invoice_id", 42,
+		"token", "eyJhbGciOi",
+		"password", "hunter2",
+		slog.Group("payment", "card_number", "4242424242424242"),
+	)
+
+	var line map[string]any
+	assert.NoError(t, json.Unmarshal(buf.Bytes(), &line))
+	assert.Equal(t, "req-123", line["request_id"])
+	assert.Equal(t, "tenant-a", line["tenant_id"])
+	assert.Equal(t, "user-1", line["principal"])
+	assert.Equal(t, float64(42), line["invoice_id"])
+	assert.Equal(t, "[REDACTED]", line["token"])
+	assert.Equal(t, "[REDACTED]", line["password"])
+	assert.Equal(t, "[REDACTED]", line["payment"].(map[string]any)["card_number"])
+	assert.NotContains(t, buf.String(), "hunter2")
+
+	_, err = logging.New(&buf, "xml", "info")
+	assert.Error(t, err)
+}
+
+func TestRequestIDMiddleware(t *testing.T) {
+	app := fiber.New()
+	app.Use(helpers.RequestID())
+	app.Get("/", func(c *fiber.Ctx) error {
+		return c.SendString(logging.RequestID(c.UserContext()))
+	})
+
+	request := func(incoming string) (string, string) {
+		req := httptest.NewRequest("GET", "/", nil)
+		if incoming != "" {
+			req.Header.Set(helpers.RequestIDHeader, incoming)
+		}
+		resp, err := app.Test(req)
+		assert.NoError(t, err)
+
+		var body bytes.Buffer
+		body.ReadFrom(resp.Body)
+		return resp.Header.Get(helpers.RequestIDHeader), body.String()
+	}
+
+	header, fromContext := request("client-abc.1")
+	assert.Equal(t, "client-abc.1", header)
+	assert.Equal(t, header, fromContext)
+
+	for _, incoming := range []string{"", "bad id\nwith newline"} {
+		header, fromContext := request(incoming)
+		assert.Len(t, header, 32)
+		assert.Equal(t, header, fromContext)
+	}
+}
@@ -0,0 +1,187 @@
+package tests
+
+import (
+	"bufio"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"sass-billing-service/src/controllers"
+	"sass-billing-service/src/helpers"
+	"sass-billing-service/src/models"
+	"sass-billing-service/src/policy"
+	"sass-billing-service/src/repositories"
+	router "sass-billing-service/src/routes"
+	"sass-billing-service/src/services"
+	"sass-billing-service/src/tenant"
+
+	"github.com/gofiber/fiber/v2"
+	"github.com/stretchr/testify/assert"
+)
+
+// TestMemoryStore monta toda la API sobre una única MemoryDB, como serve --store=memory, y comprueba
+// que el libro mayor, los informes, la exportación y las API keys ven las mismas facturas
+func TestMemoryStore(t *testing.T) {
+	db := repositories.NewMemoryDB()
+	ledgerService := services.NewLedgerService(repositories.NewMemoryLedgerRepository(db))
+	revenueService := services.NewRevenueService(repositories.NewMemoryRevenueRepository(db))
+	apiKeyService := services.NewAPIKeyService(repositories.NewMemoryAPIKeyRepository(db))
+
+	auth := func(c *fiber.Ctx) error {
+		principal := &models.Principal{Subject: "root", TenantID: "acme", Roles: []string{policy.RoleBillingAdmin}}
+		c.Locals("tenant_id", principal.TenantID)
+		c.Locals("principal", principal)
+		c.SetUserContext(tenant.WithTenant(c.UserContext(), principal.TenantID))
+		return c.Next()
+	}
+	noop := func(c *fiber.Ctx) error { return c.Next() }
+
+	app := fiber.New(fiber.Config{ErrorHandler: helpers.ErrorHandler})
+	router.SetupRoutes(app.Group("/api"),
+		controllers.NewInvoiceController(services.NewInvoiceService(repositories.NewMemoryInvoiceRepository(db), repositories.NoTx{})),
+		controllers.NewLedgerController(ledgerService),
+		controllers.NewReportController(revenueService,
+			services.NewMetricsService(repositories.NewMemoryMetricsRepository(db), "USD"),
+			services.NewAgingService(repositories.NewMemoryAgingRepository(db))),
+		controllers.NewExportController(services.NewExportService(repositories.NewMemoryExportRepository(db))),
+		controllers.NewAPIKeyController(apiKeyService),
+		auth, noop, noop)
+
+	send := func(method, path, body string) (int, []byte) {
+		req := httptest.NewRequest(method, path, strings.NewReader(body))
+		req.Header.Set(fiber.HeaderContentType, fiber.MIMEApplicationJSON)
+		resp, err := app.Test(req)
+		assert.NoError(t, err)
+		raw, _ := io.ReadAll(resp.Body)
+		return resp.StatusCode, raw
+	}
+
+	// Una suscripción trimestral que empieza este mes y una factura suelta que queda pendiente
+	month := models.MonthStart(time.Now())
+	ctx := tenant.WithTenant(context.Background(), "acme")
+	status, raw := send("POST", "/api/invoices", fmt.Sprintf(`{"user_id": 7, "customer_name": "Ada", "amount": 300,
+		"currency": "usd", "description": "Pro quarterly", "payment_method": "card", "lines": [{"product": "pro", "amount": 300,
+		"service_start": %q, "service_end": %q}]}`, month.Format(time.RFC3339), month.AddDate(0, 3, -1).Format(time.RFC3339)))
+	assert.Equal(t, fiber.StatusCreated, status, string(raw))
+	status, raw = send("POST", "/api/invoices", `{"user_id": 8, "customer_name": "Grace", "amount": 50,
+		"currency": "usd", "description": "Onboarding", "payment_method": "card", "lines": [{"product": "setup", "amount": 50}]}`)
+	assert.Equal(t, fiber.StatusCreated, status, string(raw))
+	status, raw = send("POST", "/api/invoices/1/pay", "")
+	assert.Equal(t, fiber.StatusOK, status, string(raw))
+
+	t.Run("LedgerBalances", func(t *testing.T) {
+		status, raw := send("GET", "/api/ledger/trial-balance", "")
+		assert.Equal(t, fiber.StatusOK, status, string(raw))
+
+		var balance invoiceEnvelope[models.TrialBalance]
+		assert.NoError(t, json.Unmarshal(raw, &balance))
+		assert.Equal(t, 650.0, balance.Data.TotalDebit)
+		assert.Equal(t, 650.0, balance.Data.TotalCredit)
+		assert.Zero(t, balance.Data.Balance)
+
+		reconciliation, err := ledgerService.Reconcile(ctx)
+		assert.NoError(t, err)
+		assert.True(t, reconciliation.Clean(), reconciliation.Discrepancies)
+	})
+
+	t.Run("RevenueRecognition", func(t *testing.T) {
+		_, err := revenueService.CloseMonth(ctx, month)
+		assert.ErrorIs(t, err, tenant.ErrMissingTenant)
+
+		entries, err := revenueService.CloseMonth(tenant.WithSystem(context.Background()), month)
+		assert.NoError(t, err)
+		if assert.Len(t, entries, 1) {
+			assert.Equal(t, "acme", entries[0].TenantID)
+		}
+
+		period := month.Format("2006-01")
+		status, raw := send("GET", "/api/reports/revenue?from="+period+"&to="+period, "")
+		assert.Equal(t, fiber.StatusOK, status, string(raw))
+
+		var report invoiceEnvelope[models.RevenueReport]
+		assert.NoError(t, json.Unmarshal(raw, &report))
+		// La factura suelta se reconoce entera al emitirla; la trimestral, un tercio en el cierre
+		if assert.Len(t, report.Data.Months, 1) {
+			assert.Equal(t, 350.0, report.Data.Months[0].Recognized+report.Data.Months[0].Deferred)
+			assert.Greater(t, report.Data.Months[0].Recognized, 50.0)
+		}
+
+		reconciliation, err := ledgerService.Reconcile(ctx)
+		assert.NoError(t, err)
+		assert.True(t, reconciliation.Clean(), reconciliation.Discrepancies)
+	})
+
+	t.Run("MRR", func(t *testing.T) {
+		period := month.Format("2006-01")
+		status, raw := send("GET", "/api/reports/mrr?from="+period+"&to="+period, "")
+		assert.Equal(t, fiber.StatusOK, status, string(raw))
+		assert.Contains(t, string(raw), `"mrr":100`)
+	})
+
+	t.Run("Aging", func(t *testing.T) {
+		asOf := time.Now().AddDate(0, 0, 40).Format("2006-01-02")
+		status, raw := send("GET", "/api/reports/ar-aging?as_of="+asOf, "")
+		assert.Equal(t, fiber.StatusOK, status, string(raw))
+
+		var report invoiceEnvelope[models.AgingReport]
+		assert.NoError(t, json.Unmarshal(raw, &report))
+		if assert.Len(t, report.Data.Customers, 1) && assert.Len(t, report.Data.Totals, 1) {
+			assert.Equal(t, 8, report.Data.Customers[0].UserID)
+			assert.Equal(t, 50.0, report.Data.Customers[0].Days1To30)
+			assert.Equal(t, 50.0, report.Data.Totals[0].Total)
+		}
+	})
+
+	t.Run("Export", func(t *testing.T) {
+		status, raw := send("GET", "/api/invoices/export?format=jsonl&include=lines,payments", "")
+		assert.Equal(t, fiber.StatusOK, status, string(raw))
+
+		var rows []map[string]any
+		scanner := bufio.NewScanner(strings.NewReader(string(raw)))
+		for scanner.Scan() {
+			var row map[string]any
+			assert.NoError(t, json.Unmarshal(scanner.Bytes(), &row))
+			rows = append(rows, row)
+		}
+		assert.Len(t, rows, 2)
+		assert.Contains(t, string(raw), `"product":"pro"`)
+		assert.Contains(t, string(raw), `"type":"payment"`)
+	})
+
+	t.Run("APIKeys", func(t *testing.T) {
+		status, raw := send("POST", "/api/api-keys", `{"name": "ci", "scopes": ["invoices:read"]}`)
+		assert.Equal(t, fiber.StatusCreated, status, string(raw))
+
+		var issued invoiceEnvelope[models.IssuedAPIKey]
+		assert.NoError(t, json.Unmarshal(raw, &issued))
+		principal, err := apiKeyService.Authenticate(context.Background(), issued.Data.Key)
+		assert.NoError(t, err)
+		assert.Equal(t, "acme", principal.TenantID)
+
+		status, raw = send("POST", fmt.Sprintf("/api/api-keys/%d/rotate", issued.Data.ID), "")
+		assert.Equal(t, fiber.StatusCreated, status, string(raw))
+		var rotated invoiceEnvelope[models.IssuedAPIKey]
+		assert.NoError(t, json.Unmarshal(raw, &rotated))
+
+		_, err = apiKeyService.Authenticate(context.Background(), issued.Data.Key)
+		assert.Error(t, err)
+		_, err = apiKeyService.Authenticate(context.Background(), rotated.Data.Key)
+		assert.NoError(t, err)
+
+		status, _ = send("DELETE", fmt.Sprintf("/api/api-keys/%d", rotated.Data.ID), "")
+		assert.Equal(t, fiber.StatusOK, status)
+		status, _ = send("DELETE", fmt.Sprintf("/api/api-keys/%d", rotated.Data.ID), "")
+		assert.Equal(t, fiber.StatusNotFound, status)
+
+		status, raw = send("GET", "/api/api-keys", "")
+		var keys invoiceEnvelope[[]models.APIKey]
+		assert.NoError(t, json.Unmarshal(raw, &keys))
+		assert.Equal(t, fiber.StatusOK, status)
+		assert.Len(t, keys.Data, 2)
+	})
+}
@@ -0,0 +1,59 @@
+package tests
+
+import (
+	"io"
+	"net/http/httptest"
+	"testing"
+
+	"sass-billing-service/src/helpers"
+	"sass-billing-service/src/metrics"
+
+	"github.com/DATA-DOG/go-sqlmock"
+	"github.com/gofiber/fiber/v2"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestMetrics(t *testing.T) {
+	db, _, err := sqlmock.New()
+	if err != nil {
+		t.Fatalf("an error '%s' was not expected when opening a stub database connection", err)
+	}
+	defer db.Close()
+	assert.NoError(t, metrics.RegisterDB(db, "billing_test"))
+
+	app := fiber.New()
+	app.Use(metrics.Middleware(), helpers.HandleErrors())
+	app.Get("/metrics", metrics.Handler())
+	app.Get("/invoices/:id", func(c *fiber.Ctx) error {
+		metrics.InvoiceEvent(metrics.InvoicePaid)
+		metrics.PaymentFailure("Card", metrics.PaymentInvalidStatus)
+		metrics.PaymentFailure("Stripe-"+c.Params("id"), metrics.PaymentError)
+		metrics.DunningAttempt(metrics.DunningFailed)
+		return c.SendStatus(fiber.StatusOK)
+	})
+
+	for _, path := range []string{"/invoices/1", "/invoices/2", "/invoices/3", "/random/path/1", "/random/path/2"} {
+		_, err := app.Test(httptest.NewRequest("GET", path, nil))
+		assert.NoError(t, err)
+	}
+
+	resp, err := app.Test(httptest.NewRequest("GET", "/metrics", nil))
+	assert.NoError(t, err)
+	body, err := io.ReadAll(resp.Body)
+	assert.NoError(t, err)
+	exposition := string(body)
+
+	// Las series usan el patrón de la ruta, nunca el ID ni la URL de una ruta inexistente
+	assert.Contains(t, exposition, `billing_http_request_duration_seconds_count{method="GET",route="/invoices/:id",status="200"} 3`)
+	assert.Contains(t, exposition, `billing_http_request_duration_seconds_count{method="GET",route="unmatched",status="404"} 2`)
+	assert.NotContains(t, exposition, "/invoices/1")
+	assert.NotContains(t, exposition, "/random/path")
+
+	assert.Contains(t, exposition, `billing_invoice_events_total{event="paid"}`)
+	// La pasarela sale de un conjunto cerrado: un medio de pago desconocido cuenta como "other"
+	assert.Contains(t, exposition, `billing_payment_failures_total{gateway="card",reason="invalid_status"}`)
+	assert.Contains(t, exposition, `billing_payment_failures_total{gateway="other",reason="error"}`)
+	assert.NotContains(t, exposition, "Stripe-")
+	assert.Contains(t, exposition, `billing_dunning_attempts_total{outcome="failed"}`)
+	assert.Contains(t, exposition, `go_sql_open_connections{db_name="billing_test"}`)
+}
@@ -0,0 +1,183 @@
+package tests
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"testing"
+	"testing/fstest"
+	"time"
+
+	"sass-billing-service/src/migrations"
+
+	"github.com/DATA-DOG/go-sqlmock"
+	"github.com/stretchr/testify/assert"
+)
+
+var migrationFiles = fstest.MapFS{
+	"001_create_things.up.sql":   {Data: []byte("CREATE TABLE things (id INTEGER);")},
+	"001_create_things.down.sql": {Data: []byte("DROP TABLE things;")},
+	"002_add_name.up.sql":        {Data: []byte("ALTER TABLE things ADD COLUMN name TEXT;")},
+	"002_add_name.down.sql":      {Data: []byte("ALTER TABLE things DROP COLUMN name;")},
+	"003_add_index.up.sql":       {Data: []byte("CREATE INDEX idx_things_name ON things(name);")},
+}
+
+func migrationChecksum(name string) string {
+	sum := sha256.Sum256(migrationFiles[name].Data)
+	return hex.EncodeToString(sum[:])
+}
+
+// expectMigrationLock espera el lock, la tabla de control y la lectura de las migraciones aplicadas
+func expectMigrationLock(mock sqlmock.Sqlmock, applied *sqlmock.Rows) {
+	mock.ExpectExec(`SELECT pg_advisory_lock\(\$1\)`).WillReturnResult(sqlmock.NewResult(0, 0))
+	mock.ExpectExec(`CREATE TABLE IF NOT EXISTS schema_migrations`).WillReturnResult(sqlmock.NewResult(0, 0))
+	mock.ExpectQuery(`SELECT version, checksum, applied_at FROM schema_migrations`).WillReturnRows(applied)
+}
+
+func TestMigrationRunner(t *testing.T) {
+	appliedColumns := []string{"version", "checksum", "applied_at"}
+	ctx := context.Background()
+
+	t.Run("EmbeddedMigrationsAreReversible", func(t *testing.T) {
+		all, err := migrations.Load(migrations.Files)
+
+		assert.NoError(t, err)
+		assert.Len(t, all, 15)
+		for i, migration := range all {
+			assert.Equal(t, i+1, migration.Version)
+			assert.NotEmpty(t, migration.Down, migration.Name)
+		}
+	})
+
+	t.Run("UpAppliesPendingInOrderUnderLock", func(t *testing.T) {
+		db, mock, err := sqlmock.New()
+		if err != nil {
+			t.Fatalf("an error '%s' was not expected when opening a stub database connection", err)
+		}
+		defer db.Close()
+
+		runner, err := migrations.NewRunner(db, migrationFiles)
+		assert.NoError(t, err)
+
+		expectMigrationLock(mock, sqlmock.NewRows(appliedColumns).AddRow(1, migrationChecksum("001_create_things.up.sql"), time.Now()))
+		for _, file := range []string{"002_add_name.up.sql", "003_add_index.up.sql"} {
+			mock.ExpectBegin()
+			mock.ExpectExec(`ALTER TABLE things ADD COLUMN name TEXT;|CREATE INDEX idx_things_name`).WillReturnResult(sqlmock.NewResult(0, 0))
+			mock.ExpectExec(`INSERT INTO schema_migrations`).
+				WithArgs(sqlmock.AnyArg(), sqlmock.AnyArg(), migrationChecksum(file), sqlmock.AnyArg()).
+				WillReturnResult(sqlmock.NewResult(0, 1))
+			mock.ExpectCommit()
+		}
+		mock.ExpectExec(`SELECT pg_advisory_unlock\(\$1\)`).WillReturnResult(sqlmock.NewResult(0, 0))
+
+		applied, err := runner.Up(ctx)
+
+		assert.NoError(t, err)
+		assert.Len(t, applied, 2)
+		assert.Equal(t, 2, applied[0].Version)
+		assert.Equal(t, 3, applied[1].Version)
+		assert.NoError(t, mock.ExpectationsWereMet())
+	})
+
+	t.Run("ToRevertsNewerMigrations", func(t *testing.T) {
+		db, mock, err := sqlmock.New()
+		if err != nil {
+			t.Fatalf("an error '%s' was not expected when opening a stub database connection", err)
+		}
+		defer db.Close()
+
+		runner, err := migrations.NewRunner(db, migrationFiles)
+		assert.NoError(t, err)
+
+		expectMigrationLock(mock, sqlmock.NewRows(appliedColumns).
+			AddRow(1, migrationChecksum("001_create_things.up.sql"), time.Now()).
+			AddRow(2, migrationChecksum("002_add_name.up.sql"), time.Now()))
+		mock.ExpectBegin()
+		mock.ExpectExec(`ALTER TABLE things DROP COLUMN name;`).WillReturnResult(sqlmock.NewResult(0, 0))
+		mock.ExpectExec(`DELETE FROM schema_migrations WHERE version = \$1`).WithArgs(2).WillReturnResult(sqlmock.NewResult(0, 1))
+		mock.ExpectCommit()
+		mock.ExpectExec(`SELECT pg_advisory_unlock\(\$1\)`).WillReturnResult(sqlmock.NewResult(0, 0))
+
+		reverted, err := runner.To(ctx, 1)
+
+		assert.NoError(t, err)
+		assert.Len(t, reverted, 1)
+		assert.NoError(t, mock.ExpectationsWereMet())
+
+		_, err = runner.To(ctx, 7)
+		assert.ErrorIs(t, err, migrations.ErrUnknownVersion)
+	})
+
+	t.Run("BaselineRecordsWithoutRunning", func(t *testing.T) {
+		db, mock, err := sqlmock.New()
+		if err != nil {
+			t.Fatalf("an error '%s' was not expected when opening a stub database connection", err)
+		}
+		defer db.Close()
+
+		runner, err := migrations.NewRunner(db, migrationFiles)
+		assert.NoError(t, err)
+
+		// El esquema de 001 y 002 ya existe: solo se anotan, sin ejecutar su SQL
+		expectMigrationLock(mock, sqlmock.NewRows(appliedColumns))
+		mock.ExpectBegin()
+		for _, file := range []string{"001_create_things.up.sql", "002_add_name.up.sql"} {
+			mock.ExpectExec(`INSERT INTO schema_migrations`).
+				WithArgs(sqlmock.AnyArg(), sqlmock.AnyArg(), migrationChecksum(file), sqlmock.AnyArg()).
+				WillReturnResult(sqlmock.NewResult(0, 1))
+		}
+		mock.ExpectCommit()
+		mock.ExpectExec(`SELECT pg_advisory_unlock\(\$1\)`).WillReturnResult(sqlmock.NewResult(0, 0))
+
+		recorded, err := runner.Baseline(ctx, 2)
+
+		assert.NoError(t, err)
+		assert.Len(t, recorded, 2)
+		assert.NoError(t, mock.ExpectationsWereMet())
+
+		_, err = runner.Baseline(ctx, 7)
+		assert.ErrorIs(t, err, migrations.ErrUnknownVersion)
+	})
+
+	t.Run("RefusesModifiedAppliedMigration", func(t *testing.T) {
+		db, mock, err := sqlmock.New()
+		if err != nil {
+			t.Fatalf("an error '%s' was not expected when opening a stub database connection", err)
+		}
+		defer db.Close()
+
+		runner, err := migrations.NewRunner(db, migrationFiles)
+		assert.NoError(t, err)
+
+		expectMigrationLock(mock, sqlmock.NewRows(appliedColumns).AddRow(1, migrationChecksum("002_add_name.up.sql"), time.Now()))
+		mock.ExpectExec(`SELECT pg_advisory_unlock\(\$1\)`).WillReturnResult(sqlmock.NewResult(0, 0))
+
+		applied, err := runner.Up(ctx)
+
+		assert.ErrorIs(t, err, migrations.ErrChecksumMismatch)
+		assert.Empty(t, applied)
+		assert.NoError(t, mock.ExpectationsWereMet())
+	})
+
+	t.Run("DownNeedsDownFile", func(t *testing.T) {
+		db, mock, err := sqlmock.New()
+		if err != nil {
+			t.Fatalf("an error '%s' was not expected when opening a stub database connection", err)
+		}
+		defer db.Close()
+
+		runner, err := migrations.NewRunner(db, migrationFiles)
+		assert.NoError(t, err)
+
+		expectMigrationLock(mock, sqlmock.NewRows(appliedColumns).
+			AddRow(1, migrationChecksum("001_create_things.up.sql"), time.Now()).
+			AddRow(2, migrationChecksum("002_add_name.up.sql"), time.Now()).
+			AddRow(3, migrationChecksum("003_add_index.up.sql"), time.Now()))
+		mock.ExpectExec(`SELECT pg_advisory_unlock\(\$1\)`).WillReturnResult(sqlmock.NewResult(0, 0))
+
+		_, err = runner.Down(ctx, 1)
+
+		assert.ErrorIs(t, err, migrations.ErrIrreversible)
+		assert.NoError(t, mock.ExpectationsWereMet())
+	})
+}
@@ -0,0 +1,75 @@
+package tests
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+	"time"
+
+	"sass-billing-service/src/reports"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func month(year int, m time.Month) time.Time {
+	return time.Date(year, m, 1, 0, 0, 0, 0, time.UTC)
+}
+
+func TestComputeMRR(t *testing.T) {
+	history := []reports.CustomerMonth{
+		// Cliente 1: entra en enero y amplía en febrero
+		{UserID: 1, Month: month(2026, time.January), MRRCents: 10000},
+		{UserID: 1, Month: month(2026, time.February), MRRCents: 15000},
+		{UserID: 1, Month: month(2026, time.March), MRRCents: 15000},
+		// Cliente 2: reduce en febrero y se va en marzo
+		{UserID: 2, Month: month(2026, time.January), MRRCents: 20000},
+		{UserID: 2, Month: month(2026, time.February), MRRCents: 5000},
+		// Cliente 3: activo en 2025, vuelve en marzo
+		{UserID: 3, Month: month(2025, time.November), MRRCents: 3000},
+		{UserID: 3, Month: month(2026, time.March), MRRCents: 4000},
+	}
+
+	report := reports.ComputeMRR(history, month(2026, time.January), month(2026, time.March), "USD")
+
+	assert.Equal(t, "USD", report.Currency)
+	assert.Len(t, report.Months, 3)
+
+	jan := report.Months[0]
+	assert.Equal(t, "2026-01", jan.Month)
+	assert.Equal(t, 300.0, jan.MRR)
+	assert.Equal(t, 3600.0, jan.ARR)
+	assert.Equal(t, 300.0, jan.New)
+	assert.Equal(t, 0.0, jan.NetRevenueRetention)
+
+	feb := report.Months[1]
+	assert.Equal(t, 200.0, feb.MRR)
+	assert.Equal(t, 50.0, feb.Expansion)
+	assert.Equal(t, -150.0, feb.Contraction)
+	assert.Equal(t, -100.0, feb.NetNew)
+	assert.Equal(t, 0.6667, feb.NetRevenueRetention)
+
+	mar := report.Months[2]
+	assert.Equal(t, 190.0, mar.MRR)
+	assert.Equal(t, -50.0, mar.Churn)
+	assert.Equal(t, 40.0, mar.Reactivation)
+	assert.Equal(t, 0.0, mar.New)
+	assert.Equal(t, 1, mar.ChurnedCustomers)
+	assert.Equal(t, 2, mar.Customers)
+	assert.Equal(t, 0.5, mar.LogoChurnRate)
+	assert.Equal(t, 0.75, mar.NetRevenueRetention)
+}
+
+func TestWriteMRRCSV(t *testing.T) {
+	report := reports.ComputeMRR([]reports.CustomerMonth{
+		{UserID: 1, Month: month(2026, time.January), MRRCents: 9999},
+	}, month(2026, time.January), month(2026, time.February), "EUR")
+
+	var buf bytes.Buffer
+	assert.NoError(t, reports.WriteMRRCSV(&buf, report))
+
+	lines := strings.Split(strings.TrimSpace(buf.String()), "\n")
+	assert.Len(t, lines, 3)
+	assert.True(t, strings.HasPrefix(lines[0], "month,currency,mrr,arr"))
+	assert.True(t, strings.HasPrefix(lines[1], "2026-01,EUR,99.99,1199.88,99.99"))
+	assert.True(t, strings.HasPrefix(lines[2], "2026-02,EUR,0.00,0.00,0.00,0.00,0.00,-99.99"))
+}
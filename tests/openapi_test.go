@@ -0,0 +1,85 @@
+package tests
+
+import (
+	"encoding/json"
+	"io"
+	"net/http/httptest"
+	"regexp"
+	"strings"
+	"testing"
+
+	"sass-billing-service/src/openapi"
+	router "sass-billing-service/src/routes"
+
+	"github.com/gofiber/fiber/v2"
+	"github.com/stretchr/testify/assert"
+)
+
+type openAPIDocument struct {
+	OpenAPI string                                `json:"openapi"`
+	Paths   map[string]map[string]json.RawMessage `json:"paths"`
+}
+
+var routeParam = regexp.MustCompile(`:(\w+)`)
+
+func TestOpenAPICoversRegisteredRoutes(t *testing.T) {
+	var spec openAPIDocument
+	assert.NoError(t, json.Unmarshal(openapi.Spec, &spec))
+	assert.Equal(t, "3.1.0", spec.OpenAPI)
+
+	// Los controladores no se invocan: basta con registrar las rutas igual que main
+	noop := func(c *fiber.Ctx) error { return c.Next() }
+	app := fiber.New()
+	router.SetupHealthRoutes(app, nil)
+	router.SetupMetricsRoutes(app)
+	api := app.Group("/api")
+	router.SetupDocsRoutes(api)
+	router.SetupRoutes(api, nil, nil, nil, nil, nil, noop, noop, noop)
+
+	for _, route := range app.GetRoutes(true) {
+		if route.Method == fiber.MethodHead {
+			continue
+		}
+
+		// /api es el servidor por defecto de la especificación; las sondas y las métricas cuelgan de la raíz
+		path := strings.TrimSuffix(strings.TrimPrefix(route.Path, "/api"), "/")
+		path = routeParam.ReplaceAllString(path, "{$1}")
+
+		operations, ok := spec.Paths[path]
+		if !assert.True(t, ok, "route %s %s is missing from openapi.json", route.Method, route.Path) {
+			continue
+		}
+		assert.Contains(t, operations, strings.ToLower(route.Method), "operation %s %s is missing from openapi.json", route.Method, route.Path)
+	}
+}
+
+func TestOpenAPIServed(t *testing.T) {
+	app := fiber.New()
+	router.SetupDocsRoutes(app.Group("/api"))
+
+	resp, err := app.Test(httptest.NewRequest("GET", "/api/openapi.json", nil))
+	assert.NoError(t, err)
+	assert.Equal(t, fiber.StatusOK, resp.StatusCode)
+	assert.Equal(t, fiber.MIMEApplicationJSON, resp.Header.Get(fiber.HeaderContentType))
+
+	resp, err = app.Test(httptest.NewRequest("GET", "/api/docs", nil))
+	assert.NoError(t, err)
+	body, _ := io.ReadAll(resp.Body)
+	assert.Contains(t, string(body), `data-spec-url="openapi.json"`)
+	assert.Contains(t, string(body), `src="docs/assets/viewer.js"`)
+	// El visor no debe depender de nada fuera del servicio
+	assert.NotRegexp(t, `(src|href)="(https?:)?//`, string(body))
+
+	resp, err = app.Test(httptest.NewRequest("GET", "/api/docs/assets/viewer.js", nil))
+	assert.NoError(t, err)
+	assert.Equal(t, fiber.StatusOK, resp.StatusCode)
+	assert.Equal(t, "text/javascript; charset=utf-8", resp.Header.Get(fiber.HeaderContentType))
+
+	resp, err = app.Test(httptest.NewRequest("GET", "/api/docs/assets/viewer.css", nil))
+	assert.NoError(t, err)
+	assert.Equal(t, fiber.StatusOK, resp.StatusCode)
+
+	resp, err = app.Test(httptest.NewRequest("GET", "/api/docs/assets/missing.js", nil))
+	assert.NoError(t, err)
+	assert.Equal(t, fiber.StatusNotFound, resp.StatusCode)
+}
@@ -0,0 +1,70 @@
+package tests
+
+import (
+	"net/http/httptest"
+	"testing"
+
+	"sass-billing-service/src/helpers"
+	"sass-billing-service/src/models"
+	"sass-billing-service/src/policy"
+
+	"github.com/gofiber/fiber/v2"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestPolicy(t *testing.T) {
+	customer := &models.Principal{Subject: "ada", UserID: 7, TenantID: "acme", Roles: []string{policy.RoleCustomer}}
+	support := &models.Principal{Subject: "bob", TenantID: "acme", Roles: []string{policy.RoleSupport}}
+	finance := &models.Principal{Subject: "eve", TenantID: "acme", Roles: []string{policy.RoleFinanceReadonly}}
+	admin := &models.Principal{Subject: "root", TenantID: "acme", Roles: []string{policy.RoleBillingAdmin}}
+
+	t.Run("Permissions by role", func(t *testing.T) {
+		assert.True(t, policy.Can(customer, policy.InvoicesPay))
+		assert.False(t, policy.Can(customer, policy.InvoicesCreate))
+		assert.False(t, policy.Can(support, policy.InvoicesRefund))
+		assert.True(t, policy.Can(finance, policy.ReportsRead))
+		assert.False(t, policy.Can(finance, policy.InvoicesVoid))
+		assert.True(t, policy.Can(admin, policy.InvoicesRefund))
+		assert.False(t, policy.Can(nil, policy.InvoicesRead))
+		assert.False(t, policy.Can(&models.Principal{Roles: []string{"unknown"}}, policy.InvoicesRead))
+	})
+
+	t.Run("Invoice ownership", func(t *testing.T) {
+		own := &models.Invoice{ID: 1, TenantID: "acme", UserID: 7}
+		other := &models.Invoice{ID: 2, TenantID: "acme", UserID: 8}
+		foreign := &models.Invoice{ID: 3, TenantID: "globex", UserID: 7}
+
+		assert.True(t, policy.CanAccessInvoice(customer, policy.InvoicesRead, own))
+		assert.False(t, policy.CanAccessInvoice(customer, policy.InvoicesRead, other))
+		assert.False(t, policy.CanAccessInvoice(customer, policy.InvoicesVoid, own))
+		assert.True(t, policy.CanAccessInvoice(support, policy.InvoicesRead, other))
+		assert.False(t, policy.CanAccessInvoice(admin, policy.InvoicesRead, foreign))
+	})
+
+	t.Run("RequirePermission middleware", func(t *testing.T) {
+		app := fiber.New(fiber.Config{ErrorHandler: helpers.ErrorHandler})
+		app.Use(func(c *fiber.Ctx) error {
+			if c.Get("X-Role") != "" {
+				c.Locals("principal", &models.Principal{TenantID: "acme", Roles: []string{c.Get("X-Role")}})
+			}
+			return c.Next()
+		})
+		app.Get("/reports", helpers.RequirePermission(policy.ReportsRead), func(c *fiber.Ctx) error {
+			return c.SendStatus(fiber.StatusOK)
+		})
+
+		for role, status := range map[string]int{
+			policy.RoleFinanceReadonly: fiber.StatusOK,
+			policy.RoleBillingAdmin:    fiber.StatusOK,
+			policy.RoleCustomer:        fiber.StatusForbidden,
+			policy.RoleSupport:         fiber.StatusForbidden,
+			"":                         fiber.StatusForbidden,
+		} {
+			req := httptest.NewRequest("GET", "/reports", nil)
+			req.Header.Set("X-Role", role)
+			resp, err := app.Test(req)
+			assert.NoError(t, err)
+			assert.Equal(t, status, resp.StatusCode, role)
+		}
+	})
+}
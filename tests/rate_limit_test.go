@@ -0,0 +1,142 @@
+package tests
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"sass-billing-service/src/helpers"
+	"sass-billing-service/src/models"
+	"sass-billing-service/src/ratelimit"
+	"sass-billing-service/src/repositories"
+
+	"github.com/DATA-DOG/go-sqlmock"
+	"github.com/gofiber/fiber/v2"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestTokenBucket(t *testing.T) {
+	limit, err := ratelimit.ParseLimit("2/m")
+	assert.NoError(t, err)
+	assert.Equal(t, ratelimit.Limit{Requests: 2, Per: time.Minute}, limit)
+
+	for _, invalid := range []string{"", "2", "0/m", "2/d", "x/s"} {
+		_, err := ratelimit.ParseLimit(invalid)
+		assert.Error(t, err, invalid)
+	}
+
+	start := time.Now()
+	bucket := ratelimit.NewBucket(limit, start)
+	assert.True(t, bucket.Take(limit, start).Allowed)
+	assert.True(t, bucket.Take(limit, start).Allowed)
+
+	denied := bucket.Take(limit, start)
+	assert.False(t, denied.Allowed)
+	assert.Equal(t, 0, denied.Remaining)
+	assert.Equal(t, 30*time.Second, denied.RetryAfter)
+	assert.Equal(t, time.Minute, denied.Reset)
+
+	// Se repone un token cada 30 segundos
+	assert.True(t, bucket.Take(limit, start.Add(30*time.Second)).Allowed)
+	assert.False(t, bucket.Take(limit, start.Add(30*time.Second)).Allowed)
+}
+
+func TestRateLimitMiddleware(t *testing.T) {
+	policy := ratelimit.Policy{
+		Default: ratelimit.Limit{Requests: 100, Per: time.Minute},
+		Routes:  map[string]ratelimit.Limit{"GET /api/invoices": {Requests: 2, Per: time.Minute}},
+		Tenant:  ratelimit.Limit{Requests: 100, Per: time.Minute},
+		Tenants: map[string]ratelimit.Limit{"globex": {Requests: 3, Per: time.Minute}},
+	}
+
+	app := fiber.New(fiber.Config{ErrorHandler: helpers.ErrorHandler})
+	authenticate := func(c *fiber.Ctx) error {
+		principal := &models.Principal{Subject: c.Get("X-Subject"), TenantID: c.Get("X-Tenant")}
+		c.Locals("principal", principal)
+		c.Locals("tenant_id", principal.TenantID)
+		return c.Next()
+	}
+	rateLimit := helpers.RateLimitMiddleware(ratelimit.NewMemoryStore(), policy)
+	ok := func(c *fiber.Ctx) error { return c.SendStatus(fiber.StatusOK) }
+	invoices := app.Group("/api/invoices")
+	invoices.Get("/", authenticate, rateLimit, ok)
+	invoices.Get("/:id", authenticate, rateLimit, ok)
+
+	call := func(path, tenantID, subject string) *http.Response {
+		req := httptest.NewRequest("GET", path, nil)
+		req.Header.Set("X-Tenant", tenantID)
+		req.Header.Set("X-Subject", subject)
+		resp, err := app.Test(req)
+		assert.NoError(t, err)
+		return resp
+	}
+
+	t.Run("PerRouteLimit", func(t *testing.T) {
+		first := call("/api/invoices", "acme", "user-1")
+		assert.Equal(t, fiber.StatusOK, first.StatusCode)
+		assert.Equal(t, "2", first.Header.Get("RateLimit-Limit"))
+		assert.Equal(t, "1", first.Header.Get("RateLimit-Remaining"))
+
+		assert.Equal(t, fiber.StatusOK, call("/api/invoices", "acme", "user-1").StatusCode)
+
+		limited := call("/api/invoices", "acme", "user-1")
+		assert.Equal(t, fiber.StatusTooManyRequests, limited.StatusCode)
+		assert.Equal(t, "0", limited.Header.Get("RateLimit-Remaining"))
+		assert.Equal(t, "30", limited.Header.Get("Retry-After"))
+
+		// Otro principal y otra ruta tienen sus propios buckets
+		assert.Equal(t, fiber.StatusOK, call("/api/invoices", "acme", "user-2").StatusCode)
+		assert.Equal(t, fiber.StatusOK, call("/api/invoices/1", "acme", "user-1").StatusCode)
+	})
+
+	t.Run("TenantOverride", func(t *testing.T) {
+		for _, subject := range []string{"user-1", "user-2", "user-3"} {
+			assert.Equal(t, fiber.StatusOK, call("/api/invoices/1", "globex", subject).StatusCode)
+		}
+
+		limited := call("/api/invoices/1", "globex", "user-4")
+		assert.Equal(t, fiber.StatusTooManyRequests, limited.StatusCode)
+		assert.Equal(t, "3", limited.Header.Get("RateLimit-Limit"))
+		assert.Equal(t, "20", limited.Header.Get("Retry-After"))
+	})
+}
+
+func TestRateLimitRepository(t *testing.T) {
+	db, mock, err := sqlmock.New()
+	if err != nil {
+		t.Fatalf("an error '%s' was not expected when opening a stub database connection", err)
+	}
+	defer db.Close()
+
+	limit := ratelimit.Limit{Requests: 2, Per: time.Minute}
+	repo := repositories.NewRateLimitRepository(db)
+	takeQuery := `INSERT INTO rate_limit_buckets AS b (.+) ON CONFLICT \(bucket_key\) DO UPDATE SET tokens = (.+) - 1, (.+) WHERE (.+) >= 1 RETURNING tokens`
+
+	// La reposición y el consumo se hacen en SQL, en una sola sentencia y sin transacción explícita
+	mock.ExpectQuery(takeQuery).
+		WithArgs("tenant:acme", 2, limit.Rate()).
+		WillReturnRows(sqlmock.NewRows([]string{"allowed", "tokens"}).AddRow(true, 0.0))
+
+	result, err := repo.Take(context.Background(), "tenant:acme", limit)
+
+	assert.NoError(t, err)
+	assert.True(t, result.Allowed)
+	assert.Equal(t, 0, result.Remaining)
+	assert.Equal(t, time.Minute, result.Reset)
+
+	// Sin token la fila no cambia y se informa de cuándo habrá uno: a 2/m falta medio token, 15s
+	mock.ExpectQuery(takeQuery).
+		WithArgs("tenant:acme", 2, limit.Rate()).
+		WillReturnRows(sqlmock.NewRows([]string{"allowed", "tokens"}).AddRow(false, 0.5))
+
+	result, err = repo.Take(context.Background(), "tenant:acme", limit)
+
+	assert.NoError(t, err)
+	assert.False(t, result.Allowed)
+	assert.Equal(t, 0, result.Remaining)
+	assert.Equal(t, 15*time.Second, result.RetryAfter)
+
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
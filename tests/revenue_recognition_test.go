@@ -0,0 +1,151 @@
+package tests
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"sass-billing-service/src/models"
+	"sass-billing-service/src/repositories"
+	"sass-billing-service/src/tenant"
+
+	"github.com/DATA-DOG/go-sqlmock"
+	"github.com/stretchr/testify/assert"
+)
+
+func date(year int, month time.Month, day int) *time.Time {
+	d := time.Date(year, month, day, 0, 0, 0, 0, time.UTC)
+	return &d
+}
+
+func TestRecognitionSchedule(t *testing.T) {
+	t.Run("AnnualLineSpreadsAcrossTwelveMonths", func(t *testing.T) {
+		line := &models.InvoiceLine{
+			Product:      "pro",
+			Amount:       1200.00,
+			ServiceStart: date(2026, time.January, 1),
+			ServiceEnd:   date(2026, time.December, 31),
+		}
+
+		schedule := line.RecognitionSchedule(time.Date(2026, time.January, 1, 10, 0, 0, 0, time.UTC))
+
+		assert.Len(t, schedule, 12)
+		var total int64
+		for _, entry := range schedule {
+			assert.Equal(t, models.ScheduleScheduled, entry.Status)
+			assert.Equal(t, "pro", entry.Product)
+			total += entry.AmountCents
+		}
+		assert.Equal(t, int64(120000), total)
+		assert.Equal(t, int64(120000*31/365), schedule[0].AmountCents)
+		assert.Equal(t, *date(2026, time.December, 1), schedule[11].Period)
+	})
+
+	t.Run("PartialMonthsAreProrated", func(t *testing.T) {
+		line := &models.InvoiceLine{
+			Product:      "seats",
+			Amount:       100.00,
+			ServiceStart: date(2026, time.January, 17),
+			ServiceEnd:   date(2026, time.February, 15),
+		}
+
+		schedule := line.RecognitionSchedule(time.Now())
+
+		assert.Len(t, schedule, 2)
+		assert.Equal(t, int64(5000), schedule[0].AmountCents)
+		assert.Equal(t, int64(5000), schedule[1].AmountCents)
+	})
+
+	t.Run("LineWithoutPeriodIsRecognizedImmediately", func(t *testing.T) {
+		line := &models.InvoiceLine{Product: "setup", Amount: 49.99}
+
+		schedule := line.RecognitionSchedule(time.Date(2026, time.March, 20, 0, 0, 0, 0, time.UTC))
+
+		assert.Len(t, schedule, 1)
+		assert.Equal(t, models.ScheduleRecognized, schedule[0].Status)
+		assert.Equal(t, int64(4999), schedule[0].AmountCents)
+		assert.Equal(t, *date(2026, time.March, 1), schedule[0].Period)
+	})
+}
+
+func TestRecognizeThrough(t *testing.T) {
+	db, mock, err := sqlmock.New()
+	if err != nil {
+		t.Fatalf("an error '%s' was not expected when opening a stub database connection", err)
+	}
+	defer db.Close()
+
+	mock.ExpectBegin()
+	mock.ExpectExec(`SET LOCAL ROLE billing_system`).
+		WillReturnResult(sqlmock.NewResult(0, 0))
+	mock.ExpectQuery(`SELECT tenant_id, period, amount_cents FROM revenue_schedules`).
+		WithArgs(*date(2026, time.February, 1)).
+		WillReturnRows(sqlmock.NewRows([]string{"tenant_id", "period", "amount_cents"}).
+			AddRow("acme", *date(2026, time.February, 1), int64(1000)).
+			AddRow("acme", *date(2026, time.January, 1), int64(1000)).
+			AddRow("globex", *date(2026, time.January, 1), int64(300)).
+			AddRow("acme", *date(2026, time.January, 1), int64(500)))
+
+	// Un asiento por tenant y mes: nunca se mezclan importes de tenants distintos
+	for i, expected := range []struct {
+		tenantID string
+		period   time.Time
+		amount   int64
+	}{
+		{"acme", *date(2026, time.January, 1), 1500},
+		{"globex", *date(2026, time.January, 1), 300},
+		{"acme", *date(2026, time.February, 1), 1000},
+	} {
+		entryID := 20 + i
+		mock.ExpectQuery(`INSERT INTO journal_entries`).
+			WithArgs(expected.tenantID, models.EntryRevenueRecognition, "revenue_period", expected.period.Year()*100+int(expected.period.Month()), sqlmock.AnyArg(), sqlmock.AnyArg()).
+			WillReturnRows(sqlmock.NewRows([]string{"id"}).AddRow(entryID))
+		mock.ExpectExec(`INSERT INTO journal_lines`).
+			WithArgs(entryID, models.AccountDeferredRevenue, expected.amount, int64(0)).
+			WillReturnResult(sqlmock.NewResult(1, 1))
+		mock.ExpectExec(`INSERT INTO journal_lines`).
+			WithArgs(entryID, models.AccountRevenue, int64(0), expected.amount).
+			WillReturnResult(sqlmock.NewResult(2, 1))
+		mock.ExpectExec(`UPDATE revenue_schedules`).
+			WithArgs(sqlmock.AnyArg(), entryID, expected.tenantID, expected.period).
+			WillReturnResult(sqlmock.NewResult(0, 1))
+	}
+	mock.ExpectCommit()
+
+	repo := repositories.NewRevenueRepository(db)
+	entries, err := repo.RecognizeThrough(tenant.WithSystem(context.Background()), time.Date(2026, time.February, 28, 0, 0, 0, 0, time.UTC))
+
+	assert.NoError(t, err)
+	assert.Len(t, entries, 3)
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
+
+func TestRevenueReport(t *testing.T) {
+	db, mock, err := sqlmock.New()
+	if err != nil {
+		t.Fatalf("an error '%s' was not expected when opening a stub database connection", err)
+	}
+	defer db.Close()
+
+	expectTenantScope(mock, "acme")
+	// Un calendario anulado solo deja de contar en los cierres posteriores a su anulación
+	mock.ExpectQuery(`WITH months AS (.+) s.tenant_id = \$3 (.+) AND \(s.cancelled_at IS NULL OR s.cancelled_at >= m.month \+ interval '1 month'\)`).
+		WithArgs(*date(2026, time.January, 1), *date(2026, time.March, 1), "acme").
+		WillReturnRows(sqlmock.NewRows([]string{"month", "product", "recognized", "deferred"}).
+			AddRow(*date(2026, time.January, 1), "pro", int64(10000), int64(110000)).
+			AddRow(*date(2026, time.January, 1), "setup", int64(5000), int64(0)).
+			AddRow(*date(2026, time.February, 1), "pro", int64(10000), int64(100000)))
+	mock.ExpectCommit()
+
+	repo := repositories.NewRevenueRepository(db)
+	report, err := repo.Report(tenantContext("acme"), *date(2026, time.January, 15), *date(2026, time.March, 1))
+
+	assert.NoError(t, err)
+	assert.Len(t, report.Months, 3)
+	assert.Equal(t, "2026-01", report.Months[0].Month)
+	assert.Equal(t, 150.0, report.Months[0].Recognized)
+	assert.Equal(t, 1100.0, report.Months[0].Deferred)
+	assert.Len(t, report.Months[0].Products, 2)
+	assert.Empty(t, report.Months[2].Products)
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
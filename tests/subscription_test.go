@@ -0,0 +1,110 @@
+package tests
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"testing"
+	"time"
+
+	"sass-billing-service/src/cli"
+	"sass-billing-service/src/models"
+	"sass-billing-service/src/repositories"
+	"sass-billing-service/src/services"
+	"sass-billing-service/src/tenant"
+
+	"github.com/DATA-DOG/go-sqlmock"
+	"github.com/stretchr/testify/assert"
+)
+
+// day convierte una fecha YYYY-MM-DD
+func day(value string) time.Time {
+	parsed, _ := time.Parse("2006-01-02", value)
+	return parsed
+}
+
+func TestSubscriptionNextPeriod(t *testing.T) {
+	for _, tc := range []struct{ start, end, nextStart, nextEnd string }{
+		{"2026-01-01", "2026-01-31", "2026-02-01", "2026-02-28"},
+		{"2026-01-15", "2026-04-14", "2026-04-15", "2026-07-14"},
+		{"2026-01-01", "2026-12-31", "2027-01-01", "2027-12-31"},
+		// Sin meses completos se repite el número de días
+		{"2026-03-01", "2026-03-07", "2026-03-08", "2026-03-14"},
+		{"2026-01-31", "2026-02-27", "2026-02-28", "2026-03-27"},
+	} {
+		subscription := models.Subscription{ServiceStart: day(tc.start), ServiceEnd: day(tc.end)}
+
+		start, end := subscription.NextPeriod()
+
+		assert.Equal(t, day(tc.nextStart), start, tc.start)
+		assert.Equal(t, day(tc.nextEnd), end, tc.start)
+	}
+}
+
+func TestSubscriptionCommand(t *testing.T) {
+	db, mock, err := sqlmock.New()
+	if err != nil {
+		t.Fatalf("an error '%s' was not expected when opening a stub database connection", err)
+	}
+	defer db.Close()
+
+	invoices := repositories.NewMemoryInvoiceRepository(repositories.NewMemoryDB())
+	service := services.NewSubscriptionService(repositories.NewSubscriptionRepository(db), services.NewInvoiceService(invoices, repositories.NoTx{}))
+	expectDue := func() {
+		expectTenantScope(mock, "acme")
+		mock.ExpectQuery(`SELECT (.+) FROM invoice_lines l (.+) WHERE status NOT IN \('cancelled', 'refunded'\) AND service_end <= \$2::date`).
+			WithArgs("acme", day("2026-03-31")).
+			WillReturnRows(sqlmock.NewRows([]string{"invoice_id", "user_id", "customer_name", "customer_email", "currency", "payment_method",
+				"product", "description", "amount", "tax_rate", "service_start", "service_end"}).
+				AddRow(42, 7, "Ada", "ada@example.com", "EUR", "card", "pro", "Pro plan", 100.0, 0.21, day("2026-03-01"), day("2026-03-31")))
+		mock.ExpectCommit()
+	}
+
+	t.Run("DryRunIssuesNothing", func(t *testing.T) {
+		expectDue()
+		var out bytes.Buffer
+
+		err := cli.RunSubscription(context.Background(), service, []string{"renew", "--tenant", "acme", "--through", "2026-03-31", "--dry-run"}, &out)
+
+		assert.NoError(t, err)
+		assert.Regexp(t, `7\s+pro\s+2026-04-01..2026-04-30\s+121.00\s+EUR\s+42\s+-`, out.String())
+		assert.Contains(t, out.String(), "dry run: 1 subscriptions would be renewed")
+		assert.NoError(t, mock.ExpectationsWereMet())
+
+		_, err = invoices.GetByID(tenant.WithTenant(context.Background(), "acme"), 1)
+		assert.Error(t, err)
+	})
+
+	t.Run("RenewIssuesNextPeriod", func(t *testing.T) {
+		expectDue()
+		var out bytes.Buffer
+
+		err := cli.RunSubscription(context.Background(), service, []string{"renew", "--tenant", "acme", "--through", "2026-03-31", "--json"}, &out)
+
+		var renewals []models.Renewal
+		assert.NoError(t, err)
+		assert.NoError(t, json.Unmarshal(out.Bytes(), &renewals))
+		if assert.Len(t, renewals, 1) {
+			assert.Equal(t, 1, renewals[0].InvoiceID)
+			assert.Equal(t, 42, renewals[0].PreviousInvoiceID)
+		}
+		assert.NoError(t, mock.ExpectationsWereMet())
+
+		invoice, err := invoices.GetByID(tenant.WithTenant(context.Background(), "acme"), 1)
+		assert.NoError(t, err)
+		assert.Equal(t, 121.0, invoice.Amount)
+		assert.Equal(t, 21.0, invoice.TaxAmount)
+		assert.Equal(t, "ada@example.com", invoice.CustomerEmail)
+		if assert.Len(t, invoice.Lines, 1) {
+			assert.Equal(t, day("2026-04-01"), *invoice.Lines[0].ServiceStart)
+			assert.Equal(t, day("2026-04-30"), *invoice.Lines[0].ServiceEnd)
+		}
+	})
+
+	t.Run("RejectsBadArguments", func(t *testing.T) {
+		var out bytes.Buffer
+		for _, args := range [][]string{{}, {"cancel"}, {"renew", "--through", "31/03/2026"}} {
+			assert.Error(t, cli.RunSubscription(context.Background(), service, args, &out), args)
+		}
+	})
+}
@@ -0,0 +1,98 @@
+package tests
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"testing"
+	"time"
+
+	"sass-billing-service/src/models"
+	"sass-billing-service/src/repositories"
+	"sass-billing-service/src/tenant"
+
+	"github.com/DATA-DOG/go-sqlmock"
+	"github.com/stretchr/testify/assert"
+)
+
+func tenantContext(tenantID string) context.Context {
+	return tenant.WithTenant(context.Background(), tenantID)
+}
+
+// expectTenantScope espera la apertura de una transacción limitada al tenant
+func expectTenantScope(mock sqlmock.Sqlmock, tenantID string) {
+	mock.ExpectBegin()
+	mock.ExpectExec(`SELECT set_config\('app.tenant_id', \$1, true\)`).
+		WithArgs(tenantID).
+		WillReturnResult(sqlmock.NewResult(0, 0))
+}
+
+func TestTenantIsolation(t *testing.T) {
+	t.Run("RepositoriesRequireTenant", func(t *testing.T) {
+		db, mock, err := sqlmock.New()
+		if err != nil {
+			t.Fatalf("an error '%s' was not expected when opening a stub database connection", err)
+		}
+		defer db.Close()
+
+		ctx := context.Background()
+		invoices := repositories.NewInvoiceRepository(db)
+		idempotency := repositories.NewIdempotencyRepository(db)
+		now := time.Now()
+
+		_, err = invoices.GetByID(ctx, 1)
+		assert.ErrorIs(t, err, tenant.ErrMissingTenant)
+		_, err = invoices.GetByUserID(ctx, 7)
+		assert.ErrorIs(t, err, tenant.ErrMissingTenant)
+		_, err = invoices.List(ctx, models.InvoiceFilter{}, models.PageRequest{})
+		assert.ErrorIs(t, err, tenant.ErrMissingTenant)
+		_, err = invoices.Search(ctx, 0, "pro", 20)
+		assert.ErrorIs(t, err, tenant.ErrMissingTenant)
+		_, err = invoices.Create(ctx, &models.CreateInvoiceRequest{UserID: 7, Amount: 10})
+		assert.ErrorIs(t, err, tenant.ErrMissingTenant)
+		_, err = invoices.MarkPaid(ctx, 1)
+		assert.ErrorIs(t, err, tenant.ErrMissingTenant)
+		_, err = repositories.NewLedgerRepository(db).TrialBalance(ctx)
+		assert.ErrorIs(t, err, tenant.ErrMissingTenant)
+		_, err = repositories.NewRevenueRepository(db).Report(ctx, now, now)
+		assert.ErrorIs(t, err, tenant.ErrMissingTenant)
+		_, err = repositories.NewMetricsRepository(db).CustomerMRR(ctx, now, "USD")
+		assert.ErrorIs(t, err, tenant.ErrMissingTenant)
+		_, err = repositories.NewAgingRepository(db).Report(ctx, now)
+		assert.ErrorIs(t, err, tenant.ErrMissingTenant)
+		err = repositories.NewExportRepository(db).Stream(ctx, models.ExportFilter{}, func(*models.ExportRow) error { return nil })
+		assert.ErrorIs(t, err, tenant.ErrMissingTenant)
+		_, _, err = idempotency.Reserve(ctx, "ada", "key-1", "fingerprint", time.Hour)
+		assert.ErrorIs(t, err, tenant.ErrMissingTenant)
+
+		// Las tareas que recorren todos los tenants no aceptan el contexto de una petición
+		_, err = repositories.NewRevenueRepository(db).RecognizeThrough(tenantContext("acme"), now)
+		assert.ErrorIs(t, err, tenant.ErrMissingTenant)
+		_, err = idempotency.DeleteExpired(tenantContext("acme"))
+		assert.ErrorIs(t, err, tenant.ErrMissingTenant)
+
+		assert.NoError(t, mock.ExpectationsWereMet())
+	})
+
+	t.Run("CreateIgnoresTenantFromRequest", func(t *testing.T) {
+		db, mock, err := sqlmock.New()
+		if err != nil {
+			t.Fatalf("an error '%s' was not expected when opening a stub database connection", err)
+		}
+		defer db.Close()
+
+		var req models.CreateInvoiceRequest
+		assert.NoError(t, json.Unmarshal([]byte(`{"tenant_id":"globex","user_id":7,"amount":10,"currency":"USD"}`), &req))
+
+		expectTenantScope(mock, "acme")
+		mock.ExpectQuery(`INSERT INTO invoices`).
+			WithArgs("acme", 7, "", "", 10.0, 0.0, "USD", "", "", sqlmock.AnyArg(), sqlmock.AnyArg()).
+			WillReturnError(sql.ErrConnDone)
+		mock.ExpectRollback()
+
+		_, err = repositories.NewInvoiceRepository(db).Create(tenantContext("acme"), &req)
+
+		assert.ErrorIs(t, err, sql.ErrConnDone)
+		assert.NoError(t, mock.ExpectationsWereMet())
+	})
+}
@@ -0,0 +1,121 @@
+package tests
+
+import (
+	"context"
+	"database/sql"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"sass-billing-service/src/repositories"
+	"sass-billing-service/src/services"
+	"sass-billing-service/src/tenant"
+	"sass-billing-service/src/tracing"
+
+	"github.com/DATA-DOG/go-sqlmock"
+	"github.com/gofiber/fiber/v2"
+	"github.com/stretchr/testify/assert"
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/codes"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	"go.opentelemetry.io/otel/sdk/trace/tracetest"
+	"go.opentelemetry.io/otel/trace/noop"
+)
+
+// recordSpans instala un proveedor que guarda en memoria los spans terminados
+func recordSpans(t *testing.T) *tracetest.SpanRecorder {
+	_, err := tracing.Setup(context.Background(), tracing.ExporterNone, "", nil)
+	assert.NoError(t, err)
+
+	recorder := tracetest.NewSpanRecorder()
+	otel.SetTracerProvider(sdktrace.NewTracerProvider(sdktrace.WithSpanProcessor(recorder)))
+	t.Cleanup(func() { otel.SetTracerProvider(noop.NewTracerProvider()) })
+
+	return recorder
+}
+
+func TestTracingSpans(t *testing.T) {
+	recorder := recordSpans(t)
+
+	db, mock, err := sqlmock.New()
+	if err != nil {
+		t.Fatalf("an error '%s' was not expected when opening a stub database connection", err)
+	}
+	defer db.Close()
+
+	expectTenantScope(mock, "acme")
+	mock.ExpectQuery(`SELECT (.+) FROM invoices WHERE tenant_id = \$1 AND id = \$2`).
+		WithArgs("acme", 42).
+		WillReturnRows(sqlmock.NewRows(invoiceColumns))
+	mock.ExpectRollback()
+
+	service := services.NewInvoiceService(repositories.NewInvoiceRepository(db), repositories.NewTxManager(db, sql.LevelReadCommitted, 3))
+	app := fiber.New()
+	app.Use(tracing.Middleware())
+	app.Get("/invoices/:id", func(c *fiber.Ctx) error {
+		id, _ := c.ParamsInt("id")
+		if _, err := service.GetInvoiceByID(tenant.WithTenant(c.UserContext(), "acme"), id); err != nil {
+			return c.SendStatus(fiber.StatusNotFound)
+		}
+		return c.SendStatus(fiber.StatusOK)
+	})
+
+	req := httptest.NewRequest("GET", "/invoices/42", nil)
+	req.Header.Set("traceparent", "00-4bf92f3577b34da6a3ce929d0e0e4736-00f067aa0ba902b7-01")
+	resp, err := app.Test(req)
+	assert.NoError(t, err)
+	assert.Equal(t, fiber.StatusNotFound, resp.StatusCode)
+	assert.NoError(t, mock.ExpectationsWereMet())
+
+	spans := map[string]sdktrace.ReadOnlySpan{}
+	for _, span := range recorder.Ended() {
+		spans[span.Name()] = span
+	}
+	server, serviceSpan, query := spans["GET /invoices/:id"], spans["InvoiceService.GetInvoiceByID"], spans["invoice.GetByID"]
+	if !assert.NotNil(t, server) || !assert.NotNil(t, serviceSpan) || !assert.NotNil(t, query) {
+		return
+	}
+
+	// La traza continúa la del cliente y los spans se anidan petición > servicio > consulta
+	assert.Equal(t, "4bf92f3577b34da6a3ce929d0e0e4736", server.SpanContext().TraceID().String())
+	assert.Equal(t, "00f067aa0ba902b7", server.Parent().SpanID().String())
+	assert.Equal(t, server.SpanContext().SpanID(), serviceSpan.Parent().SpanID())
+	assert.Equal(t, serviceSpan.SpanContext().SpanID(), query.Parent().SpanID())
+	assert.Equal(t, codes.Error, serviceSpan.Status().Code)
+
+	var statement string
+	for _, attr := range query.Attributes() {
+		if attr.Key == "db.query.text" {
+			statement = attr.Value.AsString()
+		}
+	}
+	assert.Contains(t, statement, "FROM invoices WHERE tenant_id = $1 AND id = $2")
+}
+
+func TestSanitizeSQL(t *testing.T) {
+	assert.Equal(t,
+		"SELECT id FROM invoices WHERE status = ? AND amount > ? AND tenant_id = $1 LIMIT $2",
+		tracing.SanitizeSQL("SELECT id FROM invoices\n\tWHERE status = 'it''s paid' AND amount > 10.50 AND tenant_id = $1 LIMIT $2"))
+}
+
+func TestTracingTransportPropagatesContext(t *testing.T) {
+	recordSpans(t)
+
+	var traceparent string
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		traceparent = r.Header.Get("traceparent")
+	}))
+	defer server.Close()
+
+	ctx, span := tracing.Start(context.Background(), "webhook")
+	defer span.End()
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, server.URL, nil)
+	assert.NoError(t, err)
+	resp, err := (&http.Client{Transport: tracing.Transport(nil)}).Do(req)
+	assert.NoError(t, err)
+	resp.Body.Close()
+
+	assert.Contains(t, traceparent, span.SpanContext().TraceID().String())
+	assert.Empty(t, req.Header.Get("traceparent"))
+}
@@ -0,0 +1,152 @@
+package tests
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"testing"
+	"time"
+
+	"sass-billing-service/src/apperror"
+	"sass-billing-service/src/repositories"
+	"sass-billing-service/src/tenant"
+
+	"github.com/DATA-DOG/go-sqlmock"
+	"github.com/lib/pq"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestTxManager(t *testing.T) {
+	usedAt := time.Date(2026, 3, 2, 10, 0, 0, 0, time.UTC)
+	touchQuery := `UPDATE api_keys SET last_used_at = \$3`
+
+	setup := func(t *testing.T, retries int) (*repositories.TxManager, *repositories.APIKeyRepository, sqlmock.Sqlmock) {
+		db, mock, err := sqlmock.New()
+		if err != nil {
+			t.Fatalf("an error '%s' was not expected when opening a stub database connection", err)
+		}
+		t.Cleanup(func() { db.Close() })
+
+		return repositories.NewTxManager(db, sql.LevelSerializable, retries), repositories.NewAPIKeyRepository(db), mock
+	}
+
+	t.Run("RepositoriesShareTheTransaction", func(t *testing.T) {
+		manager, repo, mock := setup(t, 3)
+		expectTenantScope(mock, "acme")
+		mock.ExpectExec(touchQuery).WithArgs("acme", 1, usedAt).WillReturnResult(sqlmock.NewResult(0, 1))
+		mock.ExpectExec(touchQuery).WithArgs("acme", 2, usedAt).WillReturnResult(sqlmock.NewResult(0, 1))
+		mock.ExpectCommit()
+
+		ctx := tenant.WithTenant(context.Background(), "acme")
+		err := manager.WithinTx(ctx, func(ctx context.Context) error {
+			if err := repo.TouchLastUsed(ctx, 1, usedAt); err != nil {
+				return err
+			}
+			// Una transacción anidada se une a la exterior en lugar de abrir otra
+			return manager.WithinTx(ctx, func(ctx context.Context) error {
+				return repo.TouchLastUsed(ctx, 2, usedAt)
+			})
+		})
+
+		assert.NoError(t, err)
+		assert.NoError(t, mock.ExpectationsWereMet())
+	})
+
+	t.Run("RollsBackWhenTheFunctionFails", func(t *testing.T) {
+		manager, repo, mock := setup(t, 3)
+		expectTenantScope(mock, "acme")
+		mock.ExpectExec(touchQuery).WithArgs("acme", 1, usedAt).WillReturnResult(sqlmock.NewResult(0, 1))
+		mock.ExpectRollback()
+
+		failure := errors.New("ledger rejected the entry")
+		err := manager.WithinTx(tenant.WithTenant(context.Background(), "acme"), func(ctx context.Context) error {
+			if err := repo.TouchLastUsed(ctx, 1, usedAt); err != nil {
+				return err
+			}
+			return failure
+		})
+
+		assert.ErrorIs(t, err, failure)
+		assert.NoError(t, mock.ExpectationsWereMet())
+	})
+
+	t.Run("RetriesSerializationFailures", func(t *testing.T) {
+		manager, repo, mock := setup(t, 3)
+		expectTenantScope(mock, "acme")
+		mock.ExpectExec(touchQuery).WillReturnError(&pq.Error{Code: "40001"})
+		mock.ExpectRollback()
+		// El conflicto también puede aparecer al confirmar
+		expectTenantScope(mock, "acme")
+		mock.ExpectExec(touchQuery).WillReturnResult(sqlmock.NewResult(0, 1))
+		mock.ExpectCommit().WillReturnError(&pq.Error{Code: "40P01"})
+		expectTenantScope(mock, "acme")
+		mock.ExpectExec(touchQuery).WillReturnResult(sqlmock.NewResult(0, 1))
+		mock.ExpectCommit()
+
+		attempts := 0
+		err := manager.WithinTx(tenant.WithTenant(context.Background(), "acme"), func(ctx context.Context) error {
+			attempts++
+			return repo.TouchLastUsed(ctx, 1, usedAt)
+		})
+
+		assert.NoError(t, err)
+		assert.Equal(t, 3, attempts)
+		assert.NoError(t, mock.ExpectationsWereMet())
+	})
+
+	t.Run("GivesUpAfterTheLastRetry", func(t *testing.T) {
+		manager, repo, mock := setup(t, 0)
+		expectTenantScope(mock, "acme")
+		mock.ExpectExec(touchQuery).WillReturnError(&pq.Error{Code: "40001"})
+		mock.ExpectRollback()
+
+		err := manager.WithinTx(tenant.WithTenant(context.Background(), "acme"), func(ctx context.Context) error {
+			return repo.TouchLastUsed(ctx, 1, usedAt)
+		})
+
+		assert.Equal(t, apperror.CodeConflict, apperror.From(err).Code)
+		assert.NoError(t, mock.ExpectationsWereMet())
+	})
+
+	t.Run("OtherErrorsAreNotRetried", func(t *testing.T) {
+		manager, repo, mock := setup(t, 3)
+		expectTenantScope(mock, "acme")
+		mock.ExpectExec(touchQuery).WillReturnError(&pq.Error{Code: "23505"})
+		mock.ExpectRollback()
+
+		attempts := 0
+		err := manager.WithinTx(tenant.WithTenant(context.Background(), "acme"), func(ctx context.Context) error {
+			attempts++
+			return repo.TouchLastUsed(ctx, 1, usedAt)
+		})
+
+		assert.Error(t, err)
+		assert.Equal(t, 1, attempts)
+		assert.NoError(t, mock.ExpectationsWereMet())
+	})
+
+	t.Run("RejectsAnotherTenant", func(t *testing.T) {
+		manager, repo, mock := setup(t, 3)
+		expectTenantScope(mock, "acme")
+		mock.ExpectRollback()
+
+		err := manager.WithinTx(tenant.WithTenant(context.Background(), "acme"), func(ctx context.Context) error {
+			return repo.TouchLastUsed(tenant.WithTenant(ctx, "globex"), 1, usedAt)
+		})
+
+		assert.ErrorIs(t, err, repositories.ErrTenantMismatch)
+		assert.NoError(t, mock.ExpectationsWereMet())
+	})
+
+	t.Run("RequiresTenant", func(t *testing.T) {
+		manager, _, mock := setup(t, 3)
+
+		err := manager.WithinTx(context.Background(), func(ctx context.Context) error {
+			t.Fatal("the function must not run without a tenant")
+			return nil
+		})
+
+		assert.ErrorIs(t, err, tenant.ErrMissingTenant)
+		assert.NoError(t, mock.ExpectationsWereMet())
+	})
+}
@@ -0,0 +1,120 @@
+package tests
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"sass-billing-service/src/apperror"
+	"sass-billing-service/src/cli"
+	"sass-billing-service/src/models"
+	"sass-billing-service/src/validation"
+
+	"github.com/stretchr/testify/assert"
+)
+
+// fieldMessages indexa los errores por campo para compararlos sin depender del orden
+func fieldMessages(fields []apperror.FieldError) map[string]string {
+	messages := make(map[string]string, len(fields))
+	for _, field := range fields {
+		messages[field.Field] = field.Message
+	}
+	return messages
+}
+
+func TestValidation(t *testing.T) {
+	t.Run("ValidInvoice", func(t *testing.T) {
+		start := time.Date(2026, 3, 1, 0, 0, 0, 0, time.UTC)
+		end := start.AddDate(0, 1, -1)
+		req := models.CreateInvoiceRequest{
+			UserID:        7,
+			CustomerEmail: "ada@example.com",
+			Amount:        121,
+			TaxAmount:     21,
+			Description:   "Pro plan",
+			PaymentMethod: "card",
+			Lines: []models.CreateInvoiceLineRequest{
+				{Product: "pro", Amount: 100, ServiceStart: &start, ServiceEnd: &end},
+			},
+		}
+		req.Normalize()
+
+		assert.Equal(t, models.DefaultCurrency, req.Currency)
+		assert.NoError(t, validation.Struct(&req))
+	})
+
+	t.Run("ReportsEveryField", func(t *testing.T) {
+		end := time.Date(2026, 3, 1, 0, 0, 0, 0, time.UTC)
+		req := models.CreateInvoiceRequest{
+			Amount:        -5,
+			TaxAmount:     -1,
+			Currency:      "us1",
+			CustomerEmail: "not an email",
+			PaymentMethod: "card",
+			Lines: []models.CreateInvoiceLineRequest{
+				{Product: "pro", Amount: 10},
+				{Amount: 0, ServiceEnd: &end},
+			},
+		}
+
+		assert.Equal(t, map[string]string{
+			"user_id":              "is required",
+			"customer_email":       "must be a valid email address",
+			"amount":               "must be greater than 0",
+			"tax_amount":           "must be at least 0",
+			"currency":             "must be a 3-letter ISO 4217 code",
+			"description":          "is required",
+			"lines[1].product":     "is required",
+			"lines[1].amount":      "is required",
+			"lines[1].service_end": "service period requires both start and end",
+		}, fieldMessages(validation.Fields(&req)))
+
+		var appErr *apperror.Error
+		assert.True(t, errors.As(validation.Struct(&req), &appErr))
+		assert.Equal(t, apperror.CodeValidationFailed, appErr.Code)
+	})
+
+	t.Run("CrossFieldRules", func(t *testing.T) {
+		req := models.CreateInvoiceRequest{
+			UserID: 7, Amount: 100, TaxAmount: 21, Currency: "EUR", Description: "Pro", PaymentMethod: "card",
+			Lines: []models.CreateInvoiceLineRequest{{Product: "pro", Amount: 50}},
+		}
+		assert.Equal(t, map[string]string{"lines": "must add up to amount minus tax_amount"}, fieldMessages(validation.Fields(&req)))
+
+		req.TaxAmount, req.Lines = 101, nil
+		assert.Equal(t, map[string]string{"tax_amount": "must not exceed amount"}, fieldMessages(validation.Fields(&req)))
+	})
+
+	t.Run("APIKeyRequest", func(t *testing.T) {
+		past := time.Now().Add(-time.Hour)
+		req := models.CreateAPIKeyRequest{Name: string(make([]byte, 101)), Scopes: []string{"invoices:read", ""}, ExpiresAt: &past}
+
+		assert.Equal(t, map[string]string{
+			"name":       "must be at most 100 characters",
+			"scopes[1]":  "is required",
+			"expires_at": "must be in the future",
+		}, fieldMessages(validation.Fields(&req)))
+	})
+
+	t.Run("Var", func(t *testing.T) {
+		assert.Empty(t, validation.Var("limit", 20, models.PageLimitRules))
+		assert.Equal(t, []apperror.FieldError{{Field: "limit", Message: "must be at most 100"}}, validation.Var("limit", 500, models.PageLimitRules))
+		assert.Equal(t, []apperror.FieldError{{Field: "status", Message: "must be one of pending, paid"}}, validation.Var("status", "void", "oneof=pending paid"))
+	})
+
+	t.Run("CLIUsesTheSameRules", func(t *testing.T) {
+		var out bytes.Buffer
+		err := cli.RunInvoice(context.Background(), nil, []string{"create", "--user", "7", "--amount", "10", "--currency", "EURO"}, &out)
+
+		var appErr *apperror.Error
+		assert.True(t, errors.As(err, &appErr))
+		assert.Equal(t, map[string]string{
+			"currency":       "must be a 3-letter ISO 4217 code",
+			"description":    "is required",
+			"payment_method": "is required",
+		}, fieldMessages(appErr.Fields))
+		assert.Contains(t, err.Error(), "currency must be a 3-letter ISO 4217 code")
+	})
+}